@@ -0,0 +1,405 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Commands accepted by POST /command and WebSocket clients
+const (
+	CommandPause      = "pause"
+	CommandResume     = "resume"
+	CommandSeek       = "seek"
+	CommandSpeed      = "speed"
+	CommandHUD        = "hud"
+	CommandScreenshot = "screenshot"
+)
+
+// Status values of State
+const (
+	StatusIdle    = "idle"
+	StatusPlaying = "playing"
+	StatusPaused  = "paused"
+)
+
+// State is sent to WebSocket clients and returned by GET /state
+type State struct {
+	Status string    `json:"status"`
+	Map    *MapState `json:"map,omitempty"`
+
+	Time   float64 `json:"time"`   // in milliseconds
+	Length float64 `json:"length"` // in milliseconds
+	Speed  float64 `json:"speed"`  // playback rate set with speed command, 1 keeps -speed and mods' rate
+	HUD    bool    `json:"hud"`
+
+	Players []PlayerState `json:"players"`
+}
+
+type MapState struct {
+	Artist     string `json:"artist"`
+	Title      string `json:"title"`
+	Difficulty string `json:"difficulty"`
+	Creator    string `json:"creator"`
+	MD5        string `json:"md5"`
+	ID         int64  `json:"id"`
+	SetID      int64  `json:"setId"`
+}
+
+type PlayerState struct {
+	Name     string  `json:"name"`
+	Score    int64   `json:"score"`
+	Accuracy float64 `json:"accuracy"` // in percent
+	Combo    int64   `json:"combo"`
+	PP       float64 `json:"pp"`
+	HP       float64 `json:"hp"`    // from 0 to 1
+	Alive    bool    `json:"alive"` // false only for players knocked out in knockout
+}
+
+type Command struct {
+	Command string  `json:"command"`
+	Time    float64 `json:"time,omitempty"`  // seek target in milliseconds
+	Speed   float64 `json:"speed,omitempty"` // playback rate
+}
+
+type Result struct {
+	Command string `json:"command"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+}
+
+// message wraps everything sent over WebSocket so clients can tell states from command results
+type message struct {
+	Type   string  `json:"type"` // "state" or "result"
+	State  *State  `json:"state,omitempty"`
+	Result *Result `json:"result,omitempty"`
+}
+
+// Source is the state that's published and controlled through the API, its methods are called from HTTP goroutines
+type Source interface {
+	GetAPIState() State
+	Pause() error
+	Resume() error
+	Seek(time float64) error
+	SetSpeed(speed float64) error
+	ToggleHUD()
+}
+
+var mutex = &sync.Mutex{}
+
+var source Source
+var screenshotHandler func()
+
+var server *http.Server
+var clients map[*wsConn]bool
+var stop chan struct{}
+
+// Start starts the HTTP and WebSocket server if it's enabled in settings
+func Start() {
+	if !settings.General.APIEnabled {
+		return
+	}
+
+	listener, err := net.Listen("tcp", settings.General.APIAddress)
+	if err != nil {
+		log.Println("API: Failed to start:", err)
+		return
+	}
+
+	log.Println("API: Listening on http://" + listener.Addr().String())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state", handleState)
+	mux.HandleFunc("/command", handleCommand)
+	mux.HandleFunc("/ws", handleWebSocket)
+
+	mutex.Lock()
+	server = &http.Server{Handler: mux}
+	clients = make(map[*wsConn]bool)
+	stop = make(chan struct{})
+	mutex.Unlock()
+
+	goroutines.Run(func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Println("API:", err)
+		}
+	})
+
+	goroutines.Run(broadcastLoop)
+}
+
+func Stop() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if server == nil {
+		return
+	}
+
+	close(stop)
+
+	_ = server.Close()
+
+	for c := range clients {
+		_ = c.Close()
+	}
+
+	server = nil
+	clients = nil
+}
+
+// SetSource sets what's published and controlled, nil makes danser idle
+func SetSource(s Source) {
+	mutex.Lock()
+	source = s
+	mutex.Unlock()
+}
+
+func SetScreenshotHandler(fn func()) {
+	mutex.Lock()
+	screenshotHandler = fn
+	mutex.Unlock()
+}
+
+func getState() State {
+	mutex.Lock()
+	s := source
+	mutex.Unlock()
+
+	if s == nil {
+		return State{Status: StatusIdle, Speed: 1, Players: []PlayerState{}}
+	}
+
+	return s.GetAPIState()
+}
+
+func execute(cmd Command) Result {
+	mutex.Lock()
+	s, screenshot := source, screenshotHandler
+	mutex.Unlock()
+
+	var err error
+
+	switch {
+	case cmd.Command == CommandScreenshot && screenshot != nil:
+		screenshot()
+	case cmd.Command == CommandScreenshot:
+		err = errors.New("screenshots are not available")
+	case s == nil:
+		err = errors.New("nothing is playing")
+	case cmd.Command == CommandPause:
+		err = s.Pause()
+	case cmd.Command == CommandResume:
+		err = s.Resume()
+	case cmd.Command == CommandSeek:
+		err = s.Seek(cmd.Time)
+	case cmd.Command == CommandSpeed:
+		err = s.SetSpeed(cmd.Speed)
+	case cmd.Command == CommandHUD:
+		s.ToggleHUD()
+	default:
+		err = fmt.Errorf("unknown command: %q", cmd.Command)
+	}
+
+	result := Result{Command: cmd.Command, OK: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// isLocalOrigin checks whether request comes from a page that's allowed to control danser, other websites can only read the state.
+// Requests without Origin come from tools other than browsers. "null" is rejected as sandboxed frames of any website send it too.
+func isLocalOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func handleState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Browser sources of streaming software have to be able to read it
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	writeJSON(w, http.StatusOK, getState())
+}
+
+func handleCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isLocalOrigin(r) {
+		http.Error(w, "commands are accepted only from local pages", http.StatusForbidden)
+		return
+	}
+
+	var cmd Command
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMessageSize)).Decode(&cmd); err != nil {
+		writeJSON(w, http.StatusBadRequest, Result{Error: "invalid command: " + err.Error()})
+		return
+	}
+
+	result := execute(cmd)
+
+	status := http.StatusOK
+	if !result.OK {
+		status = http.StatusBadRequest
+	}
+
+	writeJSON(w, status, result)
+}
+
+func handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Errors are already sent to the client by upgrade if it was possible
+	conn, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+
+	local := isLocalOrigin(r)
+
+	mutex.Lock()
+
+	if clients == nil {
+		mutex.Unlock()
+		_ = conn.Close()
+
+		return
+	}
+
+	clients[conn] = true
+
+	mutex.Unlock()
+
+	// New clients don't have to wait for the next broadcast
+	state := getState()
+	send(conn, message{Type: "state", State: &state})
+
+	goroutines.Run(func() {
+		defer removeClient(conn)
+
+		for {
+			data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var cmd Command
+
+			var result Result
+
+			switch {
+			case !local:
+				result = Result{Error: "commands are accepted only from local pages"}
+			case json.Unmarshal(data, &cmd) != nil:
+				result = Result{Error: "invalid command"}
+			default:
+				result = execute(cmd)
+			}
+
+			send(conn, message{Type: "result", Result: &result})
+		}
+	})
+}
+
+func send(conn *wsConn, msg message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	if conn.WriteText(data) != nil {
+		removeClient(conn)
+	}
+}
+
+func removeClient(conn *wsConn) {
+	mutex.Lock()
+	delete(clients, conn)
+	mutex.Unlock()
+
+	_ = conn.Close()
+}
+
+// broadcastLoop sends the state to WebSocket clients General.APIUpdateRate times per second
+func broadcastLoop() {
+	rate := settings.General.APIUpdateRate
+	if rate <= 0 {
+		rate = 1
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		mutex.Lock()
+
+		conns := make([]*wsConn, 0, len(clients))
+		for c := range clients {
+			conns = append(conns, c)
+		}
+
+		mutex.Unlock()
+
+		if len(conns) == 0 {
+			continue
+		}
+
+		state := getState()
+
+		data, err := json.Marshal(message{Type: "state", State: &state})
+		if err != nil {
+			continue
+		}
+
+		for _, c := range conns {
+			if c.WriteText(data) != nil {
+				removeClient(c)
+			}
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Minimal RFC 6455 server side, enough for JSON text messages
+
+const (
+	wsGUID    = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	wsVersion = "13"
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Commands are small, anything bigger is most likely not a danser client
+const maxMessageSize = 1 << 16
+
+const writeTimeout = time.Second
+
+var errClosed = errors.New("connection closed")
+
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMutex *sync.Mutex
+}
+
+// upgrade performs WebSocket handshake and takes over the connection.
+// Handshake errors are answered with an HTTP error, once the connection is taken over it's only closed.
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	fail := func(status int, message string) (*wsConn, error) {
+		http.Error(w, message, status)
+		return nil, errors.New(message)
+	}
+
+	if r.Method != http.MethodGet {
+		return fail(http.StatusMethodNotAllowed, "method not allowed")
+	}
+
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, "not a websocket handshake")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != wsVersion {
+		w.Header().Set("Sec-WebSocket-Version", wsVersion)
+		return fail(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return fail(http.StatusInternalServerError, "connection can't be hijacked")
+	}
+
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha1.Sum([]byte(key + wsGUID))
+
+	_, err = fmt.Fprintf(buf, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(hash[:]))
+	if err == nil {
+		err = buf.Flush()
+	}
+
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &wsConn{
+		conn:       conn,
+		reader:     buf.Reader,
+		writeMutex: &sync.Mutex{},
+	}, nil
+}
+
+func headerContains(header http.Header, name, value string) bool {
+	for _, v := range header.Values(name) {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (ws *wsConn) WriteText(data []byte) error {
+	return ws.writeFrame(opText, data)
+}
+
+func (ws *wsConn) writeFrame(opcode byte, payload []byte) error {
+	ws.writeMutex.Lock()
+	defer ws.writeMutex.Unlock()
+
+	header := make([]byte, 10)
+	header[0] = 0x80 | opcode // FIN, server frames are never fragmented
+
+	switch {
+	case len(payload) < 126:
+		header[1] = byte(len(payload))
+		header = header[:2]
+	case len(payload) <= 0xFFFF:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+		header = header[:4]
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(len(payload)))
+	}
+
+	_ = ws.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	_, err := ws.conn.Write(append(header, payload...))
+
+	return err
+}
+
+// ReadMessage returns the next text or binary message, control frames are answered on the way
+func (ws *wsConn) ReadMessage() ([]byte, error) {
+	var message []byte
+
+	fragmented := false
+
+	for {
+		fin, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case opPing:
+			if err = ws.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+
+			continue
+		case opPong:
+			continue
+		case opClose:
+			_ = ws.writeFrame(opClose, nil)
+			return nil, errClosed
+		case opText, opBinary, opContinuation:
+			if (opcode == opContinuation) != fragmented {
+				return nil, errors.New("invalid message fragmentation")
+			}
+
+			fragmented = !fin
+
+			message = append(message, payload...)
+		default:
+			return nil, fmt.Errorf("unknown opcode: %d", opcode)
+		}
+
+		if len(message) > maxMessageSize {
+			return nil, errors.New("message too big")
+		}
+
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+func (ws *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+
+	if _, err = io.ReadFull(ws.reader, header[:]); err != nil {
+		return
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+
+	// No extensions are negotiated so reserved bits have to be 0
+	if header[0]&0x70 != 0 {
+		err = errors.New("reserved bits are set")
+		return
+	}
+
+	if header[1]&0x80 == 0 {
+		err = errors.New("client frames have to be masked")
+		return
+	}
+
+	length := uint64(header[1] & 0x7F)
+
+	if opcode&0x8 != 0 && (!fin || length > 125) {
+		err = errors.New("invalid control frame")
+		return
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(ws.reader, ext[:]); err != nil {
+			return
+		}
+
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(ws.reader, ext[:]); err != nil {
+			return
+		}
+
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if length > maxMessageSize {
+		err = errors.New("message too big")
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(ws.reader, mask[:]); err != nil {
+		return
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.reader, payload); err != nil {
+		return
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return
+}
+
+func (ws *wsConn) Close() error {
+	return ws.conn.Close()
+}
@@ -2,6 +2,7 @@ package app
 
 import "C"
 import (
+	"bufio"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/host"
 	"github.com/shirou/gopsutil/mem"
+	"github.com/wieku/danser-go/app/api"
 	"github.com/wieku/danser-go/app/audio"
 	"github.com/wieku/danser-go/app/beatmap"
 	difficulty2 "github.com/wieku/danser-go/app/beatmap/difficulty"
@@ -19,10 +21,14 @@ import (
 	"github.com/wieku/danser-go/app/database"
 	"github.com/wieku/danser-go/app/discord"
 	"github.com/wieku/danser-go/app/ffmpeg"
+	"github.com/wieku/danser-go/app/hitlog"
 	"github.com/wieku/danser-go/app/input"
+	"github.com/wieku/danser-go/app/lint"
+	"github.com/wieku/danser-go/app/progress"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/states"
 	"github.com/wieku/danser-go/app/utils"
+	"github.com/wieku/danser-go/app/verify"
 	"github.com/wieku/danser-go/build"
 	"github.com/wieku/danser-go/framework/assets"
 	"github.com/wieku/danser-go/framework/bass"
@@ -46,10 +52,13 @@ import (
 	"io/ioutil"
 	"log"
 	"math"
+	"math/rand"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
 )
@@ -78,12 +87,20 @@ var lastVSync bool
 
 var output string
 
+var hitLog *hitlog.Logger
+
 var recordMode bool
 var screenshotMode bool
 var screenshotTime float64
 
 var preciseProgress bool
 
+var chunkIndex, chunkCount int
+
+var queueMode bool
+
+var logFile *os.File
+
 func run() {
 	defer func() {
 		if err := recover(); err != nil {
@@ -92,6 +109,9 @@ func run() {
 		}
 	}()
 
+	var jobs []*queueJob
+	var queue *renderQueue
+
 	mainthread.Call(func() {
 		id := flag.Int64("id", -1, "Specify the beatmap id. Overrides other beatmap search flags")
 
@@ -153,6 +173,29 @@ func run() {
 
 		flag.BoolVar(&preciseProgress, "preciseprogress", false, "Show rendering progress in 1% increments")
 
+		hitLogFormat := flag.String("hitlog", "", "Save every judgement to a JSON Lines (-hitlog=jsonl) or CSV (-hitlog=csv) file next to the video, or in the working directory when not recording. Overrides Recording.HitEventLog setting")
+
+		verifyReplay := flag.Bool("verify", false, "Simulate the replay given by -replay without opening a window and compare the computed score with the one stored in it")
+
+		exportPath := flag.String("export", "", "Write the beatmap with applied -mods, -ar/-od/-cs/-hp and -speed to a given .osu file and exit. Audio isn't rate-changed")
+
+		lintFormat := flag.String("lint", "", "Check the beatmap for mapping problems, print a report in a given format (-lint=text or -lint=json) and exit")
+
+		seed := flag.Int64("seed", -1, "Seed for the Random (RN) mod, negative picks a random one. Used seed is logged so the map can be reproduced")
+
+		mirror := flag.String("mirror", "h", "Reflection used by the Mirror (MR) mod: h, v or both")
+
+		chunks := flag.Int("chunks", 1, "Split the recording into N parts rendered at the same time by separate danser processes and join them at the end")
+		chunk := flag.String("chunk", "", "Used internally by -chunks. Render only i-th of n parts of the video, given as i/n")
+
+		queuePath := flag.String("queue", "", "Record all jobs from a given JSON file one after another, see README for the format. Beatmap, replay, mods and -out flags are taken from jobs")
+
+		progressFormat := flag.String("progress", "text", "Format of recording progress: text or json. With json, stage and progress events are written to stdout as JSON lines and logs are moved to stderr")
+
+		storyboardMode := flag.Bool("storyboard", false, "Play only map's storyboard and video with the music, without objects, cursors and HUD. Works with -record and -ss")
+
+		gameMode := flag.String("mode", "osu", "Game mode used to play the map: osu, taiko, catch or mania. osu!standard maps are converted. Overridden by -replay")
+
 		flag.Parse()
 
 		var knockoutReplays []string
@@ -165,6 +208,20 @@ func run() {
 			*knockout = true
 		}
 
+		switch *progressFormat {
+		case "text":
+		case "json":
+			progress.Enable()
+
+			if logFile != nil {
+				log.SetOutput(io.MultiWriter(progress.LogOutput(), logFile))
+			} else {
+				log.SetOutput(progress.LogOutput())
+			}
+		default:
+			panic(fmt.Sprintf("Unknown progress format: %s", *progressFormat))
+		}
+
 		if !*noUpdCheck {
 			checkForUpdates()
 		}
@@ -176,10 +233,22 @@ func run() {
 			}
 		}
 
+		queueMode = *queuePath != ""
+
+		if queueMode {
+			*record = true
+		}
+
 		recordMode = *record
 		screenshotMode = !math.IsNaN(*ss)
 		screenshotTime = *ss
 
+		if *chunk != "" {
+			if _, err := fmt.Sscanf(*chunk, "%d/%d", &chunkIndex, &chunkCount); err != nil || chunkIndex < 0 || chunkIndex >= chunkCount {
+				panic(fmt.Sprintf("Invalid chunk: %s", *chunk))
+			}
+		}
+
 		if *record && *play {
 			panic("Incompatible flags selected: -record, -play")
 		} else if *replay != "" && *play {
@@ -192,43 +261,106 @@ func run() {
 			panic("Incompatible flags selected: -ss, -play")
 		} else if screenshotMode && recordMode {
 			panic("Incompatible flags selected: -ss, -record")
+		} else if *verifyReplay && *replay == "" {
+			panic("-verify requires -replay to be specified")
+		} else if *verifyReplay && (recordMode || screenshotMode) {
+			panic("Incompatible flags selected: -verify, -record/-ss")
+		} else if *exportPath != "" && (recordMode || screenshotMode || *play || *verifyReplay) {
+			panic("Incompatible flags selected: -export, -record/-ss/-play/-verify")
+		} else if *lintFormat != "" && (recordMode || screenshotMode || *play || *verifyReplay || *exportPath != "") {
+			panic("Incompatible flags selected: -lint, -record/-ss/-play/-verify/-export")
+		} else if *lintFormat != "" && *lintFormat != "text" && *lintFormat != "json" {
+			panic(fmt.Sprintf("Unknown lint report format: %s", *lintFormat))
+		} else if (*chunks > 1 || chunkCount > 0) && !recordMode {
+			panic("-chunks requires -record")
+		} else if *chunks > 1 && chunkCount > 0 {
+			panic("Incompatible flags selected: -chunks, -chunk")
+		} else if *storyboardMode && (*play || *knockout || *replay != "" || *verifyReplay || *exportPath != "" || *lintFormat != "" || queueMode) {
+			panic("Incompatible flags selected: -storyboard, -play/-knockout/-replay/-verify/-export/-lint/-queue")
+		} else if queueMode && (screenshotMode || *play || *knockout || *replay != "" || *out != "" || *verifyReplay || *exportPath != "" || *lintFormat != "" || *chunks > 1 || chunkCount > 0) {
+			panic("Incompatible flags selected: -queue, -ss/-play/-knockout/-replay/-out/-verify/-export/-lint/-chunks")
 		}
 
-		modsParsed := difficulty2.ParseMods(*mods)
+		if queueMode {
+			jobs = loadQueue(*queuePath)
+		}
 
-		if *replay != "" {
-			bytes, err := ioutil.ReadFile(*replay)
-			if err != nil {
-				panic(err)
-			}
+		switch strings.ToLower(*gameMode) {
+		case "osu", "std", "standard":
+			settings.MODE = settings.ModeOsu
+		case "taiko":
+			settings.MODE = settings.ModeTaiko
+		case "catch", "fruits", "ctb":
+			settings.MODE = settings.ModeCatch
+		case "mania":
+			settings.MODE = settings.ModeMania
+		default:
+			panic(fmt.Sprintf("Unknown game mode: %s", *gameMode))
+		}
 
-			rp, err := rplpa.ParseReplay(bytes)
-			if err != nil {
-				panic(err)
-			}
+		var reflection difficulty2.Reflection
+
+		switch strings.ToLower(*mirror) {
+		case "h", "horizontal":
+			reflection = difficulty2.ReflectHorizontal
+		case "v", "vertical":
+			reflection = difficulty2.ReflectVertical
+		case "both":
+			reflection = difficulty2.ReflectBoth
+		default:
+			panic(fmt.Sprintf("Unknown mirror reflection: %s", *mirror))
+		}
 
-			if rp.PlayMode != 0 {
-				panic("Modes other than osu!standard are not supported")
-			}
+		modsParsed, modParams, modsErr := difficulty2.ParseModsWithParams(*mods)
+		if modsErr != nil {
+			panic(fmt.Sprintf("Failed to parse mods: %s", modsErr))
+		}
 
-			if rp.ReplayData == nil || len(rp.ReplayData) < 2 {
-				panic("Replay is missing input data")
-			}
+		// DifficultyAdjust values given in -mods work the same as -ar, -od, -cs and -hp, flags take precedence
+		if math.IsNaN(*ar) {
+			*ar = modParams.AR
+		}
+
+		if math.IsNaN(*od) {
+			*od = modParams.OD
+		}
+
+		if math.IsNaN(*cs) {
+			*cs = modParams.CS
+		}
+
+		if math.IsNaN(*hp) {
+			*hp = modParams.HP
+		}
+
+		if *replay != "" {
+			rp := readReplay(*replay)
+
+			settings.MODE = int(rp.PlayMode)
 
 			*md5 = rp.BeatmapMD5
 			*id = -1
 			modsParsed = difficulty2.Modifier(rp.Mods)
+			modParams.Rate = difficulty2.RateAdjust{}
 			*knockout = true
 			settings.REPLAY = *replay
 		}
 
+		if settings.MODE != settings.ModeOsu && *play {
+			panic("-play supports only osu!standard")
+		} else if settings.MODE != settings.ModeOsu && *verifyReplay {
+			panic("-verify supports only osu!standard")
+		} else if settings.MODE != settings.ModeOsu && settings.MODE != settings.ModeCatch && *knockout && settings.REPLAY == "" {
+			panic("-knockout supports only osu!standard and osu!catch")
+		}
+
 		if !modsParsed.Compatible() {
 			panic("Incompatible mods selected!")
 		}
 
 		closeAfterSettingsLoad := false
 
-		if (*md5+*artist+*title+*difficulty+*creator) == "" && *id < 0 {
+		if (*md5+*artist+*title+*difficulty+*creator) == "" && *id < 0 && !queueMode {
 			log.Println("No beatmap specified, closing...")
 			closeAfterSettingsLoad = true
 		}
@@ -237,6 +369,7 @@ func run() {
 		settings.KNOCKOUT = *knockout
 		settings.KNOCKOUTREPLAYS = knockoutReplays
 		settings.PLAY = *play
+		settings.STORYBOARD = *storyboardMode
 		settings.DIVIDES = *cursors
 		settings.TAG = *tag
 		settings.SPEED = *speed
@@ -247,12 +380,18 @@ func run() {
 		settings.RECORD = recordMode || screenshotMode
 		settings.LOCALOFFSET = *offset
 
-		if *settingsVersion == "credentials" || *settingsVersion == "launcher" {
-			panic(fmt.Sprintf("flag -settings: name \"%s\" is forbidden", *settingsVersion))
+		if err := checkSettingsName(*settingsVersion); err != nil {
+			panic(fmt.Sprintf("flag -settings: %s", err))
 		}
 
 		newSettings := settings.LoadSettings(*settingsVersion)
 
+		if *hitLogFormat != "" {
+			settings.Recording.HitEventLog = *hitLogFormat
+		}
+
+		checkReplaySaving()
+
 		if !newSettings && len(os.Args) == 1 {
 			platform.OpenURL("https://youtu.be/dQw4w9WgXcQ")
 			closeAfterSettingsLoad = true
@@ -260,67 +399,76 @@ func run() {
 
 		player = nil
 		var beatMap *beatmap.BeatMap = nil
+		var beatmaps []*beatmap.BeatMap
 
 		if !closeAfterSettingsLoad {
+			progress.Send(progress.Event{Stage: progress.Database})
+
 			err := database.Init()
 			if err != nil {
 				log.Println("Failed to initialize database:", err)
 			} else {
-				beatmaps := database.LoadBeatmaps(*noDbCheck, nil)
-
-				if *id > -1 {
-					for _, b := range beatmaps {
-						if b.ID == *id {
-							beatMap = b
+				beatmaps = database.LoadBeatmaps(*noDbCheck, nil)
 
-							break
-						}
-					}
-				} else if *md5 != "" {
-					for _, b := range beatmaps {
-						if strings.EqualFold(b.MD5, *md5) {
-							beatMap = b
-
-							break
-						}
-					}
-				} else {
-					for _, b := range beatmaps {
-						if (*artist == "" || strings.EqualFold(*artist, b.Artist)) &&
-							(*title == "" || strings.EqualFold(*title, b.Name)) &&
-							(*difficulty == "" || strings.EqualFold(*difficulty, b.Difficulty)) &&
-							(*creator == "" || strings.EqualFold(*creator, b.Creator)) {
-							beatMap = b
-
-							break
-						}
-					}
-
-					if beatMap == nil {
-						log.Println("Beatmap with exact parameters not found, searching partially...")
-						for _, b := range beatmaps {
-							if (*artist == "" || strings.Contains(strings.ToLower(b.Artist), strings.ToLower(*artist))) &&
-								(*title == "" || strings.Contains(strings.ToLower(b.Name), strings.ToLower(*title))) &&
-								(*difficulty == "" || strings.Contains(strings.ToLower(b.Difficulty), strings.ToLower(*difficulty))) &&
-								(*creator == "" || strings.Contains(strings.ToLower(b.Creator), strings.ToLower(*creator))) {
-								beatMap = b
-
-								break
-							}
-						}
-					}
+				if !queueMode {
+					beatMap = findBeatmap(beatmaps, *id, *md5, *artist, *title, *difficulty, *creator)
 				}
 			}
 
-			if beatMap == nil {
+			if queueMode {
+				// Database stays open to update play stats of every job
+				if beatmaps == nil {
+					closeAfterSettingsLoad = true
+				}
+			} else if beatMap == nil {
 				log.Println("Beatmap not found, closing...")
+
+				progress.Send(progress.Event{Stage: progress.Error, Error: "Beatmap not found"})
+
 				closeAfterSettingsLoad = true
 			} else {
 				beatMap.UpdatePlayStats()
 				database.UpdatePlayStats(beatMap)
+
+				beatMap.Diff.Seed = *seed
+				beatMap.Diff.Reflection = reflection
 			}
 
-			database.Close()
+			if !queueMode {
+				database.Close()
+			}
+		}
+
+		if *verifyReplay {
+			if closeAfterSettingsLoad {
+				os.Exit(1)
+			}
+
+			runVerification(beatMap)
+		}
+
+		if *exportPath != "" {
+			if closeAfterSettingsLoad {
+				os.Exit(1)
+			}
+
+			runExport(beatMap, *exportPath, modsParsed, modParams.Rate, *ar, *od, *cs, *hp)
+		}
+
+		if *lintFormat != "" {
+			if closeAfterSettingsLoad {
+				os.Exit(1)
+			}
+
+			runLint(beatMap, *lintFormat)
+		}
+
+		if *chunks > 1 {
+			if closeAfterSettingsLoad {
+				os.Exit(1)
+			}
+
+			runChunks(*chunks, *seed)
 		}
 
 		assets.Init(build.Stream == "Dev")
@@ -377,14 +525,16 @@ func run() {
 		}
 
 		if settings.RECORD {
-			//HACK: some in-app variables depend on these settings so we force them here
-			settings.Graphics.VSync = false
-			settings.Graphics.ShowFPS = false
-			settings.DEBUG = false
-			settings.Graphics.Fullscreen = false
-			settings.Graphics.WindowWidth = int64(settings.Recording.FrameWidth)
-			settings.Graphics.WindowHeight = int64(settings.Recording.FrameHeight)
-			settings.Playfield.LeadInTime = 0
+			forceRecordSettings()
+		}
+
+		if settings.STORYBOARD {
+			// Storyboard is shown as it is in osu!, without effects following the cursor
+			settings.Playfield.Background.LoadStoryboards = true
+			settings.Playfield.Background.LoadVideos = true
+			settings.Playfield.Background.Blur.Enabled = false
+			settings.Playfield.Background.Parallax.Amount = 0
+			settings.Playfield.Background.Triangles.Enabled = false
 		}
 
 		if screenshotMode {
@@ -415,7 +565,12 @@ func run() {
 			})
 		}
 
-		win.SetTitle("danser " + build.VERSION + " - " + beatMap.Artist + " - " + beatMap.Name + " [" + beatMap.Difficulty + "]")
+		if queueMode {
+			win.SetTitle("danser " + build.VERSION + " - " + *queuePath)
+		} else {
+			win.SetTitle("danser " + build.VERSION + " - " + beatMap.Artist + " - " + beatMap.Name + " [" + beatMap.Difficulty + "]")
+		}
+
 		input.Win = win
 
 		icon, eee := assets.GetPixmap("assets/textures/dansercoin.png")
@@ -486,6 +641,15 @@ func run() {
 
 		if !settings.RECORD {
 			discord.Connect()
+
+			api.SetScreenshotHandler(func() {
+				mainthread.CallNonBlock(func() {
+					scheduleScreenshot = true
+				})
+			})
+
+			api.Start()
+
 			win.Show()
 		}
 
@@ -517,49 +681,28 @@ func run() {
 		bass.Init(settings.RECORD)
 		audio.LoadSamples()
 
-		speedBefore := settings.SPEED
+		limiter = frame.NewLimiter(int(settings.Graphics.FPSCap))
 
-		if modsParsed.Active(difficulty2.Nightcore) {
-			settings.SPEED *= 1.5
-			settings.PITCH *= 1.5
-		} else if modsParsed.Active(difficulty2.DoubleTime) {
-			settings.SPEED *= 1.5
-		} else if modsParsed.Active(difficulty2.Daycore) {
-			settings.PITCH *= 0.75
-			settings.SPEED *= 0.75
-		} else if modsParsed.Active(difficulty2.HalfTime) {
-			settings.SPEED *= 0.75
+		if queueMode {
+			queue = newRenderQueue(*queuePath, jobs, beatmaps, *settingsVersion, *hitLogFormat, reflection, *quickstart)
+			return
 		}
 
-		if settings.PLAY || !settings.KNOCKOUT || allowDA {
-			if !math.IsNaN(*ar) {
-				beatMap.Diff.SetARCustom(*ar)
-			}
-
-			if !math.IsNaN(*od) {
-				beatMap.Diff.SetODCustom(*od)
-			}
-
-			if !math.IsNaN(*cs) {
-				beatMap.Diff.SetCSCustom(*cs)
-			}
+		progress.Send(progress.Event{Stage: progress.Loading})
 
-			if !math.IsNaN(*hp) {
-				beatMap.Diff.SetHPCustom(*hp)
-			}
+		loadPlayer(beatMap, modsParsed, modParams.Rate, *ar, *od, *cs, *hp, allowDA)
 
-			beatMap.Diff.SetCustomSpeed(speedBefore)
+		// Only the first chunk simulates the whole map
+		if !screenshotMode && chunkIndex == 0 {
+			startHitLog()
 		}
-
-		beatMap.Diff.SetMods(modsParsed)
-		beatmap.ParseTimingPointsAndPauses(beatMap)
-		beatmap.ParseObjects(beatMap, false, true)
-		beatMap.LoadCustomSamples()
-		player = states.NewPlayer(beatMap)
-
-		limiter = frame.NewLimiter(int(settings.Graphics.FPSCap))
 	})
 
+	if queue != nil {
+		queue.run()
+		return
+	}
+
 	if recordMode {
 		mainLoopRecord()
 	} else if screenshotMode {
@@ -567,6 +710,142 @@ func run() {
 	} else {
 		mainLoopNormal()
 	}
+
+	closeHitLog()
+}
+
+// forceRecordSettings overrides settings which some in-app variables depend on while recording
+func forceRecordSettings() {
+	//HACK: some in-app variables depend on these settings so we force them here
+	settings.Graphics.VSync = false
+	settings.Graphics.ShowFPS = false
+	settings.DEBUG = false
+	settings.Graphics.Fullscreen = false
+	settings.Graphics.WindowWidth = int64(settings.Recording.FrameWidth)
+	settings.Graphics.WindowHeight = int64(settings.Recording.FrameHeight)
+	settings.Playfield.LeadInTime = 0
+}
+
+// checkSettingsName makes sure the settings name refers to a file in danser's settings directory and not to its internal files
+func checkSettingsName(name string) error {
+	if name == "credentials" || name == "launcher" {
+		return fmt.Errorf("name \"%s\" is forbidden", name)
+	}
+
+	if strings.ContainsAny(name, `/\:`) || strings.Contains(name, "..") {
+		return fmt.Errorf("name \"%s\" has to be a plain file name", name)
+	}
+
+	return nil
+}
+
+// checkReplaySaving rejects flags that conflict with Gameplay.SaveReplays
+func checkReplaySaving() {
+	// -tag splits a single play between several cursors while .osr holds input of only one
+	if settings.Gameplay.SaveReplays && settings.TAG > 1 {
+		panic("Incompatible settings selected: -tag > 1, Gameplay.SaveReplays")
+	}
+}
+
+// loadPlayer applies mods and difficulty overrides to the map and creates the player, allowDA enables overrides in replay modes
+func loadPlayer(beatMap *beatmap.BeatMap, mods difficulty2.Modifier, rate difficulty2.RateAdjust, ar, od, cs, hp float64, allowDA bool) {
+	beatMap.Diff.SetRateAdjust(rate)
+	beatMap.Diff.SetMods(mods)
+
+	speedBefore := settings.SPEED
+
+	settings.SPEED *= beatMap.Diff.GetModRate()
+
+	if mods.Active(difficulty2.PitchChangingMask) {
+		settings.PITCH *= beatMap.Diff.GetModRate()
+	}
+
+	if settings.PLAY || !settings.KNOCKOUT || allowDA {
+		if !math.IsNaN(ar) {
+			beatMap.Diff.SetARCustom(ar)
+		}
+
+		if !math.IsNaN(od) {
+			beatMap.Diff.SetODCustom(od)
+		}
+
+		if !math.IsNaN(cs) {
+			beatMap.Diff.SetCSCustom(cs)
+		}
+
+		if !math.IsNaN(hp) {
+			beatMap.Diff.SetHPCustom(hp)
+		}
+
+		beatMap.Diff.SetCustomSpeed(speedBefore)
+	}
+
+	beatmap.ParseTimingPointsAndPauses(beatMap)
+	beatmap.ParseObjects(beatMap, false, true)
+	beatMap.LoadCustomSamples()
+
+	if settings.STORYBOARD {
+		player = states.NewStoryboardViewer(beatMap)
+		return
+	}
+
+	p := states.NewPlayer(beatMap)
+
+	player = p
+
+	if !settings.RECORD {
+		api.SetSource(p)
+	}
+}
+
+func startHitLog() {
+	if !settings.Recording.IsHitEventLogEnabled() {
+		return
+	}
+
+	format := settings.Recording.HitEventLog
+
+	p, ok := player.(*states.Player)
+	if !ok || p.GetRuleset() == nil {
+		log.Println("Hit log is available only in play and knockout modes, skipping...")
+		return
+	}
+
+	// Resolve video's name early so both files share it
+	if strings.TrimSpace(output) == "" {
+		output = "danser_" + time.Now().Format("2006-01-02_15-04-05")
+	}
+
+	var err error
+
+	// Without recording there's no video to put it next to
+	dir := "."
+	if settings.RECORD {
+		dir = settings.Recording.GetOutputDir()
+	} else if wd, wdErr := os.Getwd(); wdErr == nil {
+		dir = wd
+	}
+
+	hitLog, err = hitlog.New(p.GetRuleset(), filepath.Join(dir, output), format)
+	if err != nil {
+		log.Println("Failed to create hit log:", err)
+		return
+	}
+
+	log.Println("Saving hit log to:", hitLog.GetPath())
+}
+
+// closeHitLog closes the hit log of the current map, also called on crashes so it's not left incomplete
+func closeHitLog() {
+	if hitLog == nil {
+		return
+	}
+
+	if err := hitLog.Close(); err != nil {
+		log.Println("Failed to save hit log:", err)
+	}
+
+	hitLog = nil
 }
 
 func mainLoopRecord() {
@@ -575,36 +854,47 @@ func mainLoopRecord() {
 	fps := float64(settings.Recording.FPS)
 	audioFPS := 1000.0
 
+	// Number of drawn frames per video frame
+	oversample := int64(1)
+
 	if settings.Recording.MotionBlur.Enabled {
 		fps *= float64(settings.Recording.MotionBlur.OversampleMultiplier)
+		oversample = int64(settings.Recording.MotionBlur.OversampleMultiplier)
 	}
 
 	w, h := int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight())
 
 	var fbo *buffer.Framebuffer
 
-	mainthread.Call(func() {
+	callMain(func() {
 		fbo = buffer.NewFrameMultisampleScreen(w, h, false, 0)
 	})
 
+	p, _ := player.(states.TimedState)
+
+	fpsDelta := 1000 / fps
+
+	totalFrames := int64(math.Ceil(p.GetRunningTime() * float64(settings.Recording.FPS) / 1000))
+
+	if chunkCount > 0 {
+		ffmpeg.SetChunk(chunkIndex, chunkCount, totalFrames)
+	}
+
 	ffmpeg.StartFFmpeg(int(fps), w, h, audioFPS, output)
 
 	updateFPS := math.Max(fps, 1000)
 	updateDelta := 1000 / updateFPS
-	fpsDelta := 1000 / fps
 	audioDelta := 1000.0 / audioFPS
 
 	deltaSumF := fpsDelta
 	deltaSumA := 0.0
 
-	p, _ := player.(*states.Player)
-
 	lastCount := int64(0)
 	lastRealTime := qpc.GetMilliTimeF()
 
-	var lastProgress, progress int
+	var lastProgress, currentProgress int
 
-	if preciseProgress {
+	if preciseProgress || progress.Enabled() {
 		lastProgress = -1
 	}
 
@@ -618,38 +908,55 @@ func mainLoopRecord() {
 
 		deltaSumF += updateDelta
 		if deltaSumF >= fpsDelta {
-			mainthread.Call(func() {
-				fbo.Bind()
+			callMain(func() {
+				if ffmpeg.NeedsFrame() {
+					fbo.Bind()
 
-				ffmpeg.PreFrame()
+					ffmpeg.PreFrame()
 
-				viewport.Push(int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight()))
-				pushFrame()
-				viewport.Pop()
+					viewport.Push(int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight()))
+					pushFrame()
+					viewport.Pop()
 
-				ffmpeg.MakeFrame()
+					ffmpeg.MakeFrame()
 
-				fbo.Unbind()
+					fbo.Unbind()
+				} else {
+					ffmpeg.SkipFrame()
+				}
 
 				count++
 
 				timeOffset := p.GetTimeOffset()
-				progress = int(math.Round(timeOffset / p.RunningTime * 100))
+				currentProgress = int(math.Round(timeOffset / p.GetRunningTime() * 100))
+
+				// JSON events are sent in 1% increments
+				if (preciseProgress || progress.Enabled() || currentProgress%5 == 0) && lastProgress != currentProgress {
+					elapsed := qpc.GetMilliTimeF() - lastRealTime
 
-				if (preciseProgress || progress%5 == 0) && lastProgress != progress {
-					speed := float64(count-lastCount) * (1000 / fps) / (qpc.GetMilliTimeF() - lastRealTime)
+					speed := float64(count-lastCount) * (1000 / fps) / elapsed
 
-					eta := int((p.RunningTime - timeOffset) / 1000 / speed)
+					eta := (p.GetRunningTime() - timeOffset) / 1000 / speed
 
-					etaText := util.FormatSeconds(eta)
+					etaText := util.FormatSeconds(int(eta))
 
 					if settings.Recording.ShowFFmpegLogs {
-						fmt.Println()
+						_, _ = fmt.Fprintln(progress.LogOutput())
 					}
 
-					log.Println(fmt.Sprintf("Progress: %d%%, Speed: %.2fx, ETA: %s", progress, speed, etaText))
+					log.Println(fmt.Sprintf("Progress: %d%%, Speed: %.2fx, ETA: %s", currentProgress, speed, etaText))
+
+					progress.Send(progress.Event{
+						Stage:    progress.Encoding,
+						Progress: float64(currentProgress),
+						Frame:    count / oversample,
+						Frames:   totalFrames,
+						FPS:      float64(count-lastCount) / float64(oversample) / elapsed * 1000,
+						Speed:    speed,
+						ETA:      eta,
+					})
 
-					lastProgress = progress
+					lastProgress = currentProgress
 
 					lastCount = count
 					lastRealTime = qpc.GetMilliTimeF()
@@ -657,14 +964,304 @@ func mainLoopRecord() {
 			})
 
 			deltaSumF -= fpsDelta
+
+			if ffmpeg.ChunkFinished() {
+				break
+			}
 		}
 	}
 
-	mainthread.Call(func() {
+	callMain(func() {
 		ffmpeg.StopFFmpeg()
 	})
 }
 
+// findBeatmap looks for a map with given id or md5, otherwise with given metadata, first exactly and then partially
+func findBeatmap(beatmaps []*beatmap.BeatMap, id int64, md5, artist, title, difficulty, creator string) *beatmap.BeatMap {
+	if id > -1 {
+		for _, b := range beatmaps {
+			if b.ID == id {
+				return b
+			}
+		}
+
+		return nil
+	}
+
+	if md5 != "" {
+		for _, b := range beatmaps {
+			if strings.EqualFold(b.MD5, md5) {
+				return b
+			}
+		}
+
+		return nil
+	}
+
+	for _, b := range beatmaps {
+		if (artist == "" || strings.EqualFold(artist, b.Artist)) &&
+			(title == "" || strings.EqualFold(title, b.Name)) &&
+			(difficulty == "" || strings.EqualFold(difficulty, b.Difficulty)) &&
+			(creator == "" || strings.EqualFold(creator, b.Creator)) {
+			return b
+		}
+	}
+
+	log.Println("Beatmap with exact parameters not found, searching partially...")
+
+	for _, b := range beatmaps {
+		if (artist == "" || strings.Contains(strings.ToLower(b.Artist), strings.ToLower(artist))) &&
+			(title == "" || strings.Contains(strings.ToLower(b.Name), strings.ToLower(title))) &&
+			(difficulty == "" || strings.Contains(strings.ToLower(b.Difficulty), strings.ToLower(difficulty))) &&
+			(creator == "" || strings.Contains(strings.ToLower(b.Creator), strings.ToLower(creator))) {
+			return b
+		}
+	}
+
+	return nil
+}
+
+// readReplay loads a replay given by -replay or a queue job
+func readReplay(path string) *rplpa.Replay {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+
+	rp, err := rplpa.ParseReplay(bytes)
+	if err != nil {
+		panic(err)
+	}
+
+	if rp.PlayMode < settings.ModeOsu || rp.PlayMode > settings.ModeMania {
+		panic(fmt.Sprintf("Unknown game mode in replay: %d", rp.PlayMode))
+	}
+
+	if rp.ReplayData == nil || len(rp.ReplayData) < 2 {
+		panic("Replay is missing input data")
+	}
+
+	return rp
+}
+
+// runChunks renders the recording in count processes started with the same arguments and joins their videos
+func runChunks(count int, seed int64) {
+	if strings.TrimSpace(output) == "" {
+		output = "danser_" + time.Now().Format("2006-01-02_15-04-05")
+	}
+
+	// All chunks have to play the same map
+	if seed < 0 {
+		seed = int64(rand.Int31())
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		panic(err)
+	}
+
+	var args []string
+
+	for i := 1; i < len(os.Args); i++ {
+		arg := strings.TrimLeft(os.Args[i], "-")
+
+		// Chunks always send JSON events, they are forwarded to our own output
+		if arg == "chunks" || arg == "progress" {
+			i++
+			continue
+		} else if strings.HasPrefix(arg, "chunks=") || strings.HasPrefix(arg, "progress=") {
+			continue
+		}
+
+		args = append(args, os.Args[i])
+	}
+
+	args = append(args, "-out="+output, fmt.Sprintf("-seed=%d", seed), "-nodbcheck", "-noupdatecheck", "-progress=json")
+
+	ffmpeg.PrepareChunks(output)
+
+	log.Println(fmt.Sprintf("Rendering in %d chunks...", count))
+
+	errs := make([]error, count)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < count; i++ {
+		cmd := exec.Command(executable, append(args, fmt.Sprintf("-chunk=%d/%d", i, count))...)
+
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			panic(err)
+		}
+
+		stderr, err := cmd.StderrPipe()
+		if err != nil {
+			panic(err)
+		}
+
+		if err = cmd.Start(); err != nil {
+			panic(err)
+		}
+
+		wg.Add(1)
+
+		go func(i int) {
+			var outputs sync.WaitGroup
+
+			outputs.Add(2)
+
+			go func() {
+				forwardChunkEvents(i+1, stdout)
+				outputs.Done()
+			}()
+
+			go func() {
+				forwardChunkLog(i+1, stderr)
+				outputs.Done()
+			}()
+
+			// Pipes have to be drained before waiting for the process
+			outputs.Wait()
+
+			errs[i] = cmd.Wait()
+			wg.Done()
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			panic(fmt.Sprintf("Chunk %d failed: %s", i+1, err))
+		}
+	}
+
+	ffmpeg.CombineChunks(output, count)
+
+	os.Exit(0)
+}
+
+// forwardChunkEvents sends JSON events of a chunk process as our own, tagged with 1-based chunk index
+func forwardChunkEvents(chunk int, stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+
+	for scanner.Scan() {
+		var event progress.Event
+
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			chunkLog(chunk, scanner.Text())
+			continue
+		}
+
+		event.Chunk = chunk
+
+		progress.Send(event)
+	}
+}
+
+// forwardChunkLog writes human-readable output of a chunk process to our log
+func forwardChunkLog(chunk int, stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+
+	for scanner.Scan() {
+		chunkLog(chunk, scanner.Text())
+	}
+}
+
+func chunkLog(chunk int, line string) {
+	out := progress.LogOutput()
+	if logFile != nil {
+		out = io.MultiWriter(out, logFile)
+	}
+
+	_, _ = fmt.Fprintf(out, "[Chunk %d] %s\n", chunk, line)
+}
+
+func runVerification(beatMap *beatmap.BeatMap) {
+	result, err := verify.Run(beatMap, settings.REPLAY)
+	if err != nil {
+		panic(err)
+	}
+
+	log.Println(fmt.Sprintf("Verification results for \"%s\" (%s):", result.Username, result.Mods.String()))
+
+	for _, s := range strings.Split(result.Report(), "\n") {
+		log.Println(s)
+	}
+
+	if !result.Matches() {
+		log.Println("Computed score doesn't match the one stored in the replay!")
+		os.Exit(1)
+	}
+
+	log.Println("Computed score matches the one stored in the replay.")
+	os.Exit(0)
+}
+
+func runExport(beatMap *beatmap.BeatMap, path string, mods difficulty2.Modifier, rate difficulty2.RateAdjust, ar, od, cs, hp float64) {
+	if mods.Active(difficulty2.TimeRampMask) {
+		panic("WindUp and WindDown change the rate over time, they can't be exported to a .osu file")
+	}
+
+	if !math.IsNaN(ar) {
+		beatMap.Diff.SetARCustom(ar)
+	}
+
+	if !math.IsNaN(od) {
+		beatMap.Diff.SetODCustom(od)
+	}
+
+	if !math.IsNaN(cs) {
+		beatMap.Diff.SetCSCustom(cs)
+	}
+
+	if !math.IsNaN(hp) {
+		beatMap.Diff.SetHPCustom(hp)
+	}
+
+	beatMap.Diff.SetCustomSpeed(settings.SPEED)
+	beatMap.Diff.SetRateAdjust(rate)
+	beatMap.Diff.SetMods(mods)
+
+	// All objects have to be loaded regardless of the mode danser would play
+	settings.Objects.LoadSpinners = true
+
+	if beatMap.Mode == settings.ModeMania {
+		settings.MODE = settings.ModeMania
+	}
+
+	beatmap.ParseTimingPointsAndPauses(beatMap)
+	beatmap.ParseObjects(beatMap, false, false)
+
+	if err := beatmap.SaveBeatMap(beatMap, path); err != nil {
+		panic(err)
+	}
+
+	log.Println("Beatmap saved to:", path)
+	os.Exit(0)
+}
+
+func runLint(beatMap *beatmap.BeatMap, format string) {
+	report := lint.Run(beatMap)
+
+	if format == "json" {
+		data, err := report.JSON()
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(report.Text())
+	}
+
+	if report.Count(lint.Error) > 0 {
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
+
 func mainLoopSS() {
 	w, h := int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight())
 
@@ -674,7 +1271,7 @@ func mainLoopSS() {
 		fbo = buffer.NewFrameMultisampleScreen(w, h, false, 0)
 	})
 
-	p, _ := player.(*states.Player)
+	p, _ := player.(states.TimedState)
 
 	for !p.Update(1) {
 		if p.GetTime() >= screenshotTime*1000 {
@@ -800,7 +1397,12 @@ func pushFrame() {
 		screenFBO.Bind()
 	}
 
-	gl.ClearColor(0, 0, 0, 1)
+	if settings.Recording.IsTransparent() {
+		gl.ClearColor(0, 0, 0, 0)
+	} else {
+		gl.ClearColor(0, 0, 0, 1)
+	}
+
 	gl.Clear(gl.COLOR_BUFFER_BIT)
 
 	if player != nil {
@@ -878,16 +1480,25 @@ func Run() {
 
 	log.Println("danser-go version:", build.VERSION)
 
-	file, err := os.Create(filepath.Join(env.DataDir(), "danser.log"))
-	if err != nil {
-		panic(err)
-	}
+	// Output of chunk processes is logged by the process that started them
+	if isChunkProcess() {
+		log.SetOutput(os.Stdout)
 
-	log.SetOutput(file)
+		printPlatformInfo()
+	} else {
+		file, err := os.Create(filepath.Join(env.DataDir(), "danser.log"))
+		if err != nil {
+			panic(err)
+		}
+
+		log.SetOutput(file)
 
-	printPlatformInfo()
+		printPlatformInfo()
 
-	log.SetOutput(io.MultiWriter(os.Stdout, file))
+		log.SetOutput(io.MultiWriter(os.Stdout, file))
+
+		logFile = file
+	}
 
 	platform.DisableQuickEdit()
 
@@ -896,14 +1507,29 @@ func Run() {
 	mainthread.Run(run)
 }
 
+// isChunkProcess checks whether danser was started by -chunks, it has to be known before flags are parsed
+func isChunkProcess() bool {
+	for _, arg := range os.Args[1:] {
+		if strings.HasPrefix(strings.TrimLeft(arg, "-"), "chunk=") {
+			return true
+		}
+	}
+
+	return false
+}
+
 func closeHandler(err any, stackTrace []string) {
 	settings.CloseWatcher()
 	discord.Disconnect()
+	api.Stop()
+	closeHitLog()
 	platform.EnableQuickEdit()
 
 	if err != nil {
 		log.Println("panic:", err)
 
+		progress.Send(progress.Event{Stage: progress.Error, Error: fmt.Sprint(err)})
+
 		for _, s := range stackTrace {
 			log.Println(s)
 		}
@@ -37,6 +37,11 @@ func AddListener(function func(sampleSet int, hitsoundIndex, index int, volume f
 	listeners = append(listeners, function)
 }
 
+// RemoveListeners drops all listeners, used when the map they were added for is closed
+func RemoveListeners() {
+	listeners = listeners[:0]
+}
+
 func LoadSamples() {
 	Samples[0][0] = LoadSample("normal-hitnormal")
 	Samples[0][1] = LoadSample("normal-hitwhistle")
@@ -52,6 +52,7 @@ type BeatMap struct {
 	Timings    *objects.Timings
 	HitObjects []objects.IHitObject
 	Pauses     []*Pause
+	Colours    [][]string
 	Queue      []objects.IHitObject
 	Version    int
 
@@ -82,6 +83,20 @@ func (beatMap *BeatMap) Reset() {
 	}
 }
 
+// Copy returns map's metadata without parsed objects and applied mods, so the same map can be loaded again
+func (beatMap *BeatMap) Copy() *BeatMap {
+	c := *beatMap
+
+	c.Diff = difficulty.NewDifficulty(beatMap.Diff.GetBaseHP(), beatMap.Diff.GetBaseCS(), beatMap.Diff.GetBaseOD(), beatMap.Diff.GetBaseAR())
+	c.Timings = objects.NewTimings()
+	c.HitObjects = nil
+	c.Pauses = nil
+	c.Colours = nil
+	c.Queue = nil
+
+	return &c
+}
+
 func (beatMap *BeatMap) Clear() {
 	beatMap.HitObjects = make([]objects.IHitObject, 0)
 	beatMap.Timings = objects.NewTimings()
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/wieku/danser-go/framework/math/mutils"
 	"math"
+	"strings"
 )
 
 const (
@@ -42,12 +43,19 @@ type Difficulty struct {
 	Hit300 int64
 
 	HPMod        float64
+	CSMod        float64
+	ODMod        float64
+	ARMod        float64
 	SpinnerRatio float64
 	Speed        float64
 
 	ARReal      float64
 	ODReal      float64
 	CustomSpeed float64
+	RateAdjust  RateAdjust
+
+	Seed       int64 // Random mod's seed, negative means that it will be picked when objects are parsed
+	Reflection Reflection
 }
 
 func NewDifficulty(hp, cs, od, ar float64) *Difficulty {
@@ -66,6 +74,7 @@ func NewDifficulty(hp, cs, od, ar float64) *Difficulty {
 	diff.ar = ar
 
 	diff.CustomSpeed = 1
+	diff.Seed = -1
 
 	diff.calculate()
 
@@ -75,6 +84,13 @@ func NewDifficulty(hp, cs, od, ar float64) *Difficulty {
 func (diff *Difficulty) calculate() {
 	hpDrain, cs, od, ar := diff.hp, diff.cs, diff.od, diff.ar
 
+	// Custom values are shown and scored as osu!lazer's DifficultyAdjust mod
+	if hpDrain != diff.baseHP || cs != diff.baseCS || od != diff.baseOD || ar != diff.baseAR {
+		diff.Mods |= DifficultyAdjust
+	} else {
+		diff.Mods &= ^DifficultyAdjust
+	}
+
 	if diff.Mods&HardRock > 0 {
 		ar = math.Min(ar*1.4, 10)
 		cs = math.Min(cs*1.3, 10)
@@ -90,6 +106,9 @@ func (diff *Difficulty) calculate() {
 	}
 
 	diff.HPMod = hpDrain
+	diff.CSMod = cs
+	diff.ODMod = od
+	diff.ARMod = ar
 
 	diff.CircleRadiusU = DifficultyRate(cs, 54.4, 32, 9.6)
 	diff.CircleRadius = diff.CircleRadiusU * 1.00041 //some weird allowance osu has
@@ -124,13 +143,7 @@ func (diff *Difficulty) CheckModActive(mods Modifier) bool {
 }
 
 func (diff *Difficulty) GetModifiedTime(time float64) float64 {
-	if diff.Mods&DoubleTime > 0 {
-		return time / (1.5 * diff.CustomSpeed)
-	} else if diff.Mods&HalfTime > 0 {
-		return time / (0.75 * diff.CustomSpeed)
-	} else {
-		return time / diff.CustomSpeed
-	}
+	return time / (diff.GetModRate() * diff.CustomSpeed)
 }
 
 func (diff *Difficulty) GetScoreMultiplier() float64 {
@@ -149,23 +162,27 @@ func (diff *Difficulty) GetScoreMultiplier() float64 {
 	return baseMultiplier
 }
 
-func (diff *Difficulty) GetModStringFull() []string {
-	mods := diff.Mods.StringFull()
+func (diff *Difficulty) GetModStringFull() (mods []string) {
+	acronyms := diff.Mods.names(modsString[:])
 
-	if ar := diff.GetAR(); ar != diff.GetBaseAR() {
-		mods = append(mods, fmt.Sprintf("DA:AR%s", mutils.FormatWOZeros(ar, 2)))
-	}
+	for i, mod := range diff.Mods.StringFull() {
+		acronym := acronyms[i]
 
-	if od := diff.GetOD(); od != diff.GetBaseOD() {
-		mods = append(mods, fmt.Sprintf("DA:OD%s", mutils.FormatWOZeros(od, 2)))
-	}
+		if acronym == "DA" {
+			mods = append(mods, diff.getAdjustedValues("DA:")...)
+			continue
+		}
 
-	if cs := diff.GetCS(); cs != diff.GetBaseCS() {
-		mods = append(mods, fmt.Sprintf("DA:CS%s", mutils.FormatWOZeros(cs, 2)))
-	}
+		// osu!stable skins don't have textures for osu!lazer mods
+		if ParseMods(acronym)&LazerMask > 0 {
+			mods = append(mods, "DA:"+acronym)
+		} else {
+			mods = append(mods, mod)
+		}
 
-	if hp := diff.GetHP(); hp != diff.GetBaseHP() {
-		mods = append(mods, fmt.Sprintf("DA:HP%s", mutils.FormatWOZeros(hp, 2)))
+		if rate := diff.getRateString(acronym); rate != "" {
+			mods = append(mods, "DA:"+strings.ReplaceAll(rate, ",", "-")+"x")
+		}
 	}
 
 	if cSpeed := diff.CustomSpeed; cSpeed != 1 {
@@ -175,30 +192,59 @@ func (diff *Difficulty) GetModStringFull() []string {
 	return mods
 }
 
-func (diff *Difficulty) GetModString() string {
-	mods := diff.Mods.String()
+// GetModString returns mods with their parameters in the format accepted by ParseModsWithParams, e.g. HDDT(1.35)DA(AR9.5)
+func (diff *Difficulty) GetModString() (mods string) {
+	for _, acronym := range diff.Mods.names(modsString[:]) {
+		mods += acronym
 
+		if acronym == "DA" {
+			mods += "(" + strings.Join(diff.getAdjustedValues(""), ",") + ")"
+		} else if rate := diff.getRateString(acronym); rate != "" {
+			mods += "(" + rate + ")"
+		}
+	}
+
+	if cSpeed := diff.CustomSpeed; cSpeed != 1 {
+		mods += fmt.Sprintf("S%sx", mutils.FormatWOZeros(cSpeed, 2))
+	}
+
+	return mods
+}
+
+func (diff *Difficulty) getAdjustedValues(prefix string) (values []string) {
 	if ar := diff.GetAR(); ar != diff.GetBaseAR() {
-		mods += fmt.Sprintf("AR%s", mutils.FormatWOZeros(ar, 2))
+		values = append(values, fmt.Sprintf("%sAR%s", prefix, mutils.FormatWOZeros(ar, 2)))
 	}
 
 	if od := diff.GetOD(); od != diff.GetBaseOD() {
-		mods += fmt.Sprintf("OD%s", mutils.FormatWOZeros(od, 2))
+		values = append(values, fmt.Sprintf("%sOD%s", prefix, mutils.FormatWOZeros(od, 2)))
 	}
 
 	if cs := diff.GetCS(); cs != diff.GetBaseCS() {
-		mods += fmt.Sprintf("CS%s", mutils.FormatWOZeros(cs, 2))
+		values = append(values, fmt.Sprintf("%sCS%s", prefix, mutils.FormatWOZeros(cs, 2)))
 	}
 
 	if hp := diff.GetHP(); hp != diff.GetBaseHP() {
-		mods += fmt.Sprintf("HP%s", mutils.FormatWOZeros(hp, 2))
+		values = append(values, fmt.Sprintf("%sHP%s", prefix, mutils.FormatWOZeros(hp, 2)))
 	}
 
-	if cSpeed := diff.CustomSpeed; cSpeed != 1 {
-		mods += fmt.Sprintf("S%sx", mutils.FormatWOZeros(cSpeed, 2))
+	return
+}
+
+// getRateString returns custom rate parameters of a speed changing mod, empty if the default ones are used
+func (diff *Difficulty) getRateString(acronym string) string {
+	switch acronym {
+	case "DT", "NC", "HT", "DC":
+		if diff.RateAdjust.Rate > 0 {
+			return mutils.FormatWOZeros(diff.RateAdjust.Rate, 2)
+		}
+	case "WU", "WD":
+		if diff.RateAdjust.InitialRate > 0 || diff.RateAdjust.FinalRate > 0 {
+			return mutils.FormatWOZeros(diff.GetModRate(), 2) + "," + mutils.FormatWOZeros(diff.GetFinalModRate(), 2)
+		}
 	}
 
-	return mods
+	return ""
 }
 
 func (diff *Difficulty) GetBaseHP() float64 {
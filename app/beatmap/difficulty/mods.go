@@ -1,5 +1,13 @@
 package difficulty
 
+import (
+	"fmt"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"math"
+	"strconv"
+	"strings"
+)
+
 type Modifier int64
 
 const (
@@ -36,7 +44,33 @@ const (
 	ScoreV2
 	LastMod
 	Daycore
-	DifficultyAdjustMask = HardRock | Easy | DoubleTime | Nightcore | HalfTime | Daycore | Flashlight | Relax
+	Mirror // osu!lazer mods, they don't exist in osu!stable and can't be stored in .osr
+	Wiggle
+	Transform
+	Grow
+	Deflate
+	SpinIn
+	Depth
+	DifficultyAdjust
+	WindUp
+	WindDown
+	DifficultyAdjustMask = HardRock | Easy | DoubleTime | Nightcore | HalfTime | Daycore | Flashlight | Relax | DifficultyAdjust | WindUp | WindDown
+	SpeedChangingMask    = DoubleTime | Nightcore | HalfTime | Daycore | WindUp | WindDown
+	PitchChangingMask    = Nightcore | Daycore | WindUp | WindDown
+	TimeRampMask         = WindUp | WindDown
+	LazerMask            = Mirror | Wiggle | Transform | Grow | Deflate | SpinIn | Depth | DifficultyAdjust | WindUp | WindDown
+	PositionMask         = Random | Mirror
+	ApproachMask         = Grow | Deflate | SpinIn | Depth
+	VisualMask           = Wiggle | Transform | ApproachMask
+)
+
+// Reflection is the axis Mirror mod flips objects over
+type Reflection int
+
+const (
+	ReflectHorizontal = Reflection(iota)
+	ReflectVertical
+	ReflectBoth
 )
 
 var modsString = [...]string{
@@ -72,6 +106,16 @@ var modsString = [...]string{
 	"V2",
 	"LM",
 	"DC",
+	"MR",
+	"WG",
+	"TR",
+	"GR",
+	"DF",
+	"SI",
+	"DP",
+	"DA",
+	"WU",
+	"WD",
 }
 
 var modsStringFull = [...]string{
@@ -107,6 +151,16 @@ var modsStringFull = [...]string{
 	"ScoreV2",
 	"LastMod",
 	"Daycore",
+	"Mirror",
+	"Wiggle",
+	"Transform",
+	"Grow",
+	"Deflate",
+	"SpinIn",
+	"Depth",
+	"DifficultyAdjust",
+	"WindUp",
+	"WindDown",
 }
 
 func (mods Modifier) GetScoreMultiplier() float64 {
@@ -156,10 +210,26 @@ func (mods Modifier) GetScoreMultiplier() float64 {
 		multiplier *= 0.9
 	}
 
+	if mods&DifficultyAdjust > 0 {
+		multiplier *= 0.5
+	}
+
+	if mods&TimeRampMask > 0 {
+		multiplier *= 0.5
+	}
+
 	return multiplier
 }
 
-func (mods Modifier) String() (s string) {
+func (mods Modifier) String() string {
+	return strings.Join(mods.names(modsString[:]), "")
+}
+
+func (mods Modifier) StringFull() []string {
+	return mods.names(modsStringFull[:])
+}
+
+func (mods Modifier) names(table []string) (s []string) {
 	if mods.Active(Nightcore) {
 		mods &= ^DoubleTime
 	}
@@ -172,10 +242,10 @@ func (mods Modifier) String() (s string) {
 		mods &= ^SuddenDeath
 	}
 
-	for i := 0; i < len(modsString); i++ {
+	for i := 0; i < len(table); i++ {
 		activated := mods&1 == 1
 		if activated {
-			s += modsString[i]
+			s = append(s, table[i])
 		}
 
 		mods >>= 1
@@ -184,41 +254,53 @@ func (mods Modifier) String() (s string) {
 	return
 }
 
-func (mods Modifier) StringFull() (s []string) {
-	if mods.Active(Nightcore) {
-		mods &= ^DoubleTime
-	}
+// ModParams holds parameters of osu!lazer style mods given in a mod string, e.g. "DT(1.35)", "WU(1,1.5)" or "DA(AR9.5,OD8)"
+type ModParams struct {
+	Rate RateAdjust
 
-	if mods.Active(Daycore) {
-		mods &= ^HalfTime
-	}
+	// DifficultyAdjust values, NaN if not changed
+	AR float64
+	OD float64
+	CS float64
+	HP float64
+}
 
-	if mods.Active(Perfect) {
-		mods &= ^SuddenDeath
-	}
+func ParseMods(mods string) Modifier {
+	m, _, _ := ParseModsWithParams(mods)
 
-	for i := 0; i < len(modsString); i++ {
-		activated := mods&1 == 1
-		if activated {
-			s = append(s, modsStringFull[i])
-		}
+	return m
+}
 
-		mods >>= 1
-	}
+// ParseModsWithParams parses two-letter mod acronyms, each optionally followed by its parameters in parentheses
+func ParseModsWithParams(mods string) (m Modifier, params ModParams, err error) {
+	params.AR, params.OD, params.CS, params.HP = math.NaN(), math.NaN(), math.NaN(), math.NaN()
 
-	return
-}
+	for i := 0; i+1 < len(mods); {
+		acronym := mods[i : i+2]
+		i += 2
 
-func ParseMods(mods string) (m Modifier) {
-	modsSl := make([]string, len(mods)/2)
-	for n, modPart := range mods {
-		modsSl[n/2] += string(modPart)
-	}
+		var args []string
+
+		if i < len(mods) && mods[i] == '(' {
+			end := strings.IndexByte(mods[i:], ')')
+			if end < 0 {
+				return m, params, fmt.Errorf("missing closing parenthesis after %s", acronym)
+			}
+
+			args = strings.Split(mods[i+1:i+end], ",")
+			i += end + 1
+		}
 
-	for _, mod := range modsSl {
 		for index, availableMod := range modsString {
-			if availableMod == mod {
+			if availableMod == acronym {
 				m |= 1 << uint(index)
+
+				if len(args) > 0 {
+					if err = params.parse(acronym, args); err != nil {
+						return
+					}
+				}
+
 				break
 			}
 		}
@@ -239,10 +321,127 @@ func ParseMods(mods string) (m Modifier) {
 	return
 }
 
+func (params *ModParams) parse(acronym string, args []string) error {
+	values := make([]float64, len(args))
+
+	if acronym == "DA" {
+		for _, arg := range args {
+			if len(arg) < 3 {
+				return fmt.Errorf("invalid DA parameter: %s", arg)
+			}
+
+			value, err := strconv.ParseFloat(arg[2:], 64)
+			if err != nil {
+				return fmt.Errorf("invalid DA parameter: %s", arg)
+			}
+
+			minValue := 0.0
+
+			switch strings.ToUpper(arg[:2]) {
+			case "AR":
+				minValue = -10
+				params.AR = value
+			case "OD":
+				params.OD = value
+			case "CS":
+				params.CS = value
+			case "HP":
+				params.HP = value
+			default:
+				return fmt.Errorf("unknown DA parameter: %s", arg)
+			}
+
+			if value < minValue || value > 11 {
+				return fmt.Errorf("DA parameter out of range: %s", arg)
+			}
+		}
+
+		return nil
+	}
+
+	for i, arg := range args {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(arg, "x"), 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s parameter: %s", acronym, arg)
+		}
+
+		values[i] = value
+	}
+
+	inRange := func(value, min, max float64) error {
+		if value < min || value > max {
+			return fmt.Errorf("%s rate has to be between %s and %s, got %s", acronym, mutils.FormatWOZeros(min, 2), mutils.FormatWOZeros(max, 2), mutils.FormatWOZeros(value, 2))
+		}
+
+		return nil
+	}
+
+	switch acronym {
+	case "DT", "NC":
+		if len(values) != 1 {
+			return fmt.Errorf("%s takes one parameter: rate", acronym)
+		}
+
+		params.Rate.Rate = values[0]
+
+		return inRange(values[0], 1.01, 2)
+	case "HT", "DC":
+		if len(values) != 1 {
+			return fmt.Errorf("%s takes one parameter: rate", acronym)
+		}
+
+		params.Rate.Rate = values[0]
+
+		return inRange(values[0], 0.5, 0.99)
+	case "WU", "WD":
+		initial, final := 1.0, values[0]
+
+		if len(values) == 2 {
+			initial, final = values[0], values[1]
+		} else if len(values) != 1 {
+			return fmt.Errorf("%s takes one or two parameters: [initial rate,] final rate", acronym)
+		}
+
+		if err := inRange(initial, 0.5, 2); err != nil {
+			return err
+		}
+
+		if err := inRange(final, 0.5, 2); err != nil {
+			return err
+		}
+
+		if acronym == "WU" && final <= initial {
+			return fmt.Errorf("WU final rate has to be higher than the initial one")
+		}
+
+		if acronym == "WD" && final >= initial {
+			return fmt.Errorf("WD final rate has to be lower than the initial one")
+		}
+
+		params.Rate.InitialRate = initial
+		params.Rate.FinalRate = final
+
+		return nil
+	}
+
+	return fmt.Errorf("mod %s doesn't take parameters", acronym)
+}
+
 func (mods Modifier) Active(mod Modifier) bool {
 	return mods&mod > 0
 }
 
+// KeyCount returns the number of osu!mania columns forced by K1-K9 mods, 0 if none of them is active
+func (mods Modifier) KeyCount() int {
+	for i, mod := range []Modifier{Key1, Key2, Key3, Key4, Key5, Key6, Key7, Key8, Key9} {
+		if mods.Active(mod) {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
 func (mods Modifier) Compatible() bool {
 	if mods == None {
 		return true
@@ -254,7 +453,26 @@ func (mods Modifier) Compatible() bool {
 		((mods.Active(Perfect) || mods.Active(SuddenDeath)) && mods.Active(NoFail)) ||
 		(mods.Active(Relax) && mods.Active(Relax2)) ||
 		((mods.Active(Relax) || mods.Active(Relax2)) && (mods.Active(SuddenDeath) || mods.Active(Perfect) || mods.Active(Autoplay) || mods.Active(NoFail))) ||
-		(mods.Active(Relax2) && mods.Active(SpunOut)) {
+		(mods.Active(Relax2) && mods.Active(SpunOut)) ||
+		(mods.Active(Mirror) && mods.Active(HardRock)) ||
+		(mods.Active(Transform) && (mods.Active(Wiggle) || mods.Active(Depth))) ||
+		(mods.Active(SpinIn) && mods.Active(Hidden)) ||
+		(mods.Active(DifficultyAdjust) && (mods.Active(HardRock) || mods.Active(Easy))) ||
+		(mods.Active(WindUp) && mods.Active(WindDown)) ||
+		(mods.Active(TimeRampMask) && mods.Active(DoubleTime|Nightcore|HalfTime|Daycore)) {
+		return false
+	}
+
+	// Only one of them can change the approach of objects
+	approachMods := 0
+
+	for _, mod := range []Modifier{Grow, Deflate, SpinIn, Depth} {
+		if mods.Active(mod) {
+			approachMods++
+		}
+	}
+
+	if approachMods > 1 {
 		return false
 	}
 
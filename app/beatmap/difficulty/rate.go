@@ -0,0 +1,140 @@
+package difficulty
+
+import (
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"math"
+)
+
+// WindUp/WindDown reach the final rate at 75% of the map, same as in osu!lazer
+const finalRateProgress = 0.75
+
+// RateAdjust holds custom rates of DT/NC/HT/DC and WindUp/WindDown mods, zero values mean the default ones
+type RateAdjust struct {
+	Rate        float64 // DT/NC/HT/DC rate
+	InitialRate float64 // WindUp/WindDown rate at the first object
+	FinalRate   float64 // WindUp/WindDown rate at the end of the ramp
+
+	rampStart float64
+	rampEnd   float64
+}
+
+func (diff *Difficulty) SetRateAdjust(rate RateAdjust) {
+	diff.RateAdjust = rate
+	diff.calculate()
+}
+
+// SetRampBounds sets the part of the map where WindUp/WindDown change the rate
+func (diff *Difficulty) SetRampBounds(firstObjectStart, lastObjectEnd float64) {
+	diff.RateAdjust.rampStart = firstObjectStart
+	diff.RateAdjust.rampEnd = firstObjectStart + (lastObjectEnd-firstObjectStart)*finalRateProgress
+}
+
+// GetRampBounds returns map times between which WindUp/WindDown change the rate
+func (diff *Difficulty) GetRampBounds() (start, end float64) {
+	return diff.RateAdjust.rampStart, math.Max(diff.RateAdjust.rampEnd, diff.RateAdjust.rampStart+1)
+}
+
+func (diff *Difficulty) getRampRates() (initial, final float64) {
+	initial, final = 1.0, 1.5
+
+	if diff.Mods&WindDown > 0 {
+		final = 0.75
+	}
+
+	if diff.RateAdjust.InitialRate > 0 {
+		initial = diff.RateAdjust.InitialRate
+	}
+
+	if diff.RateAdjust.FinalRate > 0 {
+		final = diff.RateAdjust.FinalRate
+	}
+
+	return
+}
+
+// GetModRate returns the rate set by mods without custom speed, for WindUp/WindDown it's the initial one
+func (diff *Difficulty) GetModRate() float64 {
+	switch {
+	case diff.Mods&DoubleTime > 0:
+		if diff.RateAdjust.Rate > 0 {
+			return diff.RateAdjust.Rate
+		}
+
+		return 1.5
+	case diff.Mods&HalfTime > 0:
+		if diff.RateAdjust.Rate > 0 {
+			return diff.RateAdjust.Rate
+		}
+
+		return 0.75
+	case diff.Mods&TimeRampMask > 0:
+		initial, _ := diff.getRampRates()
+
+		return initial
+	}
+
+	return 1
+}
+
+// GetFinalModRate returns the rate set by mods at the end of the map, it differs from GetModRate only with WindUp/WindDown
+func (diff *Difficulty) GetFinalModRate() float64 {
+	if diff.Mods&TimeRampMask > 0 {
+		_, final := diff.getRampRates()
+
+		return final
+	}
+
+	return diff.GetModRate()
+}
+
+// GetSpeedAt returns playback speed at a given map time, it differs from Speed only with WindUp/WindDown
+func (diff *Difficulty) GetSpeedAt(time float64) float64 {
+	if diff.Mods&TimeRampMask == 0 {
+		return diff.Speed
+	}
+
+	initial, final := diff.getRampRates()
+	start, end := diff.GetRampBounds()
+
+	progress := mutils.ClampF((time-start)/(end-start), 0, 1)
+
+	return (initial + (final-initial)*progress) * diff.CustomSpeed
+}
+
+// GetModifiedTimeAt converts a duration starting at a given map time to real time
+func (diff *Difficulty) GetModifiedTimeAt(time, duration float64) float64 {
+	return duration / diff.GetSpeedAt(time)
+}
+
+// GetRealTime converts map time to real time elapsed since map time 0, integrating the speed if it changes over time
+func (diff *Difficulty) GetRealTime(time float64) float64 {
+	if diff.Mods&TimeRampMask == 0 {
+		return time / diff.Speed
+	}
+
+	start, end := diff.GetRampBounds()
+
+	initialSpeed := diff.GetSpeedAt(start)
+	finalSpeed := diff.GetSpeedAt(end)
+
+	if time <= start {
+		return time / initialSpeed
+	}
+
+	rampTime := math.Min(time, end)
+	slope := (finalSpeed - initialSpeed) / (end - start)
+
+	realTime := start / initialSpeed
+
+	if slope == 0 {
+		realTime += (rampTime - start) / initialSpeed
+	} else {
+		realTime += math.Log(diff.GetSpeedAt(rampTime)/initialSpeed) / slope
+	}
+
+	if time > end {
+		realTime += (time - end) / finalSpeed
+	}
+
+	return realTime
+}
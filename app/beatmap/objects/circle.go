@@ -14,6 +14,7 @@ import (
 	"github.com/wieku/danser-go/framework/math/vector"
 	"math"
 	"strconv"
+	"strings"
 )
 
 const defaultCircleName = "hit"
@@ -41,6 +42,7 @@ type Circle struct {
 	appearTime      float64
 	bounceStartTime float64
 	ArrowRotation   float64
+	visuals         *Visuals
 
 	SliderPoint      bool
 	SliderPointStart bool
@@ -60,6 +62,31 @@ func NewCircle(data []string) *Circle {
 	return circle
 }
 
+// NewHoldNote parses osu!mania hold note as a circle lasting until note's end time
+func NewHoldNote(data []string) *Circle {
+	// End time is stored in front of hit sample: x,y,time,type,hitSound,endTime:hitSample
+	holdData := make([]string, len(data))
+	copy(holdData, data)
+
+	endTime := 0.0
+
+	if len(holdData) > 5 {
+		parts := strings.SplitN(holdData[5], ":", 2)
+
+		endTime, _ = strconv.ParseFloat(parts[0], 64)
+
+		holdData[5] = ""
+		if len(parts) > 1 {
+			holdData[5] = parts[1]
+		}
+	}
+
+	circle := NewCircle(holdData)
+	circle.EndTime = math.Max(circle.StartTime, endTime)
+
+	return circle
+}
+
 func DummyCircle(pos vector.Vector2f, time float64) *Circle {
 	return DummyCircleInherit(pos, time, false, false, false)
 }
@@ -106,6 +133,8 @@ func (circle *Circle) Update(time float64) bool {
 		s.Update(time)
 	}
 
+	circle.visuals.Update(time)
+
 	circle.lastTime = time
 
 	return true
@@ -132,6 +161,11 @@ func (circle *Circle) PlaySound() {
 	audio.PlaySample(sampleSet, circle.BasicHitSound.AdditionSet, circle.sample, index, point.SampleVolume, circle.HitObjectID, circle.GetStackedStartPosition().X64())
 }
 
+// GetSample returns hitsound bits (1 normal, 2 whistle, 4 finish, 8 clap)
+func (circle *Circle) GetSample() int {
+	return circle.sample
+}
+
 func (circle *Circle) SetTiming(timings *Timings, _ bool) {
 	circle.Timings = timings
 }
@@ -139,6 +173,10 @@ func (circle *Circle) SetTiming(timings *Timings, _ bool) {
 func (circle *Circle) SetDifficulty(diff *difficulty.Difficulty) {
 	circle.diff = diff
 
+	if !circle.SliderPoint {
+		circle.visuals = NewVisuals(circle.HitObject, false, diff)
+	}
+
 	startTime := circle.StartTime - diff.Preempt
 
 	if circle.SliderPoint {
@@ -212,7 +250,7 @@ func (circle *Circle) SetDifficulty(diff *difficulty.Difficulty) {
 
 		circle.sprites = append(circle.sprites, circle.approachCircle)
 
-		if !diff.CheckModActive(difficulty.Hidden) || circle.HitObjectID == 0 {
+		if (!diff.CheckModActive(difficulty.Hidden) || circle.HitObjectID == 0) && !diff.CheckModActive(difficulty.ApproachMask) {
 			circle.approachCircle.AddTransform(animation.NewSingleTransform(animation.Fade, easing.Linear, startTime, math.Min(endTime, endTime-diff.Preempt+diff.TimeFadeIn*2), 0.0, 0.9))
 			circle.approachCircle.AddTransform(animation.NewSingleTransform(animation.Fade, easing.Linear, endTime, endTime, 0.0, 0.0))
 
@@ -288,10 +326,15 @@ func (circle *Circle) UpdateStacking() {
 }
 
 func (circle *Circle) Draw(time float64, color color2.Color, batch *batch.QuadBatch) bool {
-	position := circle.GetStackedPositionAtMod(time, circle.diff.Mods)
+	position := circle.visuals.Apply(circle.GetStackedPositionAtMod(time, circle.diff.Mods))
+	scale := circle.visuals.GetScale()
+	rotation := circle.visuals.GetRotation()
 
-	batch.SetSubScale(1, 1)
+	prevRotation := batch.GetRotation()
+
+	batch.SetSubScale(scale.X, scale.Y)
 	batch.SetTranslation(position.Copy64())
+	batch.SetRotation(prevRotation + rotation)
 
 	alpha := float64(color.A)
 
@@ -318,14 +361,13 @@ func (circle *Circle) Draw(time float64, color color2.Color, batch *batch.QuadBa
 				circle.comboText.Draw(0, batch)
 			}
 		} else if !circle.SliderPointEnd {
-			prevRotation := batch.GetRotation()
-			batch.SetRotation(circle.ArrowRotation)
+			batch.SetRotation(circle.ArrowRotation + rotation)
 			//circle.reverseArrow.SetRotation(circle.ArrowRotation)
 			circle.reverseArrow.Draw(time, batch)
-			batch.SetRotation(prevRotation)
+			batch.SetRotation(prevRotation + rotation)
 		}
 
-		batch.SetSubScale(1, 1)
+		batch.SetSubScale(scale.X, scale.Y)
 		batch.SetTranslation(position.Copy64())
 		batch.SetColor(1, 1, 1, alpha)
 
@@ -336,6 +378,7 @@ func (circle *Circle) Draw(time float64, color color2.Color, batch *batch.QuadBa
 
 	batch.SetSubScale(1, 1)
 	batch.SetTranslation(vector.NewVec2d(0, 0))
+	batch.SetRotation(prevRotation)
 
 	if time >= circle.StartTime && circle.hitCircle.GetAlpha() <= 0.001 {
 		return true
@@ -349,7 +392,7 @@ func (circle *Circle) DrawApproach(time float64, color color2.Color, batch *batc
 		return
 	}
 
-	position := circle.GetStackedPositionAtMod(time, circle.diff.Mods)
+	position := circle.visuals.Apply(circle.GetStackedPositionAtMod(time, circle.diff.Mods))
 
 	batch.SetSubScale(1, 1)
 	batch.SetTranslation(position.Copy64())
@@ -45,13 +45,15 @@ var snakeEase = easing.Easing(func(f float64) float64 {
 type Slider struct {
 	*HitObject
 
-	multiCurve  *curves.MultiCurve
-	scorePath   []PathLine
-	Timings     *Timings
-	TPoint      TimingPoint
-	pixelLength float64
-	partLen     float64
-	RepeatCount int64
+	multiCurve    *curves.MultiCurve
+	curveType     string
+	controlPoints []vector.Vector2f
+	scorePath     []PathLine
+	Timings       *Timings
+	TPoint        TimingPoint
+	pixelLength   float64
+	partLen       float64
+	RepeatCount   int64
 
 	sampleSets   []int
 	additionSets []int
@@ -85,6 +87,8 @@ type Slider struct {
 
 	isSliding bool
 
+	visuals *Visuals
+
 	EndTimeLazer     float64
 	ScorePointsLazer []TickPoint
 	spanDuration     float64
@@ -114,6 +118,9 @@ func NewSlider(data []string) *Slider {
 		points = append(points, vector.NewVec2f(float32(x), float32(y)))
 	}
 
+	slider.curveType = list[0]
+	slider.controlPoints = points[1:]
+
 	slider.multiCurve = curves.NewMultiCurveT(list[0], points, slider.pixelLength)
 
 	slider.EndTime = slider.StartTime
@@ -188,6 +195,54 @@ func (slider *Slider) GetEndAngleMod(modifier difficulty.Modifier) float32 {
 	return slider.GetStackedEndPositionMod(modifier).AngleRV(slider.GetStackedPositionAtMod(slider.EndTime-math.Min(10, slider.partLen), modifier)) //temporary solution
 }
 
+func (slider *Slider) GetPixelLength() float64 {
+	return slider.pixelLength
+}
+
+// GetBaseSample returns slider's hitsound bits used by the slider body
+func (slider *Slider) GetBaseSample() int {
+	return slider.baseSample
+}
+
+// GetEdgeSamples returns hitsound bits of every slider edge (head, repeats and tail)
+func (slider *Slider) GetEdgeSamples() []int {
+	return slider.samples
+}
+
+// GetEdgeSets returns sample and addition sets of every slider edge (head, repeats and tail)
+func (slider *Slider) GetEdgeSets() (sampleSets []int, additionSets []int) {
+	return slider.sampleSets, slider.additionSets
+}
+
+// GetCurveType returns the curve type letter as written in .osu file
+func (slider *Slider) GetCurveType() string {
+	return slider.curveType
+}
+
+// GetCurve returns slider's path already cut or extended to slider's length
+func (slider *Slider) GetCurve() *curves.MultiCurve {
+	return slider.multiCurve
+}
+
+// GetControlPoints returns raw curve control points, excluding slider's head
+func (slider *Slider) GetControlPoints() []vector.Vector2f {
+	return slider.controlPoints
+}
+
+// SetPath moves slider's head and replaces its control points (excluding the head), the curve is regenerated.
+// Used by position altering mods, has to be called before SetTiming.
+func (slider *Slider) SetPath(position vector.Vector2f, controlPoints []vector.Vector2f) {
+	points := append([]vector.Vector2f{position}, controlPoints...)
+
+	slider.StartPosRaw = position
+	slider.controlPoints = points[1:]
+
+	slider.multiCurve = curves.NewMultiCurveT(slider.curveType, points, slider.pixelLength)
+
+	slider.EndPosRaw = slider.multiCurve.PointAt(1.0)
+	slider.Pos = slider.StartPosRaw
+}
+
 func (slider *Slider) GetPartLen() float32 {
 	return float32(20.0) / float32(slider.Timings.GetSliderTimeP(slider.TPoint, slider.pixelLength)) * float32(slider.pixelLength)
 }
@@ -453,6 +508,7 @@ func (slider *Slider) UpdateStacking() {
 
 func (slider *Slider) SetDifficulty(diff *difficulty.Difficulty) {
 	slider.diff = diff
+	slider.visuals = NewVisuals(slider.HitObject, true, diff)
 	slider.sliderSnakeTail = animation.NewGlider(0)
 	slider.sliderSnakeHead = animation.NewGlider(0)
 
@@ -493,6 +549,7 @@ func (slider *Slider) SetDifficulty(diff *difficulty.Difficulty) {
 	slider.startCircle.StackOffsetHR = slider.StackOffsetHR
 	slider.startCircle.StackOffsetEZ = slider.StackOffsetEZ
 	slider.startCircle.SetDifficulty(diff)
+	slider.startCircle.visuals = slider.visuals
 
 	slider.edges = append(slider.edges, slider.startCircle)
 
@@ -536,6 +593,7 @@ func (slider *Slider) SetDifficulty(diff *difficulty.Difficulty) {
 		circle.StackOffsetEZ = slider.StackOffsetEZ
 		circle.SetTiming(slider.Timings, false)
 		circle.SetDifficulty(diff)
+		circle.visuals = slider.visuals
 
 		slider.endCircles = append(slider.endCircles, circle)
 		slider.edges = append(slider.edges, circle)
@@ -639,6 +697,8 @@ func (slider *Slider) Update(time float64) bool {
 	slider.fade.Update(time)
 	slider.bodyFade.Update(time)
 
+	slider.visuals.Update(time)
+
 	headPos := slider.multiCurve.PointAt(float32(slider.sliderSnakeHead.GetValue()))
 	tailPos := slider.multiCurve.PointAt(float32(slider.sliderSnakeTail.GetValue()))
 	headAngle := slider.multiCurve.GetStartAngleAt(float32(slider.sliderSnakeHead.GetValue())) + math.Pi
@@ -671,10 +731,10 @@ func (slider *Slider) Update(time float64) bool {
 	if settings.Objects.Sliders.Snaking.Out && slider.RepeatCount%2 == 1 && time >= math.Floor(slider.EndTime-slider.partLen) {
 		snakeTime := slider.EndTime - slider.partLen*(1-slider.sliderSnakeHead.GetValue())
 		p2 := slider.GetStackedPositionAtMod(snakeTime, slider.diff.Mods)
-		slider.ball.SetPosition(p2.Copy64())
+		slider.ball.SetPosition(slider.visuals.Apply(p2).Copy64())
 		slider.startCircle.StartPosRaw = slider.GetPositionAt(snakeTime)
 	} else {
-		slider.ball.SetPosition(pos.Copy64())
+		slider.ball.SetPosition(slider.visuals.Apply(pos).Copy64())
 	}
 
 	if time-slider.lastTime > 0 && time >= slider.StartTime {
@@ -952,7 +1012,7 @@ func (slider *Slider) DrawBody(_ float64, bodyColor, innerBorder, outerBorder co
 		stackOffset = slider.StackOffsetEZ
 	}
 
-	slider.body.DrawNormal(projection, stackOffset, scale, bodyInner, bodyOuter, borderInner, borderOuter)
+	slider.body.DrawNormal(slider.visuals.ApplyToMatrix(projection), stackOffset, scale, bodyInner, bodyOuter, borderInner, borderOuter)
 }
 
 func (slider *Slider) Draw(time float64, color color2.Color, batch *batch.QuadBatch) bool {
@@ -979,8 +1039,10 @@ func (slider *Slider) Draw(time float64, color color2.Color, batch *batch.QuadBa
 					al := p.fade.GetValue()
 
 					if al > 0.001 {
-						batch.SetTranslation(p.Pos.Copy64())
-						batch.SetSubScale(p.scale.GetValue(), p.scale.GetValue())
+						visualScale := slider.visuals.GetScale()
+
+						batch.SetTranslation(slider.visuals.Apply(p.Pos).Copy64())
+						batch.SetSubScale(p.scale.GetValue()*visualScale.X, p.scale.GetValue()*visualScale.Y)
 
 						if settings.Objects.Colors.Sliders.WhiteScorePoints || settings.Skin.UseColorsFromSkin {
 							batch.SetColor(1, 1, 1, alpha*al)
@@ -1011,7 +1073,7 @@ func (slider *Slider) Draw(time float64, color color2.Color, batch *batch.QuadBa
 	}
 
 	if settings.DIVIDES < settings.Objects.Colors.MandalaTexturesTrigger && settings.Objects.Sliders.DrawSliderFollowCircle && slider.follower != nil {
-		batch.SetTranslation(slider.Pos.Copy64())
+		batch.SetTranslation(slider.visuals.Apply(slider.Pos).Copy64())
 		batch.SetColor(1, 1, 1, alpha)
 		slider.follower.Draw(time, batch)
 	}
@@ -1033,6 +1095,13 @@ func (slider *Slider) Draw(time float64, color color2.Color, batch *batch.QuadBa
 	return false
 }
 
+// DisposeBody frees slider's body framebuffer if the slider is thrown away while still visible
+func (slider *Slider) DisposeBody() {
+	if slider.body != nil {
+		slider.body.Dispose()
+	}
+}
+
 func (slider *Slider) drawBall(time float64, batch *batch.QuadBatch, color color2.Color, alpha float64, useBallTexture bool) {
 	batch.SetTranslation(slider.ball.GetPosition())
 
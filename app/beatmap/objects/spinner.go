@@ -86,6 +86,11 @@ func (spinner *Spinner) GetPosition() vector.Vector2f {
 	return spinner.pos
 }
 
+// GetSample returns hitsound bits (1 normal, 2 whistle, 4 finish, 8 clap)
+func (spinner *Spinner) GetSample() int {
+	return spinner.sample
+}
+
 func (spinner *Spinner) SetTiming(timings *Timings, _ bool) {
 	spinner.Timings = timings
 }
@@ -40,6 +40,11 @@ func (t TimingPoint) GetBeatLength() float64 {
 	return t.beatLengthBase * t.GetRatio()
 }
 
+// GetRawBeatLength returns beat length as written in .osu file, negative slider velocity multiplier for inherited points
+func (t TimingPoint) GetRawBeatLength() float64 {
+	return t.beatLength
+}
+
 type Timings struct {
 	SliderMult float64
 	TickRate   float64
@@ -110,6 +115,11 @@ func (tim *Timings) Update(time float64) {
 	tim.Current = tim.GetPointAt(time)
 }
 
+// GetPoints returns all timing points sorted by time
+func (tim *Timings) GetPoints() []TimingPoint {
+	return tim.points
+}
+
 func (tim *Timings) GetDefault() TimingPoint {
 	return tim.defaultTimingPoint
 }
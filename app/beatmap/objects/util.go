@@ -12,11 +12,13 @@ func CreateObject(data []string) IHitObject {
 	if (objType & CIRCLE) > 0 {
 		return NewCircle(data)
 	} else if (objType & SPINNER) > 0 {
-		if settings.Objects.LoadSpinners || settings.KNOCKOUT || settings.PLAY {
+		if settings.Objects.LoadSpinners || settings.KNOCKOUT || settings.PLAY || settings.MODE != settings.ModeOsu {
 			return NewSpinner(data)
 		}
 	} else if (objType & SLIDER) > 0 {
 		return NewSlider(data)
+	} else if (objType&LONGNOTE) > 0 && settings.MODE == settings.ModeMania {
+		return NewHoldNote(data)
 	}
 
 	return nil
@@ -0,0 +1,190 @@
+package objects
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/framework/math/animation"
+	"github.com/wieku/danser-go/framework/math/animation/easing"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"github.com/wieku/danser-go/framework/util"
+	"math"
+)
+
+const (
+	wiggleStep     = 100.0
+	wiggleDuration = 90.0
+	wiggleStrength = 7.0
+
+	spinInWidth = 2.0
+
+	maxDepth      = 100.0
+	focusDistance = 200.0
+)
+
+var playfieldCentre = vector.NewVec2f(256, 192)
+
+// Visuals are draw-only transformations done by osu!lazer's Transform, Wiggle, Grow, Deflate, Spin In and Depth mods.
+// Gameplay positions aren't changed. Nil Visuals are valid and don't transform anything.
+type Visuals struct {
+	origin vector.Vector2f
+
+	offsetX  *animation.Glider
+	offsetY  *animation.Glider
+	scaleX   *animation.Glider
+	scaleY   *animation.Glider
+	rotation *animation.Glider
+}
+
+// NewVisuals creates transformations for the object if any visual mod is active, slider parts should share slider's Visuals
+func NewVisuals(hitObject *HitObject, isSlider bool, diff *difficulty.Difficulty) *Visuals {
+	if !diff.CheckModActive(difficulty.VisualMask) {
+		return nil
+	}
+
+	visuals := &Visuals{
+		origin:   hitObject.GetStackedStartPositionMod(diff.Mods),
+		offsetX:  animation.NewGlider(0),
+		offsetY:  animation.NewGlider(0),
+		scaleX:   animation.NewGlider(1),
+		scaleY:   animation.NewGlider(1),
+		rotation: animation.NewGlider(0),
+	}
+
+	appearTime := hitObject.StartTime - diff.Preempt
+
+	switch {
+	case diff.CheckModActive(difficulty.Transform):
+		// Every next object appears rotated further around its position
+		theta := float64(hitObject.HitObjectID) * diff.TimeFadeIn / 1000
+		appearDistance := (diff.Preempt - diff.TimeFadeIn) / 2
+
+		visuals.offsetX.AddEventSEase(appearTime-1, hitObject.StartTime, math.Cos(theta)*appearDistance, 0, easing.InOutSine)
+		visuals.offsetY.AddEventSEase(appearTime-1, hitObject.StartTime, math.Sin(theta)*appearDistance, 0, easing.InOutSine)
+	case diff.CheckModActive(difficulty.Wiggle):
+		random := util.NewNetRandom(int32(hitObject.StartTime))
+
+		wiggle := func(time float64) {
+			angle := random.NextDouble() * 2 * math.Pi
+			distance := random.NextDouble() * wiggleStrength
+
+			visuals.offsetX.AddEvent(time, time+wiggleDuration, distance*math.Cos(angle))
+			visuals.offsetY.AddEvent(time, time+wiggleDuration, distance*math.Sin(angle))
+		}
+
+		for i := 0; i < int(diff.Preempt)/wiggleStep; i++ {
+			wiggle(appearTime + float64(i)*wiggleStep)
+		}
+
+		// Keep wiggling sliders for their duration
+		for i := 0; i < int(hitObject.GetDuration()/wiggleStep); i++ {
+			wiggle(hitObject.StartTime + float64(i)*wiggleStep)
+		}
+	}
+
+	switch {
+	case diff.CheckModActive(difficulty.Grow):
+		visuals.addScale(appearTime, hitObject.StartTime, 0.5, 0.5, easing.OutSine)
+	case diff.CheckModActive(difficulty.Deflate):
+		visuals.addScale(appearTime, hitObject.StartTime, 2, 2, easing.OutSine)
+	case diff.CheckModActive(difficulty.SpinIn):
+		if isSlider {
+			visuals.addScale(appearTime, hitObject.StartTime, 0, 0, easing.InOutSine)
+		} else {
+			visuals.addScale(appearTime, hitObject.StartTime, spinInWidth, 0, easing.InOutSine)
+			visuals.rotation.AddEventSEase(appearTime, hitObject.StartTime, 2*math.Pi, 0, easing.InOutSine)
+		}
+	case diff.CheckModActive(difficulty.Depth):
+		// Objects come from the depth to the playfield, they are scaled with perspective towards playfield's centre
+		startScale := depthScale(maxDepth)
+		offset := visuals.origin.Sub(playfieldCentre).Copy64().Scl(startScale - 1)
+
+		visuals.addScale(appearTime, hitObject.StartTime, startScale, startScale, depthEasing)
+		visuals.offsetX.AddEventSEase(appearTime, hitObject.StartTime, offset.X, 0, depthEasing)
+		visuals.offsetY.AddEventSEase(appearTime, hitObject.StartTime, offset.Y, 0, depthEasing)
+	}
+
+	return visuals
+}
+
+func (visuals *Visuals) addScale(startTime, endTime, startX, startY float64, easeFunc easing.Easing) {
+	visuals.scaleX.AddEventSEase(startTime, endTime, startX, 1, easeFunc)
+	visuals.scaleY.AddEventSEase(startTime, endTime, startY, 1, easeFunc)
+}
+
+func (visuals *Visuals) Update(time float64) {
+	if visuals == nil {
+		return
+	}
+
+	visuals.offsetX.Update(time)
+	visuals.offsetY.Update(time)
+	visuals.scaleX.Update(time)
+	visuals.scaleY.Update(time)
+	visuals.rotation.Update(time)
+}
+
+// Apply transforms the position of object's part (e.g. slider tick) to the place where it should be drawn
+func (visuals *Visuals) Apply(position vector.Vector2f) vector.Vector2f {
+	if visuals == nil {
+		return position
+	}
+
+	scale := visuals.GetScale()
+
+	relative := position.Sub(visuals.origin).Mult(vector.NewVec2f(float32(scale.X), float32(scale.Y))).Rotate(float32(visuals.GetRotation()))
+
+	return visuals.origin.Add(relative).Add(visuals.GetOffset().Copy32())
+}
+
+// ApplyToMatrix returns projection with the transformations applied, used to draw slider bodies
+func (visuals *Visuals) ApplyToMatrix(projection mgl32.Mat4) mgl32.Mat4 {
+	if visuals == nil {
+		return projection
+	}
+
+	scale := visuals.GetScale()
+	translation := visuals.origin.Add(visuals.GetOffset().Copy32())
+
+	transform := mgl32.Translate3D(translation.X, translation.Y, 0).
+		Mul4(mgl32.HomogRotate3DZ(float32(visuals.GetRotation()))).
+		Mul4(mgl32.Scale3D(float32(scale.X), float32(scale.Y), 1)).
+		Mul4(mgl32.Translate3D(-visuals.origin.X, -visuals.origin.Y, 0))
+
+	return projection.Mul4(transform)
+}
+
+func (visuals *Visuals) GetOffset() vector.Vector2d {
+	if visuals == nil {
+		return vector.NewVec2d(0, 0)
+	}
+
+	return vector.NewVec2d(visuals.offsetX.GetValue(), visuals.offsetY.GetValue())
+}
+
+func (visuals *Visuals) GetScale() vector.Vector2d {
+	if visuals == nil {
+		return vector.NewVec2d(1, 1)
+	}
+
+	return vector.NewVec2d(visuals.scaleX.GetValue(), visuals.scaleY.GetValue())
+}
+
+func (visuals *Visuals) GetRotation() float64 {
+	if visuals == nil {
+		return 0
+	}
+
+	return visuals.rotation.GetValue()
+}
+
+// depthScale returns perspective scale of an object at a given depth
+func depthScale(depth float64) float64 {
+	return focusDistance / (focusDistance + depth)
+}
+
+// depthEasing converts linear movement from maxDepth to the playfield into perspective scale progress
+func depthEasing(t float64) float64 {
+	startScale := depthScale(maxDepth)
+
+	return (depthScale(maxDepth*(1-t)) - startScale) / (1 - startScale)
+}
@@ -252,6 +252,53 @@ func ParseBeatMapFile(file *os.File) *BeatMap {
 	return beatMap
 }
 
+// ReloadBeatMap parses map's file again, database info, mods and custom difficulty values of the loaded map are kept.
+// Objects have to be parsed with ParseObjects.
+func ReloadBeatMap(beatMap *BeatMap) (*BeatMap, error) {
+	fresh := NewBeatMap()
+	fresh.Dir = beatMap.Dir
+	fresh.File = beatMap.File
+
+	if err := ParseBeatMap(fresh); err != nil {
+		return nil, err
+	}
+
+	fresh.MD5 = beatMap.MD5
+	fresh.SetID = beatMap.SetID
+	fresh.ID = beatMap.ID
+	fresh.LastModified = beatMap.LastModified
+	fresh.TimeAdded = beatMap.TimeAdded
+	fresh.PlayCount = beatMap.PlayCount
+	fresh.LastPlayed = beatMap.LastPlayed
+	fresh.LocalOffset = beatMap.LocalOffset
+
+	diff := beatMap.Diff
+
+	if diff.GetHP() != diff.GetBaseHP() {
+		fresh.Diff.SetHPCustom(diff.GetHP())
+	}
+
+	if diff.GetCS() != diff.GetBaseCS() {
+		fresh.Diff.SetCSCustom(diff.GetCS())
+	}
+
+	if diff.GetOD() != diff.GetBaseOD() {
+		fresh.Diff.SetODCustom(diff.GetOD())
+	}
+
+	if diff.GetAR() != diff.GetBaseAR() {
+		fresh.Diff.SetARCustom(diff.GetAR())
+	}
+
+	fresh.Diff.Seed = diff.Seed
+	fresh.Diff.Reflection = diff.Reflection
+	fresh.Diff.SetCustomSpeed(diff.CustomSpeed)
+	fresh.Diff.SetRateAdjust(diff.RateAdjust)
+	fresh.Diff.SetMods(diff.Mods)
+
+	return fresh, nil
+}
+
 func ParseTimingPointsAndPauses(beatMap *BeatMap) {
 	if beatMap.Timings.HasPoints() {
 		return
@@ -330,8 +377,10 @@ func ParseObjects(beatMap *BeatMap, diffCalcOnly, parseColors bool) {
 
 		switch currentSection {
 		case "Colours": //nolint:misspell
-			if parseColors {
-				if arr := tokenize(line, ":"); arr != nil {
+			if arr := tokenize(line, ":"); arr != nil {
+				beatMap.Colours = append(beatMap.Colours, arr)
+
+				if parseColors {
 					skin.AddBeatmapColor(arr)
 				}
 			}
@@ -377,9 +426,15 @@ func ParseObjects(beatMap *BeatMap, diffCalcOnly, parseColors bool) {
 		num++
 	}
 
+	applyPositionMods(beatMap)
+
 	for _, obj := range beatMap.HitObjects {
 		obj.SetTiming(beatMap.Timings, diffCalcOnly)
 	}
 
+	if len(beatMap.HitObjects) > 0 {
+		beatMap.Diff.SetRampBounds(beatMap.HitObjects[0].GetStartTime(), beatMap.HitObjects[len(beatMap.HitObjects)-1].GetEndTime())
+	}
+
 	calculateStackLeniency(beatMap)
 }
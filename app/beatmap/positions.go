@@ -0,0 +1,592 @@
+package beatmap
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/math/math32"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"github.com/wieku/danser-go/framework/util"
+	"log"
+	"math"
+	"math/rand"
+)
+
+//Original code by: https://github.com/ppy/osu/blob/master/osu.Game.Rulesets.Osu/Mods/OsuModRandom.cs
+//and https://github.com/ppy/osu/blob/master/osu.Game.Rulesets.Osu/Utils/OsuHitObjectGenerationUtils_Reposition.cs
+
+const (
+	playfieldWidth = 512.0
+
+	playfieldEdgeRatio = 0.375
+	borderDistanceX    = playfieldWidth * playfieldEdgeRatio
+	borderDistanceY    = playfieldHeight * playfieldEdgeRatio
+
+	precedingObjectsToShift = 10
+
+	angleSharpness    = 7.0
+	maxAngleSharpness = 10.0
+)
+
+var playfieldCentre = vector.NewVec2f(playfieldWidth/2, playfieldHeight/2)
+
+var playfieldDiagonal = float32(math.Sqrt(playfieldWidth*playfieldWidth + playfieldHeight*playfieldHeight))
+
+type positionInfo struct {
+	object objects.IHitObject
+
+	relativeAngle        float32
+	distanceFromPrevious float32
+	rotation             float32
+}
+
+type workingObject struct {
+	*positionInfo
+
+	rotationOriginal    float32
+	positionModified    vector.Vector2f
+	endPositionModified vector.Vector2f
+}
+
+// applyPositionMods moves objects according to Mirror and Random mods, has to be done before timing and stacking is calculated
+func applyPositionMods(beatMap *BeatMap) {
+	if settings.MODE != settings.ModeOsu || !beatMap.Diff.CheckModActive(difficulty.PositionMask) {
+		return
+	}
+
+	if beatMap.Diff.CheckModActive(difficulty.Mirror) {
+		applyMirror(beatMap.HitObjects, beatMap.Diff.Reflection)
+	}
+
+	if beatMap.Diff.CheckModActive(difficulty.Random) {
+		if beatMap.Diff.Seed < 0 {
+			beatMap.Diff.Seed = int64(rand.Int31())
+		}
+
+		log.Println("Random mod seed:", beatMap.Diff.Seed)
+
+		newRandomizer(beatMap).apply()
+	}
+}
+
+func applyMirror(hitObjects []objects.IHitObject, reflection difficulty.Reflection) {
+	flip := func(pos vector.Vector2f) vector.Vector2f {
+		if reflection != difficulty.ReflectVertical {
+			pos.X = playfieldWidth - pos.X
+		}
+
+		if reflection != difficulty.ReflectHorizontal {
+			pos.Y = playfieldHeight - pos.Y
+		}
+
+		return pos
+	}
+
+	for _, obj := range hitObjects {
+		switch o := obj.(type) {
+		case *objects.Circle:
+			o.StartPosRaw = flip(o.StartPosRaw)
+			o.EndPosRaw = o.StartPosRaw
+		case *objects.Slider:
+			points := make([]vector.Vector2f, len(o.GetControlPoints()))
+			for i, p := range o.GetControlPoints() {
+				points[i] = flip(p)
+			}
+
+			o.SetPath(flip(o.StartPosRaw), points)
+		}
+	}
+}
+
+type randomizer struct {
+	beatMap *BeatMap
+	random  *util.NetRandom
+	radius  float32
+}
+
+func newRandomizer(beatMap *BeatMap) *randomizer {
+	return &randomizer{
+		beatMap: beatMap,
+		random:  util.NewNetRandom(int32(beatMap.Diff.Seed)),
+		radius:  float32(beatMap.Diff.CircleRadiusU),
+	}
+}
+
+func (r *randomizer) apply() {
+	positionInfos := generatePositionInfos(r.beatMap.HitObjects)
+
+	// Offsets the angles of all hit objects in a "section" by the same amount
+	sectionOffset := float32(0)
+
+	// Whether the angles are positive or negative (clockwise or counter-clockwise flow)
+	flowDirection := false
+
+	for i, info := range positionInfos {
+		if r.shouldStartNewSection(positionInfos, i) {
+			sectionOffset = r.randomOffset(0.0008)
+			flowDirection = !flowDirection
+		}
+
+		if slider, ok := info.object.(*objects.Slider); ok && r.random.NextDouble() < 0.5 {
+			flipSliderHorizontally(slider)
+		}
+
+		if i == 0 {
+			info.distanceFromPrevious = float32(r.random.NextDouble() * playfieldHeight / 2)
+			info.relativeAngle = float32(r.random.NextDouble()*2*math.Pi - math.Pi)
+
+			continue
+		}
+
+		// Offsets only the angle of the current hit object if a flow change occurs
+		flowChangeOffset := float32(0)
+
+		// Offsets only the angle of the current hit object
+		oneTimeOffset := r.randomOffset(0.002)
+
+		if r.shouldApplyFlowChange(positionInfos, i) {
+			flowChangeOffset = r.randomOffset(0.002)
+			flowDirection = !flowDirection
+		}
+
+		// sectionOffset and oneTimeOffset should mainly affect patterns with large spacing, flowChangeOffset should mainly affect streams
+		totalOffset := (sectionOffset+oneTimeOffset)*info.distanceFromPrevious + flowChangeOffset*(playfieldDiagonal-info.distanceFromPrevious)
+
+		info.relativeAngle = relativeTargetAngle(info.distanceFromPrevious, totalOffset, flowDirection)
+	}
+
+	r.reposition(positionInfos)
+}
+
+// randomOffset doesn't apply lazer's angle sharpness multiplier as it's always 1 for the default sharpness
+func (r *randomizer) randomOffset(stdDev float32) float32 {
+	return randomGaussian(r.random, 0, stdDev)
+}
+
+func (r *randomizer) shouldStartNewSection(positionInfos []*positionInfo, i int) bool {
+	if i == 0 {
+		return true
+	}
+
+	// Exclude new-combo-spam and 1-2-combos
+	previousObjectStartedCombo := comboIndex(positionInfos[mutils.Max(0, i-2)].object) > 1 && positionInfos[i-1].object.IsNewCombo()
+	previousObjectWasOnDownbeat := r.isOnBeat(positionInfos[i-1].object, true)
+	previousObjectWasOnBeat := r.isOnBeat(positionInfos[i-1].object, false)
+
+	return (previousObjectStartedCombo && r.random.NextDouble() < 0.6) ||
+		previousObjectWasOnDownbeat ||
+		(previousObjectWasOnBeat && r.random.NextDouble() < 0.4)
+}
+
+func (r *randomizer) shouldApplyFlowChange(positionInfos []*positionInfo, i int) bool {
+	// Exclude new-combo-spam and 1-2-combos
+	previousObjectStartedCombo := comboIndex(positionInfos[mutils.Max(0, i-2)].object) > 1 && positionInfos[i-1].object.IsNewCombo()
+
+	return previousObjectStartedCombo && r.random.NextDouble() < 0.6
+}
+
+// isOnBeat checks if object is within 1ms of a beat (or a downbeat)
+func (r *randomizer) isOnBeat(obj objects.IHitObject, downbeatsOnly bool) bool {
+	if !r.beatMap.Timings.HasPoints() {
+		return false
+	}
+
+	point := r.beatMap.Timings.GetOriginalPointAt(obj.GetStartTime())
+
+	beatLength := point.GetBaseBeatLength()
+	if downbeatsOnly {
+		beatLength *= float64(point.Signature)
+	}
+
+	return math.Mod(obj.GetStartTime()-point.Time+1, beatLength) < 2
+}
+
+func (r *randomizer) reposition(positionInfos []*positionInfo) {
+	workingObjects := make([]*workingObject, len(positionInfos))
+
+	for i, info := range positionInfos {
+		workingObjects[i] = &workingObject{
+			positionInfo:        info,
+			positionModified:    info.object.GetStartPosition(),
+			endPositionModified: info.object.GetEndPosition(),
+		}
+
+		if slider, ok := info.object.(*objects.Slider); ok {
+			workingObjects[i].rotationOriginal = sliderRotation(slider)
+		}
+	}
+
+	var previous *workingObject
+
+	for i, current := range workingObjects {
+		if _, ok := current.object.(*objects.Spinner); ok {
+			previous = current
+			continue
+		}
+
+		var beforePrevious *workingObject
+		if i > 1 {
+			beforePrevious = workingObjects[i-2]
+		}
+
+		computeModifiedPosition(current, previous, beforePrevious)
+
+		// Move hit objects back into the playfield if they are outside of it
+		var shift vector.Vector2f
+
+		switch current.object.(type) {
+		case *objects.Circle:
+			shift = r.clampCircle(current)
+		case *objects.Slider:
+			shift = r.clampSlider(current)
+		}
+
+		if shift != (vector.Vector2f{}) {
+			toBeShifted := make([]*objects.Circle, 0, precedingObjectsToShift)
+
+			for j := i - 1; j >= i-precedingObjectsToShift && j >= 0; j-- {
+				// only shift hit circles
+				circle, ok := workingObjects[j].object.(*objects.Circle)
+				if !ok {
+					break
+				}
+
+				toBeShifted = append(toBeShifted, circle)
+			}
+
+			r.applyDecreasingShift(toBeShifted, shift)
+		}
+
+		previous = current
+	}
+}
+
+func computeModifiedPosition(current, previous, beforePrevious *workingObject) {
+	previousAbsoluteAngle := float32(0)
+
+	if previous != nil {
+		if slider, ok := previous.object.(*objects.Slider); ok {
+			previousAbsoluteAngle = sliderRotation(slider)
+		} else {
+			earliestPosition := playfieldCentre
+			if beforePrevious != nil {
+				earliestPosition = beforePrevious.object.GetEndPosition()
+			}
+
+			previousAbsoluteAngle = previous.object.GetStartPosition().Sub(earliestPosition).AngleR()
+		}
+	}
+
+	absoluteAngle := previousAbsoluteAngle + current.relativeAngle
+
+	posRelativeToPrev := vector.NewVec2f(current.distanceFromPrevious*math32.Cos(absoluteAngle), current.distanceFromPrevious*math32.Sin(absoluteAngle))
+
+	lastEndPosition := playfieldCentre
+	if previous != nil {
+		lastEndPosition = previous.endPositionModified
+	}
+
+	posRelativeToPrev = rotateAwayFromEdge(lastEndPosition, posRelativeToPrev, 0.5)
+
+	current.positionModified = lastEndPosition.Add(posRelativeToPrev)
+
+	slider, ok := current.object.(*objects.Slider)
+	if !ok {
+		return
+	}
+
+	absoluteAngle = posRelativeToPrev.AngleR()
+
+	centreOfMassOriginal := sliderCentreOfMass(slider)
+	centreOfMassModified := rotateVector(centreOfMassOriginal, current.rotation+absoluteAngle-sliderRotation(slider))
+	centreOfMassModified = rotateAwayFromEdge(current.positionModified, centreOfMassModified, 0.5)
+
+	relativeRotation := centreOfMassModified.AngleR() - centreOfMassOriginal.AngleR()
+
+	if math32.Abs(relativeRotation) > 1e-3 {
+		rotateSlider(slider, relativeRotation)
+	}
+}
+
+func (r *randomizer) clampCircle(current *workingObject) vector.Vector2f {
+	previousPosition := current.positionModified
+
+	current.positionModified = clampToPlayfield(current.positionModified, r.radius)
+	current.endPositionModified = current.positionModified
+
+	setCirclePosition(current.object.(*objects.Circle), current.positionModified)
+
+	return current.positionModified.Sub(previousPosition)
+}
+
+func (r *randomizer) clampSlider(current *workingObject) vector.Vector2f {
+	slider := current.object.(*objects.Slider)
+
+	left, top, right, bottom := r.movementBounds(slider)
+
+	// The slider rotation applied in computeModifiedPosition might make it impossible to fit the slider into the playfield.
+	// In this case, limit the rotation to either 0 or 180 degrees.
+	if right < left || bottom < top {
+		currentRotation := sliderRotation(slider)
+
+		diff1 := angleDifference(current.rotationOriginal, currentRotation)
+		diff2 := angleDifference(current.rotationOriginal+math32.Pi, currentRotation)
+
+		if diff1 < diff2 {
+			rotateSlider(slider, current.rotationOriginal-currentRotation)
+		} else {
+			rotateSlider(slider, current.rotationOriginal+math32.Pi-currentRotation)
+		}
+
+		left, top, right, bottom = r.movementBounds(slider)
+	}
+
+	previousPosition := current.positionModified
+
+	// If the slider is larger than the playfield, at least make sure that the head circle is inside the playfield
+	var newPosition vector.Vector2f
+
+	if right < left {
+		newPosition.X = mutils.ClampF(left, 0, playfieldWidth)
+	} else {
+		newPosition.X = mutils.ClampF(previousPosition.X, left, right)
+	}
+
+	if bottom < top {
+		newPosition.Y = mutils.ClampF(top, 0, playfieldHeight)
+	} else {
+		newPosition.Y = mutils.ClampF(previousPosition.Y, top, bottom)
+	}
+
+	moveSlider(slider, newPosition)
+
+	current.positionModified = newPosition
+	current.endPositionModified = slider.GetEndPosition()
+
+	return current.positionModified.Sub(previousPosition)
+}
+
+// movementBounds returns the area slider's head can be placed in while keeping the whole slider in the playfield
+func (r *randomizer) movementBounds(slider *objects.Slider) (left, top, right, bottom float32) {
+	minX, minY := math32.Inf(1), math32.Inf(1)
+	maxX, maxY := math32.Inf(-1), math32.Inf(-1)
+
+	for _, pos := range sliderPathPoints(slider) {
+		minX = math32.Min(minX, pos.X)
+		maxX = math32.Max(maxX, pos.X)
+		minY = math32.Min(minY, pos.Y)
+		maxY = math32.Max(maxY, pos.Y)
+	}
+
+	return -minX + r.radius, -minY + r.radius, playfieldWidth - maxX - r.radius, playfieldHeight - maxY - r.radius
+}
+
+func (r *randomizer) applyDecreasingShift(circles []*objects.Circle, shift vector.Vector2f) {
+	for i, circle := range circles {
+		// The first object is shifted by a vector slightly smaller than shift, the last object is shifted by a vector slightly larger than zero
+		position := circle.StartPosRaw.Add(shift.Scl(float32(len(circles)-i) / float32(len(circles)+1)))
+
+		setCirclePosition(circle, clampToPlayfield(position, r.radius))
+	}
+}
+
+func generatePositionInfos(hitObjects []objects.IHitObject) []*positionInfo {
+	positionInfos := make([]*positionInfo, 0, len(hitObjects))
+
+	previousPosition := playfieldCentre
+	previousAngle := float32(0)
+
+	for _, obj := range hitObjects {
+		relativePosition := obj.GetStartPosition().Sub(previousPosition)
+		absoluteAngle := relativePosition.AngleR()
+
+		info := &positionInfo{
+			object:               obj,
+			relativeAngle:        absoluteAngle - previousAngle,
+			distanceFromPrevious: relativePosition.Len(),
+		}
+
+		if slider, ok := obj.(*objects.Slider); ok {
+			absoluteRotation := sliderRotation(slider)
+			info.rotation = absoluteRotation - absoluteAngle
+			absoluteAngle = absoluteRotation
+		}
+
+		positionInfos = append(positionInfos, info)
+
+		previousPosition = obj.GetEndPosition()
+		previousAngle = absoluteAngle
+	}
+
+	return positionInfos
+}
+
+func relativeTargetAngle(targetDistance, offset float32, flowDirection bool) float32 {
+	sharpness := float32(angleSharpness / maxAngleSharpness)
+	wideness := 1 - sharpness
+
+	customOffsetX := sharpness*100 - 70
+	customOffsetY := wideness*0.25 - 0.075
+
+	targetDistance += customOffsetX
+
+	angle := float32(2.16/(1+200*math.Exp(0.036*float64(targetDistance-310+customOffsetX))) + 0.5)
+	angle += offset + customOffsetY
+
+	relativeAngle := math32.Pi - angle
+
+	if flowDirection {
+		return -relativeAngle
+	}
+
+	return relativeAngle
+}
+
+// rotateAwayFromEdge rotates the vector towards playfield's centre if the previous object is close to the edge
+func rotateAwayFromEdge(prevObjectPos, posRelativeToPrev vector.Vector2f, rotationRatio float32) vector.Vector2f {
+	relativeRotationDistance := float32(0)
+
+	if prevObjectPos.X < playfieldCentre.X {
+		relativeRotationDistance = math32.Max((borderDistanceX-prevObjectPos.X)/borderDistanceX, relativeRotationDistance)
+	} else {
+		relativeRotationDistance = math32.Max((prevObjectPos.X-(playfieldWidth-borderDistanceX))/borderDistanceX, relativeRotationDistance)
+	}
+
+	if prevObjectPos.Y < playfieldCentre.Y {
+		relativeRotationDistance = math32.Max((borderDistanceY-prevObjectPos.Y)/borderDistanceY, relativeRotationDistance)
+	} else {
+		relativeRotationDistance = math32.Max((prevObjectPos.Y-(playfieldHeight-borderDistanceY))/borderDistanceY, relativeRotationDistance)
+	}
+
+	return rotateVectorTowardsVector(posRelativeToPrev, playfieldCentre.Sub(prevObjectPos), math32.Min(1, relativeRotationDistance*rotationRatio))
+}
+
+func rotateVectorTowardsVector(initial, destination vector.Vector2f, rotationRatio float32) vector.Vector2f {
+	initialAngle := initial.AngleR()
+
+	diff := destination.AngleR() - initialAngle
+
+	for diff < -math32.Pi {
+		diff += 2 * math32.Pi
+	}
+
+	for diff > math32.Pi {
+		diff -= 2 * math32.Pi
+	}
+
+	finalAngle := initialAngle + rotationRatio*diff
+
+	return vector.NewVec2f(initial.Len()*math32.Cos(finalAngle), initial.Len()*math32.Sin(finalAngle))
+}
+
+func rotateVector(v vector.Vector2f, rotation float32) vector.Vector2f {
+	angle := v.AngleR() + rotation
+
+	return vector.NewVec2f(v.Len()*math32.Cos(angle), v.Len()*math32.Sin(angle))
+}
+
+func angleDifference(angle1, angle2 float32) float32 {
+	diff := math32.Mod(math32.Abs(angle1-angle2), math32.Pi*2)
+
+	return math32.Min(diff, math32.Pi*2-diff)
+}
+
+func randomGaussian(random *util.NetRandom, mean, stdDev float32) float32 {
+	// x1 must not be 0 since log(0) = undefined
+	x1 := 1 - random.NextDouble()
+	x2 := 1 - random.NextDouble()
+
+	stdNormal := math.Sqrt(-2*math.Log(x1)) * math.Sin(2*math.Pi*x2)
+
+	return mean + stdDev*float32(stdNormal)
+}
+
+func comboIndex(obj objects.IHitObject) int64 {
+	switch o := obj.(type) {
+	case *objects.Circle:
+		return o.ComboNumber - 1
+	case *objects.Slider:
+		return o.ComboNumber - 1
+	case *objects.Spinner:
+		return o.ComboNumber - 1
+	}
+
+	return 0
+}
+
+func clampToPlayfield(position vector.Vector2f, padding float32) vector.Vector2f {
+	return vector.NewVec2f(mutils.ClampF(position.X, padding, playfieldWidth-padding), mutils.ClampF(position.Y, padding, playfieldHeight-padding))
+}
+
+func setCirclePosition(circle *objects.Circle, position vector.Vector2f) {
+	circle.StartPosRaw = position
+	circle.EndPosRaw = position
+}
+
+// sliderPathPoints returns points of slider's path relative to its head
+func sliderPathPoints(slider *objects.Slider) []vector.Vector2f {
+	lines := slider.GetCurve().GetLines()
+
+	points := make([]vector.Vector2f, 0, len(lines)+1)
+	points = append(points, vector.Vector2f{})
+
+	for _, line := range lines {
+		points = append(points, line.Point2.Sub(slider.StartPosRaw))
+	}
+
+	return points
+}
+
+func sliderRotation(slider *objects.Slider) float32 {
+	return slider.GetEndPosition().Sub(slider.StartPosRaw).AngleR()
+}
+
+func sliderCentreOfMass(slider *objects.Slider) vector.Vector2f {
+	const sampleStep = 50.0
+
+	distance := slider.GetPixelLength()
+
+	// just sample the start and end positions if the slider is too short
+	if distance <= sampleStep {
+		return slider.GetEndPosition().Sub(slider.StartPosRaw).Scl(0.5)
+	}
+
+	count := 0
+	sum := vector.Vector2f{}
+
+	for i := 0.0; i < distance; i += sampleStep {
+		sum = sum.Add(slider.GetCurve().PointAt(float32(i / distance)).Sub(slider.StartPosRaw))
+		count++
+	}
+
+	return sum.Scl(1 / float32(count))
+}
+
+// transformSlider rebuilds slider's path with control points transformed relatively to slider's head
+func transformSlider(slider *objects.Slider, position vector.Vector2f, transform func(vector.Vector2f) vector.Vector2f) {
+	points := make([]vector.Vector2f, len(slider.GetControlPoints()))
+	for i, p := range slider.GetControlPoints() {
+		points[i] = transform(p.Sub(slider.StartPosRaw)).Add(position)
+	}
+
+	slider.SetPath(position, points)
+}
+
+func rotateSlider(slider *objects.Slider, rotation float32) {
+	transformSlider(slider, slider.StartPosRaw, func(p vector.Vector2f) vector.Vector2f {
+		return rotateVector(p, rotation)
+	})
+}
+
+func flipSliderHorizontally(slider *objects.Slider) {
+	transformSlider(slider, slider.StartPosRaw, func(p vector.Vector2f) vector.Vector2f {
+		return vector.NewVec2f(-p.X, p.Y)
+	})
+}
+
+func moveSlider(slider *objects.Slider, position vector.Vector2f) {
+	transformSlider(slider, position, func(p vector.Vector2f) vector.Vector2f {
+		return p
+	})
+}
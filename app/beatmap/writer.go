@@ -0,0 +1,294 @@
+package beatmap
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/wieku/danser-go/app/audio"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const playfieldHeight = 384.0
+
+var sampleSetNames = map[int]string{
+	1: "Normal",
+	2: "Soft",
+	3: "Drum",
+}
+
+type osuWriter struct {
+	w    *bufio.Writer
+	rate float64
+	flip bool
+}
+
+// SaveBeatMap writes beatmap to a .osu file at the given path, see WriteBeatMap
+func SaveBeatMap(beatMap *BeatMap, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return WriteBeatMap(beatMap, file)
+}
+
+// WriteBeatMap writes beatmap in osu file format v14. Beatmap's objects have to be parsed first.
+// Difficulty overrides, HardRock and speed changing mods are baked into the output, audio has to be rate-changed separately.
+// Storyboard and video events aren't written.
+func WriteBeatMap(beatMap *BeatMap, w io.Writer) error {
+	ow := &osuWriter{
+		w:    bufio.NewWriter(w),
+		rate: beatMap.Diff.Speed,
+		flip: beatMap.Diff.CheckModActive(difficulty.HardRock),
+	}
+
+	if ow.rate != 1 {
+		log.Println(fmt.Sprintf("Writing beatmap at %sx speed, audio file has to be rate-changed separately", mutils.FormatWOZeros(ow.rate, 2)))
+	}
+
+	ow.line("osu file format v14")
+	ow.line("")
+
+	ow.writeGeneral(beatMap)
+	ow.writeMetadata(beatMap)
+	ow.writeDifficulty(beatMap)
+	ow.writeEvents(beatMap)
+	ow.writeTimingPoints(beatMap)
+	ow.writeColours(beatMap)
+	ow.writeHitObjects(beatMap)
+
+	return ow.w.Flush()
+}
+
+func (ow *osuWriter) line(format string, args ...any) {
+	_, _ = fmt.Fprintf(ow.w, format+"\r\n", args...)
+}
+
+func (ow *osuWriter) time(t float64) string {
+	if ow.rate == 1 {
+		return formatFloat(t)
+	}
+
+	return formatFloat(math.Round(t / ow.rate))
+}
+
+func (ow *osuWriter) position(pos vector.Vector2f) string {
+	y := pos.Y
+	if ow.flip {
+		y = playfieldHeight - y
+	}
+
+	return formatFloat32(pos.X) + "," + formatFloat32(y)
+}
+
+func (ow *osuWriter) writeGeneral(beatMap *BeatMap) {
+	ow.line("[General]")
+	ow.line("AudioFilename: %s", beatMap.Audio)
+	ow.line("AudioLeadIn: 0")
+
+	if beatMap.PreviewTime >= 0 {
+		ow.line("PreviewTime: %s", ow.time(float64(beatMap.PreviewTime)))
+	} else {
+		ow.line("PreviewTime: -1")
+	}
+
+	ow.line("Countdown: 0")
+
+	sampleSet, ok := sampleSetNames[beatMap.Timings.BaseSet]
+	if !ok {
+		sampleSet = sampleSetNames[1]
+	}
+
+	ow.line("SampleSet: %s", sampleSet)
+	ow.line("StackLeniency: %s", formatFloat(beatMap.StackLeniency))
+	ow.line("Mode: %d", beatMap.Mode)
+	ow.line("")
+}
+
+func (ow *osuWriter) writeMetadata(beatMap *BeatMap) {
+	version := beatMap.Difficulty
+	id := beatMap.ID
+
+	// Modified difficulty is a different map, it can't share online ID with the original
+	if mods := beatMap.Diff.GetModString(); mods != "" {
+		version += " (" + mods + ")"
+		id = 0
+	}
+
+	ow.line("[Metadata]")
+	ow.line("Title:%s", beatMap.Name)
+	ow.line("TitleUnicode:%s", beatMap.NameUnicode)
+	ow.line("Artist:%s", beatMap.Artist)
+	ow.line("ArtistUnicode:%s", beatMap.ArtistUnicode)
+	ow.line("Creator:%s", beatMap.Creator)
+	ow.line("Version:%s", version)
+	ow.line("Source:%s", beatMap.Source)
+	ow.line("Tags:%s", beatMap.Tags)
+	ow.line("BeatmapID:%d", id)
+	ow.line("BeatmapSetID:%d", beatMap.SetID)
+	ow.line("")
+}
+
+func (ow *osuWriter) writeDifficulty(beatMap *BeatMap) {
+	diff := beatMap.Diff
+
+	ar, od := diff.ARMod, diff.ODMod
+
+	// Approach and hit windows have to stay the same in real time after timing is rescaled
+	if ow.rate != 1 {
+		ar, od = diff.ARReal, diff.ODReal
+	}
+
+	ow.line("[Difficulty]")
+	ow.line("HPDrainRate:%s", clampDifficulty("HP", diff.HPMod))
+	ow.line("CircleSize:%s", clampDifficulty("CS", diff.CSMod))
+	ow.line("OverallDifficulty:%s", clampDifficulty("OD", od))
+	ow.line("ApproachRate:%s", clampDifficulty("AR", ar))
+	ow.line("SliderMultiplier:%s", formatFloat(beatMap.Timings.SliderMult))
+	ow.line("SliderTickRate:%s", formatFloat(beatMap.Timings.TickRate))
+	ow.line("")
+}
+
+func (ow *osuWriter) writeEvents(beatMap *BeatMap) {
+	ow.line("[Events]")
+
+	if beatMap.Bg != "" {
+		ow.line("0,0,\"%s\",0,0", beatMap.Bg)
+	}
+
+	for _, pause := range beatMap.Pauses {
+		ow.line("2,%s,%s", ow.time(pause.StartTime), ow.time(pause.EndTime))
+	}
+
+	ow.line("")
+}
+
+func (ow *osuWriter) writeTimingPoints(beatMap *BeatMap) {
+	ow.line("[TimingPoints]")
+
+	for _, point := range beatMap.Timings.GetPoints() {
+		beatLength := point.GetRawBeatLength()
+		uninherited := 1
+
+		if point.Inherited {
+			uninherited = 0
+		} else {
+			beatLength /= ow.rate
+		}
+
+		effects := 0
+		if point.Kiai {
+			effects |= 1
+		}
+
+		if point.OmitFirstBarLine {
+			effects |= 8
+		}
+
+		ow.line("%s,%s,%d,%d,%d,%d,%d,%d", ow.time(point.Time), formatFloat(beatLength), point.Signature, point.SampleSet, point.SampleIndex, int(math.Round(point.SampleVolume*100)), uninherited, effects)
+	}
+
+	ow.line("")
+}
+
+func (ow *osuWriter) writeColours(beatMap *BeatMap) {
+	if len(beatMap.Colours) == 0 {
+		return
+	}
+
+	ow.line("[Colours]")
+
+	for _, c := range beatMap.Colours {
+		ow.line("%s : %s", c[0], strings.Join(c[1:], ":"))
+	}
+
+	ow.line("")
+}
+
+func (ow *osuWriter) writeHitObjects(beatMap *BeatMap) {
+	ow.line("[HitObjects]")
+
+	for _, obj := range beatMap.HitObjects {
+		switch o := obj.(type) {
+		case *objects.Circle:
+			if o.EndTime > o.StartTime {
+				ow.line("%s,%d,%s:%s", ow.header(o.HitObject, objects.LONGNOTE), o.GetSample(), ow.time(o.EndTime), hitSample(o.BasicHitSound))
+			} else {
+				ow.line("%s,%d,%s", ow.header(o.HitObject, objects.CIRCLE), o.GetSample(), hitSample(o.BasicHitSound))
+			}
+		case *objects.Slider:
+			ow.line("%s,%d,%s,%d,%s,%s,%s", ow.header(o.HitObject, objects.SLIDER), o.GetBaseSample(), ow.curve(o), o.RepeatCount, formatFloat(o.GetPixelLength()), edgeSounds(o), hitSample(o.BasicHitSound))
+		case *objects.Spinner:
+			ow.line("%s,%d,%s,%s", ow.header(o.HitObject, objects.SPINNER), o.GetSample(), ow.time(o.EndTime), hitSample(o.BasicHitSound))
+		}
+	}
+}
+
+// header returns position, time and type fields shared by all hit objects
+func (ow *osuWriter) header(obj *objects.HitObject, baseType objects.Type) string {
+	objType := int64(baseType) | obj.ColorOffset<<4
+
+	if obj.NewCombo {
+		objType |= int64(objects.NEWCOMBO)
+	}
+
+	return fmt.Sprintf("%s,%s,%d", ow.position(obj.StartPosRaw), ow.time(obj.StartTime), objType)
+}
+
+func (ow *osuWriter) curve(slider *objects.Slider) string {
+	var sb strings.Builder
+
+	sb.WriteString(slider.GetCurveType())
+
+	for _, p := range slider.GetControlPoints() {
+		sb.WriteString("|")
+		sb.WriteString(strings.Replace(ow.position(p), ",", ":", 1))
+	}
+
+	return sb.String()
+}
+
+func edgeSounds(slider *objects.Slider) string {
+	samples := slider.GetEdgeSamples()
+	sampleSets, additionSets := slider.GetEdgeSets()
+
+	sounds := make([]string, len(samples))
+	sets := make([]string, len(samples))
+
+	for i := range samples {
+		sounds[i] = strconv.Itoa(samples[i])
+		sets[i] = fmt.Sprintf("%d:%d", sampleSets[i], additionSets[i])
+	}
+
+	return strings.Join(sounds, "|") + "," + strings.Join(sets, "|")
+}
+
+func hitSample(info audio.HitSoundInfo) string {
+	return fmt.Sprintf("%d:%d:%d:%d:", info.SampleSet, info.AdditionSet, info.CustomIndex, int(math.Round(info.CustomVolume*100)))
+}
+
+func clampDifficulty(name string, value float64) string {
+	if value < 0 || value > 10 {
+		log.Println(fmt.Sprintf("%s %s is out of 0-10 range and will be clamped, difficulty won't match the one being played", name, mutils.FormatWOZeros(value, 2)))
+	}
+
+	return mutils.FormatWOZeros(mutils.ClampF(value, 0, 10), 4)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func formatFloat32(value float32) string {
+	return strconv.FormatFloat(float64(value), 'f', -1, 32)
+}
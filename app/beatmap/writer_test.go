@@ -0,0 +1,204 @@
+package beatmap
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/env"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testdata/roundtrip/map.osu covers all curve types, inherited points, kiai, breaks, colours and custom hitsounds
+const roundTripMap = "testdata/roundtrip/map.osu"
+
+var songsDir string
+
+// TestMain prepares a temporary songs directory, settings cache it so it has to be shared by all tests
+func TestMain(m *testing.M) {
+	env.Init("danser")
+
+	var err error
+
+	if songsDir, err = os.MkdirTemp("", "danser-beatmap"); err != nil {
+		panic(err)
+	}
+
+	settings.General.OsuSongsDir = songsDir
+	settings.Objects.LoadSpinners = true
+
+	data, err := os.ReadFile(roundTripMap)
+	if err != nil {
+		panic(err)
+	}
+
+	if err = os.MkdirAll(filepath.Join(songsDir, "original"), 0755); err != nil {
+		panic(err)
+	}
+
+	if err = os.WriteFile(filepath.Join(songsDir, "original", "map.osu"), data, 0644); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	_ = os.RemoveAll(songsDir)
+
+	os.Exit(code)
+}
+
+func loadMap(t *testing.T, dir string, mods difficulty.Modifier) *BeatMap {
+	beatMap := NewBeatMap()
+	beatMap.Dir = dir
+	beatMap.File = "map.osu"
+
+	if err := ParseBeatMap(beatMap); err != nil {
+		t.Fatal(err)
+	}
+
+	beatMap.Diff.SetMods(mods)
+
+	ParseObjects(beatMap, false, false)
+
+	return beatMap
+}
+
+func writeMap(t *testing.T, dir string, beatMap *BeatMap) *BeatMap {
+	if err := os.MkdirAll(filepath.Join(songsDir, dir), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveBeatMap(beatMap, filepath.Join(songsDir, dir, "map.osu")); err != nil {
+		t.Fatal(err)
+	}
+
+	return loadMap(t, dir, difficulty.None)
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	original := loadMap(t, "original", difficulty.None)
+	written := writeMap(t, "roundtrip", original)
+
+	if len(original.HitObjects) != 11 {
+		t.Fatalf("expected 11 hit objects in the original map, got %d", len(original.HitObjects))
+	}
+
+	if len(written.HitObjects) != len(original.HitObjects) {
+		t.Fatalf("hit object count changed: %d -> %d", len(original.HitObjects), len(written.HitObjects))
+	}
+
+	for i, o := range original.HitObjects {
+		w := written.HitObjects[i]
+
+		if o.GetType() != w.GetType() {
+			t.Errorf("object %d: type changed: %d -> %d", i, o.GetType(), w.GetType())
+			continue
+		}
+
+		if o.GetStartTime() != w.GetStartTime() || o.GetEndTime() != w.GetEndTime() {
+			t.Errorf("object %d: time changed: %f-%f -> %f-%f", i, o.GetStartTime(), o.GetEndTime(), w.GetStartTime(), w.GetEndTime())
+		}
+
+		if o.GetStartPosition() != w.GetStartPosition() || o.GetEndPosition() != w.GetEndPosition() {
+			t.Errorf("object %d: position changed: %v-%v -> %v-%v", i, o.GetStartPosition(), o.GetEndPosition(), w.GetStartPosition(), w.GetEndPosition())
+		}
+
+		if o.IsNewCombo() != w.IsNewCombo() || o.GetComboSet() != w.GetComboSet() || o.GetComboSetHax() != w.GetComboSetHax() {
+			t.Errorf("object %d: combo changed", i)
+		}
+
+		if oSlider, ok := o.(*objects.Slider); ok {
+			wSlider := w.(*objects.Slider)
+
+			if oSlider.GetCurveType() != wSlider.GetCurveType() || !reflect.DeepEqual(oSlider.GetControlPoints(), wSlider.GetControlPoints()) {
+				t.Errorf("object %d: slider curve changed", i)
+			}
+
+			if oSlider.GetPixelLength() != wSlider.GetPixelLength() || oSlider.RepeatCount != wSlider.RepeatCount {
+				t.Errorf("object %d: slider length or repeats changed", i)
+			}
+
+			oSets, oAdditions := oSlider.GetEdgeSets()
+			wSets, wAdditions := wSlider.GetEdgeSets()
+
+			if !reflect.DeepEqual(oSlider.GetEdgeSamples(), wSlider.GetEdgeSamples()) || !reflect.DeepEqual(oSets, wSets) || !reflect.DeepEqual(oAdditions, wAdditions) {
+				t.Errorf("object %d: slider edge hitsounds changed", i)
+			}
+		}
+
+		if circle, ok := o.(*objects.Circle); ok && circle.GetSample() != w.(*objects.Circle).GetSample() {
+			t.Errorf("object %d: hitsound changed", i)
+		}
+	}
+
+	for i := range original.HitObjects {
+		if o, ok := original.HitObjects[i].(*objects.Circle); ok && o.BasicHitSound != written.HitObjects[i].(*objects.Circle).BasicHitSound {
+			t.Errorf("object %d: hit sample changed: %+v -> %+v", i, o.BasicHitSound, written.HitObjects[i].(*objects.Circle).BasicHitSound)
+		}
+	}
+
+	if !reflect.DeepEqual(original.Timings.GetPoints(), written.Timings.GetPoints()) {
+		t.Errorf("timing points changed:\n%+v\n%+v", original.Timings.GetPoints(), written.Timings.GetPoints())
+	}
+
+	if !reflect.DeepEqual(original.Pauses, written.Pauses) {
+		t.Errorf("breaks changed")
+	}
+
+	if !reflect.DeepEqual(original.Colours, written.Colours) {
+		t.Errorf("colours changed: %v -> %v", original.Colours, written.Colours)
+	}
+
+	od, wd := original.Diff, written.Diff
+	if od.GetAR() != wd.GetAR() || od.GetOD() != wd.GetOD() || od.GetCS() != wd.GetCS() || od.GetHP() != wd.GetHP() {
+		t.Errorf("difficulty changed")
+	}
+
+	if original.Difficulty != written.Difficulty || original.ID != written.ID || original.PreviewTime != written.PreviewTime || original.Bg != written.Bg {
+		t.Errorf("metadata changed")
+	}
+}
+
+func TestWriteBakedMods(t *testing.T) {
+	original := loadMap(t, "original", difficulty.HardRock|difficulty.HalfTime)
+	written := writeMap(t, "hrht", original)
+
+	if written.Difficulty != "Writer (HRHT)" || written.ID != 0 {
+		t.Errorf("unexpected modified difficulty metadata: %s, %d", written.Difficulty, written.ID)
+	}
+
+	if math.Abs(written.Diff.GetAR()-original.Diff.ARReal) > 0.001 || math.Abs(written.Diff.GetOD()-original.Diff.ODReal) > 0.001 {
+		t.Errorf("speed adjusted difficulty wasn't baked: AR%f OD%f", written.Diff.GetAR(), written.Diff.GetOD())
+	}
+
+	if math.Abs(written.Diff.GetCS()-original.Diff.CSMod) > 0.001 || math.Abs(written.Diff.GetHP()-original.Diff.HPMod) > 0.001 {
+		t.Errorf("HardRock difficulty wasn't baked")
+	}
+
+	for i, o := range original.HitObjects {
+		w := written.HitObjects[i]
+
+		if math.Abs(w.GetStartTime()-o.GetStartTime()/0.75) > 0.5 {
+			t.Errorf("object %d: time wasn't rescaled: %f -> %f", i, o.GetStartTime(), w.GetStartTime())
+		}
+
+		if math.Abs(w.GetEndTime()-o.GetEndTime()/0.75) > 1 {
+			t.Errorf("object %d: end time wasn't rescaled: %f -> %f", i, o.GetEndTime(), w.GetEndTime())
+		}
+
+		oPos, wPos := o.GetStartPosition(), w.GetStartPosition()
+		if oPos.X != wPos.X || 384-oPos.Y != wPos.Y {
+			t.Errorf("object %d: position wasn't flipped: %v -> %v", i, oPos, wPos)
+		}
+	}
+
+	oPoints, wPoints := original.Timings.GetPoints(), written.Timings.GetPoints()
+	for i := range oPoints {
+		if math.Abs(wPoints[i].GetBeatLength()-oPoints[i].GetBeatLength()/0.75) > 0.001 {
+			t.Errorf("timing point %d: beat length wasn't rescaled", i)
+		}
+	}
+}
@@ -4,11 +4,25 @@ import (
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/framework/math/vector"
+	"math"
 )
 
 const OsuWidth = 512.0
 const OsuHeight = 384.0
 
+// Fractions of the screen the playfield takes at scale 1
+const (
+	LandscapeFit = 0.8
+	PortraitFit  = 0.875 // leaves room for circles placed at playfield edges
+)
+
+// Storyboard space, widescreen storyboards extend it to 854 width
+const (
+	StoryboardWidth     = 640.0
+	StoryboardWideWidth = 854.0
+	StoryboardHeight    = 480.0
+)
+
 type Rectangle struct {
 	MinX, MinY, MaxX, MaxY float32
 }
@@ -67,12 +81,17 @@ func (camera *Camera) SetViewport(width, height int, yDown bool) {
 }
 
 func (camera *Camera) SetOsuViewport(width, height int, scale float64, offset bool) {
+	camera.SetOsuViewportFit(width, height, scale, LandscapeFit, offset)
+}
+
+// SetOsuViewportFit fits the playfield into the given fraction of the screen
+func (camera *Camera) SetOsuViewportFit(width, height int, scale, fit float64, offset bool) {
 	baseScale := float64(height) / OsuHeight
 	if OsuWidth/OsuHeight > float64(width)/float64(height) {
 		baseScale = float64(width) / OsuWidth
 	}
 
-	scl := baseScale * 0.8 * scale
+	scl := baseScale * fit * scale
 
 	shift := settings.Playfield.ShiftY
 	if offset {
@@ -89,6 +108,24 @@ func (camera *Camera) SetOsuViewport(width, height int, scale float64, offset bo
 	camera.viewDirty = true
 }
 
+// SetStoryboardViewport fits storyboard space into the screen, storyboards are drawn in osu! coordinates so it's centered on the playfield
+func (camera *Camera) SetStoryboardViewport(width, height int, widescreen bool) {
+	sbWidth := StoryboardWidth
+	if widescreen {
+		sbWidth = StoryboardWideWidth
+	}
+
+	scl := math.Min(float64(width)/sbWidth, float64(height)/StoryboardHeight)
+
+	camera.SetViewport(width, height, true)
+	camera.originV = vector.NewVec2d(OsuWidth/2, OsuHeight/2).Scl(-1)
+	camera.scaleV = vector.NewVec2d(scl, scl)
+	camera.Update()
+
+	camera.rebuildCache = true
+	camera.viewDirty = true
+}
+
 func (camera *Camera) resetValues() {
 	camera.originV = vector.NewVec2d(0, 0)
 	camera.positionV = vector.NewVec2d(0, 0)
@@ -0,0 +1,232 @@
+package dance
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/catch"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"github.com/wieku/rplpa"
+	"math"
+	"time"
+)
+
+// CatchController plays osu!catch replay given by -replay, knockout replays or autoplay if there are none
+type CatchController struct {
+	bMap        *beatmap.BeatMap
+	replays     []RpData
+	cursors     []*graphics.Cursor
+	controllers []*subControl
+	ruleset     *catch.CatchRuleSet
+	lastTime    float64
+}
+
+func NewCatchController() Controller {
+	return &CatchController{lastTime: -200}
+}
+
+func (controller *CatchController) SetBeatMap(beatMap *beatmap.BeatMap) {
+	controller.bMap = beatMap
+
+	var candidates []*rplpa.Replay
+
+	if settings.REPLAY != "" {
+		control, data := loadSingleReplay()
+
+		controller.replays = append(controller.replays, data)
+		controller.controllers = append(controller.controllers, control)
+	} else if settings.KNOCKOUT {
+		organizeReplays()
+
+		candidates = knockoutCandidates(beatMap)
+
+		displayedMods := ^difficulty.ParseMods(settings.Knockout.HideMods)
+
+		for i, replay := range candidates {
+			control, data := loadReplay(replay, i, displayedMods)
+
+			controller.replays = append(controller.replays, data)
+			controller.controllers = append(controller.controllers, control)
+		}
+	}
+
+	if settings.REPLAY == "" && (len(candidates) == 0 || settings.Knockout.AddDanser) {
+		control := NewSubControl()
+		control.mods = difficulty.Autoplay | beatMap.Diff.Mods
+
+		if len(candidates) == 0 {
+			controller.bMap.Diff.SetMods(control.mods)
+		}
+
+		diff := difficulty.NewDifficulty(beatMap.Diff.GetBaseHP(), beatMap.Diff.GetBaseCS(), beatMap.Diff.GetBaseOD(), beatMap.Diff.GetBaseAR())
+		diff.SetMods(control.mods)
+
+		control.frames = generateCatchAutoplay(catch.ConvertObjects(beatMap, diff))
+
+		controller.replays = append([]RpData{{settings.Knockout.DanserName, getModString(beatMap, control.mods), control.mods, 100, 0, 0, osu.NONE, -1, time.Now()}}, controller.replays...)
+		controller.controllers = append([]*subControl{control}, controller.controllers...)
+	}
+
+	settings.PLAYERS = len(controller.replays)
+}
+
+func (controller *CatchController) InitCursors() {
+	modifiers := make([]difficulty.Modifier, 0, len(controller.controllers))
+
+	for i, c := range controller.controllers {
+		cursor := graphics.NewCursor()
+		cursor.Name = controller.replays[i].Name
+		cursor.ScoreID = controller.replays[i].scoreID
+		cursor.ScoreTime = controller.replays[i].ScoreTime
+		cursor.IsAutoplay = c.mods.Active(difficulty.Autoplay)
+		cursor.IsPlayer = cursor.IsAutoplay
+
+		// Cursor isn't used in catch, keep it in the centre so background parallax doesn't move
+		cursor.SetPos(vector.NewVec2f(256, 192))
+		cursor.Update(0)
+
+		controller.cursors = append(controller.cursors, cursor)
+
+		modifiers = append(modifiers, c.mods)
+	}
+
+	controller.ruleset = catch.NewCatchRuleset(controller.bMap, controller.cursors, modifiers)
+}
+
+func (controller *CatchController) Update(time float64, delta float64) {
+	numSkipped := int(time-controller.lastTime) - 1
+
+	if numSkipped >= 1 {
+		for nTime := numSkipped; nTime >= 1; nTime-- {
+			controller.updateMain(time - float64(nTime))
+		}
+	}
+
+	controller.updateMain(time)
+
+	for i := range controller.controllers {
+		controller.cursors[i].Update(delta)
+
+		sc := controller.ruleset.GetScore(controller.cursors[i])
+		controller.replays[i].Accuracy = sc.Accuracy
+		controller.replays[i].Combo = controller.ruleset.GetCombo(controller.cursors[i])
+		controller.replays[i].Grade = sc.Grade
+	}
+}
+
+func (controller *CatchController) updateMain(nTime float64) {
+	if int64(nTime) == int64(controller.lastTime) {
+		controller.lastTime = nTime
+		return
+	}
+
+	for i, c := range controller.controllers {
+		for c.replayIndex < len(c.frames) && c.replayTime+c.frames[c.replayIndex].Time <= int64(nTime) {
+			c.replayTime += c.frames[c.replayIndex].Time
+			c.replayIndex++
+		}
+
+		if c.replayIndex == 0 {
+			continue
+		}
+
+		current := c.frames[c.replayIndex-1]
+		x := float64(current.MouseX)
+
+		// Catcher moves between frames, interpolate so fast movement isn't judged as a teleport
+		if c.replayIndex < len(c.frames) {
+			next := c.frames[c.replayIndex]
+
+			if next.Time > 0 {
+				t := float64(int64(nTime)-c.replayTime) / float64(next.Time)
+				x += (float64(next.MouseX) - x) * t
+			}
+		}
+
+		controller.ruleset.UpdateInputFor(controller.cursors[i], x, catchDashing(current))
+	}
+
+	controller.ruleset.Update(int64(nTime))
+
+	controller.lastTime = nTime
+}
+
+func (controller *CatchController) GetCursors() []*graphics.Cursor {
+	return controller.cursors
+}
+
+func (controller *CatchController) GetReplays() []RpData {
+	return controller.replays
+}
+
+func (controller *CatchController) GetRuleset() *catch.CatchRuleSet {
+	return controller.ruleset
+}
+
+func (controller *CatchController) GetBeatMap() *beatmap.BeatMap {
+	return controller.bMap
+}
+
+// GetClick returns dashing state for key 0 and hyperdashing state for key 1, used by knockout overlay
+func (controller *CatchController) GetClick(player, key int) bool {
+	state := controller.ruleset.GetCatcher(controller.cursors[player])
+
+	switch key {
+	case 0:
+		return state.Dashing
+	case 1:
+		return state.HyperDashing
+	}
+
+	return false
+}
+
+// catchDashing decodes dash key, osu!stable stores it as the first mouse button
+func catchDashing(frame *rplpa.ReplayData) bool {
+	return frame.KeyPressed != nil && (frame.KeyPressed.LeftClick || frame.KeyPressed.Key1)
+}
+
+// generateCatchAutoplay creates replay frames placing the catcher under every object when it reaches the plate
+func generateCatchAutoplay(catchObjects []*catch.Object) []*rplpa.ReplayData {
+	frames := make([]*rplpa.ReplayData, 0, len(catchObjects)+1)
+
+	lastTime := int64(0)
+	lastX := catch.PlayfieldWidth / 2
+
+	addFrame := func(t int64, x float64, dash bool) {
+		frames = append(frames, &rplpa.ReplayData{
+			Time:       t - lastTime,
+			MouseX:     float32(x),
+			KeyPressed: &rplpa.KeyPressed{LeftClick: dash},
+		})
+
+		lastTime = t
+		lastX = x
+	}
+
+	if len(catchObjects) > 0 {
+		addFrame(int64(math.Ceil(catchObjects[0].StartTime))-1000, lastX, false)
+	}
+
+	for _, o := range catchObjects {
+		// Objects are judged on the first millisecond after they reach the plate
+		t := int64(math.Ceil(o.StartTime))
+
+		if t <= lastTime {
+			continue
+		}
+
+		x := o.EffectiveX()
+
+		// Dash flag applies to movement starting at previous frame
+		if len(frames) > 0 {
+			frames[len(frames)-1].KeyPressed.LeftClick = math.Abs(x-lastX)/float64(t-lastTime) > 0.5
+		}
+
+		addFrame(t, x, false)
+	}
+
+	return frames
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/wieku/danser-go/app/dance/schedulers"
 	"github.com/wieku/danser-go/app/dance/spinners"
 	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
 	"github.com/wieku/danser-go/app/settings"
 	"strings"
 )
@@ -18,6 +19,23 @@ type Controller interface {
 	GetCursors() []*graphics.Cursor
 }
 
+// KnockoutController is a controller which can be shown by knockout overlay
+type KnockoutController interface {
+	Controller
+	GetReplays() []RpData
+	GetBeatMap() *beatmap.BeatMap
+	GetClick(player, key int) bool
+}
+
+// KnockoutRuleset is a ruleset which reports judgements the same way as osu!standard one
+type KnockoutRuleset interface {
+	SetListener(listener osu.HitListener)
+	SetEndListener(listener osu.EndListener)
+	GetScore(cursor *graphics.Cursor) osu.Score
+	GetHP(cursor *graphics.Cursor) float64
+	GetBeatMap() *beatmap.BeatMap
+}
+
 type GenericController struct {
 	bMap       *beatmap.BeatMap
 	cursors    []*graphics.Cursor
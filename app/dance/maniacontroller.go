@@ -0,0 +1,229 @@
+package dance
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/mania"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"github.com/wieku/rplpa"
+	"math"
+	"sort"
+	"time"
+)
+
+// ManiaController plays osu!mania replay given by -replay or autoplay if there's none
+type ManiaController struct {
+	bMap        *beatmap.BeatMap
+	replays     []RpData
+	cursors     []*graphics.Cursor
+	controllers []*subControl
+	ruleset     *mania.ManiaRuleSet
+	lastTime    float64
+}
+
+func NewManiaController() Controller {
+	return &ManiaController{lastTime: -200}
+}
+
+func (controller *ManiaController) SetBeatMap(beatMap *beatmap.BeatMap) {
+	controller.bMap = beatMap
+
+	if settings.REPLAY != "" {
+		control, data := loadSingleReplay()
+
+		controller.replays = append(controller.replays, data)
+		controller.controllers = append(controller.controllers, control)
+	} else {
+		control := NewSubControl()
+		control.mods = difficulty.Autoplay | beatMap.Diff.Mods
+		control.frames = generateManiaAutoplay(mania.ConvertObjects(beatMap, mania.KeyCount(beatMap, control.mods)))
+
+		controller.bMap.Diff.SetMods(control.mods)
+
+		controller.replays = append(controller.replays, RpData{settings.Knockout.DanserName, control.mods.String(), control.mods, 100, 0, 0, osu.NONE, -1, time.Now()})
+		controller.controllers = append(controller.controllers, control)
+	}
+
+	settings.PLAYERS = len(controller.replays)
+}
+
+func (controller *ManiaController) InitCursors() {
+	modifiers := make([]difficulty.Modifier, 0, len(controller.controllers))
+
+	for i, c := range controller.controllers {
+		cursor := graphics.NewCursor()
+		cursor.Name = controller.replays[i].Name
+		cursor.ScoreID = controller.replays[i].scoreID
+		cursor.ScoreTime = controller.replays[i].ScoreTime
+		cursor.IsAutoplay = c.mods.Active(difficulty.Autoplay)
+		cursor.IsPlayer = cursor.IsAutoplay
+
+		// Cursor isn't used in mania, keep it in the centre so background parallax doesn't move
+		cursor.SetPos(vector.NewVec2f(256, 192))
+		cursor.Update(0)
+
+		controller.cursors = append(controller.cursors, cursor)
+
+		modifiers = append(modifiers, c.mods)
+	}
+
+	controller.ruleset = mania.NewManiaRuleset(controller.bMap, controller.cursors, modifiers)
+}
+
+func (controller *ManiaController) Update(time float64, delta float64) {
+	numSkipped := int(time-controller.lastTime) - 1
+
+	if numSkipped >= 1 {
+		for nTime := numSkipped; nTime >= 1; nTime-- {
+			controller.updateMain(time - float64(nTime))
+		}
+	}
+
+	controller.updateMain(time)
+
+	for i := range controller.controllers {
+		controller.cursors[i].Update(delta)
+
+		sc := controller.ruleset.GetScore(controller.cursors[i])
+		controller.replays[i].Accuracy = sc.Accuracy
+		controller.replays[i].Combo = controller.ruleset.GetCombo(controller.cursors[i])
+		controller.replays[i].Grade = sc.Grade
+	}
+}
+
+func (controller *ManiaController) updateMain(nTime float64) {
+	for i, c := range controller.controllers {
+		for c.replayIndex < len(c.frames) && c.replayTime+c.frames[c.replayIndex].Time <= int64(nTime) {
+			frame := c.frames[c.replayIndex]
+			c.replayTime += frame.Time
+
+			controller.ruleset.UpdateInputFor(controller.cursors[i], c.replayTime, maniaColumns(frame))
+
+			c.replayIndex++
+		}
+	}
+
+	if int64(nTime) != int64(controller.lastTime) {
+		controller.ruleset.Update(int64(nTime))
+	}
+
+	controller.lastTime = nTime
+}
+
+func (controller *ManiaController) GetCursors() []*graphics.Cursor {
+	return controller.cursors
+}
+
+func (controller *ManiaController) GetReplays() []RpData {
+	return controller.replays
+}
+
+func (controller *ManiaController) GetRuleset() *mania.ManiaRuleSet {
+	return controller.ruleset
+}
+
+func (controller *ManiaController) GetBeatMap() *beatmap.BeatMap {
+	return controller.bMap
+}
+
+// maniaColumns decodes pressed columns, osu! stores them as a bitfield in X coordinate of the frame
+func maniaColumns(frame *rplpa.ReplayData) mania.Columns {
+	if frame.MouseX <= 0 {
+		return 0
+	}
+
+	return mania.Columns(uint32(math.Round(float64(frame.MouseX))))
+}
+
+// generateManiaAutoplay creates replay frames hitting every object perfectly, hold notes are released at their end
+func generateManiaAutoplay(maniaObjects []*mania.Object) []*rplpa.ReplayData {
+	const releaseDelay = 20.0
+
+	type action struct {
+		time    int64
+		column  int
+		release bool
+	}
+
+	actions := make([]action, 0, len(maniaObjects)*2)
+
+	// nextInColumn is used to release notes before next one in the same column appears
+	nextInColumn := make([]float64, len(maniaObjects))
+
+	lastInColumn := make(map[int]int)
+
+	for i := len(maniaObjects) - 1; i >= 0; i-- {
+		nextInColumn[i] = math.Inf(1)
+
+		if j, ok := lastInColumn[maniaObjects[i].Column]; ok {
+			nextInColumn[i] = maniaObjects[j].StartTime
+		}
+
+		lastInColumn[maniaObjects[i].Column] = i
+	}
+
+	for i, o := range maniaObjects {
+		pressTime := int64(math.Round(o.StartTime))
+
+		releaseTime := int64(math.Round(o.EndTime))
+		if !o.IsHold {
+			releaseTime = pressTime + releaseDelay
+		}
+
+		releaseTime = int64(math.Min(float64(releaseTime), math.Floor((o.EndTime+nextInColumn[i])/2)))
+
+		if releaseTime <= pressTime {
+			releaseTime = pressTime + 1
+		}
+
+		actions = append(actions, action{pressTime, o.Column, false}, action{releaseTime, o.Column, true})
+	}
+
+	sort.SliceStable(actions, func(i, j int) bool {
+		if actions[i].time == actions[j].time {
+			return actions[i].release && !actions[j].release
+		}
+
+		return actions[i].time < actions[j].time
+	})
+
+	frames := make([]*rplpa.ReplayData, 0, len(actions)+1)
+
+	lastTime := int64(0)
+
+	var columns mania.Columns
+
+	addFrame := func(t int64) {
+		frames = append(frames, &rplpa.ReplayData{
+			Time:       t - lastTime,
+			MouseX:     float32(columns),
+			KeyPressed: new(rplpa.KeyPressed),
+		})
+
+		lastTime = t
+	}
+
+	if len(actions) > 0 {
+		addFrame(actions[0].time - 1000)
+	}
+
+	for i, a := range actions {
+		if a.release {
+			columns &= ^(1 << a.column)
+		} else {
+			columns |= 1 << a.column
+		}
+
+		// Merge actions happening at the same time into one frame
+		if i+1 < len(actions) && actions[i+1].time == a.time {
+			continue
+		}
+
+		addFrame(a.time)
+	}
+
+	return frames
+}
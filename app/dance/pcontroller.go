@@ -11,6 +11,7 @@ import (
 	"github.com/wieku/danser-go/app/dance/spinners"
 	"github.com/wieku/danser-go/app/graphics"
 	input2 "github.com/wieku/danser-go/app/input"
+	"github.com/wieku/danser-go/app/replay"
 	"github.com/wieku/danser-go/app/rulesets/osu"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/utils"
@@ -39,6 +40,10 @@ type PlayerController struct {
 
 	quickRestart     bool
 	quickRestartTime float64
+
+	recorder *replay.Recorder
+
+	keyListener int
 }
 
 func NewPlayerController() Controller {
@@ -57,8 +62,12 @@ func (controller *PlayerController) InitCursors() {
 	controller.window = glfw.GetCurrentContext()
 	controller.ruleset = osu.NewOsuRuleset(controller.bMap, controller.cursors, []difficulty.Modifier{controller.bMap.Diff.Mods})
 
+	if settings.Gameplay.SaveReplays {
+		controller.recorder = replay.NewRecorder(controller.ruleset, controller.cursors[0], controller.bMap.Diff.Mods)
+	}
+
 	if !controller.bMap.Diff.CheckModActive(difficulty.Relax) {
-		input2.RegisterListener(controller.KeyEvent)
+		controller.keyListener = input2.RegisterListener(controller.KeyEvent)
 	} else {
 		controller.relaxController = input.NewRelaxInputProcessor(controller.ruleset, controller.cursors[0])
 	}
@@ -78,6 +87,14 @@ func (controller *PlayerController) InitCursors() {
 	}
 }
 
+// Dispose stops the controller from reacting to keys, it has to be called when the controller is replaced
+func (controller *PlayerController) Dispose() {
+	if controller.keyListener != 0 {
+		input2.RemoveListener(controller.keyListener)
+		controller.keyListener = 0
+	}
+}
+
 func (controller *PlayerController) KeyEvent(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, _ glfw.ModifierKey) {
 	if key == glfw.KeyUnknown {
 		return
@@ -154,18 +171,28 @@ func (controller *PlayerController) Update(time float64, delta float64) {
 		}
 	}
 
-	controller.counter += time - controller.lastTime
-
-	if controller.counter >= 1000.0/60 {
-		controller.cursors[0].IsReplayFrame = true
-		controller.counter -= 1000.0 / 60
+	if controller.recorder != nil {
+		// Process input only on recorded frames so the saved replay is judged the same way
+		if controller.recorder.Update(int64(time)) {
+			controller.ruleset.UpdateClickFor(controller.cursors[0], int64(time))
+			controller.ruleset.UpdateNormalFor(controller.cursors[0], int64(time), true)
+			controller.ruleset.UpdatePostFor(controller.cursors[0], int64(time), true)
+		}
 	} else {
-		controller.cursors[0].IsReplayFrame = false
+		controller.counter += time - controller.lastTime
+
+		if controller.counter >= 1000.0/60 {
+			controller.cursors[0].IsReplayFrame = true
+			controller.counter -= 1000.0 / 60
+		} else {
+			controller.cursors[0].IsReplayFrame = false
+		}
+
+		controller.ruleset.UpdateClickFor(controller.cursors[0], int64(time))
+		controller.ruleset.UpdateNormalFor(controller.cursors[0], int64(time), false)
+		controller.ruleset.UpdatePostFor(controller.cursors[0], int64(time), false)
 	}
 
-	controller.ruleset.UpdateClickFor(controller.cursors[0], int64(time))
-	controller.ruleset.UpdateNormalFor(controller.cursors[0], int64(time), false)
-	controller.ruleset.UpdatePostFor(controller.cursors[0], int64(time), false)
 	controller.ruleset.Update(int64(time))
 
 	controller.lastTime = time
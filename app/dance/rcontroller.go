@@ -7,6 +7,7 @@ import (
 	"github.com/wieku/danser-go/app/dance/movers"
 	"github.com/wieku/danser-go/app/dance/schedulers"
 	"github.com/wieku/danser-go/app/dance/spinners"
+	"github.com/wieku/danser-go/app/replay"
 	"github.com/wieku/danser-go/framework/env"
 	"github.com/wieku/danser-go/framework/math/mutils"
 	"github.com/wieku/rplpa"
@@ -35,6 +36,9 @@ import (
 
 const replaysMaster = "replays"
 
+// defaultSnapshotInterval is the time between ruleset snapshots used for seeking
+const defaultSnapshotInterval = 5000.0
+
 type RpData struct {
 	Name      string
 	Mods      string
@@ -58,6 +62,7 @@ type subControl struct {
 	relaxController *input.RelaxInputProcessor
 	mouseController schedulers.Scheduler
 	mods            difficulty.Modifier
+	recorder        *replay.Recorder
 }
 
 func NewSubControl() *subControl {
@@ -72,12 +77,43 @@ type ReplayController struct {
 	controllers []*subControl
 	ruleset     *osu.OsuRuleSet
 	lastTime    float64
+
+	seekable         bool
+	snapshots        []*replaySnapshot
+	snapshotInterval float64
+}
+
+type controlState struct {
+	replayIndex int
+	replayTime  int64
+	lastTime    int64
+	relax       input.RelaxInputProcessor
+}
+
+type cursorState struct {
+	position vector.Vector2f
+
+	leftButton, rightButton bool
+	leftKey, rightKey       bool
+	leftMouse, rightMouse   bool
+	smokeKey                bool
+
+	isReplayFrame    bool
+	lastFrameTime    int64
+	currentFrameTime int64
+}
+
+type replaySnapshot struct {
+	time     float64
+	ruleset  *osu.Snapshot
+	controls []controlState
+	cursors  []cursorState
 }
 
 func NewReplayController() Controller {
 	_ = os.MkdirAll(filepath.Join(env.DataDir(), replaysMaster), 0755)
 
-	return &ReplayController{lastTime: -200}
+	return &ReplayController{lastTime: -200, snapshotInterval: defaultSnapshotInterval}
 }
 
 func (controller *ReplayController) SetBeatMap(beatMap *beatmap.BeatMap) {
@@ -105,42 +141,17 @@ func (controller *ReplayController) SetBeatMap(beatMap *beatmap.BeatMap) {
 
 			localReplay = true
 		}
-	} else if settings.Knockout.MaxPlayers > 0 || (settings.KNOCKOUTREPLAYS != nil && len(settings.KNOCKOUTREPLAYS) > 0) { // ignore max player limit with new knockout
-		candidates = controller.getCandidates()
-	}
-
-	if !localReplay {
-		sort.Slice(candidates, func(i, j int) bool {
-			return candidates[i].Score > candidates[j].Score
-		})
-
-		if settings.KNOCKOUTREPLAYS == nil || len(settings.KNOCKOUTREPLAYS) == 0 { // limit only with classic knockout
-			candidates = candidates[:mutils.Min(len(candidates), settings.Knockout.MaxPlayers)]
-		}
+	} else {
+		candidates = knockoutCandidates(beatMap)
 	}
 
 	displayedMods := ^difficulty.ParseMods(settings.Knockout.HideMods)
 
 	for i, replay := range candidates {
-		log.Println(fmt.Sprintf("Loading replay for \"%s\":", replay.Username))
-
-		control := NewSubControl()
-		control.mods = difficulty.Modifier(replay.Mods)
-
-		log.Println("\tMods:", control.mods.String())
-
-		loadFrames(control, replay.ReplayData)
-
-		mxCombo := replay.MaxCombo
-
-		control.newHandling = replay.OsuVersion >= 20190506 // This was when slider scoring was changed, so *I think* replay handling as well: https://osu.ppy.sh/home/changelog/cuttingedge/20190506
-		control.oldSpinners = replay.OsuVersion < 20190510  // This was when spinner scoring was changed: https://osu.ppy.sh/home/changelog/cuttingedge/20190510.2
+		control, data := loadReplay(replay, i, displayedMods)
 
-		controller.replays = append(controller.replays, RpData{replay.Username + string(rune(unicode.MaxRune-i)), (control.mods & displayedMods).String(), control.mods, 100, 0, int64(mxCombo), osu.NONE, replay.ScoreID, replay.Timestamp})
+		controller.replays = append(controller.replays, data)
 		controller.controllers = append(controller.controllers, control)
-
-		log.Println("\tExpected score:", replay.Score)
-		log.Println("\tReplay loaded!")
 	}
 
 	if !localReplay && (settings.Knockout.AddDanser || len(candidates) == 0) {
@@ -150,7 +161,7 @@ func (controller *ReplayController) SetBeatMap(beatMap *beatmap.BeatMap) {
 		control.danceController = NewGenericController()
 		control.danceController.SetBeatMap(beatMap)
 
-		controller.replays = append([]RpData{{settings.Knockout.DanserName, control.mods.String(), control.mods, 100, 0, 0, osu.NONE, -1, time.Now()}}, controller.replays...)
+		controller.replays = append([]RpData{{settings.Knockout.DanserName, getModString(beatMap, control.mods), control.mods, 100, 0, 0, osu.NONE, -1, time.Now()}}, controller.replays...)
 		controller.controllers = append([]*subControl{control}, controller.controllers...)
 
 		if len(candidates) == 0 {
@@ -208,7 +219,26 @@ func organizeReplays() {
 	})
 }
 
-func (controller *ReplayController) getCandidates() (candidates []*rplpa.Replay) {
+// knockoutCandidates returns replays of a given beatmap sorted by score, limited to max players in classic knockout
+func knockoutCandidates(beatMap *beatmap.BeatMap) (candidates []*rplpa.Replay) {
+	if settings.Knockout.MaxPlayers <= 0 && len(settings.KNOCKOUTREPLAYS) == 0 { // ignore max player limit with new knockout
+		return
+	}
+
+	candidates = getCandidates(beatMap)
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	if len(settings.KNOCKOUTREPLAYS) == 0 { // limit only with classic knockout
+		candidates = candidates[:mutils.Min(len(candidates), settings.Knockout.MaxPlayers)]
+	}
+
+	return
+}
+
+func getCandidates(beatMap *beatmap.BeatMap) (candidates []*rplpa.Replay) {
 	excludedMods := difficulty.ParseMods(settings.Knockout.ExcludeMods)
 
 	tryAddReplay := func(path string, modExclude bool) {
@@ -226,11 +256,16 @@ func (controller *ReplayController) getCandidates() (candidates []*rplpa.Replay)
 			return
 		}
 
-		if !strings.EqualFold(replayD.BeatmapMD5, controller.bMap.MD5) {
+		if !strings.EqualFold(replayD.BeatmapMD5, beatMap.MD5) {
 			log.Println("Incompatible maps, skipping", replayD.Username)
 			return
 		}
 
+		if int(replayD.PlayMode) != settings.MODE {
+			log.Println("Excluding for different game mode:", replayD.Username)
+			return
+		}
+
 		if !difficulty.Modifier(replayD.Mods).Compatible() || difficulty.Modifier(replayD.Mods).Active(difficulty.Target) {
 			log.Println("Excluding for incompatible mods:", replayD.Username)
 			return
@@ -254,7 +289,7 @@ func (controller *ReplayController) getCandidates() (candidates []*rplpa.Replay)
 			tryAddReplay(r, false)
 		}
 	} else {
-		replayDir := filepath.Join(env.DataDir(), replaysMaster, controller.bMap.MD5)
+		replayDir := filepath.Join(env.DataDir(), replaysMaster, beatMap.MD5)
 
 		_ = godirwalk.Walk(replayDir, &godirwalk.Options{
 			Callback: func(osPathname string, de *godirwalk.Dirent) error {
@@ -328,6 +363,55 @@ func loadFrames(subController *subControl, frames []*rplpa.ReplayData) {
 	subController.frames = frames
 }
 
+// loadReplay prepares a knockout replay, index makes names unique if the same player has several replays
+func loadReplay(replay *rplpa.Replay, index int, displayedMods difficulty.Modifier) (*subControl, RpData) {
+	log.Println(fmt.Sprintf("Loading replay for \"%s\":", replay.Username))
+
+	control := NewSubControl()
+	control.mods = difficulty.Modifier(replay.Mods)
+
+	log.Println("\tMods:", control.mods.String())
+
+	loadFrames(control, replay.ReplayData)
+
+	control.newHandling = replay.OsuVersion >= 20190506 // This was when slider scoring was changed, so *I think* replay handling as well: https://osu.ppy.sh/home/changelog/cuttingedge/20190506
+	control.oldSpinners = replay.OsuVersion < 20190510  // This was when spinner scoring was changed: https://osu.ppy.sh/home/changelog/cuttingedge/20190510.2
+
+	log.Println("\tExpected score:", replay.Score)
+	log.Println("\tReplay loaded!")
+
+	return control, RpData{replay.Username + string(rune(unicode.MaxRune-index)), (control.mods & displayedMods).String(), control.mods, 100, 0, int64(replay.MaxCombo), osu.NONE, replay.ScoreID, replay.Timestamp}
+}
+
+// loadSingleReplay loads the replay given by -replay, used by rulesets which don't support knockout
+func loadSingleReplay() (*subControl, RpData) {
+	log.Println("Loading: ", settings.REPLAY)
+
+	data, err := ioutil.ReadFile(settings.REPLAY)
+	if err != nil {
+		panic(err)
+	}
+
+	replay, err := rplpa.ParseReplay(data)
+	if err != nil {
+		panic(err)
+	}
+
+	log.Println(fmt.Sprintf("Loading replay for \"%s\":", replay.Username))
+
+	control := NewSubControl()
+	control.mods = difficulty.Modifier(replay.Mods)
+
+	log.Println("\tMods:", control.mods.String())
+
+	loadFrames(control, replay.ReplayData)
+
+	log.Println("\tExpected score:", replay.Score)
+	log.Println("\tReplay loaded!")
+
+	return control, RpData{replay.Username + string(rune(unicode.MaxRune)), control.mods.String(), control.mods, 100, 0, int64(replay.MaxCombo), osu.NONE, replay.ScoreID, replay.Timestamp}
+}
+
 func (controller *ReplayController) InitCursors() {
 	var modifiers []difficulty.Modifier
 
@@ -373,6 +457,10 @@ func (controller *ReplayController) InitCursors() {
 	controller.ruleset = osu.NewOsuRuleset(controller.bMap, controller.cursors, modifiers)
 
 	for i := range controller.controllers {
+		if controller.controllers[i].danceController != nil && settings.Gameplay.SaveReplays && settings.TAG == 1 {
+			controller.controllers[i].recorder = replay.NewRecorder(controller.ruleset, controller.cursors[i], controller.replays[i].ModsV)
+		}
+
 		if controller.replays[i].ModsV.Active(difficulty.Relax) {
 			controller.controllers[i].relaxController = input.NewRelaxInputProcessor(controller.ruleset, controller.cursors[i])
 		}
@@ -383,6 +471,7 @@ func (controller *ReplayController) InitCursors() {
 			diff := difficulty.NewDifficulty(controller.bMap.Diff.GetHP(), controller.bMap.Diff.GetCS(), controller.bMap.Diff.GetOD(), controller.bMap.Diff.GetAR())
 			diff.SetMods(controller.replays[i].ModsV)
 			diff.SetCustomSpeed(controller.bMap.Diff.CustomSpeed)
+			diff.SetRateAdjust(controller.bMap.Diff.RateAdjust)
 
 			controller.controllers[i].mouseController.Init(controller.bMap.GetObjectsCopy(), diff, controller.cursors[i], spinners.GetMoverCtorByName("circle"), false)
 		}
@@ -419,18 +508,27 @@ func (controller *ReplayController) updateMain(nTime float64) {
 		if c.danceController != nil {
 			c.danceController.Update(nTime, nTime-controller.lastTime)
 
-			if int64(nTime)%17 == 0 {
-				controller.cursors[i].LastFrameTime = int64(nTime) - 17
-				controller.cursors[i].CurrentFrameTime = int64(nTime)
-				controller.cursors[i].IsReplayFrame = true
+			if c.recorder != nil {
+				// Process input only on recorded frames so the saved replay is judged the same way
+				if int64(nTime) != c.lastTime && c.recorder.Update(int64(nTime)) {
+					controller.ruleset.UpdateClickFor(controller.cursors[i], int64(nTime))
+					controller.ruleset.UpdateNormalFor(controller.cursors[i], int64(nTime), true)
+					controller.ruleset.UpdatePostFor(controller.cursors[i], int64(nTime), true)
+				}
 			} else {
-				controller.cursors[i].IsReplayFrame = false
-			}
+				if int64(nTime)%17 == 0 {
+					controller.cursors[i].LastFrameTime = int64(nTime) - 17
+					controller.cursors[i].CurrentFrameTime = int64(nTime)
+					controller.cursors[i].IsReplayFrame = true
+				} else {
+					controller.cursors[i].IsReplayFrame = false
+				}
 
-			if int64(nTime) != c.lastTime {
-				controller.ruleset.UpdatePostFor(controller.cursors[i], int64(nTime), false)
-				controller.ruleset.UpdateClickFor(controller.cursors[i], int64(nTime))
-				controller.ruleset.UpdateNormalFor(controller.cursors[i], int64(nTime), false)
+				if int64(nTime) != c.lastTime {
+					controller.ruleset.UpdatePostFor(controller.cursors[i], int64(nTime), false)
+					controller.ruleset.UpdateClickFor(controller.cursors[i], int64(nTime))
+					controller.ruleset.UpdateNormalFor(controller.cursors[i], int64(nTime), false)
+				}
 			}
 
 			c.lastTime = int64(nTime)
@@ -539,6 +637,125 @@ func (controller *ReplayController) updateMain(nTime float64) {
 	}
 
 	controller.lastTime = nTime
+
+	if controller.seekable && (len(controller.snapshots) == 0 || nTime >= controller.snapshots[len(controller.snapshots)-1].time+controller.snapshotInterval) {
+		controller.takeSnapshot(nTime)
+	}
+}
+
+// EnableSeeking makes the controller take periodic snapshots needed by Rewind, returns false if it's not possible with loaded players.
+// It has to be called before the first update.
+func (controller *ReplayController) EnableSeeking() bool {
+	for _, c := range controller.controllers {
+		// Cursor dancers and autopilot schedulers can't be brought back in time
+		if c.danceController != nil || c.mouseController != nil {
+			return false
+		}
+	}
+
+	controller.ruleset.EnableSnapshots()
+	controller.seekable = true
+
+	return true
+}
+
+// SetSnapshotInterval changes the time between snapshots, shorter intervals make seeking faster but use more memory
+func (controller *ReplayController) SetSnapshotInterval(interval float64) {
+	controller.snapshotInterval = interval
+}
+
+func (controller *ReplayController) takeSnapshot(time float64) {
+	snapshot := &replaySnapshot{
+		time:    time,
+		ruleset: controller.ruleset.TakeSnapshot(int64(time)),
+	}
+
+	for _, c := range controller.controllers {
+		state := controlState{
+			replayIndex: c.replayIndex,
+			replayTime:  c.replayTime,
+			lastTime:    c.lastTime,
+		}
+
+		if c.relaxController != nil {
+			state.relax = *c.relaxController
+		}
+
+		snapshot.controls = append(snapshot.controls, state)
+	}
+
+	for _, cursor := range controller.cursors {
+		snapshot.cursors = append(snapshot.cursors, cursorState{
+			position:         cursor.RawPosition,
+			leftButton:       cursor.LeftButton,
+			rightButton:      cursor.RightButton,
+			leftKey:          cursor.LeftKey,
+			rightKey:         cursor.RightKey,
+			leftMouse:        cursor.LeftMouse,
+			rightMouse:       cursor.RightMouse,
+			smokeKey:         cursor.SmokeKey,
+			isReplayFrame:    cursor.IsReplayFrame,
+			lastFrameTime:    cursor.LastFrameTime,
+			currentFrameTime: cursor.CurrentFrameTime,
+		})
+	}
+
+	controller.snapshots = append(controller.snapshots, snapshot)
+}
+
+// Rewind restores the latest snapshot taken at or before given time and returns its time, updates have to continue from there.
+// Beatmap's visual state isn't restored.
+func (controller *ReplayController) Rewind(time float64) float64 {
+	if !controller.seekable || len(controller.snapshots) == 0 {
+		return controller.lastTime
+	}
+
+	index := sort.Search(len(controller.snapshots), func(i int) bool {
+		return controller.snapshots[i].time > time
+	})
+
+	index = mutils.Max(index-1, 0)
+
+	snapshot := controller.snapshots[index]
+
+	// Later snapshots will be taken again while updating
+	controller.snapshots = controller.snapshots[:index+1]
+
+	controller.ruleset.RestoreSnapshot(snapshot.ruleset)
+
+	for i, c := range controller.controllers {
+		state := snapshot.controls[i]
+
+		c.replayIndex = state.replayIndex
+		c.replayTime = state.replayTime
+		c.lastTime = state.lastTime
+
+		if c.relaxController != nil {
+			*c.relaxController = state.relax
+		}
+	}
+
+	for i, cursor := range controller.cursors {
+		state := snapshot.cursors[i]
+
+		cursor.SetPos(state.position)
+
+		cursor.LeftButton = state.leftButton
+		cursor.RightButton = state.rightButton
+		cursor.LeftKey = state.leftKey
+		cursor.RightKey = state.rightKey
+		cursor.LeftMouse = state.leftMouse
+		cursor.RightMouse = state.rightMouse
+		cursor.SmokeKey = state.smokeKey
+
+		cursor.IsReplayFrame = state.isReplayFrame
+		cursor.LastFrameTime = state.lastFrameTime
+		cursor.CurrentFrameTime = state.currentFrameTime
+	}
+
+	controller.lastTime = snapshot.time
+
+	return snapshot.time
 }
 
 func (controller *ReplayController) GetCursors() []*graphics.Cursor {
@@ -571,3 +788,11 @@ func (controller *ReplayController) GetClick(player, key int) bool {
 
 	return false
 }
+
+// getModString returns mods with parameters given to the beatmap, e.g. custom DT rate or DifficultyAdjust values
+func getModString(beatMap *beatmap.BeatMap, mods difficulty.Modifier) string {
+	diff := *beatMap.Diff
+	diff.SetMods(mods)
+
+	return diff.GetModString()
+}
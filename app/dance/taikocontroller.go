@@ -0,0 +1,258 @@
+package dance
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/rulesets/taiko"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"github.com/wieku/rplpa"
+	"math"
+	"sort"
+	"time"
+)
+
+// TaikoController plays osu!taiko replay given by -replay or autoplay if there's none
+type TaikoController struct {
+	bMap        *beatmap.BeatMap
+	replays     []RpData
+	cursors     []*graphics.Cursor
+	controllers []*subControl
+	ruleset     *taiko.TaikoRuleSet
+	lastTime    float64
+}
+
+func NewTaikoController() Controller {
+	return &TaikoController{lastTime: -200}
+}
+
+func (controller *TaikoController) SetBeatMap(beatMap *beatmap.BeatMap) {
+	controller.bMap = beatMap
+
+	if settings.REPLAY != "" {
+		control, data := loadSingleReplay()
+
+		controller.replays = append(controller.replays, data)
+		controller.controllers = append(controller.controllers, control)
+	} else {
+		control := NewSubControl()
+		control.mods = difficulty.Autoplay | beatMap.Diff.Mods
+		control.frames = generateTaikoAutoplay(taiko.ConvertObjects(beatMap))
+
+		controller.bMap.Diff.SetMods(control.mods)
+
+		controller.replays = append(controller.replays, RpData{settings.Knockout.DanserName, control.mods.String(), control.mods, 100, 0, 0, osu.NONE, -1, time.Now()})
+		controller.controllers = append(controller.controllers, control)
+	}
+
+	settings.PLAYERS = len(controller.replays)
+}
+
+func (controller *TaikoController) InitCursors() {
+	modifiers := make([]difficulty.Modifier, 0, len(controller.controllers))
+
+	for i, c := range controller.controllers {
+		cursor := graphics.NewCursor()
+		cursor.Name = controller.replays[i].Name
+		cursor.ScoreID = controller.replays[i].scoreID
+		cursor.ScoreTime = controller.replays[i].ScoreTime
+		cursor.IsAutoplay = c.mods.Active(difficulty.Autoplay)
+		cursor.IsPlayer = cursor.IsAutoplay
+
+		// Cursor isn't used in taiko, keep it in the centre so background parallax doesn't move
+		cursor.SetPos(vector.NewVec2f(256, 192))
+		cursor.Update(0)
+
+		controller.cursors = append(controller.cursors, cursor)
+
+		modifiers = append(modifiers, c.mods)
+	}
+
+	controller.ruleset = taiko.NewTaikoRuleset(controller.bMap, controller.cursors, modifiers)
+}
+
+func (controller *TaikoController) Update(time float64, delta float64) {
+	numSkipped := int(time-controller.lastTime) - 1
+
+	if numSkipped >= 1 {
+		for nTime := numSkipped; nTime >= 1; nTime-- {
+			controller.updateMain(time - float64(nTime))
+		}
+	}
+
+	controller.updateMain(time)
+
+	for i := range controller.controllers {
+		controller.cursors[i].Update(delta)
+
+		sc := controller.ruleset.GetScore(controller.cursors[i])
+		controller.replays[i].Accuracy = sc.Accuracy
+		controller.replays[i].Combo = controller.ruleset.GetCombo(controller.cursors[i])
+		controller.replays[i].Grade = sc.Grade
+	}
+}
+
+func (controller *TaikoController) updateMain(nTime float64) {
+	for i, c := range controller.controllers {
+		for c.replayIndex < len(c.frames) && c.replayTime+c.frames[c.replayIndex].Time <= int64(nTime) {
+			frame := c.frames[c.replayIndex]
+			c.replayTime += frame.Time
+
+			controller.ruleset.UpdateInputFor(controller.cursors[i], c.replayTime, taikoButtons(frame.KeyPressed))
+
+			c.replayIndex++
+		}
+	}
+
+	if int64(nTime) != int64(controller.lastTime) {
+		controller.ruleset.Update(int64(nTime))
+	}
+
+	controller.lastTime = nTime
+}
+
+func (controller *TaikoController) GetCursors() []*graphics.Cursor {
+	return controller.cursors
+}
+
+func (controller *TaikoController) GetReplays() []RpData {
+	return controller.replays
+}
+
+func (controller *TaikoController) GetRuleset() *taiko.TaikoRuleSet {
+	return controller.ruleset
+}
+
+func (controller *TaikoController) GetBeatMap() *beatmap.BeatMap {
+	return controller.bMap
+}
+
+func taikoButtons(keys *rplpa.KeyPressed) (buttons taiko.Buttons) {
+	if keys == nil {
+		return
+	}
+
+	if keys.LeftClick {
+		buttons |= taiko.LeftCentre
+	}
+
+	if keys.RightClick {
+		buttons |= taiko.LeftRim
+	}
+
+	if keys.Key1 {
+		buttons |= taiko.RightCentre
+	}
+
+	if keys.Key2 {
+		buttons |= taiko.RightRim
+	}
+
+	return
+}
+
+// generateTaikoAutoplay creates replay frames hitting every object perfectly, alternating hands like osu! does
+func generateTaikoAutoplay(taikoObjects []*taiko.Object) []*rplpa.ReplayData {
+	const releaseDelay = 20.0
+
+	type press struct {
+		time    float64
+		buttons taiko.Buttons
+	}
+
+	presses := make([]press, 0, len(taikoObjects))
+
+	leftHand := true
+
+	hit := func(t float64, centre, strong bool) {
+		var buttons taiko.Buttons
+
+		switch {
+		case centre && strong:
+			buttons = taiko.Centres
+		case !centre && strong:
+			buttons = taiko.Rims
+		case centre && leftHand:
+			buttons = taiko.LeftCentre
+		case centre:
+			buttons = taiko.RightCentre
+		case leftHand:
+			buttons = taiko.LeftRim
+		default:
+			buttons = taiko.RightRim
+		}
+
+		leftHand = !leftHand
+
+		presses = append(presses, press{t, buttons})
+	}
+
+	for _, o := range taikoObjects {
+		switch o.Type {
+		case taiko.Don, taiko.Kat:
+			hit(o.StartTime, o.Type == taiko.Don, o.Strong)
+		case taiko.DrumRoll:
+			for _, tick := range o.Ticks {
+				hit(tick, true, false)
+			}
+		case taiko.Swell:
+			interval := (o.EndTime - o.StartTime) / float64(o.RequiredHits+1)
+
+			for j := 1; j <= o.RequiredHits; j++ {
+				hit(o.StartTime+interval*float64(j), j%2 == 1, false)
+			}
+		}
+	}
+
+	sort.SliceStable(presses, func(i, j int) bool {
+		return presses[i].time < presses[j].time
+	})
+
+	frames := make([]*rplpa.ReplayData, 0, len(presses)*2+1)
+
+	lastTime := int64(0)
+
+	addFrame := func(t int64, buttons taiko.Buttons) {
+		frames = append(frames, &rplpa.ReplayData{
+			Time: t - lastTime,
+			KeyPressed: &rplpa.KeyPressed{
+				LeftClick:  buttons&taiko.LeftCentre > 0,
+				RightClick: buttons&taiko.LeftRim > 0,
+				Key1:       buttons&taiko.RightCentre > 0,
+				Key2:       buttons&taiko.RightRim > 0,
+			},
+		})
+
+		lastTime = t
+	}
+
+	if len(presses) > 0 {
+		addFrame(int64(presses[0].time)-1000, 0)
+	}
+
+	for i, p := range presses {
+		pressTime := int64(math.Round(p.time))
+
+		if pressTime <= lastTime && len(frames) > 1 {
+			continue
+		}
+
+		addFrame(pressTime, p.buttons)
+
+		releaseTime := pressTime + releaseDelay
+
+		if i+1 < len(presses) {
+			releaseTime = int64(math.Min(float64(releaseTime), math.Floor(float64(pressTime)+(presses[i+1].time-p.time)/2)))
+		}
+
+		if releaseTime <= pressTime {
+			releaseTime = pressTime + 1
+		}
+
+		addFrame(releaseTime, 0)
+	}
+
+	return frames
+}
@@ -165,7 +165,7 @@ func LoadBeatmaps(skipDatabaseCheck bool, importListener ImportListener) []*beat
 	stdMaps := make([]*beatmap.BeatMap, 0, len(allMaps)/2)
 
 	for _, b := range allMaps {
-		if b.Mode == 0 {
+		if b.Mode == 0 || b.Mode == int64(settings.MODE) {
 			stdMaps = append(stdMaps, b)
 		}
 	}
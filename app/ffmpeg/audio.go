@@ -2,6 +2,7 @@ package ffmpeg
 
 import (
 	"fmt"
+	"github.com/wieku/danser-go/app/progress"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/framework/bass"
 	"github.com/wieku/danser-go/framework/files"
@@ -18,18 +19,63 @@ import (
 
 const MaxAudioBuffers = 2000
 
-var cmdAudio *exec.Cmd
-
-var audioPipe io.WriteCloser
+var audioSink io.WriteCloser
 
 var audioPool chan []byte
 
 var audioWriteQueue chan []byte
 var endSyncAudio *sync.WaitGroup
 
+var discardBuffer []byte
+
 func startAudio(audioFPS float64) {
+	if settings.Recording.IsImageSequence() {
+		wav, err := newWavWriter(filepath.Join(getSequenceDir(), "audio.wav"), 48000)
+		if err != nil {
+			panic(fmt.Sprintf("Failed to create the audio file. Error: %s", err))
+		}
+
+		audioSink = wav
+	} else {
+		audioSink = startAudioProcess()
+	}
+
+	audioBufSize := bass.GetMixerRequiredBufferSize(1 / audioFPS)
+
+	audioPool = make(chan []byte, MaxAudioBuffers)
+
+	for i := 0; i < MaxAudioBuffers; i++ {
+		audioPool <- make([]byte, audioBufSize)
+	}
+
+	audioWriteQueue = make(chan []byte, MaxAudioBuffers)
+
+	endSyncAudio = &sync.WaitGroup{}
+	endSyncAudio.Add(1)
+
+	goroutines.RunOS(func() {
+		for data := range audioWriteQueue {
+			if _, err := audioSink.Write(data); err != nil {
+				if settings.Recording.IsImageSequence() {
+					panic(fmt.Sprintf("Failed to save the audio! Please check if you have enough storage. Error: %s", err))
+				}
+
+				panic(fmt.Sprintf("ffmpeg's audio process finished abruptly! Please check if you have enough storage or audio parameters are entered correctly. Error: %s", err))
+			}
+
+			audioPool <- data
+		}
+
+		endSyncAudio.Done()
+	})
+}
+
+// startAudioProcess starts ffmpeg encoding float samples piped to it
+func startAudioProcess() *processSink {
 	inputName := "-"
 
+	var audioPipe io.WriteCloser
+
 	if runtime.GOOS != "windows" {
 		pipe, err := files.NewNamedPipe("")
 		if err != nil {
@@ -67,11 +113,11 @@ func startAudio(audioFPS float64) {
 		options = append(options, encOptions...)
 	}
 
-	options = append(options, filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "audio."+settings.Recording.Container))
+	options = append(options, getAudioPath())
 
 	log.Println("Running ffmpeg with options:", options)
 
-	cmdAudio = exec.Command(ffmpegExec, options...)
+	cmdAudio := exec.Command(ffmpegExec, options...)
 
 	if runtime.GOOS == "windows" {
 		audioPipe, err = cmdAudio.StdinPipe()
@@ -81,7 +127,7 @@ func startAudio(audioFPS float64) {
 	}
 
 	if settings.Recording.ShowFFmpegLogs {
-		cmdAudio.Stdout = os.Stdout
+		cmdAudio.Stdout = progress.LogOutput()
 		cmdAudio.Stderr = os.Stderr
 	}
 
@@ -90,49 +136,30 @@ func startAudio(audioFPS float64) {
 		panic(fmt.Sprintf("ffmpeg's audio process failed to start! Please check if audio parameters are entered correctly or audio codec is supported by provided container. Error: %s", err))
 	}
 
-	audioBufSize := bass.GetMixerRequiredBufferSize(1 / audioFPS)
-
-	audioPool = make(chan []byte, MaxAudioBuffers)
-
-	for i := 0; i < MaxAudioBuffers; i++ {
-		audioPool <- make([]byte, audioBufSize)
-	}
-
-	audioWriteQueue = make(chan []byte, MaxAudioBuffers)
-
-	endSyncAudio = &sync.WaitGroup{}
-	endSyncAudio.Add(1)
-
-	goroutines.RunOS(func() {
-		for data := range audioWriteQueue {
-			if _, err := audioPipe.Write(data); err != nil {
-				panic(fmt.Sprintf("ffmpeg's audio process finished abruptly! Please check if you have enough storage or audio parameters are entered correctly. Error: %s", err))
-			}
-
-			audioPool <- data
-		}
-
-		endSyncAudio.Done()
-	})
+	return &processSink{pipe: audioPipe, cmd: cmdAudio}
 }
 
 func stopAudio() {
-	log.Println("Audio finished! Stopping audio pipe...")
+	log.Println("Audio finished! Closing audio output...")
 
 	close(audioWriteQueue)
 
 	endSyncAudio.Wait()
 
-	_ = audioPipe.Close()
-
-	log.Println("Audio pipe closed. Waiting for audio ffmpeg process to finish...")
-
-	_ = cmdAudio.Wait()
+	if err := audioSink.Close(); err != nil && settings.Recording.IsImageSequence() {
+		log.Println("Failed to save the audio:", err)
+	}
 
 	log.Println("Audio process finished.")
 }
 
 func PushAudio() {
+	// Chunks without audio process the mixer anyway because beat-reactive visuals depend on it
+	if !recordsAudio() {
+		bass.ProcessMixer(discardBuffer)
+		return
+	}
+
 	data := <-audioPool
 
 	bass.ProcessMixer(data)
@@ -0,0 +1,124 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/settings"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var chunkIndex, chunkCount int
+
+// firstFrame and lastFrame are bounds of video frames encoded by this process, lastFrame < 0 means the end of the video
+var firstFrame, lastFrame = int64(0), int64(-1)
+
+// SetChunk makes this process encode only the index-th of count parts of a video with totalFrames frames, it has to be called before StartFFmpeg
+func SetChunk(index, count int, totalFrames int64) {
+	chunkIndex, chunkCount = index, count
+
+	firstFrame = totalFrames * int64(index) / int64(count)
+
+	if index < count-1 {
+		lastFrame = totalFrames * int64(index+1) / int64(count)
+	}
+
+	log.Println(fmt.Sprintf("Rendering chunk %d/%d, frames %d-%d...", index+1, count, firstFrame, totalFrames*int64(index+1)/int64(count)-1))
+}
+
+// PrepareChunks clears the directory shared by chunk processes
+func PrepareChunks(output string) {
+	dir := filepath.Join(settings.Recording.GetOutputDir(), output+"_chunks")
+
+	_ = os.RemoveAll(dir)
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil && !os.IsExist(err) {
+		panic(err)
+	}
+}
+
+// CombineChunks joins video parts rendered by count chunk processes and muxes them with the audio recorded by the first one
+func CombineChunks(_output string, count int) {
+	output = _output
+	chunkCount = count
+
+	dir := getTempDir()
+
+	// Chunks saved their frames and audio to the same directory already
+	if settings.Recording.IsImageSequence() {
+		cleanup(dir)
+		finish(getSequenceDir())
+
+		return
+	}
+
+	preCheck()
+
+	var list strings.Builder
+
+	for i := 0; i < count; i++ {
+		list.WriteString(fmt.Sprintf("file 'video_%d.%s'\n", i, settings.Recording.Container))
+	}
+
+	listPath := filepath.Join(dir, "chunks.txt")
+
+	if err := os.WriteFile(listPath, []byte(list.String()), 0644); err != nil {
+		panic(err)
+	}
+
+	log.Println("Joining chunks...")
+
+	combine([]string{"-f", "concat", "-safe", "0", "-i", listPath}, getAudioPath(), dir)
+}
+
+// NeedsFrame tells whether the next frame has to be drawn, chunks skip frames which don't affect their part of the video
+func NeedsFrame() bool {
+	if chunkCount == 0 {
+		return true
+	}
+
+	next := frameNumber + 1
+
+	if lastFrame >= 0 && next > (lastFrame-1)*oversample() {
+		return false
+	}
+
+	return next >= firstFrame*oversample()-warmupFrames()
+}
+
+// SkipFrame advances the frame counter without drawing
+func SkipFrame() {
+	frameNumber++
+}
+
+// ChunkFinished tells whether all frames of this chunk were encoded, the first chunk has to run till the end anyway to record the audio
+func ChunkFinished() bool {
+	return chunkCount > 0 && !recordsAudio() && lastFrame >= 0 && frameNumber >= (lastFrame-1)*oversample()
+}
+
+func recordsAudio() bool {
+	return chunkIndex == 0
+}
+
+// oversample returns the number of drawn frames per video frame
+func oversample() int64 {
+	if settings.Recording.MotionBlur.Enabled {
+		return int64(settings.Recording.MotionBlur.OversampleMultiplier)
+	}
+
+	return 1
+}
+
+// warmupFrames is the number of frames drawn before chunk's first frame, one second to let any state advanced
+// while drawing settle plus motion blur's history
+func warmupFrames() int64 {
+	frames := int64(settings.Recording.FPS) * oversample()
+
+	if settings.Recording.MotionBlur.Enabled {
+		frames += int64(settings.Recording.MotionBlur.BlendFrames)
+	}
+
+	return frames
+}
@@ -2,7 +2,9 @@ package ffmpeg
 
 import (
 	"fmt"
+	"github.com/wieku/danser-go/app/progress"
 	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/bass"
 	"github.com/wieku/danser-go/framework/files"
 	"log"
 	"os"
@@ -16,6 +18,8 @@ var ffmpegExec string
 
 var output string
 
+var encoding bool
+
 // check used encoders exist
 func preCheck() {
 	var err error
@@ -74,46 +78,124 @@ func preCheck() {
 }
 
 func StartFFmpeg(fps, _w, _h int, audioFPS float64, _output string) {
-	preCheck()
+	if !settings.Recording.IsImageSequence() {
+		preCheck()
+	}
 
 	if strings.TrimSpace(_output) == "" {
 		_output = "danser_" + time.Now().Format("2006-01-02_15-04-05")
 	}
 
 	output = _output
+	encoding = true
 
 	log.Println("Starting encoding!")
 
-	_ = os.RemoveAll(filepath.Join(settings.Recording.GetOutputDir(), output+"_temp"))
+	// Image sequences don't have intermediate files
+	if !settings.Recording.IsImageSequence() {
+		if chunkCount == 0 {
+			_ = os.RemoveAll(getTempDir())
+		}
 
-	err := os.MkdirAll(filepath.Join(settings.Recording.GetOutputDir(), output+"_temp"), 0755)
-	if err != nil && !os.IsExist(err) {
-		panic(err)
+		err := os.MkdirAll(getTempDir(), 0755)
+		if err != nil && !os.IsExist(err) {
+			panic(err)
+		}
 	}
 
 	startVideo(fps, _w, _h)
-	startAudio(audioFPS)
+
+	if recordsAudio() {
+		startAudio(audioFPS)
+	} else {
+		discardBuffer = make([]byte, bass.GetMixerRequiredBufferSize(1/audioFPS))
+	}
 }
 
 func StopFFmpeg() {
+	encoding = false
+
 	log.Println("Finishing rendering...")
 
 	stopVideo()
-	stopAudio()
+
+	if recordsAudio() {
+		stopAudio()
+	}
 
 	log.Println("Ffmpeg finished.")
 
-	combine()
+	// Chunks are joined by the process that started them
+	if chunkCount > 0 {
+		return
+	}
+
+	if settings.Recording.IsImageSequence() {
+		finish(getSequenceDir())
+		return
+	}
+
+	combine([]string{"-i", getVideoPath()}, getAudioPath(), getTempDir())
+}
+
+// Abort stops encoding without producing the video, used when recording fails midway
+func Abort() {
+	if !encoding {
+		return
+	}
+
+	encoding = false
+
+	log.Println("Aborting encoding...")
+
+	stopVideo()
+
+	if recordsAudio() {
+		stopAudio()
+	}
+
+	if !settings.Recording.IsImageSequence() {
+		cleanup(getTempDir())
+	}
+}
+
+// getTempDir returns the directory with intermediate files, it's shared by all chunk processes
+func getTempDir() string {
+	if chunkCount > 0 {
+		return filepath.Join(settings.Recording.GetOutputDir(), output+"_chunks")
+	}
+
+	return filepath.Join(settings.Recording.GetOutputDir(), output+"_temp")
+}
+
+func getVideoPath() string {
+	if chunkCount > 0 {
+		return filepath.Join(getTempDir(), fmt.Sprintf("video_%d.%s", chunkIndex, settings.Recording.Container))
+	}
+
+	return filepath.Join(getTempDir(), "video."+settings.Recording.Container)
+}
+
+// getSequenceDir returns the directory with image sequence frames, chunk processes save theirs there too
+func getSequenceDir() string {
+	return filepath.Join(settings.Recording.GetOutputDir(), output)
 }
 
-func combine() {
-	options := []string{
-		"-y",
-		"-i", filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "video."+settings.Recording.Container),
-		"-i", filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "audio."+settings.Recording.Container),
+func getAudioPath() string {
+	return filepath.Join(getTempDir(), "audio."+settings.Recording.Container)
+}
+
+// combine muxes given video input with the audio file into the final video and removes tempDir
+func combine(videoInput []string, audioPath, tempDir string) {
+	options := []string{"-y"}
+
+	options = append(options, videoInput...)
+
+	options = append(options,
+		"-i", audioPath,
 		"-c:v", "copy",
 		"-c:a", "copy", "-strict", "-2",
-	}
+	)
 
 	if settings.Recording.Container == "mp4" {
 		options = append(options, "-movflags", "+faststart")
@@ -123,33 +205,45 @@ func combine() {
 
 	options = append(options, finalOutputPath)
 
+	progress.Send(progress.Event{Stage: progress.Combining, Output: finalOutputPath})
+
 	log.Println("Starting composing audio and video into one file...")
 	log.Println("Running ffmpeg with options:", options)
 	cmd2 := exec.Command(ffmpegExec, options...)
 
 	if settings.Recording.ShowFFmpegLogs {
-		cmd2.Stdout = os.Stdout
+		cmd2.Stdout = progress.LogOutput()
 		cmd2.Stderr = os.Stderr
 	}
 
 	if err := cmd2.Start(); err != nil {
 		log.Println("Failed to start ffmpeg:", err)
+
+		progress.Send(progress.Event{Stage: progress.Error, Error: err.Error()})
 	} else {
 		if err = cmd2.Wait(); err != nil {
 			panic(fmt.Sprintf("ffmpeg finished abruptly! Please check if you have enough storage. Error: %s", err))
 		} else {
 			log.Println("Finished!")
-			log.Println("Video is available at:", finalOutputPath)
+
+			finish(finalOutputPath)
 		}
 	}
 
-	cleanup()
+	cleanup(tempDir)
+}
+
+// finish reports where the final output was saved
+func finish(outputPath string) {
+	log.Println("Video is available at:", outputPath)
+
+	progress.Send(progress.Event{Stage: progress.Finished, Output: outputPath})
 }
 
-func cleanup() {
+func cleanup(tempDir string) {
 	log.Println("Cleaning up intermediate files...")
 
-	_ = os.RemoveAll(filepath.Join(settings.Recording.GetOutputDir(), output+"_temp"))
+	_ = os.RemoveAll(tempDir)
 
 	log.Println("Finished.")
 }
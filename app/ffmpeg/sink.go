@@ -0,0 +1,297 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/image/tiff"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// Sinks receive raw video frames or audio samples in presentation order, a video frame is passed in a single Write call
+
+// processSink feeds an ffmpeg process and waits for it to finish on Close
+type processSink struct {
+	pipe io.WriteCloser
+	cmd  *exec.Cmd
+}
+
+func (sink *processSink) Write(data []byte) (int, error) {
+	return sink.pipe.Write(data)
+}
+
+func (sink *processSink) Close() error {
+	_ = sink.pipe.Close()
+
+	return sink.cmd.Wait()
+}
+
+type sequenceFrame struct {
+	index int64
+	data  []byte
+}
+
+// imageSequence saves bottom-up BGRA frames as numbered PNG or TIFF files, encoding them on all cores
+type imageSequence struct {
+	dir    string
+	format string
+	alpha  bool
+
+	w, h int
+
+	next int64
+
+	queue chan sequenceFrame
+	pool  chan []byte
+
+	wg *sync.WaitGroup
+
+	errMutex *sync.Mutex
+	err      error
+}
+
+func newImageSequence(dir, format string, w, h int, firstIndex int64, alpha bool) *imageSequence {
+	if err := os.MkdirAll(dir, 0755); err != nil && !os.IsExist(err) {
+		panic(err)
+	}
+
+	workers := runtime.NumCPU()
+
+	sink := &imageSequence{
+		dir:      dir,
+		format:   format,
+		alpha:    alpha,
+		w:        w,
+		h:        h,
+		next:     firstIndex,
+		queue:    make(chan sequenceFrame, workers),
+		pool:     make(chan []byte, workers*2),
+		wg:       &sync.WaitGroup{},
+		errMutex: &sync.Mutex{},
+	}
+
+	for i := 0; i < workers*2; i++ {
+		sink.pool <- make([]byte, w*h*4)
+	}
+
+	sink.wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go sink.work()
+	}
+
+	return sink
+}
+
+func (sink *imageSequence) Write(data []byte) (int, error) {
+	if err := sink.getError(); err != nil {
+		return 0, err
+	}
+
+	buf := <-sink.pool
+
+	copy(buf, data)
+
+	sink.queue <- sequenceFrame{index: sink.next, data: buf}
+
+	sink.next++
+
+	return len(data), nil
+}
+
+func (sink *imageSequence) Close() error {
+	close(sink.queue)
+
+	sink.wg.Wait()
+
+	return sink.getError()
+}
+
+func (sink *imageSequence) work() {
+	defer sink.wg.Done()
+
+	for frame := range sink.queue {
+		if err := sink.save(frame); err != nil {
+			sink.errMutex.Lock()
+
+			if sink.err == nil {
+				sink.err = err
+			}
+
+			sink.errMutex.Unlock()
+		}
+
+		sink.pool <- frame.data
+	}
+}
+
+func (sink *imageSequence) save(frame sequenceFrame) error {
+	file, err := os.Create(filepath.Join(sink.dir, fmt.Sprintf("%06d.%s", frame.index, sink.format)))
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+
+	if sink.format == "tiff" {
+		err = tiff.Encode(writer, sink.toRGBA64(frame.data), &tiff.Options{Compression: tiff.Deflate})
+	} else {
+		err = (&png.Encoder{CompressionLevel: png.BestSpeed}).Encode(writer, sink.toRGBA(frame.data))
+	}
+
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	if cErr := file.Close(); err == nil {
+		err = cErr
+	}
+
+	return err
+}
+
+func (sink *imageSequence) getError() error {
+	sink.errMutex.Lock()
+	defer sink.errMutex.Unlock()
+
+	return sink.err
+}
+
+// toRGBA flips the frame read from OpenGL and swaps its channels, colors are already premultiplied like image.RGBA expects
+func (sink *imageSequence) toRGBA(data []byte) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, sink.w, sink.h))
+
+	stride := sink.w * 4
+
+	for y := 0; y < sink.h; y++ {
+		src := data[(sink.h-1-y)*stride : (sink.h-y)*stride]
+		dst := img.Pix[y*stride : (y+1)*stride]
+
+		for x := 0; x < stride; x += 4 {
+			dst[x], dst[x+1], dst[x+2], dst[x+3] = src[x+2], src[x+1], src[x], src[x+3]
+
+			if !sink.alpha {
+				dst[x+3] = 0xff
+			}
+		}
+	}
+
+	return img
+}
+
+// toRGBA64 does the same as toRGBA for TIFF files, they hold 8-bit data in a 16-bit container since frames are read back as 8-bit
+func (sink *imageSequence) toRGBA64(data []byte) *image.RGBA64 {
+	img := image.NewRGBA64(image.Rect(0, 0, sink.w, sink.h))
+
+	stride := sink.w * 4
+
+	for y := 0; y < sink.h; y++ {
+		src := data[(sink.h-1-y)*stride : (sink.h-y)*stride]
+		dst := img.Pix[y*img.Stride : (y+1)*img.Stride]
+
+		for x := 0; x < stride; x += 4 {
+			r, g, b, a := src[x+2], src[x+1], src[x], src[x+3]
+
+			if !sink.alpha {
+				a = 0xff
+			}
+
+			// 8-bit values are expanded to the full 16-bit range, it doesn't add any precision
+			for i, v := range [4]byte{r, g, b, a} {
+				dst[x*2+i*2] = v
+				dst[x*2+i*2+1] = v
+			}
+		}
+	}
+
+	return img
+}
+
+// wavWriter saves 32-bit float stereo samples as a WAV file
+type wavWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+
+	sampleRate int
+	size       int64
+}
+
+func newWavWriter(path string, sampleRate int) (*wavWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	wav := &wavWriter{
+		file:       file,
+		writer:     bufio.NewWriter(file),
+		sampleRate: sampleRate,
+	}
+
+	// Sizes are filled in on Close
+	if err = wav.writeHeader(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return wav, nil
+}
+
+func (wav *wavWriter) writeHeader() error {
+	const channels, bytesPerSample = 2, 4
+
+	header := []interface{}{
+		[]byte("RIFF"),
+		uint32(36 + wav.size),
+		[]byte("WAVE"),
+		[]byte("fmt "),
+		uint32(16),
+		uint16(3), // IEEE float
+		uint16(channels),
+		uint32(wav.sampleRate),
+		uint32(wav.sampleRate * channels * bytesPerSample),
+		uint16(channels * bytesPerSample),
+		uint16(bytesPerSample * 8),
+		[]byte("data"),
+		uint32(wav.size),
+	}
+
+	for _, v := range header {
+		if err := binary.Write(wav.writer, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	return wav.writer.Flush()
+}
+
+func (wav *wavWriter) Write(data []byte) (int, error) {
+	n, err := wav.writer.Write(data)
+
+	wav.size += int64(n)
+
+	return n, err
+}
+
+func (wav *wavWriter) Close() error {
+	err := wav.writer.Flush()
+
+	if err == nil {
+		if _, err = wav.file.Seek(0, io.SeekStart); err == nil {
+			err = wav.writeHeader()
+		}
+	}
+
+	if cErr := wav.file.Close(); err == nil {
+		err = cErr
+	}
+
+	return err
+}
@@ -0,0 +1,80 @@
+package ffmpeg
+
+import (
+	"golang.org/x/image/tiff"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Two pixels stacked vertically, stored bottom-up in BGRA like they're read from OpenGL
+var sequenceFrameData = []byte{
+	0x10, 0x20, 0x30, 0x80, // bottom: B, G, R, A
+	0x40, 0x50, 0x60, 0xc0, // top
+}
+
+func decodeSequenceFrame(t *testing.T, format string, alpha bool) image.Image {
+	dir := t.TempDir()
+
+	sink := newImageSequence(dir, format, 1, 2, 1, alpha)
+
+	if _, err := sink.Write(sequenceFrameData); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, "000001."+format))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	var img image.Image
+
+	if format == "tiff" {
+		img, err = tiff.Decode(file)
+	} else {
+		img, err = png.Decode(file)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
+
+// Colors are compared premultiplied with one 8-bit step of tolerance, PNG stores them unpremultiplied
+func TestImageSequenceChannels(t *testing.T) {
+	expected := [][4]uint32{
+		{0x60, 0x50, 0x40, 0xc0},
+		{0x30, 0x20, 0x10, 0x80},
+	}
+
+	for _, format := range []string{"png", "tiff"} {
+		for _, alpha := range []bool{true, false} {
+			img := decodeSequenceFrame(t, format, alpha)
+
+			for y, e := range expected {
+				if !alpha {
+					e[3] = 0xff
+				}
+
+				r, g, b, a := img.At(0, y).RGBA()
+
+				for i, v := range [4]uint32{r, g, b, a} {
+					if diff := int(v) - int(e[i]*0x101); diff < -0x101 || diff > 0x101 {
+						t.Errorf("%s (alpha: %t): expected %#x at row %d, got %#x", format, alpha, e, y, [4]uint32{r >> 8, g >> 8, b >> 8, a >> 8})
+						break
+					}
+				}
+			}
+		}
+	}
+}
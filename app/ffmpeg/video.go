@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/faiface/mainthread"
 	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/wieku/danser-go/app/progress"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/framework/files"
 	"github.com/wieku/danser-go/framework/frame"
@@ -16,7 +17,6 @@ import (
 	"log"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -26,9 +26,7 @@ import (
 
 const MaxVideoBuffers = 10
 
-var cmdVideo *exec.Cmd
-
-var videoPipe io.WriteCloser
+var videoSink io.WriteCloser
 
 var videoWriteQueue chan *PBO
 var endSyncVideo *sync.WaitGroup
@@ -67,13 +65,15 @@ func createPBO(format pixconv.PixFmt) *PBO {
 
 	glSize := w * h * 3
 
-	if pbo.convFormat == pixconv.I420 || pbo.convFormat == pixconv.NV12 || pbo.convFormat == pixconv.NV21 {
+	if isRaw(pbo.convFormat) {
+		glSize = pixconv.GetRequiredBufferSize(pbo.convFormat, w, h)
+	} else if pbo.convFormat == pixconv.I420 || pbo.convFormat == pixconv.NV12 || pbo.convFormat == pixconv.NV21 {
 		glSize = w * h * 3 / 2
 
 		if pbo.convFormat == pixconv.NV12 || pbo.convFormat == pixconv.NV21 {
 			pbo.convData = make([]byte, glSize)
 		}
-	} else if pbo.convFormat != pixconv.ARGB && pbo.convFormat != pixconv.I444 {
+	} else if pbo.convFormat != pixconv.I444 {
 		convSize := pixconv.GetRequiredBufferSize(pbo.convFormat, w, h)
 		pbo.convData = make([]byte, convSize)
 	}
@@ -95,18 +95,97 @@ var rgbToYuvConverter *effects.RGBYUV
 func startVideo(fps, _w, _h int) {
 	w, h = _w, _h
 
+	frameNumber = -1
+
 	if settings.Recording.MotionBlur.Enabled {
 		fps /= settings.Recording.MotionBlur.OversampleMultiplier
 	}
 
+	rgbToYuvConverter = nil
+
+	if settings.Recording.IsImageSequence() {
+		parsedFormat = pixconv.ARGB
+
+		videoError = ""
+		videoErrorWait = &sync.WaitGroup{}
+
+		log.Println("Saving image sequence to:", getSequenceDir())
+
+		videoSink = newImageSequence(getSequenceDir(), settings.Recording.OutputType, w, h, firstFrame, settings.Recording.IsTransparent())
+	} else {
+		videoSink = startVideoProcess(fps)
+	}
+
+	freePBOPool = make(chan *PBO, MaxVideoBuffers)
+
+	mainthread.Call(func() {
+		if !isRaw(parsedFormat) {
+			rgbToYuvConverter = effects.NewRGBYUV(w, h, parsedFormat != pixconv.I444 && parsedFormat != pixconv.I422)
+		}
+
+		for i := 0; i < MaxVideoBuffers; i++ {
+			freePBOPool <- createPBO(parsedFormat)
+		}
+
+		if settings.Recording.MotionBlur.Enabled {
+			bFrames := settings.Recording.MotionBlur.BlendFrames
+			blend = effects.NewBlend(w, h, bFrames, calculateWeights(bFrames))
+		}
+	})
+
+	videoWriteQueue = make(chan *PBO, MaxVideoBuffers)
+
+	limiter = frame.NewLimiter(settings.Recording.EncodingFPSCap)
+
+	endSyncVideo = &sync.WaitGroup{}
+	endSyncVideo.Add(1)
+
+	goroutines.RunOS(func() {
+		for pbo := range videoWriteQueue {
+			pbo.convertSync.Wait() // Wait for conversion to end
+
+			if _, err := videoSink.Write(pbo.convData); err != nil {
+				if settings.Recording.IsImageSequence() {
+					panic(fmt.Sprintf("Failed to save the frame! Please check if you have enough storage. Error: %s", err))
+				}
+
+				errorMsg := err.Error()
+
+				videoErrorWait.Wait()
+
+				if videoError != "" {
+					errorMsg = videoError
+				}
+
+				panic(fmt.Sprintf("ffmpeg's video process finished abruptly! Please check if you have enough storage or video parameters are entered correctly. Error: %s", errorMsg))
+			}
+
+			freePBOPool <- pbo
+		}
+
+		endSyncVideo.Done()
+	})
+}
+
+// startVideoProcess starts ffmpeg encoding raw frames piped to it
+func startVideoProcess(fps int) *processSink {
 	encoder := strings.ToLower(settings.Recording.Encoder)
 	outputFormat := strings.ToLower(settings.Recording.PixelFormat)
 
 	if strings.HasSuffix(encoder, "_qsv") { // qsv works best with nv12 format
 		outputFormat = "nv12"
+	} else if encoder == "prores_ks" { // ffmpeg picks the 10-bit format matching the profile
+		outputFormat = "yuv444p"
 	}
 
-	parsedFormat = pixconv.ARGB
+	parsedFormat = pixconv.RGB
+
+	if settings.Recording.IsTransparent() {
+		outputFormat = getAlphaFormat(encoder)
+
+		// Frames are read with alpha and ffmpeg converts them to a pixel format with alpha plane
+		parsedFormat = pixconv.ARGB
+	}
 
 	switch outputFormat {
 	case "yuv420p":
@@ -121,9 +200,13 @@ func startVideo(fps, _w, _h int) {
 		parsedFormat = pixconv.NV21
 	}
 
-	inputPixFmt := "rgb24"
-	if parsedFormat != pixconv.ARGB {
-		inputPixFmt = outputFormat
+	inputPixFmt := outputFormat
+
+	switch parsedFormat {
+	case pixconv.RGB:
+		inputPixFmt = "rgb24"
+	case pixconv.ARGB:
+		inputPixFmt = "bgra" // libyuv's ARGB is stored as BGRA in memory
 	}
 
 	videoFilters := strings.TrimSpace(settings.Recording.Filters)
@@ -133,6 +216,8 @@ func startVideo(fps, _w, _h int) {
 
 	inputName := "-"
 
+	var videoPipe io.WriteCloser
+
 	if runtime.GOOS != "windows" {
 		pipe, err := files.NewNamedPipe("")
 		if err != nil {
@@ -164,7 +249,7 @@ func startVideo(fps, _w, _h int) {
 		"-movflags", "+write_colr",
 	}
 
-	if parsedFormat == pixconv.ARGB {
+	if isRaw(parsedFormat) {
 		options = append(options, "-pix_fmt", outputFormat)
 	}
 
@@ -175,11 +260,11 @@ func startVideo(fps, _w, _h int) {
 		options = append(options, encOptions...)
 	}
 
-	options = append(options, filepath.Join(settings.Recording.GetOutputDir(), output+"_temp", "video."+settings.Recording.Container))
+	options = append(options, getVideoPath())
 
 	log.Println("Running ffmpeg with options:", options)
 
-	cmdVideo = exec.Command(ffmpegExec, options...)
+	cmdVideo := exec.Command(ffmpegExec, options...)
 
 	if runtime.GOOS == "windows" {
 		videoPipe, err = cmdVideo.StdinPipe()
@@ -197,7 +282,7 @@ func startVideo(fps, _w, _h int) {
 	errList := []io.Writer{oFile}
 
 	if settings.Recording.ShowFFmpegLogs {
-		outList = append(outList, os.Stdout)
+		outList = append(outList, progress.LogOutput())
 		errList = append(errList, os.Stderr)
 	}
 
@@ -209,27 +294,6 @@ func startVideo(fps, _w, _h int) {
 		panic(fmt.Sprintf("ffmpeg's video process failed to start! Please check if video parameters are entered correctly or video codec is supported by provided container. Error: %s", err))
 	}
 
-	freePBOPool = make(chan *PBO, MaxVideoBuffers)
-
-	mainthread.Call(func() {
-		if parsedFormat != pixconv.ARGB {
-			rgbToYuvConverter = effects.NewRGBYUV(w, h, parsedFormat != pixconv.I444 && parsedFormat != pixconv.I422)
-		}
-
-		for i := 0; i < MaxVideoBuffers; i++ {
-			freePBOPool <- createPBO(parsedFormat)
-		}
-
-		if settings.Recording.MotionBlur.Enabled {
-			bFrames := settings.Recording.MotionBlur.BlendFrames
-			blend = effects.NewBlend(w, h, bFrames, calculateWeights(bFrames))
-		}
-	})
-
-	videoWriteQueue = make(chan *PBO, MaxVideoBuffers)
-
-	limiter = frame.NewLimiter(settings.Recording.EncodingFPSCap)
-
 	videoErrorWait = &sync.WaitGroup{}
 	videoErrorWait.Add(1)
 
@@ -265,30 +329,7 @@ func startVideo(fps, _w, _h int) {
 		videoErrorWait.Done()
 	})
 
-	endSyncVideo = &sync.WaitGroup{}
-	endSyncVideo.Add(1)
-
-	goroutines.RunOS(func() {
-		for pbo := range videoWriteQueue {
-			pbo.convertSync.Wait() // Wait for conversion to end
-
-			if _, err := videoPipe.Write(pbo.convData); err != nil {
-				errorMsg := err.Error()
-
-				videoErrorWait.Wait()
-
-				if videoError != "" {
-					errorMsg = videoError
-				}
-
-				panic(fmt.Sprintf("ffmpeg's video process finished abruptly! Please check if you have enough storage or video parameters are entered correctly. Error: %s", errorMsg))
-			}
-
-			freePBOPool <- pbo
-		}
-
-		endSyncVideo.Done()
-	})
+	return &processSink{pipe: videoPipe, cmd: cmdVideo}
 }
 
 func stopVideo() {
@@ -300,13 +341,19 @@ func stopVideo() {
 
 	endSyncVideo.Wait()
 
-	log.Println("Finished! Stopping video pipe...")
+	log.Println("Finished! Closing video output...")
 
-	_ = videoPipe.Close()
+	if err := videoSink.Close(); err != nil && settings.Recording.IsImageSequence() {
+		log.Println("Failed to save the image sequence:", err)
+	}
 
-	log.Println("Video pipe closed. Waiting for video ffmpeg process to finish...")
+	// Buffers are created again by the next recording
+	for len(freePBOPool) > 0 {
+		pbo := <-freePBOPool
 
-	_ = cmdVideo.Wait()
+		gl.UnmapNamedBuffer(pbo.handle)
+		gl.DeleteBuffers(1, &pbo.handle)
+	}
 
 	log.Println("Video process finished.")
 }
@@ -346,6 +393,11 @@ func MakeFrame() {
 		yuvFull, yuvHalf = rgbToYuvConverter.Draw()
 	}
 
+	// Warm-up frames of a chunk aren't encoded
+	if frameNumber < firstFrame*oversample() {
+		return
+	}
+
 	checkData(len(freePBOPool) == 0, false) // Force wait for at least one frame to be retrieved if pbo pool is empty
 
 	pbo := <-freePBOPool // Wait for free PBO
@@ -361,12 +413,17 @@ func MakeFrame() {
 
 		gl.GetTextureSubImage(yuvHalf.GetID(), 0, 0, 0, 0, int32(w/2), int32(h/2), 1, gl.GREEN, gl.UNSIGNED_BYTE, int32(w*h/4), gl.PtrOffset(w*h))
 		gl.GetTextureSubImage(yuvHalf.GetID(), 0, 0, 0, 0, int32(w/2), int32(h/2), 1, gl.BLUE, gl.UNSIGNED_BYTE, int32(w*h/4), gl.PtrOffset(w*h*5/4))
-	} else if pbo.convFormat != pixconv.ARGB { //Read as yuv444p
+	} else if !isRaw(pbo.convFormat) { //Read as yuv444p
 		gl.GetTextureSubImage(yuvFull.GetID(), 0, 0, 0, 0, int32(w), int32(h), 1, gl.RED, gl.UNSIGNED_BYTE, int32(w*h), gl.Ptr(nil))
 		gl.GetTextureSubImage(yuvFull.GetID(), 0, 0, 0, 0, int32(w), int32(h), 1, gl.GREEN, gl.UNSIGNED_BYTE, int32(w*h), gl.PtrOffset(w*h))
 		gl.GetTextureSubImage(yuvFull.GetID(), 0, 0, 0, 0, int32(w), int32(h), 1, gl.BLUE, gl.UNSIGNED_BYTE, int32(w*h), gl.PtrOffset(w*h*2))
 	} else {
-		gl.ReadPixels(0, 0, int32(w), int32(h), uint32(gl.RGB), gl.UNSIGNED_BYTE, gl.Ptr(nil))
+		format := uint32(gl.RGB)
+		if pbo.convFormat == pixconv.ARGB {
+			format = gl.BGRA
+		}
+
+		gl.ReadPixels(0, 0, int32(w), int32(h), format, gl.UNSIGNED_BYTE, gl.Ptr(nil))
 	}
 
 	pbo.sync = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
@@ -380,6 +437,35 @@ func MakeFrame() {
 	limiter.Sync()
 }
 
+// isRaw tells whether frames in given format are read from OpenGL as they are, without conversion to yuv
+func isRaw(format pixconv.PixFmt) bool {
+	return format == pixconv.RGB || format == pixconv.ARGB
+}
+
+// getAlphaFormat returns the pixel format with alpha plane supported by given encoder
+func getAlphaFormat(encoder string) string {
+	switch encoder {
+	case "prores_ks":
+		if !settings.Recording.ProResSettings.HasAlpha() {
+			panic("Transparent recordings need ProRes 4444 or 4444 XQ profile")
+		}
+
+		if container := settings.Recording.Container; container != "mov" && container != "mkv" {
+			panic("Transparent ProRes recordings need mov or mkv container")
+		}
+
+		return "yuva444p10le"
+	case "libvpx-vp9":
+		if container := settings.Recording.Container; container != "webm" && container != "mkv" {
+			panic("Transparent VP9 recordings need webm or mkv container")
+		}
+
+		return "yuva420p"
+	}
+
+	panic(fmt.Sprintf("Encoder \"%s\" doesn't support transparency, please use ProRes 4444, VP9 or an image sequence", encoder))
+}
+
 func checkData(waitForFirst, waitForAll bool) { // I tried to do that on another thread, but it needs another opengl context and creates other funky problems
 	for i := 0; len(frameReadQueue) > 0; i++ {
 		pbo := frameReadQueue[0]
@@ -411,7 +497,7 @@ func checkData(waitForFirst, waitForAll bool) { // I tried to do that on another
 }
 
 func submitFrame(pbo *PBO) {
-	if pbo.convFormat == pixconv.I444 || pbo.convFormat == pixconv.I420 || pbo.convFormat == pixconv.ARGB { // For yuv444p and yuv420p or raw just dump the frame
+	if pbo.convFormat == pixconv.I444 || pbo.convFormat == pixconv.I420 || isRaw(pbo.convFormat) { // For yuv444p and yuv420p or raw just dump the frame
 		pbo.convData = pbo.data
 	} else {
 		pbo.convertSync.Add(1)
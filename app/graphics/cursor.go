@@ -102,6 +102,10 @@ type Cursor struct {
 }
 
 func NewCursor() *Cursor {
+	if settings.HEADLESS {
+		return &Cursor{Position: vector.NewVec2f(100, 100)}
+	}
+
 	if cursorFbo == nil {
 		initCursor()
 	}
@@ -169,7 +173,10 @@ func (cursor *Cursor) SetPos(pt vector.Vector2f) {
 	}
 
 	cursor.Position = tmp
-	cursor.renderer.SetPosition(cursor.Position)
+
+	if cursor.renderer != nil {
+		cursor.renderer.SetPosition(cursor.Position)
+	}
 }
 
 func (cursor *Cursor) SetScreenPos(pt vector.Vector2f) {
@@ -180,6 +187,10 @@ func (cursor *Cursor) Update(delta float64) {
 	delta = math.Abs(delta)
 	cursor.time += delta
 
+	if settings.HEADLESS {
+		return
+	}
+
 	leftState := cursor.LeftKey || cursor.LeftMouse
 	rightState := cursor.RightKey || cursor.RightMouse
 
@@ -0,0 +1,199 @@
+package hitlog
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+var csvHeader = []string{"player", "time", "object", "type", "result", "comboResult", "hitError", "distance", "score", "accuracy", "pp", "hp"}
+
+// Event is a single judgement received from the ruleset
+type Event struct {
+	Player      string   `json:"player"`
+	Time        int64    `json:"time"`
+	Object      int64    `json:"object"`
+	Type        string   `json:"type"`
+	Result      string   `json:"result"`
+	ComboResult string   `json:"comboResult"`
+	HitError    *float64 `json:"hitError"` // nil if the judgement isn't timing-based
+	Distance    float64  `json:"distance"` // distance between the cursor and object's centre in osu!pixels
+	Score       int64    `json:"score"`
+	Accuracy    float64  `json:"accuracy"`
+	PP          float64  `json:"pp"`
+	HP          float64  `json:"hp"`
+}
+
+// Logger streams judgements of all players to a JSON Lines or CSV file
+type Logger struct {
+	ruleset *osu.OsuRuleSet
+
+	file   *os.File
+	writer *bufio.Writer
+	csv    *csv.Writer
+
+	mutex  sync.Mutex
+	closed bool
+}
+
+// New creates the log file at basePath + ".hits." + format and attaches the logger to the ruleset
+func New(ruleset *osu.OsuRuleSet, basePath, format string) (*Logger, error) {
+	format = strings.ToLower(format)
+
+	if format != FormatJSONL && format != FormatCSV {
+		return nil, fmt.Errorf("unknown hit log format: %s", format)
+	}
+
+	path := basePath + ".hits." + format
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := &Logger{
+		ruleset: ruleset,
+		file:    file,
+		writer:  bufio.NewWriter(file),
+	}
+
+	if format == FormatCSV {
+		logger.csv = csv.NewWriter(logger.writer)
+		_ = logger.csv.Write(csvHeader)
+	}
+
+	ruleset.AddListener(logger.hitReceived)
+
+	return logger, nil
+}
+
+// GetPath returns the path of the log file
+func (logger *Logger) GetPath() string {
+	return logger.file.Name()
+}
+
+func (logger *Logger) hitReceived(cursor *graphics.Cursor, time int64, number int64, position vector.Vector2d, result osu.HitResult, comboResult osu.ComboResult, ppResults performance.PPv2Results, score int64) {
+	object := logger.ruleset.GetBeatMap().HitObjects[number]
+
+	event := Event{
+		Player:      cursor.Name,
+		Time:        time,
+		Object:      number,
+		Result:      result.String(),
+		ComboResult: comboResult.String(),
+		Distance:    cursor.RawPosition.Copy64().Dst(position),
+		Score:       score,
+		Accuracy:    logger.ruleset.GetScore(cursor).Accuracy,
+		PP:          ppResults.Total,
+		HP:          logger.ruleset.GetHP(cursor),
+	}
+
+	// Same rules as the hit error meter: only circle hits and slider heads are judged by timing
+	switch object.(type) {
+	case *objects.Circle:
+		event.Type = "circle"
+
+		if result&(osu.BaseHits|osu.PositionalMiss) > 0 {
+			hitError := float64(time) - object.GetStartTime()
+			event.HitError = &hitError
+		}
+	case *objects.Slider:
+		event.Type = "slider"
+
+		if result&(osu.SliderStart|osu.PositionalMiss) > 0 {
+			hitError := float64(time) - object.GetStartTime()
+			event.HitError = &hitError
+		}
+	case *objects.Spinner:
+		event.Type = "spinner"
+	}
+
+	logger.write(event)
+}
+
+func (logger *Logger) write(event Event) {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	if logger.closed {
+		return
+	}
+
+	if logger.csv != nil {
+		hitError := ""
+		if event.HitError != nil {
+			hitError = strconv.FormatFloat(*event.HitError, 'f', -1, 64)
+		}
+
+		_ = logger.csv.Write([]string{
+			event.Player,
+			strconv.FormatInt(event.Time, 10),
+			strconv.FormatInt(event.Object, 10),
+			event.Type,
+			event.Result,
+			event.ComboResult,
+			hitError,
+			strconv.FormatFloat(event.Distance, 'f', 2, 64),
+			strconv.FormatInt(event.Score, 10),
+			strconv.FormatFloat(event.Accuracy, 'f', 2, 64),
+			strconv.FormatFloat(event.PP, 'f', 2, 64),
+			strconv.FormatFloat(event.HP, 'f', 4, 64),
+		})
+
+		logger.csv.Flush()
+	} else {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+
+		_, _ = logger.writer.Write(data)
+		_ = logger.writer.WriteByte('\n')
+	}
+
+	// Flushed right away so crashes and os.Exit don't lose the events
+	_ = logger.writer.Flush()
+}
+
+// Close closes the file, events received afterwards are dropped
+func (logger *Logger) Close() error {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	if logger.closed {
+		return nil
+	}
+
+	logger.closed = true
+
+	if logger.csv != nil {
+		logger.csv.Flush()
+	}
+
+	if err := logger.writer.Flush(); err != nil {
+		_ = logger.file.Close()
+		return err
+	}
+
+	return logger.file.Close()
+}
@@ -8,14 +8,36 @@ var Focused = true
 
 type KeyListener glfw.KeyCallback
 
-var listeners []KeyListener
+type registeredListener struct {
+	id       int
+	listener KeyListener
+}
+
+var listeners []registeredListener
+
+var lastListenerID int
+
+// RegisterListener adds a key listener and returns its id, it can be used to remove the listener later
+func RegisterListener(listener KeyListener) int {
+	lastListenerID++
+
+	listeners = append(listeners, registeredListener{id: lastListenerID, listener: listener})
 
-func RegisterListener(listener KeyListener) {
-	listeners = append(listeners, listener)
+	return lastListenerID
+}
+
+// RemoveListener removes a key listener registered with given id
+func RemoveListener(id int) {
+	for i, l := range listeners {
+		if l.id == id {
+			listeners = append(listeners[:i], listeners[i+1:]...)
+			return
+		}
+	}
 }
 
 func CallListeners(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
 	for _, l := range listeners {
-		l(w, key, scancode, action, mods)
+		l.listener(w, key, scancode, action, mods)
 	}
-}
\ No newline at end of file
+}
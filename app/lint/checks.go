@@ -0,0 +1,408 @@
+package lint
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	playfieldWidth  = 512.0
+	playfieldHeight = 384.0
+
+	// unsnapTolerance allows for 1ms rounding osu! editor does when placing objects
+	unsnapTolerance = 1.0
+
+	minBreakLength = 650.0
+
+	// Inherited points outside of this range are clamped by osu!
+	minVelocity = 0.1
+	maxVelocity = 10.0
+)
+
+var snapDivisors = []int{1, 2, 3, 4, 6, 8, 12, 16}
+
+var sampleSetNames = []string{"normal", "soft", "drum"}
+
+var additionNames = []string{"hitwhistle", "hitfinish", "hitclap"}
+
+var sampleFileRegex = regexp.MustCompile(`^(normal|soft|drum)-(hitnormal|hitwhistle|hitfinish|hitclap|slidertick|sliderslide|sliderwhistle)(\d*)\.(wav|ogg|mp3)$`)
+
+func (c *checker) mapDir() string {
+	return filepath.Join(settings.General.GetSongsDir(), c.beatMap.Dir)
+}
+
+// fileExists matches file names case-insensitively like osu! does on Windows
+func (c *checker) fileExists(name string) bool {
+	if _, err := os.Stat(filepath.Join(c.mapDir(), name)); err == nil {
+		return true
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(filepath.Join(c.mapDir(), name)))
+	if err != nil {
+		return false
+	}
+
+	for _, e := range entries {
+		if strings.EqualFold(e.Name(), filepath.Base(name)) {
+			c.add("files", Info, -1, "\"%s\" is named \"%s\" on disk, it won't load on case-sensitive systems", name, e.Name())
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *checker) checkFiles() {
+	if c.beatMap.Audio == "" {
+		c.add("files", Error, -1, "Audio file is not specified")
+	} else if !c.fileExists(c.beatMap.Audio) {
+		c.add("files", Error, -1, "Audio file \"%s\" is missing", c.beatMap.Audio)
+	}
+
+	if c.beatMap.Bg == "" {
+		c.add("files", Warning, -1, "Background is not specified")
+	} else if !c.fileExists(c.beatMap.Bg) {
+		c.add("files", Error, -1, "Background \"%s\" is missing", c.beatMap.Bg)
+	}
+}
+
+func (c *checker) checkTimingPoints() {
+	points := c.beatMap.Timings.GetPoints()
+
+	if len(points) == 0 {
+		c.add("timing", Error, -1, "Beatmap has no timing points")
+		return
+	}
+
+	if points[0].Inherited {
+		c.add("timing", Error, points[0].Time, "First timing point is inherited")
+	}
+
+	for _, point := range points {
+		beatLength := point.GetRawBeatLength()
+
+		if !point.Inherited {
+			if beatLength <= 0 || math.IsNaN(beatLength) || math.IsInf(beatLength, 0) {
+				c.add("timing", Error, point.Time, "Uninherited timing point has invalid beat length: %s", formatFloat(beatLength))
+			}
+
+			continue
+		}
+
+		velocity := -100 / beatLength
+
+		if math.IsNaN(velocity) || velocity < minVelocity || velocity > maxVelocity {
+			c.add("velocity", Warning, point.Time, "Slider velocity %sx is outside of %sx-%sx range and will be clamped", formatFloat(velocity), formatFloat(minVelocity), formatFloat(maxVelocity))
+		}
+	}
+}
+
+func (c *checker) checkBreaks() {
+	for _, pause := range c.beatMap.Pauses {
+		if pause.Length() < minBreakLength {
+			c.add("break", Warning, pause.StartTime, "Break is too short (%sms), it should last at least %sms", formatFloat(pause.Length()), formatFloat(minBreakLength))
+		}
+
+		for _, o := range c.beatMap.HitObjects {
+			if o.GetStartTime() < pause.EndTime && o.GetEndTime() > pause.StartTime {
+				c.add("break", Error, pause.StartTime, "Break overlaps an object at %s", timestamp(o.GetStartTime()))
+				break
+			}
+		}
+	}
+}
+
+func (c *checker) checkObjects() {
+	standard := c.beatMap.Mode == settings.ModeOsu
+
+	for i, o := range c.beatMap.HitObjects {
+		name := objectName(o)
+
+		c.checkSnap(o.GetStartTime(), name)
+
+		if o.GetEndTime() > o.GetStartTime() {
+			c.checkSnap(o.GetEndTime(), name+" end")
+		}
+
+		if slider, ok := o.(*objects.Slider); ok {
+			c.checkSlider(slider)
+		}
+
+		// Mania has chords and positions that don't matter
+		if c.beatMap.Mode == settings.ModeMania {
+			continue
+		}
+
+		if i > 0 {
+			prev := c.beatMap.HitObjects[i-1]
+
+			if o.GetStartTime() == prev.GetStartTime() {
+				c.add("overlap", Error, o.GetStartTime(), "Two objects are placed at the same time")
+			} else if o.GetStartTime() < prev.GetEndTime() {
+				c.add("overlap", Error, o.GetStartTime(), "%s starts before the previous %s ends", name, strings.ToLower(objectName(prev)))
+			}
+		}
+
+		if standard && o.GetType() != objects.SPINNER {
+			c.checkPlayfield(o, name)
+		}
+	}
+}
+
+// checkSnap reports times which aren't on any of the common beat divisors of the current uninherited timing point
+func (c *checker) checkSnap(time float64, what string) {
+	point := c.beatMap.Timings.GetOriginalPointAt(time)
+
+	beatLength := point.GetBaseBeatLength()
+	if beatLength <= 0 || math.IsNaN(beatLength) {
+		return
+	}
+
+	offset := time - point.Time
+
+	closestDiff := math.Inf(1)
+	closestDivisor := 1
+
+	for _, divisor := range snapDivisors {
+		step := beatLength / float64(divisor)
+		diff := offset - math.Round(offset/step)*step
+
+		if math.Abs(diff) <= unsnapTolerance {
+			return
+		}
+
+		if math.Abs(diff) < math.Abs(closestDiff) {
+			closestDiff = diff
+			closestDivisor = divisor
+		}
+	}
+
+	c.add("unsnapped", Warning, time, "%s is unsnapped by %sms (closest to 1/%d)", what, formatFloat(math.Round(closestDiff*10)/10), closestDivisor)
+}
+
+func (c *checker) checkPlayfield(o objects.IHitObject, name string) {
+	mods := []difficulty.Modifier{difficulty.None, difficulty.HardRock, difficulty.Easy}
+
+	for _, mod := range mods {
+		for t := o.GetStartTime(); ; t = math.Min(t+10, o.GetEndTime()) {
+			pos := o.GetStackedPositionAtMod(t, mod)
+
+			if pos.X < 0 || pos.X > playfieldWidth || pos.Y < 0 || pos.Y > playfieldHeight {
+				modName := mod.String()
+				if modName == "" {
+					modName = "NM"
+				}
+
+				c.add("playfield", Warning, o.GetStartTime(), "%s goes outside of the playfield with %s at %s (%.0f, %.0f)", name, modName, timestamp(t), pos.X, pos.Y)
+
+				break
+			}
+
+			if t >= o.GetEndTime() {
+				break
+			}
+		}
+	}
+}
+
+func (c *checker) checkSlider(slider *objects.Slider) {
+	time := slider.GetStartTime()
+
+	switch slider.GetCurveType() {
+	case "B", "P", "C", "L":
+	default:
+		c.add("slider", Error, time, "Slider has unknown curve type \"%s\"", slider.GetCurveType())
+	}
+
+	if len(slider.GetControlPoints()) == 0 {
+		c.add("slider", Error, time, "Slider has no control points")
+		return
+	}
+
+	if slider.GetPixelLength() <= 0 || math.IsNaN(slider.GetPixelLength()) {
+		c.add("slider", Error, time, "Slider has zero length")
+		return
+	}
+
+	if slider.RepeatCount < 1 {
+		c.add("slider", Error, time, "Slider has invalid repeat count: %d", slider.RepeatCount)
+	}
+
+	if len(slider.GetCurve().GetLines()) == 0 || slider.GetCurve().GetLength() == 0 {
+		c.add("slider", Error, time, "Slider's curve is broken, it has no length")
+		return
+	}
+
+	for i := 0; i <= 100; i++ {
+		pos := slider.GetCurve().PointAt(float32(i) / 100)
+
+		if math.IsNaN(float64(pos.X)) || math.IsNaN(float64(pos.Y)) || math.IsInf(float64(pos.X), 0) || math.IsInf(float64(pos.Y), 0) {
+			c.add("slider", Error, time, "Slider's curve is broken, path contains invalid points")
+			return
+		}
+	}
+}
+
+// checkHitSounds compares custom samples used by the map with the ones present in map's directory
+func (c *checker) checkHitSounds() {
+	used := make(map[string]float64)
+
+	use := func(time float64, sampleSet, index int, name string) {
+		if index < 1 {
+			return
+		}
+
+		if sampleSet == 0 {
+			sampleSet = c.beatMap.Timings.BaseSet
+		}
+
+		file := sampleSetNames[sampleSetIndex(sampleSet)] + "-" + name
+		if index > 1 {
+			file += strconv.Itoa(index)
+		}
+
+		if _, ok := used[file]; !ok {
+			used[file] = time
+		}
+	}
+
+	useHit := func(time float64, sampleSet, additionSet, sample, customIndex int) {
+		point := c.beatMap.Timings.GetPointAt(time)
+
+		index := customIndex
+		if index == 0 {
+			index = point.SampleIndex
+		}
+
+		if sampleSet == 0 {
+			sampleSet = point.SampleSet
+		}
+
+		if additionSet == 0 {
+			additionSet = sampleSet
+		}
+
+		use(time, sampleSet, index, "hitnormal")
+
+		for i, name := range additionNames {
+			if sample&(2<<i) > 0 {
+				use(time, additionSet, index, name)
+			}
+		}
+	}
+
+	for _, o := range c.beatMap.HitObjects {
+		switch obj := o.(type) {
+		case *objects.Circle:
+			useHit(obj.GetStartTime(), obj.BasicHitSound.SampleSet, obj.BasicHitSound.AdditionSet, obj.GetSample(), obj.BasicHitSound.CustomIndex)
+		case *objects.Spinner:
+			useHit(obj.GetEndTime(), obj.BasicHitSound.SampleSet, obj.BasicHitSound.AdditionSet, obj.GetSample(), obj.BasicHitSound.CustomIndex)
+		case *objects.Slider:
+			samples := obj.GetEdgeSamples()
+			sampleSets, additionSets := obj.GetEdgeSets()
+
+			span := (obj.GetEndTime() - obj.GetStartTime()) / float64(mutils.Max(obj.RepeatCount, 1))
+
+			for i := range samples {
+				sampleSet := sampleSets[i]
+				if sampleSet == 0 && i == 0 {
+					sampleSet = obj.BasicHitSound.SampleSet
+				}
+
+				useHit(obj.GetStartTime()+float64(i)*span, sampleSet, additionSets[i], samples[i], 0)
+			}
+
+			point := c.beatMap.Timings.GetPointAt(obj.GetStartTime())
+
+			sampleSet := obj.BasicHitSound.SampleSet
+			if sampleSet == 0 {
+				sampleSet = point.SampleSet
+			}
+
+			use(obj.GetStartTime(), sampleSet, point.SampleIndex, "sliderslide")
+
+			if obj.GetBaseSample()&2 > 0 {
+				use(obj.GetStartTime(), sampleSet, point.SampleIndex, "sliderwhistle")
+			}
+
+			if len(obj.TickPoints) > 0 {
+				use(obj.GetStartTime(), point.SampleSet, point.SampleIndex, "slidertick")
+			}
+		}
+	}
+
+	present := make(map[string]bool)
+
+	if entries, err := os.ReadDir(c.mapDir()); err == nil {
+		for _, e := range entries {
+			name := strings.ToLower(e.Name())
+
+			if e.IsDir() || !sampleFileRegex.MatchString(name) {
+				continue
+			}
+
+			base := strings.TrimSuffix(name, filepath.Ext(name))
+			present[base] = true
+
+			if _, ok := used[base]; !ok {
+				c.add("hitsounds", Info, -1, "Sample \"%s\" is not used by this difficulty", e.Name())
+			}
+		}
+	}
+
+	usedFiles := make([]string, 0, len(used))
+	for file := range used {
+		usedFiles = append(usedFiles, file)
+	}
+
+	sort.Strings(usedFiles)
+
+	for _, file := range usedFiles {
+		// Index 1 only overrides skin's samples, osu! falls back to them silently
+		if !present[file] && unicode.IsDigit(rune(file[len(file)-1])) {
+			c.add("hitsounds", Warning, used[file], "Sample \"%s\" is used but missing, skin's sample will be played instead", file)
+		}
+	}
+}
+
+// sampleSetIndex maps osu! sample set (1 normal, 2 soft, 3 drum) to index in sampleSetNames, invalid sets fall back to normal
+func sampleSetIndex(sampleSet int) int {
+	if sampleSet < 1 || sampleSet > 3 {
+		return 0
+	}
+
+	return sampleSet - 1
+}
+
+func objectName(o objects.IHitObject) string {
+	switch obj := o.(type) {
+	case *objects.Slider:
+		return "Slider"
+	case *objects.Spinner:
+		return "Spinner"
+	case *objects.Circle:
+		if obj.GetEndTime() > obj.GetStartTime() {
+			return "Hold note"
+		}
+	}
+
+	return "Circle"
+}
+
+func timestamp(time float64) string {
+	return Issue{Time: math.Max(0, time)}.Timestamp()
+}
+
+func formatFloat(value float64) string {
+	return mutils.FormatWOZeros(value, 2)
+}
@@ -0,0 +1,125 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/settings"
+	"math"
+	"sort"
+	"strings"
+)
+
+type Severity string
+
+const (
+	Info    = Severity("info")
+	Warning = Severity("warning")
+	Error   = Severity("error")
+)
+
+// Issue is a single problem found in the beatmap. Time is -1 if the problem isn't tied to a point in the map.
+type Issue struct {
+	Check    string   `json:"check"`
+	Severity Severity `json:"severity"`
+	Time     float64  `json:"time"`
+	Message  string   `json:"message"`
+}
+
+// Timestamp returns issue's time in osu! editor's format (mm:ss:mmm)
+func (issue Issue) Timestamp() string {
+	if issue.Time < 0 {
+		return "--:--:---"
+	}
+
+	t := int64(issue.Time)
+
+	return fmt.Sprintf("%02d:%02d:%03d", t/60000, t/1000%60, t%1000)
+}
+
+type Report struct {
+	Beatmap string  `json:"beatmap"`
+	Issues  []Issue `json:"issues"`
+}
+
+// Count returns the number of issues with a given severity
+func (report *Report) Count(severity Severity) (count int) {
+	for _, issue := range report.Issues {
+		if issue.Severity == severity {
+			count++
+		}
+	}
+
+	return
+}
+
+// Text returns human-readable report, one issue per line
+func (report *Report) Text() string {
+	var sb strings.Builder
+
+	sb.WriteString(report.Beatmap + "\n")
+
+	for _, issue := range report.Issues {
+		sb.WriteString(fmt.Sprintf("  %-7s  %s  [%s] %s\n", issue.Severity, issue.Timestamp(), issue.Check, issue.Message))
+	}
+
+	sb.WriteString(fmt.Sprintf("%d errors, %d warnings, %d infos\n", report.Count(Error), report.Count(Warning), report.Count(Info)))
+
+	return sb.String()
+}
+
+func (report *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(report, "", "\t")
+}
+
+// Run checks the beatmap for mapping problems osu!'s AIMod would report.
+// Beatmap should be freshly loaded (without parsed objects), objects are parsed with every spinner and stacking enabled.
+func Run(beatMap *beatmap.BeatMap) *Report {
+	settings.Objects.LoadSpinners = true
+	settings.Objects.StackEnabled = true
+
+	if beatMap.Mode == settings.ModeMania {
+		settings.MODE = settings.ModeMania
+	}
+
+	beatmap.ParseTimingPointsAndPauses(beatMap)
+	beatmap.ParseObjects(beatMap, false, false)
+
+	c := &checker{
+		beatMap: beatMap,
+		report: &Report{
+			Beatmap: fmt.Sprintf("%s - %s [%s]", beatMap.Artist, beatMap.Name, beatMap.Difficulty),
+			Issues:  make([]Issue, 0),
+		},
+	}
+
+	c.checkFiles()
+	c.checkTimingPoints()
+	c.checkBreaks()
+	c.checkObjects()
+	c.checkHitSounds()
+
+	sort.SliceStable(c.report.Issues, func(i, j int) bool {
+		return c.report.Issues[i].Time < c.report.Issues[j].Time
+	})
+
+	return c.report
+}
+
+type checker struct {
+	beatMap *beatmap.BeatMap
+	report  *Report
+}
+
+func (c *checker) add(check string, severity Severity, time float64, format string, args ...any) {
+	if !math.IsInf(time, 0) && !math.IsNaN(time) && time >= 0 {
+		time = math.Floor(time)
+	}
+
+	c.report.Issues = append(c.report.Issues, Issue{
+		Check:    check,
+		Severity: severity,
+		Time:     time,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
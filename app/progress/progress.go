@@ -0,0 +1,76 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Stages reported by events
+const (
+	Database  = "database"
+	Loading   = "loading"
+	Encoding  = "encoding"
+	Combining = "combining"
+	Finished  = "finished"
+	Error     = "error"
+)
+
+// Event is a single JSON line written to stdout
+type Event struct {
+	Stage string `json:"stage"`
+	Time  int64  `json:"time"` // unix time in milliseconds
+
+	Progress float64 `json:"progress,omitempty"` // percent of the recording
+	Frame    int64   `json:"frame,omitempty"`
+	Frames   int64   `json:"frames,omitempty"`
+	FPS      float64 `json:"fps,omitempty"`   // encoded frames per second
+	Speed    float64 `json:"speed,omitempty"` // recording speed relative to real time
+	ETA      float64 `json:"eta,omitempty"`   // in seconds
+
+	Job    int    `json:"job,omitempty"`   // 1-based index of -queue job
+	Chunk  int    `json:"chunk,omitempty"` // 1-based index of -chunks process
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+var enabled bool
+
+var mutex sync.Mutex
+
+// Enable turns on JSON events, human-readable output has to go to LogOutput from now on
+func Enable() {
+	enabled = true
+}
+
+func Enabled() bool {
+	return enabled
+}
+
+// LogOutput returns where human-readable output should be written, stdout is reserved for events when they are enabled
+func LogOutput() io.Writer {
+	if enabled {
+		return os.Stderr
+	}
+
+	return os.Stdout
+}
+
+func Send(event Event) {
+	if !enabled {
+		return
+	}
+
+	event.Time = time.Now().UnixMilli()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	mutex.Lock()
+	_, _ = os.Stdout.Write(append(data, '\n'))
+	mutex.Unlock()
+}
@@ -0,0 +1,353 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/faiface/mainthread"
+	"github.com/wieku/danser-go/app/beatmap"
+	difficulty2 "github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/database"
+	"github.com/wieku/danser-go/app/ffmpeg"
+	"github.com/wieku/danser-go/app/progress"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// queueJob is a single recording from the -queue file
+type queueJob struct {
+	ID         int64  `json:"id"`
+	MD5        string `json:"md5"`
+	Artist     string `json:"artist"`
+	Title      string `json:"title"`
+	Difficulty string `json:"difficulty"`
+	Creator    string `json:"creator"`
+
+	Replay   string   `json:"replay"`
+	Knockout []string `json:"knockout"`
+
+	Mods     string  `json:"mods"`
+	Settings string  `json:"settings"`
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"` // 0 means the end of the map
+	Out      string  `json:"out"`
+}
+
+// jobResult is an entry of the summary file
+type jobResult struct {
+	Job     int     `json:"job"`
+	Output  string  `json:"output,omitempty"`
+	Success bool    `json:"success"`
+	Error   string  `json:"error,omitempty"`
+	Time    float64 `json:"time"`
+}
+
+// renderQueue records queue jobs one after another, reusing the database, skin and OpenGL context
+type renderQueue struct {
+	jobs     []*queueJob
+	beatmaps []*beatmap.BeatMap
+
+	summaryPath string
+
+	settingsVersion string
+	hitLogFormat    string
+	reflection      difficulty2.Reflection
+	quickstart      bool
+
+	// Values set by flags, restored before every job
+	mode        int
+	divides     int
+	tag         int
+	speed       float64
+	pitch       float64
+	skip        bool
+	localOffset int
+
+	results []jobResult
+	current int
+}
+
+func loadQueue(path string) []*queueJob {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to read queue file: %s", err))
+	}
+
+	var jobs []*queueJob
+
+	if err = json.Unmarshal(data, &jobs); err != nil {
+		panic(fmt.Sprintf("Failed to parse queue file: %s", err))
+	}
+
+	if len(jobs) == 0 {
+		panic("Queue file has no jobs")
+	}
+
+	for i, job := range jobs {
+		if job.ID <= 0 && job.MD5 == "" && (job.Artist+job.Title+job.Difficulty+job.Creator) == "" && job.Replay == "" {
+			panic(fmt.Sprintf("Queue job %d doesn't specify a beatmap", i+1))
+		}
+
+		if job.Replay != "" && len(job.Knockout) > 0 {
+			panic(fmt.Sprintf("Queue job %d has both a replay and a knockout list", i+1))
+		}
+
+		if err := checkSettingsName(job.Settings); err != nil {
+			panic(fmt.Sprintf("Queue job %d: settings %s", i+1, err))
+		}
+	}
+
+	return jobs
+}
+
+func newRenderQueue(path string, jobs []*queueJob, beatmaps []*beatmap.BeatMap, settingsVersion, hitLogFormat string, reflection difficulty2.Reflection, quickstart bool) *renderQueue {
+	return &renderQueue{
+		jobs:            jobs,
+		beatmaps:        beatmaps,
+		summaryPath:     strings.TrimSuffix(path, filepath.Ext(path)) + "_summary.json",
+		settingsVersion: settingsVersion,
+		hitLogFormat:    hitLogFormat,
+		reflection:      reflection,
+		quickstart:      quickstart,
+		mode:            settings.MODE,
+		divides:         settings.DIVIDES,
+		tag:             settings.TAG,
+		speed:           settings.SPEED,
+		pitch:           settings.PITCH,
+		skip:            settings.SKIP,
+		localOffset:     settings.LOCALOFFSET,
+	}
+}
+
+func (q *renderQueue) run() {
+	failed := 0
+
+	for i, job := range q.jobs {
+		log.Println(fmt.Sprintf("Queue: Starting job %d/%d...", i+1, len(q.jobs)))
+
+		q.current = i + 1
+
+		startTime := time.Now()
+
+		err := q.runJob(job)
+
+		result := jobResult{
+			Job:     i + 1,
+			Output:  output,
+			Success: err == nil,
+			Time:    time.Since(startTime).Seconds(),
+		}
+
+		if err != nil {
+			log.Println(fmt.Sprintf("Queue: Job %d failed: %s", i+1, err))
+
+			result.Error = err.Error()
+
+			progress.Send(progress.Event{Stage: progress.Error, Job: i + 1, Error: result.Error})
+
+			failed++
+		}
+
+		q.results = append(q.results, result)
+
+		// Saved after every job so it's there even if danser crashes
+		q.saveSummary()
+
+		output = ""
+	}
+
+	database.Close()
+
+	log.Println(fmt.Sprintf("Queue: Finished, %d/%d jobs succeeded. Summary saved to: %s", len(q.jobs)-failed, len(q.jobs), q.summaryPath))
+}
+
+// runJob records a single job, panics while loading and simulating are returned as errors
+func (q *renderQueue) runJob(job *queueJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+
+			for _, s := range goroutines.GetStackTrace(4) {
+				log.Println(s)
+			}
+
+			if abortErr := callRecover(ffmpeg.Abort); abortErr != nil {
+				log.Println("Queue: Failed to abort encoding:", abortErr)
+			}
+		}
+
+		q.finishJob()
+	}()
+
+	if err = callRecover(func() { q.loadJob(job) }); err != nil {
+		return
+	}
+
+	mainLoopRecord()
+
+	return
+}
+
+func (q *renderQueue) loadJob(job *queueJob) {
+	settings.MODE = q.mode
+	settings.KNOCKOUT = false
+	settings.KNOCKOUTREPLAYS = nil
+	settings.REPLAY = ""
+	settings.PLAY = false
+	settings.DIVIDES = q.divides
+	settings.TAG = q.tag
+	settings.SPEED = q.speed
+	settings.PITCH = q.pitch
+	settings.SKIP = q.skip
+	settings.START = job.Start
+	settings.END = math.Inf(1)
+	settings.LOCALOFFSET = q.localOffset
+
+	if job.End > 0 {
+		settings.END = job.End
+	}
+
+	settingsVersion := q.settingsVersion
+	if job.Settings != "" {
+		settingsVersion = job.Settings
+	}
+
+	settings.LoadSettings(settingsVersion)
+
+	if q.hitLogFormat != "" {
+		settings.Recording.HitEventLog = q.hitLogFormat
+	}
+
+	checkReplaySaving()
+
+	if q.quickstart {
+		settings.SKIP = true
+		settings.Playfield.LeadInTime = 0
+		settings.Playfield.LeadInHold = 0
+	}
+
+	forceRecordSettings()
+
+	mods, modParams, err := difficulty2.ParseModsWithParams(job.Mods)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to parse mods: %s", err))
+	}
+
+	md5 := job.MD5
+
+	if job.Replay != "" {
+		rp := readReplay(job.Replay)
+
+		settings.MODE = int(rp.PlayMode)
+		settings.KNOCKOUT = true
+		settings.REPLAY = job.Replay
+
+		md5 = rp.BeatmapMD5
+		mods = difficulty2.Modifier(rp.Mods)
+		modParams.Rate = difficulty2.RateAdjust{}
+	} else if len(job.Knockout) > 0 {
+		settings.KNOCKOUT = true
+		settings.KNOCKOUTREPLAYS = job.Knockout
+	}
+
+	if !mods.Compatible() {
+		panic("Incompatible mods selected!")
+	}
+
+	id := job.ID
+	if id <= 0 || md5 != job.MD5 {
+		id = -1
+	}
+
+	found := findBeatmap(q.beatmaps, id, md5, job.Artist, job.Title, job.Difficulty, job.Creator)
+	if found == nil {
+		panic("Beatmap not found")
+	}
+
+	found.UpdatePlayStats()
+	database.UpdatePlayStats(found)
+
+	// Jobs may record the same map with different mods
+	beatMap := found.Copy()
+	beatMap.Diff.Reflection = q.reflection
+
+	allowDA := false
+
+	if !settings.KNOCKOUT && mods.Active(difficulty2.Autoplay) {
+		settings.KNOCKOUT = true
+		settings.Knockout.MaxPlayers = 0
+		allowDA = true
+	}
+
+	output = job.Out
+
+	// Resolved here so the summary has it
+	if strings.TrimSpace(output) == "" {
+		output = "danser_" + time.Now().Format("2006-01-02_15-04-05")
+	}
+
+	skin.ResetBeatmapColors()
+
+	progress.Send(progress.Event{Stage: progress.Loading, Job: q.current})
+
+	loadPlayer(beatMap, mods, modParams.Rate, modParams.AR, modParams.OD, modParams.CS, modParams.HP, allowDA)
+
+	startHitLog()
+}
+
+// finishJob releases what the job's player left in shared state
+func (q *renderQueue) finishJob() {
+	closeHitLog()
+
+	if player != nil {
+		mainthread.Call(player.Dispose)
+
+		player = nil
+	}
+}
+
+func (q *renderQueue) saveSummary() {
+	data, err := json.MarshalIndent(q.results, "", "\t")
+	if err != nil {
+		log.Println("Queue: Failed to encode summary:", err)
+		return
+	}
+
+	if err = os.WriteFile(q.summaryPath, data, 0644); err != nil {
+		log.Println("Queue: Failed to save summary:", err)
+	}
+}
+
+// callRecover runs f on the main thread and returns its panic as an error
+func callRecover(f func()) (err error) {
+	mainthread.Call(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+
+				// Stack of the main thread is lost once the error leaves it
+				for _, s := range goroutines.GetStackTrace(4) {
+					log.Println(s)
+				}
+			}
+		}()
+
+		f()
+	})
+
+	return
+}
+
+// callMain runs f on the main thread and panics on the calling goroutine if f panicked,
+// panics can't be recovered from outside of mainthread.Call and would kill the whole queue
+func callMain(f func()) {
+	if err := callRecover(f); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,171 @@
+package replay
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/itchio/lzma"
+	"github.com/wieku/rplpa"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Encode serializes the replay to osu! stable's .osr format
+func Encode(replay *rplpa.Replay) ([]byte, error) {
+	frames, err := compressFrames(replay.ReplayData)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+
+	writeValue(buf, replay.PlayMode)
+	writeValue(buf, replay.OsuVersion)
+	writeString(buf, replay.BeatmapMD5)
+	writeString(buf, replay.Username)
+	writeString(buf, replay.ReplayMD5)
+	writeValue(buf, replay.Count300)
+	writeValue(buf, replay.Count100)
+	writeValue(buf, replay.Count50)
+	writeValue(buf, replay.CountGeki)
+	writeValue(buf, replay.CountKatu)
+	writeValue(buf, replay.CountMiss)
+	writeValue(buf, replay.Score)
+	writeValue(buf, replay.MaxCombo)
+	writeValue(buf, replay.Fullcombo)
+	writeValue(buf, replay.Mods)
+	writeString(buf, encodeLifeBar(replay.LifebarGraph))
+	writeValue(buf, toTicks(replay.Timestamp))
+	writeValue(buf, int32(len(frames)))
+	buf.Write(frames)
+	writeValue(buf, replay.ScoreID)
+
+	return buf.Bytes(), nil
+}
+
+// GetReplayMD5 computes the replay checksum the same way osu! stable does
+func GetReplayMD5(replay *rplpa.Replay, grade string) string {
+	data := fmt.Sprintf("%dp%do%do%dt%da%sr%de%ty%so%du%s%d%t",
+		replay.Count100+replay.Count300,
+		replay.Count50,
+		replay.CountGeki,
+		replay.CountKatu,
+		replay.CountMiss,
+		replay.BeatmapMD5,
+		replay.MaxCombo,
+		replay.Fullcombo,
+		replay.Username,
+		replay.Score,
+		grade,
+		replay.Mods,
+		true,
+	)
+
+	hash := md5.Sum([]byte(data))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func writeValue(buf *bytes.Buffer, value any) {
+	_ = binary.Write(buf, binary.LittleEndian, value)
+}
+
+func writeString(buf *bytes.Buffer, value string) {
+	if value == "" {
+		buf.WriteByte(0x00)
+		return
+	}
+
+	length := make([]byte, binary.MaxVarintLen64)
+
+	buf.WriteByte(0x0b)
+	buf.Write(length[:binary.PutUvarint(length, uint64(len(value)))])
+	buf.WriteString(value)
+}
+
+func encodeLifeBar(lifeBar []rplpa.LifeBarGraph) string {
+	builder := &strings.Builder{}
+
+	for _, point := range lifeBar {
+		builder.WriteString(strconv.FormatInt(int64(point.Time), 10))
+		builder.WriteByte('|')
+		builder.WriteString(strconv.FormatFloat(float64(point.HP), 'f', -1, 32))
+		builder.WriteByte(',')
+	}
+
+	return builder.String()
+}
+
+func compressFrames(frames []*rplpa.ReplayData) ([]byte, error) {
+	builder := &strings.Builder{}
+
+	for _, frame := range frames {
+		builder.WriteString(strconv.FormatInt(frame.Time, 10))
+		builder.WriteByte('|')
+		builder.WriteString(strconv.FormatFloat(float64(frame.MouseX), 'f', -1, 32))
+		builder.WriteByte('|')
+		builder.WriteString(strconv.FormatFloat(float64(frame.MouseY), 'f', -1, 32))
+		builder.WriteByte('|')
+		builder.WriteString(strconv.Itoa(int(encodeKeys(frame.KeyPressed))))
+		builder.WriteByte(',')
+	}
+
+	// Seed frame, osu! stable always writes it even though it's used only by osu!mania
+	builder.WriteString("-12345|0|0|0,")
+
+	raw := []byte(builder.String())
+
+	buf := new(bytes.Buffer)
+
+	writer := lzma.NewWriterSizeLevel(buf, int64(len(raw)), lzma.BestCompression)
+
+	if _, err := writer.Write(raw); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeKeys(keys *rplpa.KeyPressed) uint8 {
+	var value uint8
+
+	if keys == nil {
+		return value
+	}
+
+	if keys.LeftClick {
+		value |= 1
+	}
+
+	if keys.RightClick {
+		value |= 2
+	}
+
+	if keys.Key1 {
+		value |= 4
+	}
+
+	if keys.Key2 {
+		value |= 8
+	}
+
+	if keys.Smoke {
+		value |= 16
+	}
+
+	return value
+}
+
+// toTicks converts time to .NET DateTime ticks used by osu! stable
+func toTicks(t time.Time) int64 {
+	base := time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+	return (t.Unix()-base)*10000000 + int64(t.Nanosecond()/100)
+}
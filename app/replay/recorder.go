@@ -0,0 +1,224 @@
+package replay
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/rplpa"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Needs to be new enough for danser to use current slider and spinner handling when playing the replay back
+	osuVersion = 20220424
+
+	frameInterval   = 16
+	lifeBarInterval = 2000
+
+	endMargin = 100
+
+	// osu! treats replays with Autoplay or Cinema as autoplay plays, lazer mods don't fit in .osr at all
+	nonScoreMods = difficulty.Autoplay | difficulty.Cinema | difficulty.LazerMask
+)
+
+const (
+	keyLeftClick = uint8(1 << iota)
+	keyRightClick
+	keyKey1
+	keyKey2
+	keySmoke
+)
+
+// Recorder collects replay frames from a live cursor and saves them as .osr once the map ends.
+//
+// Frames are emitted at ~60Hz and whenever pressed keys change but never 1ms apart,
+// so that the ruleset fed only on those frames judges exactly the same way as ReplayController does during playback.
+type Recorder struct {
+	ruleset *osu.OsuRuleSet
+	cursor  *graphics.Cursor
+	mods    difficulty.Modifier
+
+	frames  []*rplpa.ReplayData
+	lifeBar []rplpa.LifeBarGraph
+
+	lastTime     int64
+	lastKeys     uint8
+	nextLifeTime int64
+
+	endTime int64
+	saved   bool
+}
+
+// NewRecorder returns nil if the map has no objects, there's nothing to record then
+func NewRecorder(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor, mods difficulty.Modifier) *Recorder {
+	bMap := ruleset.GetBeatMap()
+
+	if len(bMap.HitObjects) == 0 {
+		log.Println("Replay: Beatmap has no objects, replay won't be saved")
+		return nil
+	}
+
+	recorder := &Recorder{
+		ruleset:  ruleset,
+		cursor:   cursor,
+		mods:     mods &^ nonScoreMods,
+		lastTime: -1,
+		endTime:  int64(bMap.HitObjects[len(bMap.HitObjects)-1].GetEndTime()) + bMap.Diff.Hit50 + endMargin,
+	}
+
+	// osu! stable starts every replay with these two frames
+	recorder.frames = append(recorder.frames,
+		&rplpa.ReplayData{Time: 0, MouseX: 256, MouseY: -500, KeyPressed: &rplpa.KeyPressed{}},
+		&rplpa.ReplayData{Time: -1, MouseX: 256, MouseY: -500, KeyPressed: &rplpa.KeyPressed{}},
+	)
+
+	return recorder
+}
+
+// Update records a new frame if needed and marks the cursor as being on a replay frame.
+// Returns true if the frame was recorded and the ruleset should process the cursor at given time.
+func (recorder *Recorder) Update(time int64) bool {
+	if !recorder.saved && time >= recorder.endTime {
+		recorder.save()
+	}
+
+	keys := recorder.getKeys()
+
+	delta := time - recorder.lastTime
+
+	if delta < 2 || (keys == recorder.lastKeys && delta < frameInterval) {
+		recorder.cursor.IsReplayFrame = false
+		return false
+	}
+
+	recorder.cursor.LastFrameTime = recorder.cursor.CurrentFrameTime
+	recorder.cursor.CurrentFrameTime = time
+	recorder.cursor.IsReplayFrame = true
+
+	if !recorder.saved {
+		recorder.frames = append(recorder.frames, &rplpa.ReplayData{
+			Time:   delta,
+			MouseX: recorder.cursor.RawPosition.X,
+			MouseY: recorder.cursor.RawPosition.Y,
+			KeyPressed: &rplpa.KeyPressed{
+				LeftClick:  keys&keyLeftClick > 0,
+				RightClick: keys&keyRightClick > 0,
+				Key1:       keys&keyKey1 > 0,
+				Key2:       keys&keyKey2 > 0,
+				Smoke:      keys&keySmoke > 0,
+			},
+		})
+
+		if time >= recorder.nextLifeTime {
+			recorder.lifeBar = append(recorder.lifeBar, rplpa.LifeBarGraph{Time: int32(time), HP: float32(recorder.ruleset.GetHP(recorder.cursor))})
+			recorder.nextLifeTime = time + lifeBarInterval
+		}
+	}
+
+	recorder.lastTime = time
+	recorder.lastKeys = keys
+
+	return true
+}
+
+func (recorder *Recorder) getKeys() (keys uint8) {
+	cursor := recorder.cursor
+
+	if cursor.LeftButton {
+		keys |= keyLeftClick
+
+		if cursor.LeftKey {
+			keys |= keyKey1
+		}
+	}
+
+	if cursor.RightButton {
+		keys |= keyRightClick
+
+		if cursor.RightKey {
+			keys |= keyKey2
+		}
+	}
+
+	if cursor.SmokeKey {
+		keys |= keySmoke
+	}
+
+	return
+}
+
+func (recorder *Recorder) save() {
+	recorder.saved = true
+
+	bMap := recorder.ruleset.GetBeatMap()
+	score := recorder.ruleset.GetScore(recorder.cursor)
+
+	if bMap.Diff.GetModString() != bMap.Diff.Mods.String() {
+		log.Println("Replay: WARNING! Custom difficulty settings can't be stored in .osr, saved replay won't reproduce the score")
+	}
+
+	if bMap.Diff.CheckModActive(difficulty.Random | difficulty.Mirror) {
+		log.Printf("Replay: WARNING! Random (seed: %d) and Mirror mods can't be stored in .osr, use -mods, -seed and -mirror to reproduce the map", bMap.Diff.Seed)
+	}
+
+	replay := &rplpa.Replay{
+		PlayMode:     0,
+		OsuVersion:   osuVersion,
+		BeatmapMD5:   bMap.MD5,
+		Username:     recorder.cursor.Name,
+		Count300:     uint16(score.Count300),
+		Count100:     uint16(score.Count100),
+		Count50:      uint16(score.Count50),
+		CountGeki:    uint16(score.CountGeki),
+		CountKatu:    uint16(score.CountKatu),
+		CountMiss:    uint16(score.CountMiss),
+		Score:        int32(score.Score),
+		MaxCombo:     uint16(score.Combo),
+		Fullcombo:    score.PerfectCombo,
+		Mods:         uint32(recorder.mods),
+		LifebarGraph: recorder.lifeBar,
+		Timestamp:    recorder.cursor.ScoreTime,
+		ReplayData:   recorder.frames,
+	}
+
+	replay.ReplayMD5 = GetReplayMD5(replay, score.Grade.String())
+
+	data, err := Encode(replay)
+	if err != nil {
+		log.Println("Replay: Failed to encode the replay:", err)
+		return
+	}
+
+	dir := settings.Gameplay.GetSavedReplaysDir()
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		log.Println("Replay: Failed to create the output directory:", err)
+		return
+	}
+
+	fileName := fmt.Sprintf("%s - %s - %s [%s] (%s) Osu.osr", replay.Username, bMap.Artist, bMap.Name, bMap.Difficulty, replay.Timestamp.Format("2006-01-02_15-04-05"))
+
+	path := filepath.Join(dir, sanitizeFileName(fileName))
+
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		log.Println("Replay: Failed to save the replay:", err)
+		return
+	}
+
+	log.Println("Replay: Saved to:", path)
+}
+
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune("<>:\"/\\|?*", r) || r < 32 {
+			return '_'
+		}
+
+		return r
+	}, name)
+}
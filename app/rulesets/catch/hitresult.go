@@ -0,0 +1,103 @@
+package catch
+
+import "github.com/wieku/danser-go/app/rulesets/osu"
+
+type HitResult int64
+
+const (
+	Ignore = HitResult(0)
+	Miss   = HitResult(1 << iota)
+	Great
+	LargeTickHit
+	LargeTickMiss
+	SmallTickHit
+	SmallTickMiss
+	BananaHit
+	BananaMiss
+	Hits   = Great | LargeTickHit | SmallTickHit | BananaHit
+	Misses = Miss | LargeTickMiss | SmallTickMiss | BananaMiss
+)
+
+func (r HitResult) ScoreValue() int64 {
+	switch r {
+	case Great:
+		return 300
+	case LargeTickHit:
+		return 100
+	case SmallTickHit:
+		return 10
+	case BananaHit:
+		return 1100
+	}
+
+	return 0
+}
+
+// IsHit returns true if object was caught
+func (r HitResult) IsHit() bool {
+	return r&Hits > 0
+}
+
+// ToOsu converts the result to osu!standard one so it can be used by osu!standard's listeners, like knockout overlay
+func (r HitResult) ToOsu() (osu.HitResult, osu.ComboResult) {
+	switch r {
+	case Great, LargeTickHit:
+		return osu.Hit300, osu.Increase
+	case SmallTickHit:
+		return osu.SliderPoint, osu.Hold
+	case SmallTickMiss:
+		return osu.Hit100k, osu.Hold
+	case Miss, LargeTickMiss:
+		return osu.Miss, osu.Reset
+	case BananaHit:
+		return osu.SpinnerBonus, osu.Hold
+	}
+
+	return osu.Ignore, osu.Hold
+}
+
+func (r HitResult) String() string {
+	switch r {
+	case Ignore:
+		return "Ignore"
+	case Miss:
+		return "Miss"
+	case Great:
+		return "Great"
+	case LargeTickHit:
+		return "LargeTickHit"
+	case LargeTickMiss:
+		return "LargeTickMiss"
+	case SmallTickHit:
+		return "SmallTickHit"
+	case SmallTickMiss:
+		return "SmallTickMiss"
+	case BananaHit:
+		return "BananaHit"
+	case BananaMiss:
+		return "BananaMiss"
+	}
+
+	return "Unknown"
+}
+
+type ComboResult uint8
+
+const (
+	Reset = ComboResult(iota)
+	Hold
+	Increase
+)
+
+func (r ComboResult) String() string {
+	switch r {
+	case Reset:
+		return "Reset"
+	case Hold:
+		return "Hold"
+	case Increase:
+		return "Increase"
+	}
+
+	return "Unknown"
+}
@@ -0,0 +1,390 @@
+package catch
+
+import (
+	"github.com/wieku/danser-go/app/audio"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"math"
+	"sort"
+)
+
+const (
+	PlayfieldWidth = 512.0
+
+	// rngSeed is the seed osu!stable uses to place bananas and tiny droplets
+	rngSeed = 1337
+
+	catcherBaseSize = 106.75
+
+	// allowedCatchRange is the part of the catcher's width which can catch objects
+	allowedCatchRange = 0.8
+
+	// baseDashSpeed is catcher's dashing speed in osu!pixels per millisecond
+	baseDashSpeed = 1.0
+	baseWalkSpeed = 0.5
+)
+
+type ObjectType uint8
+
+const (
+	Fruit = ObjectType(iota)
+	Droplet
+	TinyDroplet
+	Banana
+)
+
+func (t ObjectType) String() string {
+	switch t {
+	case Fruit:
+		return "fruit"
+	case Droplet:
+		return "droplet"
+	case TinyDroplet:
+		return "tiny droplet"
+	case Banana:
+		return "banana"
+	}
+
+	return "unknown"
+}
+
+// Object is a single catchable object, juice streams and banana showers are split into their nested objects
+type Object struct {
+	Number int64
+
+	// Source is the index of the beatmap's hit object this one was created from
+	Source int64
+
+	// LastInSource is true for the final nested object of a juice stream or banana shower
+	LastInSource bool
+
+	Type ObjectType
+
+	StartTime float64
+
+	OriginalX float64
+	XOffset   float64
+
+	// ComboSet selects both the combo colour and the fruit's look
+	ComboSet int64
+
+	HyperDash bool
+
+	// HyperDashTargetX is the position of the next object if this one is a hyperdash
+	HyperDashTargetX float64
+
+	Sample   int
+	HitSound audio.HitSoundInfo
+}
+
+// EffectiveX returns the position at which object has to be caught
+func (o *Object) EffectiveX() float64 {
+	return mutils.ClampF(o.OriginalX+o.XOffset, 0, PlayfieldWidth)
+}
+
+// CatcherScale returns catcher's and fruit's scale for a given circle size
+func CatcherScale(cs float64) float64 {
+	return 1 - 0.7*(cs-5)/5
+}
+
+// CatchWidth returns the width of catcher's plate which can catch objects
+func CatchWidth(cs float64) float64 {
+	return catcherBaseSize * CatcherScale(cs) * allowedCatchRange
+}
+
+// ConvertObjects creates osu!catch objects following osu!lazer's CatchBeatmapConverter and CatchBeatmapProcessor.
+// Difficulty is needed because HardRock changes fruit positions and circle size changes hyperdashes.
+func ConvertObjects(beatMap *beatmap.BeatMap, diff *difficulty.Difficulty) []*Object {
+	result := make([]*Object, 0, len(beatMap.HitObjects))
+
+	rng := NewLegacyRandom(rngSeed)
+
+	hardRock := diff.CheckModActive(difficulty.HardRock)
+
+	var lastPosition *float64
+	lastStartTime := 0.0
+
+	for _, obj := range beatMap.HitObjects {
+		var nested []*Object
+
+		switch o := obj.(type) {
+		case *objects.Circle:
+			fruit := &Object{
+				Type:      Fruit,
+				StartTime: o.GetStartTime(),
+				OriginalX: float64(o.GetStartPosition().X),
+				Sample:    o.GetSample(),
+				HitSound:  o.BasicHitSound,
+			}
+
+			if hardRock {
+				applyHardRockOffset(fruit, &lastPosition, &lastStartTime, rng)
+			}
+
+			nested = append(nested, fruit)
+		case *objects.Slider:
+			nested = createJuiceStream(o)
+
+			// osu!stable used path's end position and slider's start time here
+			endX := float64(o.PositionAtLazer(o.GetStartTime() + (o.EndTimeLazer-o.GetStartTime())/float64(o.RepeatCount)).X)
+
+			lastPosition = &endX
+			lastStartTime = o.GetStartTime()
+
+			for _, n := range nested {
+				if n.Type == TinyDroplet {
+					n.XOffset = float64(mutils.Clamp(rng.NextRange(-20, 20), int(-n.OriginalX), int(PlayfieldWidth-n.OriginalX)))
+				} else if n.Type == Droplet {
+					rng.Next() // osu!stable retrieved a random droplet rotation
+				}
+			}
+		case *objects.Spinner:
+			nested = createBananaShower(o)
+
+			for _, n := range nested {
+				n.XOffset = rng.NextDouble() * PlayfieldWidth
+
+				rng.Next() // osu!stable retrieved a random banana type
+				rng.Next() // osu!stable retrieved a random banana rotation
+				rng.Next() // osu!stable retrieved a random banana colour
+			}
+		}
+
+		for _, n := range nested {
+			n.Source = obj.GetID()
+			n.ComboSet = obj.GetComboSet()
+		}
+
+		if len(nested) > 0 {
+			nested[len(nested)-1].LastInSource = true
+		}
+
+		result = append(result, nested...)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].StartTime < result[j].StartTime
+	})
+
+	for i, o := range result {
+		o.Number = int64(i)
+	}
+
+	initHyperDash(result, diff.CSMod)
+
+	return result
+}
+
+// createJuiceStream places fruits on slider's head, repeats and tail, droplets on ticks and tiny droplets between them
+func createJuiceStream(slider *objects.Slider) []*Object {
+	type event struct {
+		time float64
+		kind ObjectType
+	}
+
+	startTime := slider.GetStartTime()
+	endTime := slider.EndTimeLazer
+
+	// osu!lazer's LegacyLastTick, it doesn't create an object, but affects tiny droplets
+	legacyLastTick := math.Max(startTime+(endTime-startTime)/2, endTime-36)
+
+	events := []event{{startTime, Fruit}}
+
+	for _, p := range slider.ScorePointsLazer {
+		switch {
+		case p.IsReverse:
+			events = append(events, event{p.Time, Fruit})
+		case p.Time == legacyLastTick:
+			events = append(events, event{p.Time, TinyDroplet})
+		default:
+			events = append(events, event{p.Time, Droplet})
+		}
+	}
+
+	events = append(events, event{endTime, Fruit})
+
+	samples := slider.GetEdgeSamples()
+	edge := 0
+
+	var result []*Object
+
+	for i, e := range events {
+		if i > 0 {
+			last := events[i-1]
+
+			sinceLastTick := float64(int64(e.time) - int64(last.time))
+
+			if sinceLastTick > 80 {
+				timeBetweenTiny := sinceLastTick
+				for timeBetweenTiny > 100 {
+					timeBetweenTiny /= 2
+				}
+
+				for t := timeBetweenTiny; t < sinceLastTick; t += timeBetweenTiny {
+					result = append(result, &Object{
+						Type:      TinyDroplet,
+						StartTime: last.time + t,
+						OriginalX: float64(slider.PositionAtLazer(last.time + t).X),
+						HitSound:  slider.BasicHitSound,
+					})
+				}
+			}
+		}
+
+		if e.kind == TinyDroplet {
+			continue
+		}
+
+		o := &Object{
+			Type:      e.kind,
+			StartTime: e.time,
+			OriginalX: float64(slider.PositionAtLazer(e.time).X),
+			HitSound:  slider.BasicHitSound,
+		}
+
+		if e.kind == Fruit {
+			o.Sample = samples[mutils.Min(edge, len(samples)-1)]
+			edge++
+		}
+
+		result = append(result, o)
+	}
+
+	return result
+}
+
+func createBananaShower(spinner *objects.Spinner) (result []*Object) {
+	spacing := spinner.GetEndTime() - spinner.GetStartTime()
+	for spacing > 100 {
+		spacing /= 2
+	}
+
+	if spacing <= 0 {
+		return
+	}
+
+	for t := spinner.GetStartTime(); t <= spinner.GetEndTime(); t += spacing {
+		result = append(result, &Object{
+			Type:      Banana,
+			StartTime: t,
+			HitSound:  spinner.BasicHitSound,
+		})
+	}
+
+	return
+}
+
+func applyHardRockOffset(fruit *Object, lastPosition **float64, lastStartTime *float64, rng *LegacyRandom) {
+	offsetPosition := fruit.OriginalX
+	startTime := fruit.StartTime
+
+	if *lastPosition == nil {
+		*lastPosition = &offsetPosition
+		*lastStartTime = startTime
+
+		return
+	}
+
+	positionDiff := offsetPosition - **lastPosition
+
+	// osu!stable calculated time deltas as ints
+	timeDiff := int(startTime - *lastStartTime)
+
+	if timeDiff > 1000 {
+		*lastPosition = &offsetPosition
+		*lastStartTime = startTime
+
+		return
+	}
+
+	if positionDiff == 0 {
+		applyRandomOffset(&offsetPosition, float64(timeDiff)/4, rng)
+		fruit.XOffset = offsetPosition - fruit.OriginalX
+
+		return
+	}
+
+	if math.Abs(positionDiff) < float64(timeDiff/3) {
+		if positionDiff > 0 {
+			if offsetPosition+positionDiff < PlayfieldWidth {
+				offsetPosition += positionDiff
+			}
+		} else if offsetPosition+positionDiff > 0 {
+			offsetPosition += positionDiff
+		}
+	}
+
+	fruit.XOffset = offsetPosition - fruit.OriginalX
+
+	*lastPosition = &offsetPosition
+	*lastStartTime = startTime
+}
+
+func applyRandomOffset(position *float64, maxOffset float64, rng *LegacyRandom) {
+	right := rng.NextBool()
+	random := math.Min(20, float64(rng.NextRange(0, math.Max(0, maxOffset))))
+
+	if right {
+		if *position+random <= PlayfieldWidth {
+			*position += random
+		} else {
+			*position -= random
+		}
+	} else {
+		if *position-random >= 0 {
+			*position -= random
+		} else {
+			*position += random
+		}
+	}
+}
+
+// initHyperDash marks objects which can't be reached by dashing, stable used catcher's size without the margins here
+func initHyperDash(catchObjects []*Object, cs float64) {
+	palpable := make([]*Object, 0, len(catchObjects))
+
+	for _, o := range catchObjects {
+		if o.Type == Fruit || o.Type == Droplet {
+			palpable = append(palpable, o)
+		}
+	}
+
+	halfCatcherWidth := CatchWidth(cs) / 2 / allowedCatchRange
+
+	lastDirection := 0
+	lastExcess := halfCatcherWidth
+
+	for i := 0; i < len(palpable)-1; i++ {
+		current, next := palpable[i], palpable[i+1]
+
+		direction := -1
+		if next.EffectiveX() > current.EffectiveX() {
+			direction = 1
+		}
+
+		// 1/4th of a frame of grace time
+		timeToNext := next.StartTime - current.StartTime - 1000.0/60/4
+
+		distanceToNext := math.Abs(next.EffectiveX() - current.EffectiveX())
+		if lastDirection == direction {
+			distanceToNext -= lastExcess
+		} else {
+			distanceToNext -= halfCatcherWidth
+		}
+
+		distanceToHyper := timeToNext*baseDashSpeed - distanceToNext
+
+		if distanceToHyper < 0 {
+			current.HyperDash = true
+			current.HyperDashTargetX = next.EffectiveX()
+
+			lastExcess = halfCatcherWidth
+		} else {
+			lastExcess = mutils.ClampF(distanceToHyper, 0, halfCatcherWidth)
+		}
+
+		lastDirection = direction
+	}
+}
@@ -0,0 +1,62 @@
+package catch
+
+const (
+	intToReal = 1.0 / (float64(int32(^uint32(0)>>1)) + 1.0)
+	intMask   = 0x7FFFFFFF
+
+	yInitial = 842502087
+	zInitial = 3579807591
+	wInitial = 273326509
+)
+
+// LegacyRandom is osu!stable's xorshift random number generator, needed to place bananas and droplets the same way
+type LegacyRandom struct {
+	x, y, z, w uint32
+
+	bitBuffer uint32
+	bitMask   uint32
+}
+
+func NewLegacyRandom(seed int) *LegacyRandom {
+	return &LegacyRandom{
+		x:       uint32(seed),
+		y:       yInitial,
+		z:       zInitial,
+		w:       wInitial,
+		bitMask: 1,
+	}
+}
+
+func (r *LegacyRandom) NextUInt() uint32 {
+	t := r.x ^ (r.x << 11)
+
+	r.x, r.y, r.z = r.y, r.z, r.w
+	r.w = r.w ^ (r.w >> 19) ^ t ^ (t >> 8)
+
+	return r.w
+}
+
+// Next returns a random non-negative int32
+func (r *LegacyRandom) Next() int {
+	return int(int32(intMask & r.NextUInt()))
+}
+
+// NextRange returns a random int in [lower, upper) range
+func (r *LegacyRandom) NextRange(lower, upper float64) int {
+	return int(lower + r.NextDouble()*(upper-lower))
+}
+
+func (r *LegacyRandom) NextDouble() float64 {
+	return intToReal * float64(r.Next())
+}
+
+func (r *LegacyRandom) NextBool() bool {
+	if r.bitMask == 1 {
+		r.bitBuffer = r.NextUInt()
+		r.bitMask = 0x80000000
+	} else {
+		r.bitMask >>= 1
+	}
+
+	return r.bitBuffer&r.bitMask != 0
+}
@@ -0,0 +1,428 @@
+package catch
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/utils"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+// CatcherY is the position of catcher's plate in osu!pixels, used to place judgements on the playfield
+const CatcherY = 340.0
+
+// CatcherState holds catcher's position and movement as seen by the ruleset
+type CatcherState struct {
+	X float64
+
+	Dashing bool
+
+	// HyperDashing is true after catching a hyperdash fruit until the next object is judged
+	HyperDashing     bool
+	HyperDashTargetX float64
+
+	// LastResult is the result of the last fruit or droplet, used to show fail/kiai catcher sprites
+	LastResult HitResult
+}
+
+type difficultyPlayer struct {
+	cursor *graphics.Cursor
+	diff   *difficulty.Difficulty
+
+	catchWidth float64
+
+	state CatcherState
+}
+
+type subSet struct {
+	player *difficultyPlayer
+
+	// objects are separate for every player because HardRock changes fruit positions and circle size changes hyperdashes
+	objects []*Object
+	results []HitResult
+
+	score          *osu.Score
+	hp             *HealthProcessor
+	scoreProcessor *scoreV1Processor
+
+	caught uint
+	judged uint
+
+	failed bool
+}
+
+type hitListener func(cursor *graphics.Cursor, time int64, object *Object, result HitResult, comboResult ComboResult, score int64)
+
+type failListener func(cursor *graphics.Cursor)
+
+type CatchRuleSet struct {
+	beatMap *beatmap.BeatMap
+
+	cursors   map[*graphics.Cursor]*subSet
+	cursorsOr []*graphics.Cursor
+
+	// nextObject is shared by all players, objects differ only in positions
+	nextObject int
+	numObjects int
+
+	ended bool
+
+	hitListeners []hitListener
+	osuListener  osu.HitListener
+	endListener  osu.EndListener
+	failListener failListener
+}
+
+func NewCatchRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, mods []difficulty.Modifier) *CatchRuleSet {
+	log.Println("Creating osu!catch ruleset...")
+
+	ruleset := &CatchRuleSet{
+		beatMap:   beatMap,
+		cursors:   make(map[*graphics.Cursor]*subSet),
+		cursorsOr: cursors,
+	}
+
+	for i, cursor := range cursors {
+		diff := difficulty.NewDifficulty(beatMap.Diff.GetBaseHP(), beatMap.Diff.GetBaseCS(), beatMap.Diff.GetBaseOD(), beatMap.Diff.GetBaseAR())
+
+		diff.SetHPCustom(beatMap.Diff.GetHP())
+		diff.SetCSCustom(beatMap.Diff.GetCS())
+		diff.SetARCustom(beatMap.Diff.GetAR())
+
+		diff.SetMods(mods[i])
+		diff.SetCustomSpeed(beatMap.Diff.CustomSpeed)
+		diff.SetRateAdjust(beatMap.Diff.RateAdjust)
+
+		catchObjects := ConvertObjects(beatMap, diff)
+
+		ruleset.numObjects = len(catchObjects)
+
+		ruleset.cursors[cursor] = &subSet{
+			player: &difficultyPlayer{
+				cursor:     cursor,
+				diff:       diff,
+				catchWidth: CatchWidth(diff.CSMod),
+				state:      CatcherState{X: PlayfieldWidth / 2},
+			},
+			objects: catchObjects,
+			results: make([]HitResult, len(catchObjects)),
+			score: &osu.Score{
+				Accuracy: 100,
+			},
+			hp:             NewHealthProcessor(diff),
+			scoreProcessor: newScoreV1Processor(beatMap, diff),
+		}
+	}
+
+	log.Println(fmt.Sprintf("Converted %d hit objects into %d catch objects", len(beatMap.HitObjects), ruleset.numObjects))
+
+	return ruleset
+}
+
+// UpdateInputFor sets catcher's position of a player, has to be called before Update for a given time
+func (set *CatchRuleSet) UpdateInputFor(cursor *graphics.Cursor, x float64, dashing bool) {
+	state := &set.cursors[cursor].player.state
+
+	state.X = mutils.ClampF(x, 0, PlayfieldWidth)
+	state.Dashing = dashing
+}
+
+// Update judges all objects which reached the catcher
+func (set *CatchRuleSet) Update(time int64) {
+	for set.nextObject < set.numObjects {
+		first := set.cursors[set.cursorsOr[0]].objects[set.nextObject]
+
+		if first.StartTime > float64(time) {
+			break
+		}
+
+		for _, cursor := range set.cursorsOr {
+			set.judge(set.cursors[cursor], time, set.nextObject)
+		}
+
+		if first.LastInSource && set.endListener != nil {
+			set.endListener(time, first.Source)
+		}
+
+		set.nextObject++
+	}
+
+	if set.nextObject >= set.numObjects && !set.ended {
+		set.ended = true
+
+		set.printResults()
+	}
+}
+
+func (set *CatchRuleSet) judge(subSet *subSet, time int64, index int) {
+	player := subSet.player
+	o := subSet.objects[index]
+
+	caught := math.Abs(o.EffectiveX()-player.state.X) <= player.catchWidth/2
+
+	var result HitResult
+
+	switch o.Type {
+	case Fruit:
+		result = Miss
+		if caught {
+			result = Great
+		}
+	case Droplet:
+		result = LargeTickMiss
+		if caught {
+			result = LargeTickHit
+		}
+	case TinyDroplet:
+		result = SmallTickMiss
+		if caught {
+			result = SmallTickHit
+		}
+	case Banana:
+		result = BananaMiss
+		if caught {
+			result = BananaHit
+		}
+	}
+
+	if o.Type == Fruit || o.Type == Droplet {
+		player.state.HyperDashing = caught && o.HyperDash
+		player.state.HyperDashTargetX = o.HyperDashTargetX
+		player.state.LastResult = result
+	}
+
+	subSet.results[index] = result
+
+	set.sendResult(subSet, time, o, result)
+}
+
+func (set *CatchRuleSet) sendResult(subSet *subSet, time int64, object *Object, result HitResult) {
+	player := subSet.player
+
+	comboResult := Hold
+
+	switch result {
+	case Great, LargeTickHit:
+		comboResult = Increase
+	case Miss, LargeTickMiss:
+		comboResult = Reset
+	}
+
+	if player.diff.CheckModActive(difficulty.SuddenDeath|difficulty.Perfect) && comboResult == Reset {
+		set.fail(subSet)
+	}
+
+	if player.diff.CheckModActive(difficulty.Perfect) && result == SmallTickMiss {
+		set.fail(subSet)
+	}
+
+	subSet.scoreProcessor.AddResult(result, comboResult)
+	subSet.hp.AddResult(result)
+
+	if subSet.hp.Health <= 0 {
+		set.fail(subSet)
+	}
+
+	score := subSet.score
+	score.Score = subSet.scoreProcessor.GetScore()
+
+	// Counts are stored the same way osu!stable saves them in catch replays
+	switch result {
+	case Great:
+		score.Count300++
+	case LargeTickHit:
+		score.Count100++
+	case SmallTickHit:
+		score.Count50++
+	case SmallTickMiss:
+		score.CountKatu++
+	case Miss, LargeTickMiss:
+		score.CountMiss++
+	}
+
+	if object.Type != Banana {
+		subSet.judged++
+
+		if result.IsHit() {
+			subSet.caught++
+		}
+
+		score.Accuracy = 100 * float64(subSet.caught) / float64(subSet.judged)
+	}
+
+	score.Grade = calculateGrade(score.Accuracy, player.diff.Mods)
+	score.Combo = mutils.Max(uint(subSet.scoreProcessor.GetCombo()), score.Combo)
+	score.PerfectCombo = score.CountMiss == 0
+
+	for _, listener := range set.hitListeners {
+		listener(player.cursor, time, object, result, comboResult, score.Score)
+	}
+
+	if set.osuListener != nil {
+		osuResult, osuCombo := result.ToOsu()
+
+		set.osuListener(player.cursor, time, object.Source, vector.NewVec2d(object.EffectiveX(), CatcherY), osuResult, osuCombo, performance.PPv2Results{}, score.Score)
+	}
+
+	if len(set.cursors) == 1 && !settings.RECORD && !settings.HEADLESS && object.Type != TinyDroplet {
+		log.Println(fmt.Sprintf(
+			"Got: %13s, Combo: %4d, Max Combo: %4d, Score: %9d, Acc: %6.2f%%, Fruits: %4d, Drops: %4d, Droplets: %4d, Missed droplets: %4d, Miss: %3d, from: %d, at: %d, x: %.0f",
+			result.String(),
+			subSet.scoreProcessor.GetCombo(),
+			score.Combo,
+			score.Score,
+			score.Accuracy,
+			score.Count300,
+			score.Count100,
+			score.Count50,
+			score.CountKatu,
+			score.CountMiss,
+			object.Number,
+			time,
+			player.state.X,
+		))
+	}
+}
+
+func calculateGrade(accuracy float64, mods difficulty.Modifier) osu.Grade {
+	silver := mods&(difficulty.Hidden|difficulty.Flashlight) > 0
+
+	switch {
+	case accuracy >= 100:
+		if silver {
+			return osu.SSH
+		}
+
+		return osu.SS
+	case accuracy > 98:
+		if silver {
+			return osu.SH
+		}
+
+		return osu.S
+	case accuracy > 94:
+		return osu.A
+	case accuracy > 90:
+		return osu.B
+	case accuracy > 85:
+		return osu.C
+	}
+
+	return osu.D
+}
+
+func (set *CatchRuleSet) fail(subSet *subSet) {
+	if subSet.player.diff.CheckModActive(difficulty.NoFail|difficulty.Relax) || subSet.failed {
+		return
+	}
+
+	subSet.failed = true
+
+	if set.failListener != nil {
+		set.failListener(subSet.player.cursor)
+	}
+}
+
+func (set *CatchRuleSet) printResults() {
+	cs := make([]*graphics.Cursor, len(set.cursorsOr))
+	copy(cs, set.cursorsOr)
+
+	sort.Slice(cs, func(i, j int) bool {
+		return set.cursors[cs[i]].score.Score > set.cursors[cs[j]].score.Score
+	})
+
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetHeader([]string{"#", "Player", "Score", "Accuracy", "Grade", "Fruits", "Drops", "Droplets", "Missed droplets", "Miss", "Combo", "Max Combo", "Mods"})
+
+	for i, c := range cs {
+		subSet := set.cursors[c]
+
+		var data []string
+		data = append(data, fmt.Sprintf("%d", i+1))
+		data = append(data, c.Name)
+		data = append(data, utils.Humanize(subSet.score.Score))
+		data = append(data, fmt.Sprintf("%.2f", subSet.score.Accuracy))
+		data = append(data, subSet.score.Grade.String())
+		data = append(data, utils.Humanize(subSet.score.Count300))
+		data = append(data, utils.Humanize(subSet.score.Count100))
+		data = append(data, utils.Humanize(subSet.score.Count50))
+		data = append(data, utils.Humanize(subSet.score.CountKatu))
+		data = append(data, utils.Humanize(subSet.score.CountMiss))
+		data = append(data, utils.Humanize(subSet.scoreProcessor.GetCombo()))
+		data = append(data, utils.Humanize(subSet.score.Combo))
+		data = append(data, subSet.player.diff.GetModString())
+		table.Append(data)
+	}
+
+	table.Render()
+
+	for _, s := range strings.Split(tableString.String(), "\n") {
+		log.Println(s)
+	}
+}
+
+func (set *CatchRuleSet) AddListener(listener hitListener) {
+	set.hitListeners = append(set.hitListeners, listener)
+}
+
+// SetListener sets osu!standard compatible listener, used by knockout overlay
+func (set *CatchRuleSet) SetListener(listener osu.HitListener) {
+	set.osuListener = listener
+}
+
+// SetEndListener sets a listener called when all objects created from a given beatmap's hit object are judged
+func (set *CatchRuleSet) SetEndListener(listener osu.EndListener) {
+	set.endListener = listener
+}
+
+func (set *CatchRuleSet) SetFailListener(listener failListener) {
+	set.failListener = listener
+}
+
+func (set *CatchRuleSet) GetScore(cursor *graphics.Cursor) osu.Score {
+	return *(set.cursors[cursor].score)
+}
+
+func (set *CatchRuleSet) GetCombo(cursor *graphics.Cursor) int64 {
+	return set.cursors[cursor].scoreProcessor.GetCombo()
+}
+
+func (set *CatchRuleSet) GetHP(cursor *graphics.Cursor) float64 {
+	return set.cursors[cursor].hp.Health
+}
+
+func (set *CatchRuleSet) IsFailed(cursor *graphics.Cursor) bool {
+	return set.cursors[cursor].failed
+}
+
+func (set *CatchRuleSet) GetCatcher(cursor *graphics.Cursor) CatcherState {
+	return set.cursors[cursor].player.state
+}
+
+func (set *CatchRuleSet) GetCatchWidth(cursor *graphics.Cursor) float64 {
+	return set.cursors[cursor].player.catchWidth
+}
+
+// GetResult returns the judgement of a given object, Ignore if it's not judged yet
+func (set *CatchRuleSet) GetResult(cursor *graphics.Cursor, number int64) HitResult {
+	return set.cursors[cursor].results[number]
+}
+
+func (set *CatchRuleSet) GetObjects(cursor *graphics.Cursor) []*Object {
+	return set.cursors[cursor].objects
+}
+
+func (set *CatchRuleSet) GetBeatMap() *beatmap.BeatMap {
+	return set.beatMap
+}
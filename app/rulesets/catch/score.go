@@ -0,0 +1,58 @@
+package catch
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"math"
+)
+
+// scoreV1Processor follows osu!stable's scoring, fruits and droplets get combo bonus, tiny droplets and bananas are flat
+type scoreV1Processor struct {
+	score           int64
+	combo           int64
+	modMultiplier   float64
+	scoreMultiplier float64
+}
+
+func newScoreV1Processor(beatMap *beatmap.BeatMap, diff *difficulty.Difficulty) *scoreV1Processor {
+	s := &scoreV1Processor{
+		modMultiplier: diff.GetScoreMultiplier(),
+	}
+
+	pauses := int64(0)
+	for _, p := range beatMap.Pauses {
+		pauses += int64(p.GetEndTime() - p.GetStartTime())
+	}
+
+	drainTime := float32((int64(beatMap.HitObjects[len(beatMap.HitObjects)-1].GetEndTime()) - int64(beatMap.HitObjects[0].GetStartTime()) - pauses) / 1000)
+
+	s.scoreMultiplier = math.RoundToEven((float64(float32(beatMap.Diff.GetHP())) + float64(float32(beatMap.Diff.GetOD())) + float64(float32(beatMap.Diff.GetCS())) + float64(mutils.ClampF(float32(len(beatMap.HitObjects))/drainTime*8, 0, 16))) / 38 * 5)
+
+	return s
+}
+
+func (s *scoreV1Processor) AddResult(result HitResult, comboResult ComboResult) {
+	increase := result.ScoreValue()
+
+	switch result {
+	case Great, LargeTickHit:
+		s.score += increase + int64(float64(increase)*float64(mutils.Max(s.combo-1, 0))*s.scoreMultiplier*s.modMultiplier/25.0)
+	case SmallTickHit, BananaHit:
+		s.score += increase
+	}
+
+	if comboResult == Reset {
+		s.combo = 0
+	} else if comboResult == Increase {
+		s.combo++
+	}
+}
+
+func (s *scoreV1Processor) GetScore() int64 {
+	return s.score
+}
+
+func (s *scoreV1Processor) GetCombo() int64 {
+	return s.combo
+}
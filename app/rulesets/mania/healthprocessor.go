@@ -0,0 +1,44 @@
+package mania
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"math"
+)
+
+// maxHealthIncrease is the health given by a 300, modelled after lazer's judgement health values
+const maxHealthIncrease = 0.05
+
+// HealthProcessor starts at full health and fails the player when it drops to 0, there's no passive drain
+type HealthProcessor struct {
+	Health float64
+
+	missMultiplier float64
+}
+
+func NewHealthProcessor(diff *difficulty.Difficulty) *HealthProcessor {
+	return &HealthProcessor{
+		Health:         1,
+		missMultiplier: difficulty.DifficultyRate(diff.HPMod, 0.5, 1, 1.5),
+	}
+}
+
+func (hp *HealthProcessor) AddResult(result HitResult) {
+	switch result {
+	case HitMax:
+		hp.Increase(maxHealthIncrease * 1.05)
+	case Hit300:
+		hp.Increase(maxHealthIncrease)
+	case Hit200:
+		hp.Increase(maxHealthIncrease * 0.75)
+	case Hit100:
+		hp.Increase(maxHealthIncrease * 0.5)
+	case Hit50:
+		hp.Increase(-maxHealthIncrease * 0.05 * hp.missMultiplier)
+	case Miss:
+		hp.Increase(-maxHealthIncrease * hp.missMultiplier)
+	}
+}
+
+func (hp *HealthProcessor) Increase(amount float64) {
+	hp.Health = math.Max(0, math.Min(1, hp.Health+amount))
+}
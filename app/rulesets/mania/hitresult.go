@@ -0,0 +1,83 @@
+package mania
+
+type HitResult int64
+
+const (
+	Ignore = HitResult(0)
+	Miss   = HitResult(1 << iota)
+	Hit50
+	Hit100
+	Hit200
+	Hit300
+	HitMax
+	BaseHits  = Hit50 | Hit100 | Hit200 | Hit300 | HitMax
+	BaseHitsM = BaseHits | Miss
+)
+
+func (r HitResult) ScoreValue() int64 {
+	switch r {
+	case Hit50:
+		return 50
+	case Hit100:
+		return 100
+	case Hit200:
+		return 200
+	case Hit300:
+		return 300
+	case HitMax:
+		return 320
+	}
+
+	return 0
+}
+
+// AccuracyValue returns the value used for accuracy calculation, MAX is worth the same as 300
+func (r HitResult) AccuracyValue() int64 {
+	if r == HitMax {
+		return 300
+	}
+
+	return r.ScoreValue()
+}
+
+func (r HitResult) String() string {
+	switch r {
+	case Ignore:
+		return "Ignore"
+	case Miss:
+		return "Miss"
+	case Hit50:
+		return "50"
+	case Hit100:
+		return "100"
+	case Hit200:
+		return "200"
+	case Hit300:
+		return "300"
+	case HitMax:
+		return "MAX"
+	}
+
+	return "Unknown"
+}
+
+type ComboResult uint8
+
+const (
+	Reset = ComboResult(iota)
+	Hold
+	Increase
+)
+
+func (r ComboResult) String() string {
+	switch r {
+	case Reset:
+		return "Reset"
+	case Hold:
+		return "Hold"
+	case Increase:
+		return "Increase"
+	}
+
+	return "Unknown"
+}
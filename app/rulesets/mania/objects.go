@@ -0,0 +1,143 @@
+package mania
+
+import (
+	"github.com/wieku/danser-go/app/audio"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"math"
+)
+
+// Object is a mania note or hold note either read from a native mania map or converted from osu!standard one
+type Object struct {
+	Number int64
+
+	// Source is the index of the beatmap's hit object this one was created from
+	Source int64
+
+	Column int
+
+	StartTime float64
+	EndTime   float64
+
+	IsHold bool
+
+	// Sample holds hitsound bits (1 normal, 2 whistle, 4 finish, 8 clap)
+	Sample   int
+	HitSound audio.HitSoundInfo
+}
+
+// KeyCount returns the number of columns used to play the map.
+// Native maps store it in CS, converted ones follow osu!stable's heuristics unless K1-K9 mod is active.
+func KeyCount(beatMap *beatmap.BeatMap, mods difficulty.Modifier) int {
+	if beatMap.Mode == 3 {
+		return mutils.Clamp(int(math.Round(beatMap.Diff.GetBaseCS())), 1, 18)
+	}
+
+	if keys := mods.KeyCount(); keys > 0 {
+		return keys
+	}
+
+	special := 0
+
+	for _, o := range beatMap.HitObjects {
+		if _, ok := o.(*objects.Circle); !ok {
+			special++
+		}
+	}
+
+	percentSpecial := float64(special) / float64(mutils.Max(1, len(beatMap.HitObjects)))
+
+	od := int(math.Round(beatMap.Diff.GetBaseOD()))
+	cs := int(math.Round(beatMap.Diff.GetBaseCS()))
+
+	switch {
+	case percentSpecial < 0.2:
+		return 7
+	case percentSpecial < 0.3 || cs >= 5:
+		if od > 5 {
+			return 7
+		}
+
+		return 6
+	case percentSpecial > 0.6:
+		if od > 4 {
+			return 5
+		}
+
+		return 4
+	}
+
+	return mutils.Max(4, mutils.Min(od+1, 7))
+}
+
+// ConvertObjects creates mania objects from beatmap's hit objects.
+// Converted maps use positional column mapping (osu!stable's pattern generator is not replicated),
+// objects landing on an occupied column are moved to the closest free one.
+func ConvertObjects(beatMap *beatmap.BeatMap, keys int) []*Object {
+	result := make([]*Object, 0, len(beatMap.HitObjects))
+
+	// busyUntil holds the time at which the last object in a column ends
+	busyUntil := make([]float64, keys)
+	for i := range busyUntil {
+		busyUntil[i] = math.Inf(-1)
+	}
+
+	isNative := beatMap.Mode == 3
+
+	add := func(source objects.IHitObject, x float64, startTime, endTime float64, sample int, hitSound audio.HitSoundInfo) {
+		column := mutils.Clamp(int(math.Floor(x*float64(keys)/512)), 0, keys-1)
+
+		if !isNative && busyUntil[column] >= startTime {
+			column = findFreeColumn(busyUntil, column, startTime)
+
+			if column < 0 {
+				return
+			}
+		}
+
+		busyUntil[column] = endTime
+
+		result = append(result, &Object{
+			Source:    source.GetID(),
+			Column:    column,
+			StartTime: startTime,
+			EndTime:   endTime,
+			IsHold:    endTime > startTime,
+			Sample:    sample,
+			HitSound:  hitSound,
+		})
+	}
+
+	for _, obj := range beatMap.HitObjects {
+		x := float64(obj.GetStartPosition().X)
+
+		switch o := obj.(type) {
+		case *objects.Circle:
+			add(o, x, o.GetStartTime(), o.GetEndTime(), o.GetSample(), o.BasicHitSound)
+		case *objects.Slider:
+			add(o, x, o.GetStartTime(), o.GetEndTime(), o.GetEdgeSamples()[0], o.BasicHitSound)
+		case *objects.Spinner:
+			add(o, 256, o.GetStartTime(), o.GetEndTime(), o.GetSample(), o.BasicHitSound)
+		}
+	}
+
+	for i, o := range result {
+		o.Number = int64(i)
+	}
+
+	return result
+}
+
+func findFreeColumn(busyUntil []float64, column int, time float64) int {
+	for d := 1; d < len(busyUntil); d++ {
+		for _, c := range []int{column - d, column + d} {
+			if c >= 0 && c < len(busyUntil) && busyUntil[c] < time {
+				return c
+			}
+		}
+	}
+
+	return -1
+}
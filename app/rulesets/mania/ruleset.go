@@ -0,0 +1,576 @@
+package mania
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/utils"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+// releaseLenience widens hit windows of hold note tails, same as in osu!lazer
+const releaseLenience = 1.5
+
+// Columns is a bitfield of pressed columns, osu! stores it in X coordinate of mania replay frames
+type Columns uint32
+
+func (c Columns) IsPressed(column int) bool {
+	return c&(1<<column) > 0
+}
+
+// ObjectState tells how far a player got with a given object
+type ObjectState struct {
+	HeadJudged bool
+	HeadResult HitResult
+
+	// TailJudged and TailResult are used only by hold notes
+	TailJudged bool
+	TailResult HitResult
+
+	Holding bool
+	HitTime int64
+}
+
+// IsJudged returns true if object doesn't need any more input
+func (s ObjectState) IsJudged(o *Object) bool {
+	return s.HeadJudged && (!o.IsHold || s.TailJudged)
+}
+
+// HitWindows holds hit windows for MAX, 300, 200, 100, 50 and Miss results
+type HitWindows [6]float64
+
+func (w HitWindows) resultFor(offset, lenience float64) HitResult {
+	offset = math.Abs(offset)
+
+	results := [...]HitResult{HitMax, Hit300, Hit200, Hit100, Hit50, Miss}
+
+	for i, result := range results {
+		if offset <= w[i]*lenience {
+			return result
+		}
+	}
+
+	return Ignore
+}
+
+type difficultyPlayer struct {
+	cursor  *graphics.Cursor
+	diff    *difficulty.Difficulty
+	columns Columns
+
+	windows HitWindows
+
+	// holding holds the index of hold note held in every column, -1 if there's none
+	holding []int
+}
+
+type subSet struct {
+	player *difficultyPlayer
+
+	score          *Score
+	hp             *HealthProcessor
+	scoreProcessor *scoreV1Processor
+
+	states      []*ObjectState
+	firstActive int
+
+	rawAccuracy int64
+	numHits     uint
+
+	failed bool
+}
+
+type hitListener func(cursor *graphics.Cursor, time int64, number int64, column int, result HitResult, comboResult ComboResult, score int64)
+
+// pressListener receives the closest object in a pressed column, used for hitsounds, object may be nil
+type pressListener func(cursor *graphics.Cursor, time int64, column int, object *Object)
+
+type failListener func(cursor *graphics.Cursor)
+
+type ManiaRuleSet struct {
+	beatMap *beatmap.BeatMap
+	objects []*Object
+	keys    int
+
+	cursors   map[*graphics.Cursor]*subSet
+	cursorsOr []*graphics.Cursor
+
+	ended bool
+
+	hitListeners   []hitListener
+	pressListeners []pressListener
+	failListener   failListener
+}
+
+// NewManiaRuleset creates a ruleset for all players, key count is decided by the first player's mods
+func NewManiaRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, mods []difficulty.Modifier) *ManiaRuleSet {
+	log.Println("Creating osu!mania ruleset...")
+
+	keys := KeyCount(beatMap, mods[0])
+
+	ruleset := &ManiaRuleSet{
+		beatMap:   beatMap,
+		keys:      keys,
+		objects:   ConvertObjects(beatMap, keys),
+		cursors:   make(map[*graphics.Cursor]*subSet),
+		cursorsOr: cursors,
+	}
+
+	log.Println(fmt.Sprintf("Playing with %d keys, %d objects", keys, len(ruleset.objects)))
+
+	judgements := 0
+	for _, o := range ruleset.objects {
+		judgements++
+
+		if o.IsHold {
+			judgements++
+		}
+	}
+
+	for i, cursor := range cursors {
+		diff := difficulty.NewDifficulty(beatMap.Diff.GetBaseHP(), beatMap.Diff.GetBaseCS(), beatMap.Diff.GetBaseOD(), beatMap.Diff.GetBaseAR())
+
+		diff.SetHPCustom(beatMap.Diff.GetHP())
+		diff.SetODCustom(beatMap.Diff.GetOD())
+
+		diff.SetMods(mods[i])
+		diff.SetCustomSpeed(beatMap.Diff.CustomSpeed)
+		diff.SetRateAdjust(beatMap.Diff.RateAdjust)
+
+		player := &difficultyPlayer{
+			cursor:  cursor,
+			diff:    diff,
+			windows: calculateWindows(diff),
+			holding: make([]int, keys),
+		}
+
+		for j := range player.holding {
+			player.holding[j] = -1
+		}
+
+		states := make([]*ObjectState, len(ruleset.objects))
+		for j := range states {
+			states[j] = new(ObjectState)
+		}
+
+		ruleset.cursors[cursor] = &subSet{
+			player: player,
+			score: &Score{
+				Accuracy: 100,
+			},
+			hp:             NewHealthProcessor(diff),
+			scoreProcessor: newScoreV1Processor(mutils.Max(1, judgements), diff),
+			states:         states,
+		}
+	}
+
+	return ruleset
+}
+
+// calculateWindows follows osu!stable, HR and EZ scale the windows instead of OD
+func calculateWindows(diff *difficulty.Difficulty) (windows HitWindows) {
+	od := diff.GetOD()
+
+	windows = HitWindows{16, 64 - 3*od, 97 - 3*od, 127 - 3*od, 151 - 3*od, 188 - 3*od}
+
+	for i := range windows {
+		if diff.CheckModActive(difficulty.HardRock) {
+			windows[i] /= 1.4
+		} else if diff.CheckModActive(difficulty.Easy) {
+			windows[i] *= 1.4
+		}
+
+		windows[i] = math.Floor(windows[i]) + 0.5
+	}
+
+	return
+}
+
+// UpdateInputFor processes column state of a player at a given time, has to be called in chronological order
+func (set *ManiaRuleSet) UpdateInputFor(cursor *graphics.Cursor, time int64, columns Columns) {
+	subSet := set.cursors[cursor]
+	player := subSet.player
+
+	pressed := columns & (^player.columns)
+	released := player.columns & (^columns)
+
+	player.columns = columns
+
+	for c := 0; c < set.keys; c++ {
+		if released.IsPressed(c) {
+			set.processRelease(subSet, time, c)
+		}
+	}
+
+	for c := 0; c < set.keys; c++ {
+		if !pressed.IsPressed(c) {
+			continue
+		}
+
+		if len(set.pressListeners) > 0 {
+			object := set.getClosestObject(c, float64(time))
+
+			for _, listener := range set.pressListeners {
+				listener(cursor, time, c, object)
+			}
+		}
+
+		set.processPress(subSet, time, c)
+	}
+}
+
+func (set *ManiaRuleSet) getClosestObject(column int, time float64) (closest *Object) {
+	dist := math.Inf(1)
+
+	for _, o := range set.objects {
+		if o.Column != column {
+			continue
+		}
+
+		if d := math.Abs(o.StartTime - time); d < dist {
+			closest, dist = o, d
+		} else if o.StartTime > time {
+			break
+		}
+	}
+
+	return
+}
+
+func (set *ManiaRuleSet) processPress(subSet *subSet, time int64, column int) {
+	player := subSet.player
+	timeF := float64(time)
+
+	for i := subSet.firstActive; i < len(set.objects); i++ {
+		o, state := set.objects[i], subSet.states[i]
+
+		if timeF < o.StartTime-player.windows[5] {
+			break
+		}
+
+		if o.Column != column || state.HeadJudged || timeF > o.StartTime+player.windows[4] {
+			continue
+		}
+
+		result := player.windows.resultFor(timeF-o.StartTime, 1)
+		if result == Ignore {
+			continue
+		}
+
+		state.HeadJudged = true
+		state.HeadResult = result
+		state.HitTime = time
+
+		if o.IsHold && result != Miss {
+			state.Holding = true
+			player.holding[column] = i
+		}
+
+		set.sendResult(subSet, time, o, result)
+
+		return
+	}
+}
+
+func (set *ManiaRuleSet) processRelease(subSet *subSet, time int64, column int) {
+	player := subSet.player
+
+	index := player.holding[column]
+	if index < 0 {
+		return
+	}
+
+	player.holding[column] = -1
+
+	o, state := set.objects[index], subSet.states[index]
+	state.Holding = false
+
+	if state.TailJudged {
+		return
+	}
+
+	result := player.windows.resultFor(float64(time)-o.EndTime, releaseLenience)
+	if result == Ignore {
+		// Released way too early
+		result = Miss
+	}
+
+	state.TailJudged = true
+	state.TailResult = result
+
+	set.sendResult(subSet, time, o, result)
+}
+
+func (set *ManiaRuleSet) Update(time int64) {
+	timeF := float64(time)
+
+	for _, cursor := range set.cursorsOr {
+		subSet := set.cursors[cursor]
+		player := subSet.player
+
+		for i := subSet.firstActive; i < len(set.objects); i++ {
+			o, state := set.objects[i], subSet.states[i]
+
+			if o.StartTime-player.windows[5] > timeF {
+				break
+			}
+
+			if !state.HeadJudged && timeF > o.StartTime+player.windows[4] {
+				state.HeadJudged = true
+				state.HeadResult = Miss
+				state.HitTime = time
+
+				set.sendResult(subSet, time, o, Miss)
+			}
+
+			if o.IsHold && state.HeadJudged && !state.TailJudged && timeF >= o.EndTime {
+				if state.Holding {
+					// Holding past the end is always perfect, release after that isn't judged anymore
+					state.TailJudged = true
+					state.TailResult = HitMax
+					state.Holding = false
+					player.holding[o.Column] = -1
+
+					set.sendResult(subSet, time, o, HitMax)
+				} else if timeF > o.EndTime+player.windows[4]*releaseLenience {
+					state.TailJudged = true
+					state.TailResult = Miss
+
+					set.sendResult(subSet, time, o, Miss)
+				}
+			}
+
+			if i == subSet.firstActive && state.IsJudged(o) {
+				subSet.firstActive++
+			}
+		}
+	}
+
+	allDone := true
+
+	for _, subSet := range set.cursors {
+		if subSet.firstActive < len(set.objects) {
+			allDone = false
+			break
+		}
+	}
+
+	if allDone && !set.ended {
+		set.ended = true
+
+		set.printResults()
+	}
+}
+
+func (set *ManiaRuleSet) sendResult(subSet *subSet, time int64, object *Object, result HitResult) {
+	player := subSet.player
+
+	comboResult := Increase
+	if result == Miss {
+		comboResult = Reset
+	}
+
+	if player.diff.CheckModActive(difficulty.SuddenDeath|difficulty.Perfect) && result == Miss {
+		set.fail(subSet)
+	}
+
+	if player.diff.CheckModActive(difficulty.Perfect) && result != HitMax {
+		set.fail(subSet)
+	}
+
+	subSet.scoreProcessor.AddResult(result, comboResult)
+	subSet.hp.AddResult(result)
+
+	if subSet.hp.Health <= 0 {
+		set.fail(subSet)
+	}
+
+	score := subSet.score
+	score.Score = subSet.scoreProcessor.GetScore()
+
+	switch result {
+	case HitMax:
+		score.CountMax++
+	case Hit300:
+		score.Count300++
+	case Hit200:
+		score.Count200++
+	case Hit100:
+		score.Count100++
+	case Hit50:
+		score.Count50++
+	case Miss:
+		score.CountMiss++
+	}
+
+	subSet.rawAccuracy += result.AccuracyValue()
+	subSet.numHits++
+
+	score.Accuracy = 100 * float64(subSet.rawAccuracy) / float64(300*subSet.numHits)
+	score.Grade = calculateGrade(score.Accuracy, player.diff.Mods)
+	score.Combo = mutils.Max(uint(subSet.scoreProcessor.GetCombo()), score.Combo)
+	score.PerfectCombo = score.CountMiss == 0
+
+	for _, listener := range set.hitListeners {
+		listener(player.cursor, time, object.Number, object.Column, result, comboResult, score.Score)
+	}
+
+	if len(set.cursors) == 1 && !settings.RECORD && !settings.HEADLESS {
+		log.Println(fmt.Sprintf(
+			"Got: %4s, Combo: %4d, Max Combo: %4d, Score: %9d, Acc: %6.2f%%, MAX: %4d, 300: %4d, 200: %4d, 100: %3d, 50: %3d, miss: %3d, from: %d, at: %d",
+			result.String(),
+			subSet.scoreProcessor.GetCombo(),
+			score.Combo,
+			score.Score,
+			score.Accuracy,
+			score.CountMax,
+			score.Count300,
+			score.Count200,
+			score.Count100,
+			score.Count50,
+			score.CountMiss,
+			object.Number,
+			time,
+		))
+	}
+}
+
+func calculateGrade(accuracy float64, mods difficulty.Modifier) osu.Grade {
+	silver := mods&(difficulty.Hidden|difficulty.Flashlight|difficulty.FadeIn) > 0
+
+	switch {
+	case accuracy >= 100:
+		if silver {
+			return osu.SSH
+		}
+
+		return osu.SS
+	case accuracy > 95:
+		if silver {
+			return osu.SH
+		}
+
+		return osu.S
+	case accuracy > 90:
+		return osu.A
+	case accuracy > 80:
+		return osu.B
+	case accuracy > 70:
+		return osu.C
+	}
+
+	return osu.D
+}
+
+func (set *ManiaRuleSet) fail(subSet *subSet) {
+	if subSet.player.diff.CheckModActive(difficulty.NoFail|difficulty.Relax) || subSet.failed {
+		return
+	}
+
+	subSet.failed = true
+
+	if set.failListener != nil {
+		set.failListener(subSet.player.cursor)
+	}
+}
+
+func (set *ManiaRuleSet) printResults() {
+	cs := make([]*graphics.Cursor, len(set.cursorsOr))
+	copy(cs, set.cursorsOr)
+
+	sort.Slice(cs, func(i, j int) bool {
+		return set.cursors[cs[i]].score.Score > set.cursors[cs[j]].score.Score
+	})
+
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetHeader([]string{"#", "Player", "Score", "Accuracy", "Grade", "MAX", "300", "200", "100", "50", "Miss", "Combo", "Max Combo", "Mods"})
+
+	for i, c := range cs {
+		subSet := set.cursors[c]
+
+		var data []string
+		data = append(data, fmt.Sprintf("%d", i+1))
+		data = append(data, c.Name)
+		data = append(data, utils.Humanize(subSet.score.Score))
+		data = append(data, fmt.Sprintf("%.2f", subSet.score.Accuracy))
+		data = append(data, subSet.score.Grade.String())
+		data = append(data, utils.Humanize(subSet.score.CountMax))
+		data = append(data, utils.Humanize(subSet.score.Count300))
+		data = append(data, utils.Humanize(subSet.score.Count200))
+		data = append(data, utils.Humanize(subSet.score.Count100))
+		data = append(data, utils.Humanize(subSet.score.Count50))
+		data = append(data, utils.Humanize(subSet.score.CountMiss))
+		data = append(data, utils.Humanize(subSet.scoreProcessor.GetCombo()))
+		data = append(data, utils.Humanize(subSet.score.Combo))
+		data = append(data, subSet.player.diff.GetModString())
+		table.Append(data)
+	}
+
+	table.Render()
+
+	for _, s := range strings.Split(tableString.String(), "\n") {
+		log.Println(s)
+	}
+}
+
+func (set *ManiaRuleSet) AddListener(listener hitListener) {
+	set.hitListeners = append(set.hitListeners, listener)
+}
+
+// AddPressListener registers a listener called on every new column press, before it's judged
+func (set *ManiaRuleSet) AddPressListener(listener pressListener) {
+	set.pressListeners = append(set.pressListeners, listener)
+}
+
+func (set *ManiaRuleSet) SetFailListener(listener failListener) {
+	set.failListener = listener
+}
+
+func (set *ManiaRuleSet) GetScore(cursor *graphics.Cursor) Score {
+	return *(set.cursors[cursor].score)
+}
+
+func (set *ManiaRuleSet) GetCombo(cursor *graphics.Cursor) int64 {
+	return set.cursors[cursor].scoreProcessor.GetCombo()
+}
+
+func (set *ManiaRuleSet) GetHP(cursor *graphics.Cursor) float64 {
+	return set.cursors[cursor].hp.Health
+}
+
+func (set *ManiaRuleSet) IsFailed(cursor *graphics.Cursor) bool {
+	return set.cursors[cursor].failed
+}
+
+func (set *ManiaRuleSet) GetColumns(cursor *graphics.Cursor) Columns {
+	return set.cursors[cursor].player.columns
+}
+
+func (set *ManiaRuleSet) GetState(cursor *graphics.Cursor, number int64) ObjectState {
+	return *set.cursors[cursor].states[number]
+}
+
+func (set *ManiaRuleSet) GetHitWindows(cursor *graphics.Cursor) HitWindows {
+	return set.cursors[cursor].player.windows
+}
+
+func (set *ManiaRuleSet) GetKeys() int {
+	return set.keys
+}
+
+func (set *ManiaRuleSet) GetObjects() []*Object {
+	return set.objects
+}
+
+func (set *ManiaRuleSet) GetBeatMap() *beatmap.BeatMap {
+	return set.beatMap
+}
@@ -0,0 +1,93 @@
+package mania
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"math"
+)
+
+const maxScore = 1000000.0
+
+type Score struct {
+	Score        int64
+	Accuracy     float64
+	Grade        osu.Grade
+	Combo        uint
+	PerfectCombo bool
+	CountMax     uint
+	Count300     uint
+	Count200     uint
+	Count100     uint
+	Count50      uint
+	CountMiss    uint
+}
+
+// scoreV1Processor follows osu!stable's mania scoring, half of the score comes from judgements and the other half from bonus
+type scoreV1Processor struct {
+	score         float64
+	combo         int64
+	bonus         float64
+	modMultiplier float64
+	judgements    int
+}
+
+func newScoreV1Processor(judgements int, diff *difficulty.Difficulty) *scoreV1Processor {
+	return &scoreV1Processor{
+		bonus:         100,
+		modMultiplier: scoreMultiplier(diff.Mods),
+		judgements:    judgements,
+	}
+}
+
+// scoreMultiplier differs from osu!standard one, only difficulty reducing mods change the score in mania
+func scoreMultiplier(mods difficulty.Modifier) float64 {
+	multiplier := 1.0
+
+	for _, mod := range []difficulty.Modifier{difficulty.Easy, difficulty.NoFail, difficulty.HalfTime} {
+		if mods.Active(mod) {
+			multiplier *= 0.5
+		}
+	}
+
+	return multiplier
+}
+
+func (s *scoreV1Processor) AddResult(result HitResult, comboResult ComboResult) {
+	var bonusValue, bonusChange float64
+
+	switch result {
+	case HitMax:
+		bonusValue, bonusChange = 32, 2
+	case Hit300:
+		bonusValue, bonusChange = 32, 1
+	case Hit200:
+		bonusValue, bonusChange = 16, -8
+	case Hit100:
+		bonusValue, bonusChange = 8, -24
+	case Hit50:
+		bonusValue, bonusChange = 4, -44
+	case Miss:
+		bonusChange = -100
+	}
+
+	s.bonus = math.Max(0, math.Min(100, s.bonus+bonusChange))
+
+	perJudgement := maxScore * s.modMultiplier * 0.5 / float64(s.judgements)
+
+	s.score += perJudgement * float64(result.ScoreValue()) / 320
+	s.score += perJudgement * bonusValue * math.Sqrt(s.bonus) / 320
+
+	if comboResult == Reset {
+		s.combo = 0
+	} else if comboResult == Increase {
+		s.combo++
+	}
+}
+
+func (s *scoreV1Processor) GetScore() int64 {
+	return int64(math.Round(s.score))
+}
+
+func (s *scoreV1Processor) GetCombo() int64 {
+	return s.combo
+}
@@ -82,12 +82,12 @@ func (circle *Circle) UpdateClickFor(player *difficultyPlayer, time int64) bool
 						if hit == Miss {
 							combo = Reset
 						} else {
-							if len(circle.players) == 1 {
+							if circle.ruleSet.objectFeedback() {
 								circle.hitCircle.PlaySound()
 							}
 						}
 
-						if len(circle.players) == 1 {
+						if circle.ruleSet.objectFeedback() {
 							circle.hitCircle.Arm(hit != Miss, float64(time))
 						}
 
@@ -99,7 +99,7 @@ func (circle *Circle) UpdateClickFor(player *difficultyPlayer, time int64) bool
 					player.leftCondE = false
 					player.rightCondE = false
 
-					if action == Shake && len(circle.players) == 1 {
+					if action == Shake && circle.ruleSet.objectFeedback() {
 						circle.hitCircle.Shake(float64(time))
 					}
 				}
@@ -119,7 +119,7 @@ func (circle *Circle) UpdatePostFor(player *difficultyPlayer, time int64, _ bool
 		position := circle.hitCircle.GetStackedPositionAtMod(float64(time), player.diff.Mods)
 		circle.ruleSet.SendResult(time, player.cursor, circle, position.X, position.Y, Miss, Reset)
 
-		if len(circle.players) == 1 {
+		if circle.ruleSet.objectFeedback() {
 			circle.hitCircle.Arm(false, float64(time))
 		}
 
@@ -150,3 +150,19 @@ func (circle *Circle) IsHit(player *difficultyPlayer) bool {
 func (circle *Circle) GetFadeTime() int64 {
 	return int64(circle.hitCircle.GetStartTime() - circle.fadeStartRelative)
 }
+
+func (circle *Circle) saveState() interface{} {
+	states := make(map[*difficultyPlayer]objstate, len(circle.state))
+
+	for player, state := range circle.state {
+		states[player] = *state
+	}
+
+	return states
+}
+
+func (circle *Circle) loadState(state interface{}) {
+	for player, s := range state.(map[*difficultyPlayer]objstate) {
+		*circle.state[player] = s
+	}
+}
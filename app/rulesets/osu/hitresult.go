@@ -54,3 +54,51 @@ func (r HitResult) ScoreValue() int64 {
 
 	return 0
 }
+
+func (r HitResult) String() string {
+	var name string
+
+	switch r & (^Additions) {
+	case Ignore:
+		name = "Ignore"
+	case SliderMiss:
+		name = "SliderMiss"
+	case Miss:
+		name = "Miss"
+	case Hit50:
+		name = "Hit50"
+	case Hit100:
+		name = "Hit100"
+	case Hit300:
+		name = "Hit300"
+	case SliderStart:
+		name = "SliderStart"
+	case SliderPoint:
+		name = "SliderPoint"
+	case SliderRepeat:
+		name = "SliderRepeat"
+	case SliderEnd:
+		name = "SliderEnd"
+	case SpinnerSpin:
+		name = "SpinnerSpin"
+	case SpinnerPoints:
+		name = "SpinnerPoints"
+	case SpinnerBonus:
+		name = "SpinnerBonus"
+	case PositionalMiss:
+		name = "PositionalMiss"
+	default:
+		name = "Unknown"
+	}
+
+	switch {
+	case r&MuAddition > 0:
+		name += "m"
+	case r&KatuAddition > 0:
+		name += "k"
+	case r&GekiAddition > 0:
+		name += "g"
+	}
+
+	return name
+}
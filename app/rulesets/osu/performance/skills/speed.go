@@ -54,7 +54,7 @@ func (s *SpeedSkill) speedStrainValue(current *preprocessing.DifficultyObject) f
 	strainTime := current.StrainTime
 
 	previous := s.GetPrevious(0)
-	greatWindowFull := s.diff.Hit300U / s.diff.Speed * 2
+	greatWindowFull := s.diff.GetModifiedTimeAt(current.BaseObject.GetStartTime(), s.diff.Hit300U) * 2
 	speedWindowRatio := strainTime / greatWindowFull
 
 	// Aim to nerf cheesy rhythms (Very fast consecutive doubles with large deltatimes between)
@@ -81,7 +81,7 @@ func (s *SpeedSkill) speedStrainBonus(current *preprocessing.DifficultyObject) f
 		return 0
 	}
 
-	greatWindow := s.diff.Hit300U / s.diff.Speed
+	greatWindow := s.diff.GetModifiedTimeAt(current.BaseObject.GetStartTime(), s.diff.Hit300U)
 
 	previousIslandSize := 0
 	rhythmComplexitySum := 0.0
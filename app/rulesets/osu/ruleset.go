@@ -36,6 +36,19 @@ const (
 	Increase
 )
 
+func (r ComboResult) String() string {
+	switch r {
+	case Reset:
+		return "Reset"
+	case Hold:
+		return "Hold"
+	case Increase:
+		return "Increase"
+	}
+
+	return "Unknown"
+}
+
 type buttonState struct {
 	Left, Right bool
 }
@@ -53,6 +66,8 @@ type HitObject interface {
 	IsHit(player *difficultyPlayer) bool
 	GetFadeTime() int64
 	GetNumber() int64
+	saveState() interface{}
+	loadState(state interface{})
 }
 
 type difficultyPlayer struct {
@@ -77,6 +92,7 @@ type scoreProcessor interface {
 	ModifyResult(result HitResult, src HitObject) HitResult
 	GetScore() int64
 	GetCombo() int64
+	clone() scoreProcessor
 }
 
 type Score struct {
@@ -114,9 +130,11 @@ type subSet struct {
 	sdpfFail   bool
 }
 
-type hitListener func(cursor *graphics.Cursor, time int64, number int64, position vector.Vector2d, result HitResult, comboResult ComboResult, ppResults performance.PPv2Results, score int64)
+// HitListener receives every judgement, number is the index of beatmap's hit object
+type HitListener func(cursor *graphics.Cursor, time int64, number int64, position vector.Vector2d, result HitResult, comboResult ComboResult, ppResults performance.PPv2Results, score int64)
 
-type endListener func(time int64, number int64)
+// EndListener is called when all players are done with a hit object
+type EndListener func(time int64, number int64)
 
 type failListener func(cursor *graphics.Cursor)
 
@@ -130,10 +148,17 @@ type OsuRuleSet struct {
 
 	queue        []HitObject
 	processed    []HitObject
-	hitListener  hitListener
-	endListener  endListener
+	hitListener  HitListener
+	hitListeners []HitListener
+	endListener  EndListener
 	failListener failListener
 
+	initialStates map[HitObject]interface{}
+	history       []historyEvent
+
+	// Length history had when additional listeners got the last judgement, they don't get judgements simulated again after restoring a snapshot
+	listenedLength int
+
 	experimentalPP bool
 }
 
@@ -170,6 +195,7 @@ func NewOsuRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, mods []
 
 		diff.SetMods(mods[i] | (beatMap.Diff.Mods & difficulty.ScoreV2)) // if beatmap has ScoreV2 mod, force it for all players
 		diff.SetCustomSpeed(beatMap.Diff.CustomSpeed)
+		diff.SetRateAdjust(beatMap.Diff.RateAdjust)
 
 		player := &difficultyPlayer{cursor: cursor, diff: diff}
 		diffPlayers = append(diffPlayers, player)
@@ -278,6 +304,10 @@ func (set *OsuRuleSet) Update(time int64) {
 					set.endListener(time, g.GetNumber())
 				}
 
+				if set.initialStates != nil {
+					set.history = append(set.history, historyEvent{end: true, time: time, number: g.GetNumber()})
+				}
+
 				set.processed = append(set.processed[:i], set.processed[i+1:]...)
 
 				i--
@@ -432,8 +462,8 @@ func (set *OsuRuleSet) SendResult(time int64, cursor *graphics.Cursor, src HitOb
 	subSet := set.cursors[cursor]
 
 	if result == Ignore || result == PositionalMiss {
-		if result == PositionalMiss && set.hitListener != nil && !subSet.player.diff.Mods.Active(difficulty.Relax) {
-			set.hitListener(cursor, time, number, vector.NewVec2f(x, y).Copy64(), result, comboResult, subSet.ppv2.Results, subSet.scoreProcessor.GetScore())
+		if result == PositionalMiss && !subSet.player.diff.Mods.Active(difficulty.Relax) {
+			set.sendToListeners(cursor, time, number, x, y, result, comboResult)
 		}
 
 		return
@@ -571,11 +601,9 @@ func (set *OsuRuleSet) SendResult(time int64, cursor *graphics.Cursor, src HitOb
 		subSet.hp.AddResult(result)
 	}
 
-	if set.hitListener != nil {
-		set.hitListener(cursor, time, number, vector.NewVec2f(x, y).Copy64(), result, comboResult, subSet.ppv2.Results, subSet.scoreProcessor.GetScore())
-	}
+	set.sendToListeners(cursor, time, number, x, y, result, comboResult)
 
-	if len(set.cursors) == 1 && !settings.RECORD {
+	if len(set.cursors) == 1 && !settings.RECORD && !settings.HEADLESS {
 		log.Println(fmt.Sprintf(
 			"Got: %3d, Combo: %4d, Max Combo: %4d, Score: %9d, Acc: %6.2f%%, 300: %4d, 100: %3d, 50: %2d, miss: %2d, from: %d, at: %d, pos: %.0fx%.0f, pp: %.2f",
 			result.ScoreValue(),
@@ -596,6 +624,36 @@ func (set *OsuRuleSet) SendResult(time int64, cursor *graphics.Cursor, src HitOb
 	}
 }
 
+func (set *OsuRuleSet) sendToListeners(cursor *graphics.Cursor, time int64, number int64, x, y float32, result HitResult, comboResult ComboResult) {
+	subSet := set.cursors[cursor]
+	position := vector.NewVec2f(x, y).Copy64()
+
+	if set.hitListener != nil {
+		set.hitListener(cursor, time, number, position, result, comboResult, subSet.ppv2.Results, subSet.scoreProcessor.GetScore())
+	}
+
+	if set.initialStates == nil || len(set.history) >= set.listenedLength {
+		for _, listener := range set.hitListeners {
+			listener(cursor, time, number, position, result, comboResult, subSet.ppv2.Results, subSet.scoreProcessor.GetScore())
+		}
+
+		set.listenedLength = len(set.history) + 1
+	}
+
+	if set.initialStates != nil {
+		set.history = append(set.history, historyEvent{
+			cursor:      cursor,
+			time:        time,
+			number:      number,
+			position:    position,
+			result:      result,
+			comboResult: comboResult,
+			ppResults:   subSet.ppv2.Results,
+			score:       subSet.scoreProcessor.GetScore(),
+		})
+	}
+}
+
 func (set *OsuRuleSet) CanBeHit(time int64, object HitObject, player *difficultyPlayer) ClickAction {
 	if _, ok := object.(*Circle); ok {
 		index := -1
@@ -667,11 +725,22 @@ func (set *OsuRuleSet) PlayerStopped(cursor *graphics.Cursor, time int64) {
 	}
 }
 
-func (set *OsuRuleSet) SetListener(listener hitListener) {
+// objectFeedback tells whether hit objects should react visually and audibly to judgements
+func (set *OsuRuleSet) objectFeedback() bool {
+	return len(set.cursors) == 1 && !settings.HEADLESS
+}
+
+func (set *OsuRuleSet) SetListener(listener HitListener) {
 	set.hitListener = listener
 }
 
-func (set *OsuRuleSet) SetEndListener(listener endListener) {
+// AddListener registers an additional hit listener that won't be replaced by SetListener.
+// With snapshots enabled it gets every judgement only once, even if it's simulated again after rewinding.
+func (set *OsuRuleSet) AddListener(listener HitListener) {
+	set.hitListeners = append(set.hitListeners, listener)
+}
+
+func (set *OsuRuleSet) SetEndListener(listener EndListener) {
 	set.endListener = listener
 }
 
@@ -62,3 +62,8 @@ func (s *scoreV1Processor) GetScore() int64 {
 func (s *scoreV1Processor) GetCombo() int64 {
 	return s.combo
 }
+
+func (s *scoreV1Processor) clone() scoreProcessor {
+	c := *s
+	return &c
+}
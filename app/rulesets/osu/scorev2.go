@@ -116,6 +116,17 @@ func (s *scoreV2Processor) GetCombo() int64 {
 	return s.combo
 }
 
+func (s *scoreV2Processor) clone() scoreProcessor {
+	c := *s
+
+	c.hitMap = make(map[HitResult]int64, len(s.hitMap))
+	for k, v := range s.hitMap {
+		c.hitMap[k] = v
+	}
+
+	return &c
+}
+
 func scoreValueV2(result HitResult) int64 {
 	scoreVal := result.ScoreValue()
 	if result&SpinnerBonus > 0 {
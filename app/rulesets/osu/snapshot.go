@@ -0,0 +1,159 @@
+package osu
+
+import (
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
+	"github.com/wieku/danser-go/framework/math/vector"
+)
+
+// historyEvent is a judgement or an object end sent to listeners, kept to rebuild overlays after seeking
+type historyEvent struct {
+	end bool
+
+	cursor      *graphics.Cursor
+	time        int64
+	number      int64
+	position    vector.Vector2d
+	result      HitResult
+	comboResult ComboResult
+	ppResults   performance.PPv2Results
+	score       int64
+}
+
+type subSetState struct {
+	player         difficultyPlayer
+	score          Score
+	hp             HealthProcessor
+	scoreProcessor scoreProcessor
+	rawScore       int64
+	currentKatu    int
+	currentBad     int
+	numObjects     uint
+	ppv2           performance.PPv2
+	recoveries     int
+	failed         bool
+	sdpfFail       bool
+}
+
+// Snapshot holds ruleset's state at a given time
+type Snapshot struct {
+	Time int64
+
+	ended     bool
+	queue     []HitObject
+	processed []HitObject
+
+	states  map[HitObject]interface{}
+	subSets map[*graphics.Cursor]subSetState
+
+	historyLength int
+}
+
+// EnableSnapshots makes the ruleset keep data needed to restore snapshots, it has to be called before the first update
+func (set *OsuRuleSet) EnableSnapshots() {
+	if set.initialStates != nil {
+		return
+	}
+
+	set.initialStates = make(map[HitObject]interface{})
+
+	for _, o := range set.queue {
+		set.initialStates[o] = o.saveState()
+	}
+}
+
+func (set *OsuRuleSet) TakeSnapshot(time int64) *Snapshot {
+	if set.initialStates == nil {
+		panic("snapshots are not enabled")
+	}
+
+	snapshot := &Snapshot{
+		Time:          time,
+		ended:         set.ended,
+		queue:         copyObjects(set.queue),
+		processed:     copyObjects(set.processed),
+		states:        make(map[HitObject]interface{}),
+		subSets:       make(map[*graphics.Cursor]subSetState),
+		historyLength: len(set.history),
+	}
+
+	// Objects still in the queue are in their initial state and finished objects are never judged again
+	for _, o := range set.processed {
+		snapshot.states[o] = o.saveState()
+	}
+
+	for cursor, subSet := range set.cursors {
+		snapshot.subSets[cursor] = subSetState{
+			player:         *subSet.player,
+			score:          *subSet.score,
+			hp:             *subSet.hp,
+			scoreProcessor: subSet.scoreProcessor.clone(),
+			rawScore:       subSet.rawScore,
+			currentKatu:    subSet.currentKatu,
+			currentBad:     subSet.currentBad,
+			numObjects:     subSet.numObjects,
+			ppv2:           *subSet.ppv2,
+			recoveries:     subSet.recoveries,
+			failed:         subSet.failed,
+			sdpfFail:       subSet.sdpfFail,
+		}
+	}
+
+	return snapshot
+}
+
+// RestoreSnapshot brings the ruleset back to snapshot's time, judgement history after that time is dropped
+func (set *OsuRuleSet) RestoreSnapshot(snapshot *Snapshot) {
+	set.ended = snapshot.ended
+	set.queue = copyObjects(snapshot.queue)
+	set.processed = copyObjects(snapshot.processed)
+
+	for _, o := range set.queue {
+		o.loadState(set.initialStates[o])
+	}
+
+	for _, o := range set.processed {
+		o.loadState(snapshot.states[o])
+	}
+
+	// Values are copied to existing structs because objects and listeners keep pointers to them
+	for cursor, state := range snapshot.subSets {
+		subSet := set.cursors[cursor]
+
+		*subSet.player = state.player
+		*subSet.score = state.score
+		*subSet.hp = state.hp
+		*subSet.ppv2 = state.ppv2
+
+		subSet.scoreProcessor = state.scoreProcessor.clone()
+		subSet.rawScore = state.rawScore
+		subSet.currentKatu = state.currentKatu
+		subSet.currentBad = state.currentBad
+		subSet.numObjects = state.numObjects
+		subSet.recoveries = state.recoveries
+		subSet.failed = state.failed
+		subSet.sdpfFail = state.sdpfFail
+	}
+
+	set.history = set.history[:snapshot.historyLength]
+}
+
+// ResendHistory sends all kept judgements and object ends to the main hit listener and the end listener, used when they are recreated after seeking
+func (set *OsuRuleSet) ResendHistory() {
+	for _, e := range set.history {
+		if e.end {
+			if set.endListener != nil {
+				set.endListener(e.time, e.number)
+			}
+		} else if set.hitListener != nil {
+			set.hitListener(e.cursor, e.time, e.number, e.position, e.result, e.comboResult, e.ppResults, e.score)
+		}
+	}
+}
+
+func copyObjects(objs []HitObject) []HitObject {
+	c := make([]HitObject, len(objs))
+	copy(c, objs)
+
+	return c
+}
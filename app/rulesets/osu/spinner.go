@@ -117,8 +117,8 @@ func (spinner *Spinner) UpdateFor(player *difficultyPlayer, time int64, _ bool)
 				}
 
 				if math.Abs(angleDiff) < math.Pi {
-					if player.diff.GetModifiedTime(state.frameVariance) > FrameTime*1.04 {
-						state.theoreticalVelocity = angleDiff / player.diff.GetModifiedTime(timeDiff)
+					if player.diff.GetModifiedTimeAt(float64(time), state.frameVariance) > FrameTime*1.04 {
+						state.theoreticalVelocity = angleDiff / player.diff.GetModifiedTimeAt(float64(time), timeDiff)
 					} else {
 						state.theoreticalVelocity = angleDiff / FrameTime
 					}
@@ -129,7 +129,7 @@ func (spinner *Spinner) UpdateFor(player *difficultyPlayer, time int64, _ bool)
 
 			state.lastAngle = mouseAngle
 
-			maxAccelThisFrame := player.diff.GetModifiedTime(spinner.maxAcceleration * timeDiff)
+			maxAccelThisFrame := player.diff.GetModifiedTimeAt(float64(time), spinner.maxAcceleration*timeDiff)
 
 			if player.diff.CheckModActive(difficulty.SpunOut) || player.diff.CheckModActive(difficulty.Relax2) {
 				state.currentVelocity = 0.03
@@ -151,7 +151,7 @@ func (spinner *Spinner) UpdateFor(player *difficultyPlayer, time int64, _ bool)
 
 			state.currentVelocity = math.Max(-0.05, math.Min(state.currentVelocity, 0.05))
 
-			if len(spinner.players) == 1 {
+			if spinner.ruleSet.objectFeedback() {
 				if state.currentVelocity == 0 {
 					spinner.hitSpinner.PauseSpinSample()
 				} else {
@@ -164,8 +164,8 @@ func (spinner *Spinner) UpdateFor(player *difficultyPlayer, time int64, _ bool)
 			state.rotationCountFD += rotationAddition
 			state.rotationCountF += math.Abs(rotationAddition / math.Pi)
 
-			if len(spinner.players) == 1 {
-				spinner.hitSpinner.SetRotation(player.diff.GetModifiedTime(state.rotationCountFD))
+			if spinner.ruleSet.objectFeedback() {
+				spinner.hitSpinner.SetRotation(player.diff.GetModifiedTimeAt(float64(time), state.rotationCountFD))
 				spinner.hitSpinner.SetRPM(state.rpm)
 				spinner.hitSpinner.UpdateCompletion(state.rotationCountF / float64(state.requirement))
 			}
@@ -175,12 +175,12 @@ func (spinner *Spinner) UpdateFor(player *difficultyPlayer, time int64, _ bool)
 			if state.rotationCount != state.lastRotationCount {
 				state.scoringRotationCount++
 
-				if state.scoringRotationCount == spinner.getRequirementClear(player) && len(spinner.players) == 1 {
+				if state.scoringRotationCount == spinner.getRequirementClear(player) && spinner.ruleSet.objectFeedback() {
 					spinner.hitSpinner.Clear()
 				}
 
 				if state.scoringRotationCount > state.requirement+3 && (state.scoringRotationCount-(state.requirement+3))%2 == 0 {
-					if len(spinner.players) == 1 {
+					if spinner.ruleSet.objectFeedback() {
 						spinner.hitSpinner.Bonus()
 					}
 
@@ -218,7 +218,7 @@ func (spinner *Spinner) UpdatePostFor(player *difficultyPlayer, time int64, _ bo
 			combo = Increase
 		}
 
-		if len(spinner.players) == 1 {
+		if spinner.ruleSet.objectFeedback() {
 			spinner.hitSpinner.StopSpinSample()
 			spinner.hitSpinner.Hit(float64(time), hit != Miss)
 		}
@@ -285,3 +285,19 @@ func (spinner *Spinner) getRequirementClear(player *difficultyPlayer) int64 {
 
 	return spinner.state[player].requirement
 }
+
+func (spinner *Spinner) saveState() interface{} {
+	states := make(map[*difficultyPlayer]spinnerstate, len(spinner.state))
+
+	for player, state := range spinner.state {
+		states[player] = *state
+	}
+
+	return states
+}
+
+func (spinner *Spinner) loadState(state interface{}) {
+	for player, s := range state.(map[*difficultyPlayer]spinnerstate) {
+		*spinner.state[player] = s
+	}
+}
@@ -0,0 +1,213 @@
+package taiko
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"math"
+	"sort"
+)
+
+// Star rating calculation as it was in osu!stable before the 2022 taiko rework
+
+const (
+	starScalingFactor = 0.04125
+	decayBase         = 0.30
+	strainStep        = 400.0
+	decayWeight       = 0.9
+
+	typeChangeBonus            = 0.75
+	rhythmChangeBonus          = 1.0
+	rhythmChangeBaseThreshold  = 0.2
+	rhythmChangeBase           = 2.0
+	additionsMaxTimeDifference = 1000.0
+)
+
+type typeSwitch uint8
+
+const (
+	switchNone = typeSwitch(iota)
+	switchEven
+	switchOdd
+)
+
+type difficultyObject struct {
+	object *Object
+
+	isRim  bool
+	strain float64
+
+	timeElapsed        float64
+	sameTypeSince      int
+	lastTypeSwitchEven typeSwitch
+}
+
+func (o *difficultyObject) calculateStrain(previous *difficultyObject, timeRate float64) {
+	o.timeElapsed = (o.object.StartTime - previous.object.StartTime) / timeRate
+
+	decay := math.Pow(decayBase, o.timeElapsed/1000)
+
+	addition := 1.0
+
+	// Only hits that aren't too far in the past get additional bonuses
+	if previous.object.IsHit() && o.object.IsHit() && o.object.StartTime-previous.object.StartTime < additionsMaxTimeDifference {
+		addition += o.typeChangeAddition(previous)
+		addition += o.rhythmChangeAddition(previous)
+	}
+
+	additionFactor := 1.0
+
+	// Scale additionFactor linearly from 0.4 to 1 for timeElapsed from 0 to 50
+	if o.timeElapsed < 50 {
+		additionFactor = 0.4 + 0.6*o.timeElapsed/50
+	}
+
+	o.strain = previous.strain*decay + addition*additionFactor
+}
+
+func (o *difficultyObject) typeChangeAddition(previous *difficultyObject) float64 {
+	if previous.isRim != o.isRim {
+		o.lastTypeSwitchEven = switchOdd
+		if previous.sameTypeSince%2 == 0 {
+			o.lastTypeSwitchEven = switchEven
+		}
+
+		// Bonus is given only if parity of the type switch changes
+		if (previous.lastTypeSwitchEven == switchEven && o.lastTypeSwitchEven == switchOdd) ||
+			(previous.lastTypeSwitchEven == switchOdd && o.lastTypeSwitchEven == switchEven) {
+			return typeChangeBonus
+		}
+
+		return 0
+	}
+
+	o.lastTypeSwitchEven = previous.lastTypeSwitchEven
+	o.sameTypeSince = previous.sameTypeSince + 1
+
+	return 0
+}
+
+func (o *difficultyObject) rhythmChangeAddition(previous *difficultyObject) float64 {
+	// No bonus for repeating the same rhythm
+	if o.timeElapsed == 0 || previous.timeElapsed == 0 {
+		return 0
+	}
+
+	ratio := math.Max(previous.timeElapsed/o.timeElapsed, o.timeElapsed/previous.timeElapsed)
+
+	if ratio >= 8 {
+		return 0
+	}
+
+	difference := math.Mod(math.Log(ratio)/math.Log(rhythmChangeBase), 1.0)
+
+	if difference > rhythmChangeBaseThreshold && difference < 1-rhythmChangeBaseThreshold {
+		return rhythmChangeBonus
+	}
+
+	return 0
+}
+
+// Attributes holds star rating of the map up to a given hit
+type Attributes struct {
+	Stars float64
+
+	// MaxCombo is the number of hits (dons and kats) processed so far
+	MaxCombo int
+
+	// GreatWindow is the Great hit window adjusted by clock rate
+	GreatWindow float64
+}
+
+// CalculateSingle calculates the star rating of the whole map
+func CalculateSingle(taikoObjects []*Object, diff *difficulty.Difficulty) Attributes {
+	attribs := CalculateStep(taikoObjects, diff)
+	if len(attribs) == 0 {
+		return Attributes{}
+	}
+
+	return attribs[len(attribs)-1]
+}
+
+// CalculateStep calculates star rating after every hit (don or kat), used by live pp counter
+func CalculateStep(taikoObjects []*Object, diff *difficulty.Difficulty) []Attributes {
+	timeRate := diff.Speed
+
+	greatWindow := math.Floor(difficulty.DifficultyRate(diff.ODMod, 50, 35, 20)) / timeRate
+
+	diffObjects := make([]*difficultyObject, 0, len(taikoObjects))
+
+	for _, o := range taikoObjects {
+		diffObjects = append(diffObjects, &difficultyObject{
+			object:        o,
+			isRim:         o.Type == Kat,
+			sameTypeSince: 1,
+			strain:        1,
+		})
+	}
+
+	for i := 1; i < len(diffObjects); i++ {
+		diffObjects[i].calculateStrain(diffObjects[i-1], timeRate)
+	}
+
+	var attributes []Attributes
+
+	var peaks []float64
+
+	sectionLength := strainStep * timeRate
+	intervalEnd := sectionLength
+	maxStrain := 0.0
+
+	var previous *difficultyObject
+
+	maxCombo := 0
+
+	for _, o := range diffObjects {
+		for o.object.StartTime > intervalEnd {
+			peaks = append(peaks, maxStrain)
+
+			if previous == nil {
+				maxStrain = 0
+			} else {
+				maxStrain = previous.strain * math.Pow(decayBase, (intervalEnd-previous.object.StartTime)/1000)
+			}
+
+			intervalEnd += sectionLength
+		}
+
+		maxStrain = math.Max(o.strain, maxStrain)
+		previous = o
+
+		if o.object.IsHit() {
+			maxCombo++
+
+			attributes = append(attributes, Attributes{
+				Stars:       weightedPeaks(append(peaks, maxStrain)) * starScalingFactor,
+				MaxCombo:    maxCombo,
+				GreatWindow: greatWindow,
+			})
+		}
+	}
+
+	if len(attributes) > 0 {
+		// Include strains of drum rolls and swells after the last hit
+		attributes[len(attributes)-1].Stars = weightedPeaks(append(peaks, maxStrain)) * starScalingFactor
+	}
+
+	return attributes
+}
+
+func weightedPeaks(peaks []float64) float64 {
+	sorted := make([]float64, len(peaks))
+	copy(sorted, peaks)
+
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	total := 0.0
+	weight := 1.0
+
+	for _, strain := range sorted {
+		total += strain * weight
+		weight *= decayWeight
+	}
+
+	return total
+}
@@ -0,0 +1,61 @@
+package taiko
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"math"
+)
+
+const (
+	objectCountFactor = 3.0
+
+	HpGreat = 3.0
+	HpOk    = 1.1
+	HpMiss  = -1.0
+
+	// RequiredHp is the health needed at the end of the map to pass it
+	RequiredHp = 0.5
+)
+
+// HealthProcessor accumulates health from 0 without passive drain, modelled after lazer's TaikoHealthProcessor
+type HealthProcessor struct {
+	Health float64
+
+	hpMultiplier     float64
+	hpMissMultiplier float64
+}
+
+func NewHealthProcessor(taikoObjects []*Object, diff *difficulty.Difficulty) *HealthProcessor {
+	hits := 0
+
+	for _, o := range taikoObjects {
+		if o.IsHit() {
+			hits++
+		}
+	}
+
+	return &HealthProcessor{
+		hpMultiplier:     1 / (objectCountFactor * float64(mutils.Max(1, hits)) * difficulty.DifficultyRate(diff.HPMod, 0.5, 0.75, 0.98)),
+		hpMissMultiplier: difficulty.DifficultyRate(diff.HPMod, 0.0018, 0.0075, 0.0120),
+	}
+}
+
+func (hp *HealthProcessor) AddResult(result HitResult) {
+	switch result {
+	case Great:
+		hp.Increase(HpGreat * hp.hpMultiplier)
+	case Ok:
+		hp.Increase(HpOk * hp.hpMultiplier)
+	case Miss:
+		hp.Increase(HpMiss * hp.hpMissMultiplier)
+	}
+}
+
+func (hp *HealthProcessor) Increase(amount float64) {
+	hp.Health = math.Max(0, math.Min(1, hp.Health+amount))
+}
+
+// HasPassed returns true if there's enough health to pass the map
+func (hp *HealthProcessor) HasPassed() bool {
+	return hp.Health >= RequiredHp
+}
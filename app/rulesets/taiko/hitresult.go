@@ -0,0 +1,72 @@
+package taiko
+
+type HitResult int64
+
+const (
+	Ignore = HitResult(0)
+	Miss   = HitResult(1 << iota)
+	Ok
+	Great
+	StrongBonus
+	DrumRollTick
+	SwellTick
+	SwellBonus
+	BaseHits  = Ok | Great
+	BaseHitsM = BaseHits | Miss
+	RawHits   = StrongBonus | DrumRollTick | SwellTick | SwellBonus
+)
+
+func (r HitResult) ScoreValue() int64 {
+	switch r {
+	case Ok:
+		return 150
+	case Great, DrumRollTick, SwellTick, SwellBonus:
+		return 300
+	}
+
+	return 0
+}
+
+func (r HitResult) String() string {
+	switch r {
+	case Ignore:
+		return "Ignore"
+	case Miss:
+		return "Miss"
+	case Ok:
+		return "Ok"
+	case Great:
+		return "Great"
+	case StrongBonus:
+		return "StrongBonus"
+	case DrumRollTick:
+		return "DrumRollTick"
+	case SwellTick:
+		return "SwellTick"
+	case SwellBonus:
+		return "SwellBonus"
+	}
+
+	return "Unknown"
+}
+
+type ComboResult uint8
+
+const (
+	Reset = ComboResult(iota)
+	Hold
+	Increase
+)
+
+func (r ComboResult) String() string {
+	switch r {
+	case Reset:
+		return "Reset"
+	case Hold:
+		return "Hold"
+	case Increase:
+		return "Increase"
+	}
+
+	return "Unknown"
+}
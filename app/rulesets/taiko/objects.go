@@ -0,0 +1,183 @@
+package taiko
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"math"
+)
+
+const (
+	// velocityMultiplier is applied by osu! to slider velocity of all taiko maps
+	velocityMultiplier  = 1.4
+	baseScoringDistance = 100.0
+	swellHitMultiplier  = 1.65
+)
+
+type ObjectType uint8
+
+const (
+	Don = ObjectType(iota)
+	Kat
+	DrumRoll
+	Swell
+)
+
+func (t ObjectType) String() string {
+	switch t {
+	case Don:
+		return "don"
+	case Kat:
+		return "kat"
+	case DrumRoll:
+		return "drumroll"
+	case Swell:
+		return "swell"
+	}
+
+	return "unknown"
+}
+
+// Object is a taiko hit object either read from a native taiko map or converted from osu!standard one
+type Object struct {
+	Number int64
+
+	// Source is the index of the beatmap's hit object this one was created from
+	Source int64
+
+	Type   ObjectType
+	Strong bool
+
+	StartTime float64
+	EndTime   float64
+
+	// Velocity is scroll speed in osu!pixels per millisecond
+	Velocity float64
+
+	// Ticks holds drum roll tick times
+	Ticks       []float64
+	TickSpacing float64
+
+	// RequiredHits is the number of hits needed to clear a swell
+	RequiredHits int
+}
+
+func (o *Object) IsHit() bool {
+	return o.Type == Don || o.Type == Kat
+}
+
+// ConvertObjects converts beatmap objects to taiko ones following osu!stable's (and lazer's) TaikoBeatmapConverter.
+// Beatmap's timing points have to be already parsed.
+func ConvertObjects(beatMap *beatmap.BeatMap) []*Object {
+	timings := beatMap.Timings
+
+	isNative := beatMap.Mode == 1
+
+	result := make([]*Object, 0, len(beatMap.HitObjects))
+
+	add := func(source objects.IHitObject, objType ObjectType, strong bool, startTime, endTime float64) *Object {
+		point := timings.GetPointAt(startTime)
+
+		o := &Object{
+			Source:    source.GetID(),
+			Type:      objType,
+			Strong:    strong,
+			StartTime: startTime,
+			EndTime:   endTime,
+			Velocity:  baseScoringDistance * timings.SliderMult * velocityMultiplier / point.GetBeatLength(),
+		}
+
+		result = append(result, o)
+
+		return o
+	}
+
+	addHit := func(source objects.IHitObject, startTime float64, sample int) {
+		objType := Don
+		if sample&(2|8) > 0 {
+			objType = Kat
+		}
+
+		add(source, objType, sample&4 > 0, startTime, startTime)
+	}
+
+	for _, obj := range beatMap.HitObjects {
+		switch o := obj.(type) {
+		case *objects.Circle:
+			addHit(o, o.GetStartTime(), o.GetSample())
+		case *objects.Slider:
+			taikoDuration, tickSpacing, toHits := sliderConversion(beatMap, o, isNative)
+
+			if toHits {
+				samples := o.GetEdgeSamples()
+
+				i := 0
+				for t := o.GetStartTime(); t <= o.GetStartTime()+float64(taikoDuration)+tickSpacing/8; t += tickSpacing {
+					addHit(o, t, samples[i])
+
+					i = (i + 1) % len(samples)
+				}
+
+				continue
+			}
+
+			roll := add(o, DrumRoll, o.GetEdgeSamples()[0]&4 > 0, o.GetStartTime(), o.GetStartTime()+float64(taikoDuration))
+
+			tickRate := 4.0
+			if timings.TickRate == 3 {
+				tickRate = 3
+			}
+
+			roll.TickSpacing = timings.GetPointAt(roll.StartTime).GetBaseBeatLength() / tickRate
+
+			if roll.TickSpacing > 0 {
+				for t := roll.StartTime; t < roll.EndTime+roll.TickSpacing/2; t += roll.TickSpacing {
+					roll.Ticks = append(roll.Ticks, t)
+				}
+			}
+		case *objects.Spinner:
+			hitMultiplier := difficulty.DifficultyRate(beatMap.Diff.GetBaseOD(), 3, 5, 7.5) * swellHitMultiplier
+
+			swell := add(o, Swell, o.GetSample()&4 > 0, o.GetStartTime(), o.GetEndTime())
+			swell.RequiredHits = int(math.Max(1, (swell.EndTime-swell.StartTime)/1000*hitMultiplier))
+		}
+	}
+
+	for i, o := range result {
+		o.Number = int64(i)
+	}
+
+	return result
+}
+
+// sliderConversion decides whether slider should be converted to a drum roll or a stream of hits.
+// Mix of float32 and float64 calculations is intended to match osu!stable.
+func sliderConversion(beatMap *beatmap.BeatMap, slider *objects.Slider, isNative bool) (taikoDuration int, tickSpacing float64, toHits bool) {
+	timings := beatMap.Timings
+
+	spans := float64(slider.RepeatCount)
+
+	distance := slider.GetPixelLength() * spans * velocityMultiplier
+
+	point := timings.GetPointAt(slider.GetStartTime())
+	beatLength := point.GetBeatLength()
+
+	scoringPointDistance := baseScoringDistance * timings.SliderMult * velocityMultiplier / timings.TickRate
+	taikoVelocity := scoringPointDistance * timings.TickRate
+
+	taikoDuration = int(distance / taikoVelocity * beatLength)
+
+	if isNative {
+		return taikoDuration, 0, false
+	}
+
+	osuVelocity := taikoVelocity * float64(float32(1000)/float32(beatLength))
+
+	if beatMap.Version >= 8 {
+		beatLength = point.GetBaseBeatLength()
+	}
+
+	tickSpacing = math.Min(beatLength/timings.TickRate, float64(taikoDuration)/spans)
+
+	return taikoDuration, tickSpacing, tickSpacing > 0 && distance/osuVelocity*1000 < 2*beatLength
+}
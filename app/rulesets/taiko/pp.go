@@ -0,0 +1,66 @@
+package taiko
+
+import (
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"math"
+)
+
+// PPResults holds pp values calculated with osu!stable's taiko formula from before the 2022 rework
+type PPResults struct {
+	Strain float64
+	Acc    float64
+	Total  float64
+}
+
+// CalculatePP calculates performance of a play
+func CalculatePP(attribs Attributes, combo, countGreat, countOk, countMiss int, mods difficulty.Modifier) PPResults {
+	totalHits := countGreat + countOk + countMiss
+
+	accuracy := 0.0
+	if totalHits > 0 {
+		accuracy = (float64(countGreat) + float64(countOk)*0.5) / float64(totalHits)
+	}
+
+	multiplier := 1.1
+
+	if mods.Active(difficulty.NoFail) {
+		multiplier *= 0.9
+	}
+
+	if mods.Active(difficulty.Hidden) {
+		multiplier *= 1.1
+	}
+
+	strain := math.Pow(5.0*math.Max(1.0, attribs.Stars/0.0075)-4.0, 2.0) / 100000.0
+
+	lengthBonus := 1 + 0.1*math.Min(1.0, float64(totalHits)/1500.0)
+	strain *= lengthBonus
+	strain *= math.Pow(0.985, float64(countMiss))
+
+	if attribs.MaxCombo > 0 {
+		strain *= math.Min(math.Pow(float64(combo), 0.5)/math.Pow(float64(attribs.MaxCombo), 0.5), 1.0)
+	}
+
+	if mods.Active(difficulty.Hidden) {
+		strain *= 1.025
+	}
+
+	if mods.Active(difficulty.Flashlight) {
+		strain *= 1.05 * lengthBonus
+	}
+
+	strain *= accuracy
+
+	acc := 0.0
+
+	if attribs.GreatWindow > 0 {
+		acc = math.Pow(150.0/attribs.GreatWindow, 1.1) * math.Pow(accuracy, 15) * 22.0
+		acc *= math.Min(1.15, math.Pow(float64(totalHits)/1500.0, 0.3))
+	}
+
+	return PPResults{
+		Strain: strain,
+		Acc:    acc,
+		Total:  math.Pow(math.Pow(strain, 1.1)+math.Pow(acc, 1.1), 1.0/1.1) * multiplier,
+	}
+}
@@ -0,0 +1,592 @@
+package taiko
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/utils"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+// StrongHitWindow is the time in which the second key has to be pressed to get a strong bonus
+const StrongHitWindow = 30
+
+type Buttons uint8
+
+// Buttons are stored in replays in the same order: M1, M2, K1, K2
+const (
+	LeftCentre = Buttons(1 << iota)
+	LeftRim
+	RightCentre
+	RightRim
+	Centres = LeftCentre | RightCentre
+	Rims    = LeftRim | RightRim
+)
+
+func (b Buttons) IsCentre() bool {
+	return b&Centres > 0
+}
+
+func (b Buttons) IsRim() bool {
+	return b&Rims > 0
+}
+
+// ObjectState tells how far a player got with a given object
+type ObjectState struct {
+	Judged bool
+
+	// Result holds the main judgement of a don or kat
+	Result  HitResult
+	HitTime int64
+
+	// TicksHit is the number of drum roll ticks hit so far
+	TicksHit int
+
+	// SwellHits is the number of swell hits so far
+	SwellHits int
+}
+
+type objectState struct {
+	ObjectState
+
+	hitButton     Buttons
+	strongChecked bool
+	ticks         []bool
+	lastSwellHit  Buttons
+}
+
+type difficultyPlayer struct {
+	cursor  *graphics.Cursor
+	diff    *difficulty.Difficulty
+	buttons Buttons
+
+	greatWindow float64
+	okWindow    float64
+	missWindow  float64
+}
+
+type subSet struct {
+	player *difficultyPlayer
+
+	score          *Score
+	hp             *HealthProcessor
+	scoreProcessor *scoreV1Processor
+
+	states      []*objectState
+	firstActive int
+
+	rawAccuracy float64
+	numHits     uint
+
+	failed bool
+}
+
+type hitListener func(cursor *graphics.Cursor, time int64, number int64, result HitResult, comboResult ComboResult, pp PPResults, score int64)
+
+type pressListener func(cursor *graphics.Cursor, time int64, pressed Buttons)
+
+type failListener func(cursor *graphics.Cursor)
+
+type TaikoRuleSet struct {
+	beatMap *beatmap.BeatMap
+	objects []*Object
+
+	cursors   map[*graphics.Cursor]*subSet
+	cursorsOr []*graphics.Cursor
+
+	ended bool
+
+	attribs map[difficulty.Modifier][]Attributes
+
+	hitListeners   []hitListener
+	pressListeners []pressListener
+	failListener   failListener
+}
+
+func NewTaikoRuleset(beatMap *beatmap.BeatMap, cursors []*graphics.Cursor, mods []difficulty.Modifier) *TaikoRuleSet {
+	log.Println("Creating osu!taiko ruleset...")
+
+	ruleset := &TaikoRuleSet{
+		beatMap:   beatMap,
+		objects:   ConvertObjects(beatMap),
+		cursors:   make(map[*graphics.Cursor]*subSet),
+		cursorsOr: cursors,
+		attribs:   make(map[difficulty.Modifier][]Attributes),
+	}
+
+	if beatMap.Mode == 0 {
+		log.Println(fmt.Sprintf("Converted %d osu!standard objects to %d taiko objects", len(beatMap.HitObjects), len(ruleset.objects)))
+	}
+
+	for i, cursor := range cursors {
+		diff := difficulty.NewDifficulty(beatMap.Diff.GetBaseHP(), beatMap.Diff.GetBaseCS(), beatMap.Diff.GetBaseOD(), beatMap.Diff.GetBaseAR())
+
+		diff.SetHPCustom(beatMap.Diff.GetHP())
+		diff.SetODCustom(beatMap.Diff.GetOD())
+
+		diff.SetMods(mods[i])
+		diff.SetCustomSpeed(beatMap.Diff.CustomSpeed)
+		diff.SetRateAdjust(beatMap.Diff.RateAdjust)
+
+		mask := mods[i] & difficulty.DifficultyAdjustMask
+
+		if ruleset.attribs[mask] == nil {
+			ruleset.attribs[mask] = CalculateStep(ruleset.objects, diff)
+
+			if len(ruleset.attribs[mask]) > 0 {
+				star := ruleset.attribs[mask][len(ruleset.attribs[mask])-1]
+
+				log.Println("Stars:", star.Stars)
+
+				pp := CalculatePP(star, star.MaxCombo, star.MaxCombo, 0, 0, mods[i])
+
+				log.Println("SS PP:")
+				log.Println("\tStrain:", pp.Strain)
+				log.Println("\tAcc:   ", pp.Acc)
+				log.Println("\tTotal: ", pp.Total)
+			}
+		}
+
+		player := &difficultyPlayer{
+			cursor:      cursor,
+			diff:        diff,
+			greatWindow: math.Floor(difficulty.DifficultyRate(diff.ODMod, 50, 35, 20)),
+			okWindow:    math.Floor(difficulty.DifficultyRate(diff.ODMod, 120, 80, 50)),
+			missWindow:  math.Floor(difficulty.DifficultyRate(diff.ODMod, 135, 95, 70)),
+		}
+
+		states := make([]*objectState, len(ruleset.objects))
+
+		for j, o := range ruleset.objects {
+			states[j] = &objectState{
+				ticks: make([]bool, len(o.Ticks)),
+			}
+		}
+
+		ruleset.cursors[cursor] = &subSet{
+			player: player,
+			score: &Score{
+				Accuracy: 100,
+			},
+			hp:             NewHealthProcessor(ruleset.objects, diff),
+			scoreProcessor: newScoreV1Processor(beatMap, diff),
+			states:         states,
+		}
+	}
+
+	return ruleset
+}
+
+// UpdateInputFor processes button state of a player at a given time, has to be called in chronological order
+func (set *TaikoRuleSet) UpdateInputFor(cursor *graphics.Cursor, time int64, buttons Buttons) {
+	subSet := set.cursors[cursor]
+	player := subSet.player
+
+	pressed := buttons & (^player.buttons)
+
+	player.buttons = buttons
+
+	if pressed == 0 {
+		return
+	}
+
+	for _, listener := range set.pressListeners {
+		listener(cursor, time, pressed)
+	}
+
+	for _, button := range []Buttons{LeftCentre, LeftRim, RightCentre, RightRim} {
+		if pressed&button > 0 {
+			set.processPress(subSet, time, button)
+		}
+	}
+}
+
+func (set *TaikoRuleSet) processPress(subSet *subSet, time int64, button Buttons) {
+	player := subSet.player
+	relax := player.diff.CheckModActive(difficulty.Relax)
+
+	// Strong bonus is checked first so the second key doesn't trigger the next note
+	for i := subSet.firstActive; i < len(set.objects); i++ {
+		o, state := set.objects[i], subSet.states[i]
+
+		if float64(time) < o.StartTime-player.missWindow {
+			break
+		}
+
+		if !o.IsHit() || !o.Strong || !state.Judged || state.strongChecked || state.Result&BaseHits == 0 {
+			continue
+		}
+
+		if time-state.HitTime <= StrongHitWindow && button != state.hitButton && button.IsCentre() == state.hitButton.IsCentre() {
+			state.strongChecked = true
+
+			set.sendResult(subSet, time, o, StrongBonus, Hold)
+
+			return
+		}
+	}
+
+	for i := subSet.firstActive; i < len(set.objects); i++ {
+		o, state := set.objects[i], subSet.states[i]
+
+		if state.Judged {
+			continue
+		}
+
+		timeF := float64(time)
+
+		if timeF < o.StartTime-player.missWindow {
+			break
+		}
+
+		switch o.Type {
+		case Don, Kat:
+			offset := math.Abs(timeF - o.StartTime)
+
+			correct := relax || (o.Type == Don) == button.IsCentre()
+
+			result := Miss
+
+			if correct && offset < player.greatWindow {
+				result = Great
+			} else if correct && offset < player.okWindow {
+				result = Ok
+			}
+
+			state.Judged = true
+			state.Result = result
+			state.HitTime = time
+			state.hitButton = button
+
+			comboResult := Increase
+			if result == Miss {
+				comboResult = Reset
+			}
+
+			set.sendResult(subSet, time, o, result, comboResult)
+
+			return
+		case DrumRoll:
+			if timeF < o.StartTime || timeF > o.EndTime {
+				continue
+			}
+
+			for j, tick := range o.Ticks {
+				if !state.ticks[j] && math.Abs(timeF-tick) <= o.TickSpacing/2 {
+					state.ticks[j] = true
+					state.TicksHit++
+
+					set.sendResult(subSet, time, o, DrumRollTick, Hold)
+
+					break
+				}
+			}
+
+			return
+		case Swell:
+			if timeF < o.StartTime || timeF > o.EndTime {
+				continue
+			}
+
+			// Swell has to be hit alternating between centre and rim
+			if !relax && state.lastSwellHit != 0 && state.lastSwellHit.IsCentre() == button.IsCentre() {
+				return
+			}
+
+			state.lastSwellHit = button
+			state.SwellHits++
+
+			set.sendResult(subSet, time, o, SwellTick, Hold)
+
+			if state.SwellHits >= o.RequiredHits {
+				state.Judged = true
+				state.HitTime = time
+
+				set.sendResult(subSet, time, o, SwellBonus, Hold)
+			}
+
+			return
+		}
+	}
+}
+
+func (set *TaikoRuleSet) Update(time int64) {
+	timeF := float64(time)
+
+	for _, cursor := range set.cursorsOr {
+		subSet := set.cursors[cursor]
+		player := subSet.player
+
+		for i := subSet.firstActive; i < len(set.objects); i++ {
+			o, state := set.objects[i], subSet.states[i]
+
+			if o.StartTime-player.missWindow > timeF {
+				break
+			}
+
+			if !state.Judged {
+				switch o.Type {
+				case Don, Kat:
+					if timeF > o.StartTime+player.okWindow {
+						state.Judged = true
+						state.Result = Miss
+						state.HitTime = time
+
+						set.sendResult(subSet, time, o, Miss, Reset)
+					}
+				case DrumRoll, Swell:
+					if timeF > o.EndTime {
+						state.Judged = true
+						state.HitTime = time
+					}
+				}
+			}
+
+			if state.Judged && !state.strongChecked && (!o.Strong || !o.IsHit() || state.Result == Miss || time-state.HitTime > StrongHitWindow) {
+				state.strongChecked = true
+			}
+
+			if i == subSet.firstActive && state.Judged && state.strongChecked {
+				subSet.firstActive++
+			}
+		}
+	}
+
+	allDone := true
+
+	for _, subSet := range set.cursors {
+		if subSet.firstActive < len(set.objects) {
+			allDone = false
+			break
+		}
+	}
+
+	if allDone && !set.ended {
+		set.ended = true
+
+		for _, cursor := range set.cursorsOr {
+			subSet := set.cursors[cursor]
+
+			if !subSet.hp.HasPassed() && !subSet.player.diff.CheckModActive(difficulty.NoFail|difficulty.Relax) {
+				set.fail(subSet)
+			}
+		}
+
+		set.printResults()
+	}
+}
+
+func (set *TaikoRuleSet) sendResult(subSet *subSet, time int64, object *Object, result HitResult, comboResult ComboResult) {
+	player := subSet.player
+
+	if player.diff.CheckModActive(difficulty.SuddenDeath|difficulty.Perfect) && result == Miss {
+		set.fail(subSet)
+	}
+
+	if player.diff.CheckModActive(difficulty.Perfect) && result == Ok {
+		set.fail(subSet)
+	}
+
+	subSet.scoreProcessor.AddResult(result, comboResult, object.Strong)
+	subSet.hp.AddResult(result)
+
+	score := subSet.score
+	score.Score = subSet.scoreProcessor.GetScore()
+
+	if result&BaseHitsM > 0 {
+		switch result {
+		case Great:
+			score.CountGreat++
+			subSet.rawAccuracy += 1
+
+			if object.Strong {
+				score.CountGreatStrong++
+			}
+		case Ok:
+			score.CountOk++
+			subSet.rawAccuracy += 0.5
+
+			if object.Strong {
+				score.CountOkStrong++
+			}
+		case Miss:
+			score.CountMiss++
+		}
+
+		subSet.numHits++
+
+		score.Accuracy = 100 * subSet.rawAccuracy / float64(subSet.numHits)
+
+		score.Grade = calculateGrade(score, subSet.numHits, player.diff.Mods)
+
+		score.Combo = mutils.Max(uint(subSet.scoreProcessor.GetCombo()), score.Combo)
+
+		attribs := set.attribs[player.diff.Mods&difficulty.DifficultyAdjustMask]
+
+		if len(attribs) > 0 {
+			attrib := attribs[mutils.Min(int(subSet.numHits), len(attribs))-1]
+
+			score.PerfectCombo = uint(attrib.MaxCombo) == score.Combo
+			score.PP = CalculatePP(attrib, int(score.Combo), int(score.CountGreat), int(score.CountOk), int(score.CountMiss), player.diff.Mods)
+		}
+	}
+
+	for _, listener := range set.hitListeners {
+		listener(player.cursor, time, object.Number, result, comboResult, score.PP, score.Score)
+	}
+
+	if result&BaseHitsM > 0 && len(set.cursors) == 1 && !settings.RECORD && !settings.HEADLESS {
+		log.Println(fmt.Sprintf(
+			"Got: %6s, Combo: %4d, Max Combo: %4d, Score: %9d, Acc: %6.2f%%, 300: %4d, 100: %3d, miss: %2d, from: %d, at: %d, pp: %.2f",
+			result.String(),
+			subSet.scoreProcessor.GetCombo(),
+			score.Combo,
+			score.Score,
+			score.Accuracy,
+			score.CountGreat,
+			score.CountOk,
+			score.CountMiss,
+			object.Number,
+			time,
+			score.PP.Total,
+		))
+	}
+}
+
+func calculateGrade(score *Score, numHits uint, mods difficulty.Modifier) osu.Grade {
+	silver := mods&(difficulty.Hidden|difficulty.Flashlight) > 0
+
+	ratio := float64(score.CountGreat) / float64(numHits)
+
+	switch {
+	case score.CountGreat == numHits:
+		if silver {
+			return osu.SSH
+		}
+
+		return osu.SS
+	case ratio > 0.9 && score.CountMiss == 0:
+		if silver {
+			return osu.SH
+		}
+
+		return osu.S
+	case ratio > 0.8 && score.CountMiss == 0 || ratio > 0.9:
+		return osu.A
+	case ratio > 0.7 && score.CountMiss == 0 || ratio > 0.8:
+		return osu.B
+	case ratio > 0.6:
+		return osu.C
+	}
+
+	return osu.D
+}
+
+func (set *TaikoRuleSet) fail(subSet *subSet) {
+	if subSet.player.diff.CheckModActive(difficulty.NoFail|difficulty.Relax) || subSet.failed {
+		return
+	}
+
+	subSet.failed = true
+
+	if set.failListener != nil {
+		set.failListener(subSet.player.cursor)
+	}
+}
+
+func (set *TaikoRuleSet) printResults() {
+	cs := make([]*graphics.Cursor, len(set.cursorsOr))
+	copy(cs, set.cursorsOr)
+
+	sort.Slice(cs, func(i, j int) bool {
+		return set.cursors[cs[i]].score.Score > set.cursors[cs[j]].score.Score
+	})
+
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetHeader([]string{"#", "Player", "Score", "Accuracy", "Grade", "300", "100", "Miss", "Combo", "Max Combo", "Mods", "PP"})
+
+	for i, c := range cs {
+		subSet := set.cursors[c]
+
+		var data []string
+		data = append(data, fmt.Sprintf("%d", i+1))
+		data = append(data, c.Name)
+		data = append(data, utils.Humanize(subSet.score.Score))
+		data = append(data, fmt.Sprintf("%.2f", subSet.score.Accuracy))
+		data = append(data, subSet.score.Grade.String())
+		data = append(data, utils.Humanize(subSet.score.CountGreat))
+		data = append(data, utils.Humanize(subSet.score.CountOk))
+		data = append(data, utils.Humanize(subSet.score.CountMiss))
+		data = append(data, utils.Humanize(subSet.scoreProcessor.GetCombo()))
+		data = append(data, utils.Humanize(subSet.score.Combo))
+		data = append(data, subSet.player.diff.GetModString())
+		data = append(data, fmt.Sprintf("%.2f", subSet.score.PP.Total))
+		table.Append(data)
+	}
+
+	table.Render()
+
+	for _, s := range strings.Split(tableString.String(), "\n") {
+		log.Println(s)
+	}
+}
+
+func (set *TaikoRuleSet) AddListener(listener hitListener) {
+	set.hitListeners = append(set.hitListeners, listener)
+}
+
+// AddPressListener registers a listener called on every new key press, before it's judged
+func (set *TaikoRuleSet) AddPressListener(listener pressListener) {
+	set.pressListeners = append(set.pressListeners, listener)
+}
+
+func (set *TaikoRuleSet) SetFailListener(listener failListener) {
+	set.failListener = listener
+}
+
+func (set *TaikoRuleSet) GetScore(cursor *graphics.Cursor) Score {
+	return *(set.cursors[cursor].score)
+}
+
+func (set *TaikoRuleSet) GetCombo(cursor *graphics.Cursor) int64 {
+	return set.cursors[cursor].scoreProcessor.GetCombo()
+}
+
+func (set *TaikoRuleSet) GetHP(cursor *graphics.Cursor) float64 {
+	return set.cursors[cursor].hp.Health
+}
+
+func (set *TaikoRuleSet) IsFailed(cursor *graphics.Cursor) bool {
+	return set.cursors[cursor].failed
+}
+
+func (set *TaikoRuleSet) GetButtons(cursor *graphics.Cursor) Buttons {
+	return set.cursors[cursor].player.buttons
+}
+
+func (set *TaikoRuleSet) GetState(cursor *graphics.Cursor, number int64) ObjectState {
+	return set.cursors[cursor].states[number].ObjectState
+}
+
+// GetHitWindows returns Great, Ok and Miss windows for a given player
+func (set *TaikoRuleSet) GetHitWindows(cursor *graphics.Cursor) (great, ok, miss float64) {
+	player := set.cursors[cursor].player
+	return player.greatWindow, player.okWindow, player.missWindow
+}
+
+func (set *TaikoRuleSet) GetObjects() []*Object {
+	return set.objects
+}
+
+func (set *TaikoRuleSet) GetBeatMap() *beatmap.BeatMap {
+	return set.beatMap
+}
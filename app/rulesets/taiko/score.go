@@ -0,0 +1,88 @@
+package taiko
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"math"
+)
+
+type Score struct {
+	Score        int64
+	Accuracy     float64
+	Grade        osu.Grade
+	Combo        uint
+	PerfectCombo bool
+	CountGreat   uint
+	CountOk      uint
+	CountMiss    uint
+
+	// CountGreatStrong and CountOkStrong are stored in replays as geki and katu
+	CountGreatStrong uint
+	CountOkStrong    uint
+
+	PP PPResults
+}
+
+// scoreV1Processor follows osu!stable's scoring as simulated by lazer's TaikoLegacyScoreSimulator
+type scoreV1Processor struct {
+	score           int64
+	combo           int64
+	lastIncrease    int64
+	modMultiplier   float64
+	scoreMultiplier float64
+}
+
+func newScoreV1Processor(beatMap *beatmap.BeatMap, diff *difficulty.Difficulty) *scoreV1Processor {
+	s := &scoreV1Processor{
+		modMultiplier: diff.GetScoreMultiplier(),
+	}
+
+	pauses := int64(0)
+	for _, p := range beatMap.Pauses {
+		pauses += int64(p.GetEndTime() - p.GetStartTime())
+	}
+
+	drainTime := float32((int64(beatMap.HitObjects[len(beatMap.HitObjects)-1].GetEndTime()) - int64(beatMap.HitObjects[0].GetStartTime()) - pauses) / 1000)
+
+	s.scoreMultiplier = math.RoundToEven((float64(float32(beatMap.Diff.GetHP())) + float64(float32(beatMap.Diff.GetOD())) + float64(float32(beatMap.Diff.GetCS())) + float64(mutils.ClampF(float32(len(beatMap.HitObjects))/drainTime*8, 0, 16))) / 38 * 5)
+
+	return s
+}
+
+// AddResult adds judgement to the score, strong is used only by drum roll ticks which are worth double on strong rolls
+func (s *scoreV1Processor) AddResult(result HitResult, comboResult ComboResult, strong bool) {
+	switch result {
+	case Great, Ok:
+		value := result.ScoreValue()
+
+		s.lastIncrease = value + int64(float64(value/35)*2*(s.scoreMultiplier+1)*s.modMultiplier)*(mutils.Min(100, s.combo)/10)
+		s.score += s.lastIncrease
+	case StrongBonus:
+		// Second hit of a strong note doubles the score of the first one
+		s.score += s.lastIncrease
+	case DrumRollTick:
+		if strong {
+			s.score += result.ScoreValue() * 2
+		} else {
+			s.score += result.ScoreValue()
+		}
+	case SwellTick, SwellBonus:
+		s.score += result.ScoreValue()
+	}
+
+	if comboResult == Reset {
+		s.combo = 0
+	} else if comboResult == Increase {
+		s.combo++
+	}
+}
+
+func (s *scoreV1Processor) GetScore() int64 {
+	return s.score
+}
+
+func (s *scoreV1Processor) GetCombo() int64 {
+	return s.combo
+}
@@ -1,5 +1,10 @@
 package settings
 
+import (
+	"github.com/wieku/danser-go/framework/env"
+	"path/filepath"
+)
+
 var Gameplay = initGameplay()
 
 func initGameplay() *gameplay {
@@ -214,6 +219,9 @@ func initGameplay() *gameplay {
 		FlashlightDim:           1,
 		PlayUsername:            "Guest",
 		UseLazerPP:              false,
+		SaveReplays:             false,
+		SavedReplaysDir:         "saved-replays",
+		ManiaScrollSpeed:        20,
 	}
 }
 
@@ -232,6 +240,7 @@ type gameplay struct {
 	Boundaries              *boundaries
 	Underlay                *underlay
 	HUDFont                 string `file:"Select HUD font" filter:"TrueType/OpenType Font (*.ttf, *.otf)|ttf,otf"`
+	HUDLayout               string `file:"Select HUD layout" filter:"HUD layout (*.json)|json" tooltip:"Places, scales, rotates and orders HUD elements. Changes are applied while watching"`
 	ShowResultsScreen       bool
 	ResultsScreenTime       float64 `label:"Results screen duration" min:"1" max:"20" format:"%.1fs"`
 	ResultsUseLocalTimeZone bool    `label:"Show PC's time zone instead of UTC"`
@@ -240,6 +249,34 @@ type gameplay struct {
 	FlashlightDim           float64
 	PlayUsername            string
 	UseLazerPP              bool
+	SaveReplays             bool    `tooltip:"Saves -play sessions and danser's own plays as .osr replays"`
+	SavedReplaysDir         string  `path:"Select saved replays directory" showif:"SaveReplays=true"`
+	ManiaScrollSpeed        float64 `label:"osu!mania scroll speed" min:"1" max:"40" format:"%.0f"`
+
+	savedReplaysDir *string
+}
+
+func (g *gameplay) GetSavedReplaysDir() string {
+	if g.savedReplaysDir == nil {
+		dir := filepath.Join(env.DataDir(), g.SavedReplaysDir)
+
+		if filepath.IsAbs(g.SavedReplaysDir) {
+			dir = g.SavedReplaysDir
+		}
+
+		g.savedReplaysDir = &dir
+	}
+
+	return *g.savedReplaysDir
+}
+
+// GetHUDLayoutPath returns HUDLayout path, relative paths start in danser's directory
+func (g *gameplay) GetHUDLayoutPath() string {
+	if g.HUDLayout == "" || filepath.IsAbs(g.HUDLayout) {
+		return g.HUDLayout
+	}
+
+	return filepath.Join(env.DataDir(), g.HUDLayout)
 }
 
 type boundaries struct {
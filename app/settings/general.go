@@ -23,6 +23,9 @@ func initGeneral() *general {
 		OsuSkinsDir:       filepath.Join(osuBaseDir, "Skins"),
 		OsuReplaysDir:     filepath.Join(osuBaseDir, "Replays"),
 		DiscordPresenceOn: true,
+		APIEnabled:        false,
+		APIAddress:        "127.0.0.1:8091",
+		APIUpdateRate:     20,
 		UnpackOszFiles:    true,
 		VerboseImportLogs: false,
 	}
@@ -41,6 +44,15 @@ type general struct {
 	// Whether discord should show that danser is on
 	DiscordPresenceOn bool `label:"Discord Rich Presence"`
 
+	// Whether danser should publish live state and accept playback commands over HTTP and WebSocket, it's not available while recording
+	APIEnabled bool `label:"Remote control API"`
+
+	// Address of the API server, it doesn't have any authentication so it shouldn't be reachable from other machines
+	APIAddress string `label:"API address" showif:"APIEnabled=true"`
+
+	// How many times per second the state is sent to WebSocket clients
+	APIUpdateRate int `label:"API update rate" min:"1" max:"60" showif:"APIEnabled=true"`
+
 	// Whether danser should unpack .osz files in Songs folder, osu! may complain about it
 	UnpackOszFiles bool
 
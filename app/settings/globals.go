@@ -17,3 +17,14 @@ var TAG = 1
 var RECORD = false
 var REPLAY = ""
 var LOCALOFFSET = 0
+var HEADLESS = false
+var STORYBOARD = false
+var MODE = ModeOsu
+
+// Game modes, values are the same as the ones used in .osu and .osr files
+const (
+	ModeOsu = iota
+	ModeTaiko
+	ModeCatch
+	ModeMania
+)
@@ -7,6 +7,7 @@ func initPlayfield() *playfield {
 		DrawObjects:                  true,
 		DrawCursors:                  true,
 		Scale:                        1,
+		Layout:                       "auto",
 		OsuShift:                     false,
 		ShiftY:                       0,
 		ShiftX:                       0,
@@ -73,7 +74,8 @@ func initPlayfield() *playfield {
 type playfield struct {
 	DrawObjects                  bool
 	DrawCursors                  bool
-	Scale                        float64 `label:"Playfield scale" min:"0.1" max:"2"`   //1, scale the playfield (1 means that 384 will be rescaled to 900 on FullHD monitor)
+	Scale                        float64 `label:"Playfield scale" min:"0.1" max:"2"` //1, scale the playfield (1 means that 384 will be rescaled to 900 on FullHD monitor)
+	Layout                       string  `combo:"auto|Automatic,landscape|Landscape,portrait|Portrait (9:16)" tooltip:"Portrait makes the playfield as wide as possible and moves the HUD above and below it. Automatic picks it when the screen is taller than it's wide"`
 	OsuShift                     bool    `label:"Position the playfield like in osu!"` //false, offset the playfield like in osu! | Overrides ShiftY
 	playfieldShift               string  `vector:"true" label:"Playfield shift" left:"ShiftX" right:"ShiftY"`
 	ShiftX                       float64 `min:"-512" max:"512"` //offset the playfield by X osu!pixels
@@ -88,6 +90,15 @@ type playfield struct {
 	Bloom                        *bloom
 }
 
+// IsPortrait returns true if the playfield and HUD should be laid out for a vertical screen
+func (pl *playfield) IsPortrait() bool {
+	if pl.Layout == "auto" {
+		return Graphics.GetHeight() > Graphics.GetWidth()
+	}
+
+	return pl.Layout == "portrait"
+}
+
 type seizure struct {
 	// Whether seizure warning should be displayed before intro
 	Enabled bool
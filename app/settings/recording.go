@@ -14,7 +14,14 @@ func initRecording() *recording {
 		FrameHeight:    1080,
 		FPS:            60,
 		EncodingFPSCap: 0,
-		Encoder:        "libx264",
+		OutputType:     "video",
+		Transparency: &transparency{
+			Enabled:    false,
+			Background: false,
+			Storyboard: false,
+			Dim:        false,
+		},
+		Encoder: "libx264",
 		X264Settings: &x264Settings{
 			RateControl:       "crf",
 			Bitrate:           "10M",
@@ -60,6 +67,11 @@ func initRecording() *recording {
 			Preset:            "slow",
 			AdditionalOptions: "",
 		},
+		ProResSettings: &proresSettings{
+			Profile:           "4444",
+			QScale:            0,
+			AdditionalOptions: "",
+		},
 		CustomSettings: &custom{
 			CustomOptions: "",
 		},
@@ -91,6 +103,7 @@ func initRecording() *recording {
 		OutputDir:      "videos",
 		Container:      "mp4",
 		ShowFFmpegLogs: true,
+		HitEventLog:    "none",
 		MotionBlur: &motionblur{
 			Enabled:              false,
 			OversampleMultiplier: 3,
@@ -111,15 +124,18 @@ type recording struct {
 	FrameHeight         int                `min:"1" max:"17280"`
 	FPS                 int                `string:"true" min:"1" max:"10727"`
 	EncodingFPSCap      int                `string:"true" min:"0" max:"10727" label:"Max Encoding FPS (Speed)"`
-	Encoder             string             `combo:"libx264|Software x264 (AVC),libx265|Software x265 (HEVC),h264_nvenc|NVIDIA NVENC H.264 (AVC),hevc_nvenc|NVIDIA NVENC H.265 (HEVC),h264_qsv|Intel QuickSync H.264 (AVC),hevc_qsv|Intel QuickSync H.265 (HEVC),libvpx-vp9|VP9"`
+	OutputType          string             `combo:"video|Video,png|PNG Image Sequence,tiff|TIFF Image Sequence (8-bit data in 16-bit container)" tooltip:"Image sequences are saved to a folder together with a WAV of the audio, ffmpeg isn't used.\nFrames are rendered with 8 bits per channel, TIFF only stores them as 16-bit for software that expects it"`
+	Transparency        *transparency      `label:"Transparent Background" tooltip:"Records only gameplay elements so the video can be put over other footage. Requires an image sequence, ProRes 4444 or VP9"`
+	Encoder             string             `combo:"libx264|Software x264 (AVC),libx265|Software x265 (HEVC),h264_nvenc|NVIDIA NVENC H.264 (AVC),hevc_nvenc|NVIDIA NVENC H.265 (HEVC),h264_qsv|Intel QuickSync H.264 (AVC),hevc_qsv|Intel QuickSync H.265 (HEVC),libvpx-vp9|VP9,prores_ks|Apple ProRes"`
 	X264Settings        *x264Settings      `json:"libx264" label:"Software x264 (AVC) Settings" showif:"Encoder=libx264"`
 	X265Settings        *x265Settings      `json:"libx265" label:"Software x265 (HEVC) Settings" showif:"Encoder=libx265"`
 	H264NvencSettings   *h264NvencSettings `json:"h264_nvenc" label:"NVIDIA NVENC H.264 (AVC) Settings" showif:"Encoder=h264_nvenc"`
 	HEVCNvencSettings   *hevcNvencSettings `json:"hevc_nvenc" label:"NVIDIA NVENC H.265 (HEVC) Settings" showif:"Encoder=hevc_nvenc"`
 	H264QSVSettings     *h264QSVSettings   `json:"h264_qsv" label:"Intel QuickSync H.264 (AVC) Settings" showif:"Encoder=h264_qsv"`
 	HEVCQSVSettings     *hevcQSVSettings   `json:"hevc_qsv" label:"Intel QuickSync H.265 (HEVC) Settings" showif:"Encoder=hevc_qsv"`
+	ProResSettings      *proresSettings    `json:"prores_ks" label:"Apple ProRes Settings" showif:"Encoder=prores_ks"`
 	CustomSettings      *custom            `json:"custom" label:"Custom Encoder Settings" showif:"Encoder=!"`
-	PixelFormat         string             `combo:"yuv420p|I420,yuv444p|I444,nv12|NV12,nv21|NV21" showif:"Encoder=!h264_qsv,!hevc_qsv"`
+	PixelFormat         string             `combo:"yuv420p|I420,yuv444p|I444,nv12|NV12,nv21|NV21" showif:"Encoder=!h264_qsv,!hevc_qsv,!prores_ks"`
 	Filters             string             `label:"FFmpeg Video Filters"`
 	AudioCodec          string             `combo:"aac|AAC,libmp3lame|MP3,libopus|OPUS,flac|FLAC"`
 	AACSettings         *aacSettings       `json:"aac" label:"AAC Settings" showif:"AudioCodec=aac"`
@@ -130,8 +146,9 @@ type recording struct {
 	//AudioOptions        string             `label:"Audio Encoder Options"`
 	AudioFilters   string `label:"FFmpeg Audio Filters"`
 	OutputDir      string `path:"Select video output directory"`
-	Container      string `combo:"mp4,mkv,webm"`
+	Container      string `combo:"mp4,mkv,webm,mov"`
 	ShowFFmpegLogs bool
+	HitEventLog    string `combo:"none|Disabled,jsonl|JSON Lines,csv|CSV" tooltip:"Saves every judgement of every player next to the video (working directory when not recording), useful for further analysis"`
 	MotionBlur     *motionblur
 
 	outDir *string
@@ -151,6 +168,8 @@ func (g *recording) GetEncoderOptions() EncoderOptions {
 		return g.H264QSVSettings
 	case "hevc_qsv":
 		return g.HEVCQSVSettings
+	case "prores_ks":
+		return g.ProResSettings
 	default:
 		return g.CustomSettings
 	}
@@ -185,6 +204,41 @@ func (g *recording) GetOutputDir() string {
 	return *g.outDir
 }
 
+// IsImageSequence tells whether frames are saved as images instead of being encoded by ffmpeg
+func (g *recording) IsImageSequence() bool {
+	return g.OutputType == "png" || g.OutputType == "tiff"
+}
+
+// IsHitEventLogEnabled tells whether judgements are saved to a hit log
+func (g *recording) IsHitEventLogEnabled() bool {
+	return g.HitEventLog != "" && g.HitEventLog != "none"
+}
+
+// IsTransparent tells whether the current recording has a transparent background
+func (g *recording) IsTransparent() bool {
+	return RECORD && g.Transparency.Enabled
+}
+
+// DrawsBackground tells whether the background image is drawn, it's left out of transparent recordings unless enabled
+func (g *recording) DrawsBackground() bool {
+	return !g.IsTransparent() || g.Transparency.Background
+}
+
+func (g *recording) DrawsStoryboard() bool {
+	return !g.IsTransparent() || g.Transparency.Storyboard
+}
+
+func (g *recording) DrawsDim() bool {
+	return !g.IsTransparent() || g.Transparency.Dim
+}
+
+type transparency struct {
+	Enabled    bool
+	Background bool `label:"Draw Background" showif:"Enabled=true"`
+	Storyboard bool `label:"Draw Storyboard" showif:"Enabled=true"`
+	Dim        bool `label:"Draw Background Dim" showif:"Enabled=true" tooltip:"Darkens the footage below the recording as much as the background would be"`
+}
+
 type motionblur struct {
 	Enabled              bool
 	OversampleMultiplier int `string:"true" min:"1" max:"512"`
@@ -0,0 +1,47 @@
+package settings
+
+import (
+	"fmt"
+	"golang.org/x/exp/slices"
+	"strconv"
+)
+
+var proresProfiles = []string{
+	"proxy",
+	"lt",
+	"standard",
+	"hq",
+	"4444",
+	"4444xq",
+}
+
+type proresSettings struct {
+	Profile           string `combo:"proxy|Proxy,lt|LT,standard|Standard,hq|HQ,4444|4444,4444xq|4444 XQ" tooltip:"Only 4444 profiles keep transparency"`
+	QScale            int    `string:"true" min:"0" max:"32" label:"Quality (QScale)" tooltip:"Lower is better, 0 lets the encoder decide"`
+	AdditionalOptions string
+}
+
+func (s *proresSettings) GenerateFFmpegArgs() (ret []string, err error) {
+	if !slices.Contains(proresProfiles, s.Profile) {
+		return nil, fmt.Errorf("invalid profile: %s", s.Profile)
+	}
+
+	ret = append(ret, "-profile:v", s.Profile, "-vendor", "apl0")
+
+	if s.QScale < 0 || s.QScale > 32 {
+		return nil, fmt.Errorf("qscale out of range: %d", s.QScale)
+	}
+
+	if s.QScale > 0 {
+		ret = append(ret, "-qscale:v", strconv.Itoa(s.QScale))
+	}
+
+	ret = parseCustomOptions(ret, s.AdditionalOptions)
+
+	return
+}
+
+// HasAlpha tells whether the profile can store transparency
+func (s *proresSettings) HasAlpha() bool {
+	return s.Profile == "4444" || s.Profile == "4444xq"
+}
@@ -67,6 +67,8 @@ type SkinInfo struct {
 	//combo font settings
 	ComboPrefix  string
 	ComboOverlap float64
+
+	Mania []*ManiaInfo
 }
 
 func newDefaultInfo() *SkinInfo {
@@ -189,15 +191,40 @@ func LoadInfo(path string, local bool) (*SkinInfo, error) {
 
 	colorsI := make([]colorI, 0)
 
+	section := ""
+
+	var mania *ManiaInfo
+
 	for scanner.Scan() {
 		line := scanner.Text()
 
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = strings.TrimSuffix(strings.TrimPrefix(trimmed, "["), "]")
+			mania = nil
+
+			continue
+		}
+
 		tokenized := tokenize(line, ":")
 
 		if tokenized == nil {
 			continue
 		}
 
+		if section == "Mania" {
+			if tokenized[0] == "Keys" {
+				keys, err := strconv.Atoi(tokenized[1])
+				if err == nil && keys >= 1 && keys <= 18 {
+					mania = newDefaultManiaInfo(keys)
+					info.Mania = append(info.Mania, mania)
+				}
+			} else if mania != nil {
+				mania.parse(tokenized)
+			}
+
+			continue
+		}
+
 		switch tokenized[0] {
 		case "Name":
 			info.Name = tokenized[1]
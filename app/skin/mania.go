@@ -0,0 +1,260 @@
+package skin
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/framework/math/color"
+	"strconv"
+	"strings"
+)
+
+// ManiaInfo holds a single [Mania] section of skin.ini, positions are in osu!'s 640x480 coordinates
+type ManiaInfo struct {
+	Keys int
+
+	ColumnStart     float64
+	ColumnRight     float64
+	ColumnSpacing   []float64
+	ColumnWidth     []float64
+	ColumnLineWidth []float64
+
+	HitPosition   float64
+	LightPosition float64
+	ScorePosition float64
+	ComboPosition float64
+
+	JudgementLine  bool
+	UpsideDown     bool
+	KeysUnderNotes bool
+
+	Colours          []color.Color
+	ColoursLight     []color.Color
+	ColourColumnLine color.Color
+	ColourJudgement  color.Color
+	ColourHold       color.Color
+
+	KeyImages  []string
+	KeyImagesD []string
+
+	NoteImages  []string
+	NoteImagesH []string
+	NoteImagesL []string
+	NoteImagesT []string
+
+	StageLeft   string
+	StageRight  string
+	StageBottom string
+	StageHint   string
+	StageLight  string
+
+	Hit0    string
+	Hit50   string
+	Hit100  string
+	Hit200  string
+	Hit300  string
+	Hit300g string
+}
+
+func newDefaultManiaInfo(keys int) *ManiaInfo {
+	info := &ManiaInfo{
+		Keys:             keys,
+		ColumnStart:      136,
+		ColumnRight:      19,
+		HitPosition:      402,
+		LightPosition:    413,
+		ScorePosition:    325,
+		ComboPosition:    111,
+		JudgementLine:    true,
+		ColourColumnLine: color.NewL(1),
+		ColourJudgement:  color.NewL(1),
+		ColourHold:       color.NewIRGBA(255, 191, 51, 255),
+		StageLeft:        "mania-stage-left",
+		StageRight:       "mania-stage-right",
+		StageBottom:      "mania-stage-bottom",
+		StageHint:        "mania-stage-hint",
+		StageLight:       "mania-stage-light",
+		Hit0:             "mania-hit0",
+		Hit50:            "mania-hit50",
+		Hit100:           "mania-hit100",
+		Hit200:           "mania-hit200",
+		Hit300:           "mania-hit300",
+		Hit300g:          "mania-hit300g",
+	}
+
+	info.ColumnSpacing = make([]float64, keys)
+	info.ColumnWidth = make([]float64, keys)
+	info.ColumnLineWidth = make([]float64, keys+1)
+
+	info.Colours = make([]color.Color, keys)
+	info.ColoursLight = make([]color.Color, keys)
+
+	info.KeyImages = make([]string, keys)
+	info.KeyImagesD = make([]string, keys)
+	info.NoteImages = make([]string, keys)
+	info.NoteImagesH = make([]string, keys)
+	info.NoteImagesL = make([]string, keys)
+	info.NoteImagesT = make([]string, keys)
+
+	for i := 0; i < keys; i++ {
+		info.ColumnWidth[i] = 30
+		info.Colours[i] = color.NewLA(0, 1)
+		info.ColoursLight[i] = color.NewL(1)
+
+		suffix := ManiaColumnType(i, keys)
+
+		info.KeyImages[i] = "mania-key" + suffix
+		info.KeyImagesD[i] = "mania-key" + suffix + "D"
+		info.NoteImages[i] = "mania-note" + suffix
+		info.NoteImagesH[i] = "mania-note" + suffix + "H"
+		info.NoteImagesL[i] = "mania-note" + suffix + "L"
+		info.NoteImagesT[i] = "mania-note" + suffix + "T"
+	}
+
+	for i := range info.ColumnLineWidth {
+		info.ColumnLineWidth[i] = 2
+	}
+
+	return info
+}
+
+// ManiaColumnType returns the texture suffix used by osu!'s default mania skin for a given column: "1", "2" or "S" for the middle one
+func ManiaColumnType(column, keys int) string {
+	if keys%2 == 1 && column == keys/2 {
+		return "S"
+	}
+
+	if column >= (keys+1)/2 {
+		column = keys - 1 - column
+	}
+
+	if column%2 == 0 {
+		return "1"
+	}
+
+	return "2"
+}
+
+func (info *ManiaInfo) parse(tokenized []string) {
+	key, value := tokenized[0], tokenized[1]
+
+	switch key {
+	case "ColumnStart":
+		info.ColumnStart = ParseFloat(value, key)
+	case "ColumnRight":
+		info.ColumnRight = ParseFloat(value, key)
+	case "ColumnSpacing":
+		parseFloatList(info.ColumnSpacing, value, key)
+	case "ColumnWidth":
+		parseFloatList(info.ColumnWidth, value, key)
+	case "ColumnLineWidth":
+		parseFloatList(info.ColumnLineWidth, value, key)
+	case "HitPosition":
+		info.HitPosition = ParseFloat(value, key)
+	case "LightPosition":
+		info.LightPosition = ParseFloat(value, key)
+	case "ScorePosition":
+		info.ScorePosition = ParseFloat(value, key)
+	case "ComboPosition":
+		info.ComboPosition = ParseFloat(value, key)
+	case "JudgementLine":
+		info.JudgementLine = value == "1"
+	case "UpsideDown":
+		info.UpsideDown = value == "1"
+	case "KeysUnderNotes":
+		info.KeysUnderNotes = value == "1"
+	case "ColourColumnLine":
+		info.ColourColumnLine = ParseColor(value, key)
+	case "ColourJudgementLine":
+		info.ColourJudgement = ParseColor(value, key)
+	case "ColourHold":
+		info.ColourHold = ParseColor(value, key)
+	case "StageLeft":
+		info.StageLeft = value
+	case "StageRight":
+		info.StageRight = value
+	case "StageBottom":
+		info.StageBottom = value
+	case "StageHint":
+		info.StageHint = value
+	case "StageLight":
+		info.StageLight = value
+	case "Hit0":
+		info.Hit0 = value
+	case "Hit50":
+		info.Hit50 = value
+	case "Hit100":
+		info.Hit100 = value
+	case "Hit200":
+		info.Hit200 = value
+	case "Hit300":
+		info.Hit300 = value
+	case "Hit300g":
+		info.Hit300g = value
+	default:
+		info.parseColumnKey(key, value)
+	}
+}
+
+// parseColumnKey handles per-column keys like Colour1, ColourLight1 (1-indexed) or KeyImage0D, NoteImage0H (0-indexed)
+func (info *ManiaInfo) parseColumnKey(key, value string) {
+	for _, prefix := range []string{"ColourLight", "Colour"} {
+		if index, ok := parseIndex(key, prefix, ""); ok && index >= 1 && index <= info.Keys {
+			if prefix == "Colour" {
+				info.Colours[index-1] = ParseColor(value, key)
+			} else {
+				info.ColoursLight[index-1] = ParseColor(value, key)
+			}
+
+			return
+		}
+	}
+
+	images := []struct {
+		prefix, suffix string
+		target         []string
+	}{
+		{"KeyImage", "D", info.KeyImagesD},
+		{"KeyImage", "", info.KeyImages},
+		{"NoteImage", "H", info.NoteImagesH},
+		{"NoteImage", "L", info.NoteImagesL},
+		{"NoteImage", "T", info.NoteImagesT},
+		{"NoteImage", "", info.NoteImages},
+	}
+
+	for _, img := range images {
+		if index, ok := parseIndex(key, img.prefix, img.suffix); ok && index >= 0 && index < info.Keys {
+			img.target[index] = strings.ReplaceAll(value, "\\", "/")
+			return
+		}
+	}
+}
+
+func parseIndex(key, prefix, suffix string) (int, bool) {
+	if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, suffix) || len(key) <= len(prefix)+len(suffix) {
+		return 0, false
+	}
+
+	index, err := strconv.Atoi(key[len(prefix) : len(key)-len(suffix)])
+
+	return index, err == nil
+}
+
+func parseFloatList(target []float64, value, key string) {
+	for i, v := range strings.Split(value, ",") {
+		if i >= len(target) {
+			break
+		}
+
+		target[i] = ParseFloat(strings.TrimSpace(v), fmt.Sprintf("%s[%d]", key, i))
+	}
+}
+
+// GetManiaInfo returns skin's [Mania] section for a given key count, or osu!'s defaults if it's missing
+func (info *SkinInfo) GetManiaInfo(keys int) *ManiaInfo {
+	for _, m := range info.Mania {
+		if m.Keys == keys {
+			return m
+		}
+	}
+
+	return newDefaultManiaInfo(keys)
+}
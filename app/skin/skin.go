@@ -496,6 +496,12 @@ func FinishBeatmapColors() {
 	}
 }
 
+// ResetBeatmapColors removes combo colours of the previously loaded map
+func ResetBeatmapColors() {
+	beatmapColorsI = nil
+	beatmapColors = nil
+}
+
 func GetColors() []color.Color {
 	if settings.Skin.UseBeatmapColors && len(beatmapColors) > 0 {
 		return beatmapColors
@@ -0,0 +1,208 @@
+package states
+
+import (
+	"errors"
+	"github.com/wieku/danser-go/app/api"
+	"github.com/wieku/danser-go/app/dance"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/states/components/overlays"
+	"github.com/wieku/danser-go/framework/bass"
+	"time"
+)
+
+// Playback rates accepted by the speed command
+const (
+	minPlaybackRate = 0.1
+	maxPlaybackRate = 10.0
+)
+
+// queueCommand schedules a change requested through the API, it's applied by the update thread like seeking
+func (player *Player) queueCommand(fn func()) {
+	player.commandMutex.Lock()
+	player.commands = append(player.commands, fn)
+	player.commandMutex.Unlock()
+}
+
+func (player *Player) processCommands() {
+	player.commandMutex.Lock()
+	commands := player.commands
+	player.commands = nil
+	player.commandMutex.Unlock()
+
+	for _, fn := range commands {
+		fn()
+	}
+}
+
+func (player *Player) Pause() error {
+	player.apiMutex.Lock()
+	started := player.apiStarted
+	player.apiMutex.Unlock()
+
+	if !started {
+		return errors.New("music hasn't started yet")
+	}
+
+	if player.musicPlayer.GetState() != bass.MusicPlaying {
+		return errors.New("music is not playing")
+	}
+
+	player.queueCommand(player.musicPlayer.Pause)
+
+	return nil
+}
+
+func (player *Player) Resume() error {
+	if player.musicPlayer.GetState() != bass.MusicPaused {
+		return errors.New("music is not paused")
+	}
+
+	player.queueCommand(player.musicPlayer.Resume)
+
+	return nil
+}
+
+// Seek jumps to the given time in milliseconds, only replays can be seeked
+func (player *Player) Seek(time float64) error {
+	// Set before the update thread starts and never changed
+	if !player.seekable {
+		return errors.New("seeking is available only when watching replays")
+	}
+
+	player.apiMutex.Lock()
+	started := player.apiStarted
+	player.apiMutex.Unlock()
+
+	if !started {
+		return errors.New("music hasn't started yet")
+	}
+
+	player.queueCommand(func() {
+		player.requestSeek(time - player.progressMsF)
+	})
+
+	return nil
+}
+
+// SetSpeed changes playback rate on top of -speed and mods' rate, difficulty and pp are not affected
+func (player *Player) SetSpeed(speed float64) error {
+	if speed < minPlaybackRate || speed > maxPlaybackRate {
+		return errors.New("speed has to be between 0.1 and 10")
+	}
+
+	player.queueCommand(func() {
+		player.playbackRate = speed
+	})
+
+	return nil
+}
+
+func (player *Player) ToggleHUD() {
+	player.queueCommand(func() {
+		player.hudHidden = !player.hudHidden
+	})
+}
+
+// GetAPIState returns the last state built by the update thread
+func (player *Player) GetAPIState() api.State {
+	player.apiMutex.Lock()
+	defer player.apiMutex.Unlock()
+
+	return player.apiState
+}
+
+// updateAPIState builds the state published by the API, it has to be called from the update thread
+func (player *Player) updateAPIState(timeNano int64) {
+	if !settings.General.APIEnabled {
+		return
+	}
+
+	rate := settings.General.APIUpdateRate
+	if rate <= 0 {
+		rate = 1
+	}
+
+	// Twice the broadcast rate so clients don't get the same state twice
+	if player.apiLastUpdate != 0 && timeNano-player.apiLastUpdate < int64(time.Second)/int64(rate*2) {
+		return
+	}
+
+	player.apiLastUpdate = timeNano
+
+	status := api.StatusPlaying
+	if player.musicPlayer.GetState() == bass.MusicPaused {
+		status = api.StatusPaused
+	}
+
+	state := api.State{
+		Status: status,
+		Map: &api.MapState{
+			Artist:     player.bMap.Artist,
+			Title:      player.bMap.Name,
+			Difficulty: player.bMap.Difficulty,
+			Creator:    player.bMap.Creator,
+			MD5:        player.bMap.MD5,
+			ID:         player.bMap.ID,
+			SetID:      player.bMap.SetID,
+		},
+		Time:    player.progressMsF,
+		Length:  player.mapEndL,
+		Speed:   player.playbackRate,
+		HUD:     !player.hudHidden,
+		Players: player.getAPIPlayers(),
+	}
+
+	player.apiMutex.Lock()
+	player.apiState = state
+	player.apiStarted = player.start
+	player.apiMutex.Unlock()
+}
+
+// getAPIPlayers returns scores of judged cursors, players knocked out in knockout are marked as not alive
+func (player *Player) getAPIPlayers() []api.PlayerState {
+	players := make([]api.PlayerState, 0)
+
+	for _, cursor := range player.controller.GetCursors() {
+		var state api.PlayerState
+
+		switch controller := player.controller.(type) {
+		case *dance.PlayerController, *dance.ReplayController:
+			ruleset := player.GetRuleset()
+			if ruleset == nil {
+				return players
+			}
+
+			score := ruleset.GetScore(cursor)
+
+			state = api.PlayerState{Score: score.Score, Accuracy: score.Accuracy, Combo: int64(score.Combo), PP: score.PP.Total, HP: ruleset.GetHP(cursor)}
+		case *dance.TaikoController:
+			score := controller.GetRuleset().GetScore(cursor)
+
+			state = api.PlayerState{Score: score.Score, Accuracy: score.Accuracy, Combo: int64(score.Combo), PP: score.PP.Total, HP: controller.GetRuleset().GetHP(cursor)}
+		case *dance.CatchController:
+			score := controller.GetRuleset().GetScore(cursor)
+
+			state = api.PlayerState{Score: score.Score, Accuracy: score.Accuracy, Combo: int64(score.Combo), PP: score.PP.Total, HP: controller.GetRuleset().GetHP(cursor)}
+		case *dance.ManiaController:
+			// Mania has no pp calculation
+			score := controller.GetRuleset().GetScore(cursor)
+
+			state = api.PlayerState{Score: score.Score, Accuracy: score.Accuracy, Combo: int64(score.Combo), HP: controller.GetRuleset().GetHP(cursor)}
+		default:
+			// Cursor dances aren't judged
+			return players
+		}
+
+		state.Name = cursor.Name
+		state.Alive = true
+
+		// Other overlays use IsBroken only to hide the cursor, knockout is the only place players can drop out
+		if knockout, ok := player.overlay.(*overlays.KnockoutOverlay); ok {
+			state.Alive = !knockout.IsBroken(cursor)
+		}
+
+		players = append(players, state)
+	}
+
+	return players
+}
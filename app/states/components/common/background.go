@@ -3,8 +3,10 @@ package common
 import (
 	"github.com/EdlinOrg/prominentcolor"
 	"github.com/faiface/mainthread"
+	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/graphics"
 	"github.com/wieku/danser-go/app/graphics/gui/drawables"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/storyboard"
@@ -152,10 +154,19 @@ func (bg *Background) Update(time float64, x, y float64) {
 
 func project(pos vector.Vector2d, camera mgl32.Mat4) vector.Vector2d {
 	res := camera.Mul4x1(mgl32.Vec4{pos.X32(), pos.Y32(), 0.0, 1.0})
-	return vector.NewVec2d((float64(res[0])/2+0.5)*settings.Graphics.GetWidthF(), float64((res[1])/2+0.5)*settings.Graphics.GetWidthF())
+	return vector.NewVec2d((float64(res[0])/2+0.5)*settings.Graphics.GetWidthF(), float64((res[1])/2+0.5)*settings.Graphics.GetHeightF())
 }
 
 func (bg *Background) Draw(time float64, batch *batch.QuadBatch, blurVal, bgAlpha float64, camera mgl32.Mat4) {
+	dim := 1.0
+
+	// Transparent recordings draw dim as a separate layer so it darkens the footage below too
+	if settings.Recording.IsTransparent() {
+		dim, bgAlpha = bgAlpha, 1
+
+		defer bg.drawDim(batch, dim)
+	}
+
 	if bgAlpha < 0.01 {
 		return
 	}
@@ -199,6 +210,11 @@ func (bg *Background) Draw(time float64, batch *batch.QuadBatch, blurVal, bgAlph
 		if settings.Playfield.Background.Blur.Enabled {
 			bg.blur.SetBlur(blurVal, blurVal)
 			bg.blur.Begin()
+
+			if settings.Recording.IsTransparent() {
+				gl.ClearColor(0, 0, 0, 0)
+				gl.Clear(gl.COLOR_BUFFER_BIT)
+			}
 		} else {
 			opacity *= bgAlpha
 		}
@@ -209,7 +225,7 @@ func (bg *Background) Draw(time float64, batch *batch.QuadBatch, blurVal, bgAlph
 			viewport.PushScissorPos(clipX, clipY, clipW, clipH)
 		}
 
-		if bg.background != nil && (bg.storyboard == nil || !bg.storyboard.BGFileUsed()) {
+		if bg.background != nil && (bg.storyboard == nil || !bg.storyboard.BGFileUsed()) && settings.Recording.DrawsBackground() {
 			batch.SetCamera(mgl32.Ortho(float32(-settings.Graphics.GetWidthF()/2), float32(settings.Graphics.GetWidthF()/2), float32(settings.Graphics.GetHeightF()/2), float32(-settings.Graphics.GetHeightF()/2), 1, -1))
 			size := bg.scaling.Apply(float32(bg.background.GetWidth()), float32(bg.background.GetHeight()), float32(settings.Graphics.GetWidthF()), float32(settings.Graphics.GetHeightF())).Scl(0.5)
 
@@ -228,7 +244,7 @@ func (bg *Background) Draw(time float64, batch *batch.QuadBatch, blurVal, bgAlph
 			batch.SetColor(bgAlpha, bgAlpha, bgAlpha, 1)
 		}
 
-		if bg.storyboard != nil {
+		if bg.storyboard != nil && settings.Recording.DrawsStoryboard() {
 			batch.SetScale(1, 1)
 			batch.SetTranslation(vector.NewVec2d(0, 0))
 
@@ -243,7 +259,7 @@ func (bg *Background) Draw(time float64, batch *batch.QuadBatch, blurVal, bgAlph
 			bg.storyboard.Draw(time, batch)
 		}
 
-		if settings.Playfield.Background.Triangles.Enabled && !settings.Playfield.Background.Triangles.DrawOverBlur {
+		if settings.Playfield.Background.Triangles.Enabled && !settings.Playfield.Background.Triangles.DrawOverBlur && settings.Recording.DrawsBackground() {
 			bg.drawTriangles(batch, bgAlpha, settings.Playfield.Background.Blur.Enabled)
 		}
 
@@ -277,7 +293,7 @@ func (bg *Background) Draw(time float64, batch *batch.QuadBatch, blurVal, bgAlph
 		batch.ResetTransform()
 	}
 
-	if settings.Playfield.Background.Triangles.Enabled && settings.Playfield.Background.Triangles.DrawOverBlur {
+	if settings.Playfield.Background.Triangles.Enabled && settings.Playfield.Background.Triangles.DrawOverBlur && settings.Recording.DrawsBackground() {
 		bg.drawTriangles(batch, bgAlpha, false)
 	}
 
@@ -288,6 +304,23 @@ func (bg *Background) Draw(time float64, batch *batch.QuadBatch, blurVal, bgAlph
 	}
 }
 
+// drawDim darkens everything drawn before with a black layer
+func (bg *Background) drawDim(batch *batch.QuadBatch, dim float64) {
+	if dim > 0.99 || !settings.Recording.DrawsDim() {
+		return
+	}
+
+	batch.Begin()
+	batch.ResetTransform()
+	batch.SetAdditive(false)
+	batch.SetColor(0, 0, 0, 1-dim)
+	batch.SetCamera(mgl32.Ortho(-1, 1, -1, 1, 1, -1))
+	batch.DrawUnit(graphics.Pixel.GetRegion())
+	batch.End()
+
+	batch.SetColor(1, 1, 1, 1)
+}
+
 func (bg *Background) drawTriangles(batch *batch.QuadBatch, bgAlpha float64, blur bool) {
 	batch.ResetTransform()
 	cam := mgl32.Ortho(float32(-settings.Graphics.GetWidthF()/2), float32(settings.Graphics.GetWidthF()/2), float32(settings.Graphics.GetHeightF()/2), float32(-settings.Graphics.GetHeightF()/2), 1, -1)
@@ -310,10 +343,14 @@ func (bg *Background) drawTriangles(batch *batch.QuadBatch, bgAlpha float64, blu
 }
 
 func (bg *Background) DrawOverlay(time float64, batch *batch.QuadBatch, bgAlpha float64, camera mgl32.Mat4) {
-	if bgAlpha < 0.01 || bg.storyboard == nil {
+	if bgAlpha < 0.01 || bg.storyboard == nil || !settings.Recording.DrawsStoryboard() {
 		return
 	}
 
+	if !settings.Recording.DrawsDim() {
+		bgAlpha = 1
+	}
+
 	if !bg.storyboard.IsWideScreen() {
 		v1 := project(vector.NewVec2d(256-320, 192+240), camera)
 		v2 := project(vector.NewVec2d(256+320, 192-240), camera)
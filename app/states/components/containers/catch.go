@@ -0,0 +1,342 @@
+package containers
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/wieku/danser-go/app/audio"
+	camera2 "github.com/wieku/danser-go/app/bmath/camera"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/catch"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/framework/graphics/batch"
+	"github.com/wieku/danser-go/framework/graphics/texture"
+	color2 "github.com/wieku/danser-go/framework/math/color"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"log"
+	"math"
+)
+
+const (
+	// catchFruitSize is fruit's diameter in osu!pixels at circle size 5, same as osu!lazer
+	catchFruitSize = 64.0
+
+	catchDropletScale     = 0.8
+	catchTinyDropletScale = 0.4
+
+	// catchMissFade is the time in ms missed objects keep falling below the catcher
+	catchMissFade = 200.0
+
+	catchPlateHeight = 12.0
+)
+
+var (
+	catchFruitNames = [...]string{"fruit-pear", "fruit-grapes", "fruit-apple", "fruit-orange"}
+
+	bananaColor    = color2.NewIRGB(255, 240, 0)
+	hyperDashColor = color2.NewIRGB(255, 0, 0)
+)
+
+// CatchPlayfield renders falling osu!catch objects and catchers of all players, first player is drawn on top
+type CatchPlayfield struct {
+	ruleset *catch.CatchRuleSet
+	cursors []*graphics.Cursor
+
+	ScaledWidth  float64
+	ScaledHeight float64
+	camera       *camera2.Camera
+
+	// scale converts osu!pixels to playfield units
+	scale   float64
+	offsetX float64
+	offsetY float64
+
+	fruits        [4]*texture.TextureRegion
+	fruitOverlays [4]*texture.TextureRegion
+	droplet       *texture.TextureRegion
+	banana        *texture.TextureRegion
+
+	hitCircle        *texture.TextureRegion
+	hitCircleOverlay *texture.TextureRegion
+
+	catcherIdle *texture.TextureRegion
+	catcherKiai *texture.TextureRegion
+	catcherFail *texture.TextureRegion
+
+	started bool
+
+	countProcessed int
+}
+
+func NewCatchPlayfield(ruleset *catch.CatchRuleSet, cursors []*graphics.Cursor) *CatchPlayfield {
+	log.Println("Creating osu!catch playfield...")
+
+	playfield := &CatchPlayfield{
+		ruleset: ruleset,
+		cursors: cursors,
+	}
+
+	playfield.ScaledHeight = 768
+	playfield.ScaledWidth = settings.Graphics.GetAspectRatio() * playfield.ScaledHeight
+
+	playfield.camera = camera2.NewCamera()
+	playfield.camera.SetViewportF(0, int(playfield.ScaledHeight), int(playfield.ScaledWidth), 0)
+	playfield.camera.Update()
+
+	// Same playfield size as osu!standard one
+	playfield.scale = playfield.ScaledHeight * 0.8 / 384
+	playfield.offsetX = (playfield.ScaledWidth - catch.PlayfieldWidth*playfield.scale) / 2
+	playfield.offsetY = (playfield.ScaledHeight - 384*playfield.scale) / 2
+
+	for i, name := range catchFruitNames {
+		playfield.fruits[i] = skin.GetTexture(name)
+		playfield.fruitOverlays[i] = skin.GetTexture(name + "-overlay")
+	}
+
+	playfield.droplet = skin.GetTexture("fruit-drop")
+	playfield.banana = skin.GetTexture("fruit-bananas")
+
+	playfield.hitCircle = skin.GetTexture("hitcircle")
+	playfield.hitCircleOverlay = skin.GetTexture("hitcircleoverlay")
+
+	playfield.catcherIdle = skin.GetTexture("fruit-catcher-idle")
+	if playfield.catcherIdle == nil {
+		playfield.catcherIdle = skin.GetTexture("fruit-ryuuta")
+	}
+
+	playfield.catcherKiai = skin.GetTexture("fruit-catcher-kiai")
+	if playfield.catcherKiai == nil {
+		playfield.catcherKiai = playfield.catcherIdle
+	}
+
+	playfield.catcherFail = skin.GetTexture("fruit-catcher-fail")
+	if playfield.catcherFail == nil {
+		playfield.catcherFail = playfield.catcherIdle
+	}
+
+	ruleset.AddListener(playfield.hitReceived)
+
+	log.Println("Playfield created.")
+
+	return playfield
+}
+
+// hitReceived plays hitsounds of objects caught by the first player, osu!catch doesn't play them on misses
+func (playfield *CatchPlayfield) hitReceived(cursor *graphics.Cursor, time int64, object *catch.Object, result catch.HitResult, _ catch.ComboResult, _ int64) {
+	// Judgements made while skipping the intro shouldn't be heard
+	if cursor != playfield.cursors[0] || !playfield.started || !result.IsHit() {
+		return
+	}
+
+	point := playfield.ruleset.GetBeatMap().Timings.GetPointAt(object.StartTime)
+
+	sampleSet, index, volume := object.HitSound.SampleSet, object.HitSound.CustomIndex, point.SampleVolume
+
+	if sampleSet == 0 {
+		sampleSet = point.SampleSet
+	}
+
+	if index == 0 {
+		index = point.SampleIndex
+	}
+
+	if object.HitSound.CustomVolume > 0 {
+		volume = object.HitSound.CustomVolume
+	}
+
+	switch object.Type {
+	case catch.Fruit:
+		audio.PlaySample(sampleSet, object.HitSound.AdditionSet, object.Sample, index, volume, object.Source, object.EffectiveX())
+	case catch.Droplet:
+		audio.PlaySliderTick(sampleSet, index, volume, object.Source, object.EffectiveX())
+	}
+}
+
+func (playfield *CatchPlayfield) Update(_ float64) {
+	playfield.started = true
+}
+
+func (playfield *CatchPlayfield) Draw(batch *batch.QuadBatch, _ []mgl32.Mat4, time float64, _, alpha float32) {
+	if !settings.Playfield.DrawObjects {
+		return
+	}
+
+	alpha64 := float64(alpha)
+
+	batch.Begin()
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, alpha64)
+
+	prev := batch.Projection
+	batch.SetCamera(playfield.camera.GetProjectionView())
+
+	playfield.drawObjects(batch, time)
+
+	for i := len(playfield.cursors) - 1; i >= 0; i-- {
+		catcherAlpha := 1.0
+		if i > 0 {
+			catcherAlpha = 0.3
+		}
+
+		playfield.drawCatcher(batch, playfield.cursors[i], time, catcherAlpha)
+	}
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, 1)
+	batch.SetCamera(prev)
+	batch.End()
+}
+
+func (playfield *CatchPlayfield) toPlayfield(x, y float64) vector.Vector2d {
+	return vector.NewVec2d(playfield.offsetX+x*playfield.scale, playfield.offsetY+y*playfield.scale)
+}
+
+func (playfield *CatchPlayfield) drawObjects(batch *batch.QuadBatch, time float64) {
+	cursor := playfield.cursors[0]
+
+	objs := playfield.ruleset.GetObjects(cursor)
+	preempt := playfield.ruleset.GetBeatMap().Diff.Preempt
+
+	fruitScale := catchFruitSize * catch.CatcherScale(playfield.ruleset.GetBeatMap().Diff.CSMod) * playfield.scale
+
+	colors := skin.GetColors()
+
+	playfield.countProcessed = 0
+
+	// Objects are drawn in reverse so earlier ones end up on top
+	for i := len(objs) - 1; i >= 0; i-- {
+		o := objs[i]
+
+		if o.StartTime-preempt > time || o.StartTime+catchMissFade < time {
+			continue
+		}
+
+		result := playfield.ruleset.GetResult(cursor, o.Number)
+		if result.IsHit() {
+			continue
+		}
+
+		objAlpha := 1.0
+		if time > o.StartTime {
+			objAlpha = 1 - (time-o.StartTime)/catchMissFade
+		}
+
+		pos := playfield.toPlayfield(o.EffectiveX(), catch.CatcherY*(1-(o.StartTime-time)/preempt))
+
+		color := color2.NewL(1)
+		if len(colors) > 0 {
+			color = colors[int(o.ComboSet)%len(colors)]
+		}
+
+		color.A = float32(objAlpha)
+
+		switch o.Type {
+		case catch.Fruit:
+			playfield.drawFruit(batch, o, pos, fruitScale, color)
+		case catch.Droplet:
+			playfield.drawDroplet(batch, pos, fruitScale*catchDropletScale, color)
+		case catch.TinyDroplet:
+			playfield.drawDroplet(batch, pos, fruitScale*catchTinyDropletScale, color)
+		case catch.Banana:
+			if playfield.banana != nil {
+				batch.DrawStObject(pos, vector.Centre, vector.NewVec2d(1, 1).Scl(fruitScale/float64(playfield.banana.Width)), false, false, 0, color2.NewLA(1, color.A), false, *playfield.banana)
+			} else {
+				bColor := bananaColor
+				bColor.A = color.A
+
+				playfield.drawDroplet(batch, pos, fruitScale*catchDropletScale, bColor)
+			}
+		}
+
+		playfield.countProcessed++
+	}
+}
+
+func (playfield *CatchPlayfield) drawFruit(batch *batch.QuadBatch, o *catch.Object, pos vector.Vector2d, size float64, color color2.Color) {
+	if o.HyperDash {
+		glow := hyperDashColor
+		glow.A = color.A * 0.6
+
+		playfield.drawCircle(batch, pos, size*1.2, glow, true)
+	}
+
+	look := int(o.ComboSet) % len(playfield.fruits)
+
+	fruit, overlay := playfield.fruits[look], playfield.fruitOverlays[look]
+	if fruit == nil {
+		playfield.drawCircle(batch, pos, size, color, false)
+		return
+	}
+
+	batch.DrawStObject(pos, vector.Centre, vector.NewVec2d(1, 1).Scl(size/float64(fruit.Width)), false, false, 0, color, false, *fruit)
+
+	if overlay != nil {
+		batch.DrawStObject(pos, vector.Centre, vector.NewVec2d(1, 1).Scl(size/float64(overlay.Width)), false, false, 0, color2.NewLA(1, color.A), false, *overlay)
+	}
+}
+
+func (playfield *CatchPlayfield) drawDroplet(batch *batch.QuadBatch, pos vector.Vector2d, size float64, color color2.Color) {
+	if playfield.droplet != nil {
+		batch.DrawStObject(pos, vector.Centre, vector.NewVec2d(1, 1).Scl(size/float64(playfield.droplet.Width)), false, false, 0, color, false, *playfield.droplet)
+		return
+	}
+
+	playfield.drawCircle(batch, pos, size, color, false)
+}
+
+// drawCircle draws skin's hitcircle as a fallback for skins without osu!catch elements
+func (playfield *CatchPlayfield) drawCircle(batch *batch.QuadBatch, pos vector.Vector2d, size float64, color color2.Color, additive bool) {
+	if playfield.hitCircle == nil {
+		batch.DrawStObject(pos, vector.Centre, vector.NewVec2d(size, size).Scl(0.7), false, false, 0, color, additive, graphics.Pixel.GetRegion())
+		return
+	}
+
+	batch.DrawStObject(pos, vector.Centre, vector.NewVec2d(1, 1).Scl(size/float64(playfield.hitCircle.Width)), false, false, 0, color, additive, *playfield.hitCircle)
+
+	if playfield.hitCircleOverlay != nil && !additive {
+		batch.DrawStObject(pos, vector.Centre, vector.NewVec2d(1, 1).Scl(size/float64(playfield.hitCircleOverlay.Width)), false, false, 0, color2.NewLA(1, color.A), false, *playfield.hitCircleOverlay)
+	}
+}
+
+func (playfield *CatchPlayfield) drawCatcher(batch *batch.QuadBatch, cursor *graphics.Cursor, time, alpha float64) {
+	state := playfield.ruleset.GetCatcher(cursor)
+
+	// Catch width excludes plate's margins
+	width := playfield.ruleset.GetCatchWidth(cursor) / 0.8 * playfield.scale
+
+	pos := playfield.toPlayfield(state.X, catch.CatcherY)
+
+	color := color2.NewLA(1, float32(alpha))
+	if state.HyperDashing {
+		color = hyperDashColor
+		color.A = float32(alpha)
+	}
+
+	tex := playfield.catcherIdle
+
+	switch {
+	case state.LastResult&catch.Misses > 0:
+		tex = playfield.catcherFail
+	case playfield.ruleset.GetBeatMap().Timings.GetPointAt(time).Kiai:
+		tex = playfield.catcherKiai
+	}
+
+	if tex == nil {
+		batch.DrawStObject(pos, vector.TopCentre, vector.NewVec2d(width, catchPlateHeight*playfield.scale), false, false, 0, color, false, graphics.Pixel.GetRegion())
+		return
+	}
+
+	// Plate is at the top of catcher's sprite
+	batch.DrawStObject(pos, vector.TopCentre, vector.NewVec2d(1, 1).Scl(width/float64(tex.Width)), false, false, 0, color, false, *tex)
+
+	if state.HyperDashing {
+		trailPos := playfield.toPlayfield(state.X-math.Copysign(20, state.HyperDashTargetX-state.X), catch.CatcherY)
+
+		color.A *= 0.4
+
+		batch.DrawStObject(trailPos, vector.TopCentre, vector.NewVec2d(1, 1).Scl(width/float64(tex.Width)), false, false, 0, color, true, *tex)
+	}
+}
+
+func (playfield *CatchPlayfield) GetNumProcessed() int {
+	return playfield.countProcessed
+}
@@ -0,0 +1,12 @@
+package containers
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/wieku/danser-go/framework/graphics/batch"
+)
+
+type ObjectContainer interface {
+	Update(time float64)
+	Draw(batch *batch.QuadBatch, cameras []mgl32.Mat4, time float64, scale, alpha float32)
+	GetNumProcessed() int
+}
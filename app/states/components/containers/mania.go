@@ -0,0 +1,465 @@
+package containers
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	camera2 "github.com/wieku/danser-go/app/bmath/camera"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/mania"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/framework/graphics/batch"
+	"github.com/wieku/danser-go/framework/graphics/sprite"
+	"github.com/wieku/danser-go/framework/graphics/texture"
+	"github.com/wieku/danser-go/framework/math/animation"
+	"github.com/wieku/danser-go/framework/math/animation/easing"
+	color2 "github.com/wieku/danser-go/framework/math/color"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"log"
+	"math"
+)
+
+const (
+	// maniaScale converts skin.ini's 480 height units to playfield units
+	maniaScale = 768.0 / 480
+
+	// maniaScrollBase is the time in ms a note needs to travel to the hit position at scroll speed 1, same as osu!lazer
+	maniaScrollBase = 11485.0
+
+	maniaNoteHeight = 12.0
+	maniaKeyFade    = 100.0
+)
+
+var (
+	maniaColumnColors = map[string]color2.Color{
+		"1": color2.NewL(0.9),
+		"2": color2.NewIRGB(82, 158, 245),
+		"S": color2.NewIRGB(245, 199, 66),
+	}
+)
+
+type maniaColumn struct {
+	x, width float64
+
+	color color2.Color
+
+	key, keyD  *texture.TextureRegion
+	note, head *texture.TextureRegion
+	body, tail *texture.TextureRegion
+
+	lastPress float64
+}
+
+// ManiaPlayfield renders osu!mania columns configured by skin's [Mania] section
+type ManiaPlayfield struct {
+	ruleset *mania.ManiaRuleSet
+	cursor  *graphics.Cursor
+
+	ScaledWidth  float64
+	ScaledHeight float64
+	camera       *camera2.Camera
+
+	info    *skin.ManiaInfo
+	columns []*maniaColumn
+
+	stageX, stageWidth float64
+	hitY               float64
+
+	stageLeft, stageRight *texture.TextureRegion
+	stageHint, stageLight *texture.TextureRegion
+
+	judgement *sprite.Sprite
+
+	countProcessed int
+}
+
+func NewManiaPlayfield(ruleset *mania.ManiaRuleSet, cursor *graphics.Cursor) *ManiaPlayfield {
+	log.Println("Creating osu!mania playfield...")
+
+	playfield := &ManiaPlayfield{
+		ruleset: ruleset,
+		cursor:  cursor,
+	}
+
+	playfield.ScaledHeight = 768
+	playfield.ScaledWidth = settings.Graphics.GetAspectRatio() * playfield.ScaledHeight
+
+	playfield.camera = camera2.NewCamera()
+	playfield.camera.SetViewportF(0, int(playfield.ScaledHeight), int(playfield.ScaledWidth), 0)
+	playfield.camera.Update()
+
+	keys := ruleset.GetKeys()
+
+	info := skin.GetInfo().GetManiaInfo(keys)
+	playfield.info = info
+
+	for i := 0; i < keys; i++ {
+		playfield.stageWidth += info.ColumnWidth[i]
+
+		if i < keys-1 {
+			playfield.stageWidth += info.ColumnSpacing[i]
+		}
+	}
+
+	playfield.stageWidth *= maniaScale
+
+	// osu! places the stage using ColumnStart in 4:3, danser keeps it centred on every aspect ratio
+	playfield.stageX = (playfield.ScaledWidth - playfield.stageWidth) / 2
+
+	playfield.hitY = info.HitPosition * maniaScale
+	if info.UpsideDown {
+		playfield.hitY = playfield.ScaledHeight - playfield.hitY
+	}
+
+	x := playfield.stageX
+
+	for i := 0; i < keys; i++ {
+		column := &maniaColumn{
+			x:         x,
+			width:     info.ColumnWidth[i] * maniaScale,
+			color:     maniaColumnColors[skin.ManiaColumnType(i, keys)],
+			key:       skin.GetTexture(info.KeyImages[i]),
+			keyD:      skin.GetTexture(info.KeyImagesD[i]),
+			note:      skin.GetTexture(info.NoteImages[i]),
+			head:      skin.GetTexture(info.NoteImagesH[i]),
+			body:      skin.GetTexture(info.NoteImagesL[i]),
+			tail:      skin.GetTexture(info.NoteImagesT[i]),
+			lastPress: math.Inf(-1),
+		}
+
+		if column.head == nil {
+			column.head = column.note
+		}
+
+		playfield.columns = append(playfield.columns, column)
+
+		x += column.width
+
+		if i < keys-1 {
+			x += info.ColumnSpacing[i] * maniaScale
+		}
+	}
+
+	playfield.stageLeft = skin.GetTexture(info.StageLeft)
+	playfield.stageRight = skin.GetTexture(info.StageRight)
+	playfield.stageHint = skin.GetTexture(info.StageHint)
+	playfield.stageLight = skin.GetTexture(info.StageLight)
+
+	ruleset.AddListener(playfield.hitReceived)
+	ruleset.AddPressListener(playfield.pressReceived)
+
+	log.Println("Playfield created.")
+
+	return playfield
+}
+
+func (playfield *ManiaPlayfield) hitReceived(cursor *graphics.Cursor, time int64, _ int64, _ int, result mania.HitResult, _ mania.ComboResult, _ int64) {
+	if cursor != playfield.cursor || result&mania.BaseHitsM == 0 {
+		return
+	}
+
+	var name, fallback string
+
+	switch result {
+	case mania.HitMax:
+		name, fallback = playfield.info.Hit300g, "hit300g"
+	case mania.Hit300:
+		name, fallback = playfield.info.Hit300, "hit300"
+	case mania.Hit200:
+		name, fallback = playfield.info.Hit200, "hit100"
+	case mania.Hit100:
+		name, fallback = playfield.info.Hit100, "hit100"
+	case mania.Hit50:
+		name, fallback = playfield.info.Hit50, "hit50"
+	case mania.Miss:
+		name, fallback = playfield.info.Hit0, "hit0"
+	}
+
+	tex := skin.GetTexture(name)
+	if tex == nil {
+		tex = skin.GetTexture(fallback)
+	}
+
+	if tex == nil {
+		return
+	}
+
+	startTime := float64(time)
+
+	y := playfield.info.ScorePosition * maniaScale
+	if playfield.info.UpsideDown {
+		y = playfield.ScaledHeight - y
+	}
+
+	// osu!mania shows only the latest judgement
+	hit := sprite.NewSpriteSingle(tex, startTime, vector.NewVec2d(playfield.stageX+playfield.stageWidth/2, y), vector.Centre)
+	hit.AddTransform(animation.NewSingleTransform(animation.Scale, easing.OutQuad, startTime, startTime+80, 0.8*maniaScale, maniaScale))
+	hit.AddTransform(animation.NewSingleTransform(animation.Fade, easing.Linear, startTime+200, startTime+300, 1.0, 0.0))
+	hit.SortTransformations()
+	hit.AdjustTimesToTransformations()
+	hit.ResetValuesToTransforms()
+
+	playfield.judgement = hit
+}
+
+func (playfield *ManiaPlayfield) pressReceived(cursor *graphics.Cursor, time int64, column int, _ *mania.Object) {
+	if cursor != playfield.cursor {
+		return
+	}
+
+	playfield.columns[column].lastPress = float64(time)
+}
+
+func (playfield *ManiaPlayfield) Update(time float64) {
+	if playfield.judgement != nil {
+		playfield.judgement.Update(time)
+	}
+}
+
+func (playfield *ManiaPlayfield) Draw(batch *batch.QuadBatch, _ []mgl32.Mat4, time float64, _, alpha float32) {
+	if !settings.Playfield.DrawObjects {
+		return
+	}
+
+	alpha64 := float64(alpha)
+
+	batch.Begin()
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, alpha64)
+
+	prev := batch.Projection
+	batch.SetCamera(playfield.camera.GetProjectionView())
+
+	playfield.drawStage(batch, time)
+
+	if playfield.info.KeysUnderNotes {
+		playfield.drawKeys(batch, time)
+	}
+
+	playfield.drawObjects(batch, time)
+
+	if !playfield.info.KeysUnderNotes {
+		playfield.drawKeys(batch, time)
+	}
+
+	if playfield.judgement != nil {
+		playfield.judgement.Draw(time, batch)
+	}
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, 1)
+	batch.SetCamera(prev)
+	batch.End()
+}
+
+// getY returns the position of the bottom edge of a note, which is the edge touching hit position
+func (playfield *ManiaPlayfield) getY(objTime, time float64) float64 {
+	speed := playfield.hitY * settings.Gameplay.ManiaScrollSpeed / maniaScrollBase
+
+	if playfield.info.UpsideDown {
+		return playfield.hitY + (objTime-time)*speed
+	}
+
+	return playfield.hitY - (objTime-time)*speed
+}
+
+func (playfield *ManiaPlayfield) drawStage(batch *batch.QuadBatch, time float64) {
+	pixel := graphics.Pixel.GetRegion()
+	info := playfield.info
+
+	columns := playfield.ruleset.GetColumns(playfield.cursor)
+
+	for i, column := range playfield.columns {
+		batch.DrawStObject(vector.NewVec2d(column.x, 0), vector.TopLeft, vector.NewVec2d(column.width, playfield.ScaledHeight), false, false, 0, info.Colours[i], false, pixel)
+
+		lightAlpha := 1.0
+		if !columns.IsPressed(i) {
+			lightAlpha = 1 - (time-column.lastPress)/maniaKeyFade
+		}
+
+		if lightAlpha > 0 && lightAlpha <= 1 {
+			light := info.ColoursLight[i]
+			light.A *= float32(lightAlpha)
+
+			if playfield.stageLight != nil {
+				lightY := info.LightPosition * maniaScale
+				origin := vector.BottomLeft
+
+				if info.UpsideDown {
+					lightY = playfield.ScaledHeight - lightY
+					origin = vector.TopLeft
+				}
+
+				batch.DrawStObject(vector.NewVec2d(column.x, lightY), origin, vector.NewVec2d(1, 1).Scl(column.width/float64(playfield.stageLight.Width)), false, info.UpsideDown, 0, light, false, *playfield.stageLight)
+			} else {
+				light.A *= 0.2
+				batch.DrawStObject(vector.NewVec2d(column.x, 0), vector.TopLeft, vector.NewVec2d(column.width, playfield.ScaledHeight), false, false, 0, light, false, pixel)
+			}
+		}
+	}
+
+	for i, lineWidth := range info.ColumnLineWidth {
+		if lineWidth <= 0 || i > len(playfield.columns) {
+			continue
+		}
+
+		x := playfield.stageX + playfield.stageWidth
+		if i < len(playfield.columns) {
+			x = playfield.columns[i].x
+		}
+
+		batch.DrawStObject(vector.NewVec2d(x, 0), vector.TopCentre, vector.NewVec2d(lineWidth*maniaScale/2, playfield.ScaledHeight), false, false, 0, info.ColourColumnLine, false, pixel)
+	}
+
+	if playfield.stageLeft != nil {
+		batch.DrawStObject(vector.NewVec2d(playfield.stageX, 0), vector.TopRight, vector.NewVec2d(1, playfield.ScaledHeight/float64(playfield.stageLeft.Height)), false, false, 0, color2.NewL(1), false, *playfield.stageLeft)
+	}
+
+	if playfield.stageRight != nil {
+		batch.DrawStObject(vector.NewVec2d(playfield.stageX+playfield.stageWidth, 0), vector.TopLeft, vector.NewVec2d(1, playfield.ScaledHeight/float64(playfield.stageRight.Height)), false, false, 0, color2.NewL(1), false, *playfield.stageRight)
+	}
+
+	if playfield.stageHint != nil {
+		batch.DrawStObject(vector.NewVec2d(playfield.stageX, playfield.hitY), vector.CentreLeft, vector.NewVec2d(playfield.stageWidth/float64(playfield.stageHint.Width), maniaScale), false, info.UpsideDown, 0, color2.NewL(1), false, *playfield.stageHint)
+	} else if info.JudgementLine {
+		batch.DrawStObject(vector.NewVec2d(playfield.stageX, playfield.hitY), vector.CentreLeft, vector.NewVec2d(playfield.stageWidth, 2), false, false, 0, info.ColourJudgement, false, pixel)
+	}
+}
+
+func (playfield *ManiaPlayfield) drawKeys(batch *batch.QuadBatch, time float64) {
+	columns := playfield.ruleset.GetColumns(playfield.cursor)
+
+	// Keys are placed at the bottom of the stage, or at the top when it's upside down
+	keyY, origin := playfield.ScaledHeight, vector.BottomLeft
+	if playfield.info.UpsideDown {
+		keyY, origin = 0, vector.TopLeft
+	}
+
+	for i, column := range playfield.columns {
+		pressed := columns.IsPressed(i)
+
+		tex := column.key
+		if pressed && column.keyD != nil {
+			tex = column.keyD
+		}
+
+		if tex != nil {
+			batch.DrawStObject(vector.NewVec2d(column.x, keyY), origin, vector.NewVec2d(1, 1).Scl(column.width/float64(tex.Width)), false, playfield.info.UpsideDown, 0, color2.NewL(1), false, *tex)
+			continue
+		}
+
+		color := column.color
+		color.A = 0.25
+
+		if pressed {
+			color.A = 0.8
+		} else if keyAlpha := 1 - (time-column.lastPress)/maniaKeyFade; keyAlpha > 0 && keyAlpha <= 1 {
+			color.A += float32(keyAlpha) * 0.55
+		}
+
+		height := math.Abs(playfield.ScaledHeight - keyY - playfield.hitY)
+
+		batch.DrawStObject(vector.NewVec2d(column.x, keyY), origin, vector.NewVec2d(column.width, height), false, false, 0, color, false, graphics.Pixel.GetRegion())
+	}
+}
+
+func (playfield *ManiaPlayfield) drawObjects(batch *batch.QuadBatch, time float64) {
+	objs := playfield.ruleset.GetObjects()
+
+	playfield.countProcessed = 0
+
+	for i := len(objs) - 1; i >= 0; i-- {
+		o := objs[i]
+
+		startY := playfield.getY(o.StartTime, time)
+		endY := playfield.getY(o.EndTime, time)
+
+		if math.Min(startY, endY) > playfield.ScaledHeight || math.Max(startY, endY) < 0 {
+			continue
+		}
+
+		state := playfield.ruleset.GetState(playfield.cursor, o.Number)
+		column := playfield.columns[o.Column]
+
+		color := color2.NewL(1)
+		if state.HeadJudged && state.HeadResult == mania.Miss {
+			color = color2.NewL(0.5)
+		}
+
+		if !o.IsHold {
+			if state.HeadJudged && state.HeadResult != mania.Miss {
+				continue
+			}
+
+			playfield.drawNote(batch, column, column.note, startY, color)
+			playfield.countProcessed++
+
+			continue
+		}
+
+		if state.TailJudged && state.TailResult != mania.Miss {
+			continue
+		}
+
+		if state.Holding {
+			startY = playfield.hitY
+		} else if state.HeadJudged && state.HeadResult != mania.Miss {
+			// Released too early
+			color = color2.NewL(0.5)
+		}
+
+		playfield.drawHold(batch, column, startY, endY, color)
+		playfield.countProcessed++
+	}
+}
+
+func (playfield *ManiaPlayfield) drawNote(batch *batch.QuadBatch, column *maniaColumn, tex *texture.TextureRegion, y float64, color color2.Color) {
+	origin := vector.BottomLeft
+	if playfield.info.UpsideDown {
+		origin = vector.TopLeft
+	}
+
+	if tex != nil {
+		batch.DrawStObject(vector.NewVec2d(column.x, y), origin, vector.NewVec2d(1, 1).Scl(column.width/float64(tex.Width)), false, playfield.info.UpsideDown, 0, color, false, *tex)
+		return
+	}
+
+	color.R *= column.color.R
+	color.G *= column.color.G
+	color.B *= column.color.B
+
+	batch.DrawStObject(vector.NewVec2d(column.x, y), origin, vector.NewVec2d(column.width, maniaNoteHeight*maniaScale), false, false, 0, color, false, graphics.Pixel.GetRegion())
+}
+
+func (playfield *ManiaPlayfield) drawHold(batch *batch.QuadBatch, column *maniaColumn, startY, endY float64, color color2.Color) {
+	top, bottom := math.Min(startY, endY), math.Max(startY, endY)
+
+	if column.body != nil {
+		batch.DrawStObject(vector.NewVec2d(column.x, top), vector.TopLeft, vector.NewVec2d(column.width/float64(column.body.Width), (bottom-top)/float64(column.body.Height)), false, playfield.info.UpsideDown, 0, color, false, *column.body)
+	} else {
+		bodyColor := playfield.info.ColourHold
+		bodyColor.R *= color.R
+		bodyColor.G *= color.G
+		bodyColor.B *= color.B
+		bodyColor.A *= 0.8
+
+		batch.DrawStObject(vector.NewVec2d(column.x+column.width*0.1, top), vector.TopLeft, vector.NewVec2d(column.width*0.8, bottom-top), false, false, 0, bodyColor, false, graphics.Pixel.GetRegion())
+	}
+
+	if column.tail != nil {
+		// Tail is the head flipped to the other side
+		origin := vector.TopLeft
+		if playfield.info.UpsideDown {
+			origin = vector.BottomLeft
+		}
+
+		batch.DrawStObject(vector.NewVec2d(column.x, endY), origin, vector.NewVec2d(1, 1).Scl(column.width/float64(column.tail.Width)), false, !playfield.info.UpsideDown, 0, color, false, *column.tail)
+	} else {
+		playfield.drawNote(batch, column, column.head, endY, color)
+	}
+
+	playfield.drawNote(batch, column, column.head, startY, color)
+}
+
+func (playfield *ManiaPlayfield) GetNumProcessed() int {
+	return playfield.countProcessed
+}
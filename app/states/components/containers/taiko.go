@@ -0,0 +1,398 @@
+package containers
+
+import (
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	camera2 "github.com/wieku/danser-go/app/bmath/camera"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/taiko"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/framework/graphics/batch"
+	"github.com/wieku/danser-go/framework/graphics/font"
+	"github.com/wieku/danser-go/framework/graphics/sprite"
+	"github.com/wieku/danser-go/framework/graphics/texture"
+	"github.com/wieku/danser-go/framework/math/animation"
+	"github.com/wieku/danser-go/framework/math/animation/easing"
+	color2 "github.com/wieku/danser-go/framework/math/color"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"log"
+	"math"
+)
+
+const (
+	taikoLaneY      = 240.0
+	taikoLaneHeight = 160.0
+	taikoDrumWidth  = 180.0
+	taikoHitX       = 260.0
+	taikoNoteSize   = 96.0
+	taikoStrongSize = taikoNoteSize * 1.5
+
+	// taikoScrollScale converts osu!pixels to playfield units, same as stable's 640x480 to 768 height scaling
+	taikoScrollScale = 1.6
+
+	taikoKeyFade = 120.0
+)
+
+var (
+	donColor   = color2.NewIRGB(235, 69, 44)
+	katColor   = color2.NewIRGB(67, 142, 172)
+	rollColor  = color2.NewIRGB(252, 184, 6)
+	swellColor = color2.NewIRGB(240, 130, 30)
+)
+
+// TaikoPlayfield renders osu!taiko objects scrolling towards the hit target
+type TaikoPlayfield struct {
+	ruleset *taiko.TaikoRuleSet
+	cursor  *graphics.Cursor
+
+	ScaledWidth  float64
+	ScaledHeight float64
+	camera       *camera2.Camera
+
+	hitCircle        *texture.TextureRegion
+	hitCircleOverlay *texture.TextureRegion
+	bigCircle        *texture.TextureRegion
+	bigCircleOverlay *texture.TextureRegion
+	rollMiddle       *texture.TextureRegion
+	rollEnd          *texture.TextureRegion
+	approachCircle   *texture.TextureRegion
+	drumInner        *texture.TextureRegion
+	drumOuter        *texture.TextureRegion
+	barLeft          *texture.TextureRegion
+	barRight         *texture.TextureRegion
+
+	numberFont *font.Font
+
+	results *sprite.Manager
+
+	lastPresses [4]float64
+
+	countProcessed int
+	lastTime       float64
+}
+
+func NewTaikoPlayfield(ruleset *taiko.TaikoRuleSet, cursor *graphics.Cursor) *TaikoPlayfield {
+	log.Println("Creating osu!taiko playfield...")
+
+	playfield := &TaikoPlayfield{
+		ruleset:     ruleset,
+		cursor:      cursor,
+		results:     sprite.NewManager(),
+		lastPresses: [4]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1), math.Inf(-1)},
+	}
+
+	playfield.ScaledHeight = 768
+	playfield.ScaledWidth = settings.Graphics.GetAspectRatio() * playfield.ScaledHeight
+
+	playfield.camera = camera2.NewCamera()
+	playfield.camera.SetViewportF(0, int(playfield.ScaledHeight), int(playfield.ScaledWidth), 0)
+	playfield.camera.Update()
+
+	playfield.hitCircle = skin.GetTexture("taikohitcircle")
+	playfield.hitCircleOverlay = skin.GetTexture("taikohitcircleoverlay")
+	playfield.bigCircle = skin.GetTexture("taikobigcircle")
+	playfield.bigCircleOverlay = skin.GetTexture("taikobigcircleoverlay")
+
+	// Skins without taiko textures get tinted osu!standard circles
+	if playfield.hitCircle == nil {
+		playfield.hitCircle = skin.GetTexture("hitcircle")
+		playfield.hitCircleOverlay = skin.GetTexture("hitcircleoverlay")
+	}
+
+	if playfield.bigCircle == nil {
+		playfield.bigCircle = playfield.hitCircle
+		playfield.bigCircleOverlay = playfield.hitCircleOverlay
+	}
+
+	playfield.rollMiddle = skin.GetTexture("taiko-roll-middle")
+	playfield.rollEnd = skin.GetTexture("taiko-roll-end")
+	playfield.approachCircle = skin.GetTexture("approachcircle")
+	playfield.drumInner = skin.GetTexture("taiko-drum-inner")
+	playfield.drumOuter = skin.GetTexture("taiko-drum-outer")
+	playfield.barLeft = skin.GetTexture("taiko-bar-left")
+	playfield.barRight = skin.GetTexture("taiko-bar-right")
+
+	playfield.numberFont = skin.GetFont("default")
+
+	ruleset.AddListener(playfield.hitReceived)
+	ruleset.AddPressListener(playfield.pressReceived)
+
+	log.Println("Playfield created.")
+
+	return playfield
+}
+
+func (playfield *TaikoPlayfield) hitReceived(cursor *graphics.Cursor, time int64, number int64, result taiko.HitResult, _ taiko.ComboResult, _ taiko.PPResults, _ int64) {
+	if cursor != playfield.cursor || result&taiko.BaseHitsM == 0 {
+		return
+	}
+
+	var name string
+
+	switch result {
+	case taiko.Great:
+		name = "hit300"
+	case taiko.Ok:
+		name = "hit100"
+	case taiko.Miss:
+		name = "hit0"
+	}
+
+	tex := skin.GetTexture("taiko-" + name)
+	if tex == nil {
+		tex = skin.GetTexture(name)
+	}
+
+	if tex == nil {
+		return
+	}
+
+	startTime := float64(time)
+	position := vector.NewVec2d(taikoHitX, taikoLaneY)
+
+	hit := sprite.NewSpriteSingle(tex, startTime, position, vector.Centre)
+	hit.AddTransform(animation.NewSingleTransform(animation.Fade, easing.Linear, startTime, startTime+80, 0.0, 1.0))
+	hit.AddTransform(animation.NewSingleTransform(animation.Scale, easing.OutQuad, startTime, startTime+80, 0.6, 0.8))
+	hit.AddTransform(animation.NewSingleTransform(animation.MoveY, easing.OutQuad, startTime, startTime+500, position.Y, position.Y-taikoLaneHeight/3))
+	hit.AddTransform(animation.NewSingleTransform(animation.Fade, easing.Linear, startTime+300, startTime+500, 1.0, 0.0))
+	hit.SortTransformations()
+	hit.AdjustTimesToTransformations()
+	hit.ResetValuesToTransforms()
+
+	playfield.results.Add(hit)
+}
+
+func (playfield *TaikoPlayfield) pressReceived(cursor *graphics.Cursor, time int64, pressed taiko.Buttons) {
+	if cursor != playfield.cursor {
+		return
+	}
+
+	for i := range playfield.lastPresses {
+		if pressed&(taiko.Buttons(1)<<i) > 0 {
+			playfield.lastPresses[i] = float64(time)
+		}
+	}
+}
+
+func (playfield *TaikoPlayfield) Update(time float64) {
+	playfield.lastTime = time
+	playfield.results.Update(time)
+}
+
+func (playfield *TaikoPlayfield) Draw(batch *batch.QuadBatch, _ []mgl32.Mat4, time float64, scale, alpha float32) {
+	if !settings.Playfield.DrawObjects {
+		return
+	}
+
+	if !settings.Objects.ScaleToTheBeat {
+		scale = 1
+	}
+
+	alpha64 := float64(alpha)
+
+	batch.Begin()
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, alpha64)
+
+	prev := batch.Projection
+	batch.SetCamera(playfield.camera.GetProjectionView())
+
+	playfield.drawLane(batch)
+
+	objs := playfield.ruleset.GetObjects()
+
+	playfield.countProcessed = 0
+
+	// Objects are drawn in reverse so earlier ones end up on top
+	for i := len(objs) - 1; i >= 0; i-- {
+		o := objs[i]
+
+		startX := playfield.getX(o, o.StartTime, time)
+		endX := playfield.getX(o, o.EndTime, time)
+
+		if startX-taikoStrongSize > playfield.ScaledWidth || endX+taikoStrongSize < taikoDrumWidth {
+			continue
+		}
+
+		state := playfield.ruleset.GetState(playfield.cursor, o.Number)
+
+		switch o.Type {
+		case taiko.Don, taiko.Kat:
+			if state.Judged && state.Result != taiko.Miss {
+				continue
+			}
+
+			color := donColor
+			if o.Type == taiko.Kat {
+				color = katColor
+			}
+
+			playfield.drawNote(batch, startX, o.Strong, color, float64(scale))
+		case taiko.DrumRoll:
+			playfield.drawRoll(batch, o, startX, endX, float64(scale))
+		case taiko.Swell:
+			if state.Judged && state.SwellHits >= o.RequiredHits {
+				continue
+			}
+
+			x := startX
+			if time >= o.StartTime {
+				if time > o.EndTime {
+					continue
+				}
+
+				x = taikoHitX
+			}
+
+			playfield.drawNote(batch, x, false, swellColor, float64(scale))
+
+			if time >= o.StartTime && playfield.numberFont != nil {
+				batch.SetColor(1, 1, 1, alpha64)
+				playfield.numberFont.DrawOrigin(batch, x, taikoLaneY-taikoLaneHeight/2-20, vector.BottomCentre, playfield.numberFont.GetSize()*1.2, false, fmt.Sprintf("%d", o.RequiredHits-state.SwellHits))
+			}
+		}
+
+		playfield.countProcessed++
+	}
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, alpha64)
+
+	playfield.drawDrum(batch, time)
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, alpha64)
+
+	playfield.results.Draw(time, batch)
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, 1)
+	batch.SetCamera(prev)
+	batch.End()
+}
+
+func (playfield *TaikoPlayfield) getX(o *taiko.Object, objTime, time float64) float64 {
+	return taikoHitX + (objTime-time)*o.Velocity*taikoScrollScale
+}
+
+func (playfield *TaikoPlayfield) drawLane(batch *batch.QuadBatch) {
+	laneWidth := playfield.ScaledWidth - taikoDrumWidth
+
+	if playfield.barRight != nil {
+		batch.DrawStObject(vector.NewVec2d(taikoDrumWidth, taikoLaneY), vector.CentreLeft, vector.NewVec2d(laneWidth/float64(playfield.barRight.Width), taikoLaneHeight/float64(playfield.barRight.Height)), false, false, 0, color2.NewL(1), false, *playfield.barRight)
+	} else {
+		pixel := graphics.Pixel.GetRegion()
+
+		batch.DrawStObject(vector.NewVec2d(taikoDrumWidth, taikoLaneY), vector.CentreLeft, vector.NewVec2d(laneWidth, taikoLaneHeight), false, false, 0, color2.NewLA(0, 0.7), false, pixel)
+		batch.DrawStObject(vector.NewVec2d(taikoDrumWidth, taikoLaneY-taikoLaneHeight/2), vector.CentreLeft, vector.NewVec2d(laneWidth, 2), false, false, 0, color2.NewLA(1, 0.5), false, pixel)
+		batch.DrawStObject(vector.NewVec2d(taikoDrumWidth, taikoLaneY+taikoLaneHeight/2), vector.CentreLeft, vector.NewVec2d(laneWidth, 2), false, false, 0, color2.NewLA(1, 0.5), false, pixel)
+	}
+
+	// Hit target
+	if playfield.approachCircle != nil {
+		batch.DrawStObject(vector.NewVec2d(taikoHitX, taikoLaneY), vector.Centre, vector.NewVec2d(1, 1).Scl(taikoNoteSize/float64(playfield.approachCircle.Width)), false, false, 0, color2.NewLA(1, 0.6), false, *playfield.approachCircle)
+		batch.DrawStObject(vector.NewVec2d(taikoHitX, taikoLaneY), vector.Centre, vector.NewVec2d(1, 1).Scl(taikoStrongSize/float64(playfield.approachCircle.Width)), false, false, 0, color2.NewLA(1, 0.3), false, *playfield.approachCircle)
+	}
+}
+
+func (playfield *TaikoPlayfield) drawNote(batch *batch.QuadBatch, x float64, strong bool, color color2.Color, scale float64) {
+	circle, overlay, size := playfield.hitCircle, playfield.hitCircleOverlay, taikoNoteSize
+
+	if strong {
+		circle, overlay, size = playfield.bigCircle, playfield.bigCircleOverlay, taikoStrongSize
+	}
+
+	if circle == nil {
+		return
+	}
+
+	scl := vector.NewVec2d(1, 1).Scl(size * scale / float64(circle.Width))
+
+	batch.DrawStObject(vector.NewVec2d(x, taikoLaneY), vector.Centre, scl, false, false, 0, color, false, *circle)
+
+	if overlay != nil {
+		batch.DrawStObject(vector.NewVec2d(x, taikoLaneY), vector.Centre, scl, false, false, 0, color2.NewL(1), false, *overlay)
+	}
+}
+
+func (playfield *TaikoPlayfield) drawRoll(batch *batch.QuadBatch, o *taiko.Object, startX, endX, scale float64) {
+	size := taikoNoteSize
+	if o.Strong {
+		size = taikoStrongSize
+	}
+
+	color := rollColor
+
+	if playfield.rollMiddle != nil {
+		batch.DrawStObject(vector.NewVec2d(startX, taikoLaneY), vector.CentreLeft, vector.NewVec2d((endX-startX)/float64(playfield.rollMiddle.Width), size*scale/float64(playfield.rollMiddle.Height)), false, false, 0, color, false, *playfield.rollMiddle)
+	} else {
+		batch.DrawStObject(vector.NewVec2d(startX, taikoLaneY), vector.CentreLeft, vector.NewVec2d(endX-startX, size*scale*0.85), false, false, 0, color, false, graphics.Pixel.GetRegion())
+	}
+
+	if playfield.rollEnd != nil {
+		batch.DrawStObject(vector.NewVec2d(endX, taikoLaneY), vector.CentreLeft, vector.NewVec2d(1, 1).Scl(size*scale/float64(playfield.rollEnd.Height)), false, false, 0, color, false, *playfield.rollEnd)
+	} else {
+		playfield.drawNote(batch, endX, o.Strong, rollColor, scale)
+	}
+
+	tickSize := size * scale * 0.15
+
+	for _, tick := range o.Ticks {
+		if tick < playfield.lastTime {
+			continue
+		}
+
+		batch.DrawStObject(vector.NewVec2d(playfield.getX(o, tick, playfield.lastTime), taikoLaneY), vector.Centre, vector.NewVec2d(tickSize, tickSize), false, false, 0, color2.NewL(1), false, graphics.Pixel.GetRegion())
+	}
+
+	playfield.drawNote(batch, startX, o.Strong, rollColor, scale)
+}
+
+func (playfield *TaikoPlayfield) drawDrum(batch *batch.QuadBatch, time float64) {
+	drumPos := vector.NewVec2d(taikoDrumWidth/2, taikoLaneY)
+
+	if playfield.barLeft != nil {
+		batch.DrawStObject(vector.NewVec2d(0, taikoLaneY), vector.CentreLeft, vector.NewVec2d(1, 1).Scl(taikoLaneHeight/float64(playfield.barLeft.Height)), false, false, 0, color2.NewL(1), false, *playfield.barLeft)
+	} else {
+		batch.DrawStObject(vector.NewVec2d(0, taikoLaneY), vector.CentreLeft, vector.NewVec2d(taikoDrumWidth, taikoLaneHeight), false, false, 0, color2.NewLA(0.15, 0.9), false, graphics.Pixel.GetRegion())
+	}
+
+	// Order is the same as taiko.Buttons: left centre, left rim, right centre, right rim
+	for i, lastPress := range playfield.lastPresses {
+		keyAlpha := 1 - (time-lastPress)/taikoKeyFade
+		if keyAlpha <= 0 || keyAlpha > 1 {
+			continue
+		}
+
+		right := i >= 2
+		rim := i%2 == 1
+
+		origin := vector.CentreRight
+		if right {
+			origin = vector.CentreLeft
+		}
+
+		if tex := playfield.drumInner; !rim && tex != nil {
+			batch.DrawStObject(drumPos, origin, vector.NewVec2d(1, 1).Scl(taikoLaneHeight*0.7/float64(tex.Height)), right, false, 0, color2.NewLA(1, float32(keyAlpha)), false, *tex)
+		} else if tex := playfield.drumOuter; rim && tex != nil {
+			batch.DrawStObject(drumPos, origin, vector.NewVec2d(1, 1).Scl(taikoLaneHeight*0.9/float64(tex.Height)), right, false, 0, color2.NewLA(1, float32(keyAlpha)), false, *tex)
+		} else {
+			color := donColor
+			height := taikoLaneHeight * 0.5
+
+			if rim {
+				color = katColor
+				height = taikoLaneHeight * 0.9
+			}
+
+			color.A = float32(keyAlpha * 0.8)
+
+			batch.DrawStObject(drumPos, origin, vector.NewVec2d(taikoDrumWidth*0.4, height), false, false, 0, color, false, graphics.Pixel.GetRegion())
+		}
+	}
+}
+
+func (playfield *TaikoPlayfield) GetNumProcessed() int {
+	return playfield.countProcessed
+}
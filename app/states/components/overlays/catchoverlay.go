@@ -0,0 +1,163 @@
+package overlays
+
+import (
+	"fmt"
+	camera2 "github.com/wieku/danser-go/app/bmath/camera"
+	"github.com/wieku/danser-go/app/discord"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/catch"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/app/states/components/overlays/play"
+	"github.com/wieku/danser-go/framework/bass"
+	"github.com/wieku/danser-go/framework/graphics/batch"
+	"github.com/wieku/danser-go/framework/graphics/font"
+	"github.com/wieku/danser-go/framework/math/animation"
+	color2 "github.com/wieku/danser-go/framework/math/color"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"math"
+)
+
+// CatchOverlay shows score, combo and health of a single osu!catch player, hitsounds are played by the playfield
+type CatchOverlay struct {
+	ruleset *catch.CatchRuleSet
+	cursor  *graphics.Cursor
+
+	music bass.ITrack
+
+	scoreGlider    *animation.TargetGlider
+	accuracyGlider *animation.TargetGlider
+
+	ScaledWidth  float64
+	ScaledHeight float64
+	camera       *camera2.Camera
+
+	scoreFont *font.Font
+
+	comboCounter *play.ComboCounter
+	hpBar        *play.HpBar
+}
+
+func NewCatchOverlay(ruleset *catch.CatchRuleSet, cursor *graphics.Cursor) *CatchOverlay {
+	loadFonts()
+
+	overlay := &CatchOverlay{
+		ruleset: ruleset,
+		cursor:  cursor,
+	}
+
+	overlay.ScaledHeight = 768
+	overlay.ScaledWidth = settings.Graphics.GetAspectRatio() * overlay.ScaledHeight
+
+	overlay.camera = camera2.NewCamera()
+	overlay.camera.SetViewportF(0, int(overlay.ScaledHeight), int(overlay.ScaledWidth), 0)
+	overlay.camera.Update()
+
+	overlay.scoreGlider = animation.NewTargetGlider(0, 0)
+	overlay.accuracyGlider = animation.NewTargetGlider(100, 2)
+
+	overlay.scoreFont = skin.GetFont("score")
+
+	overlay.comboCounter = play.NewComboCounter()
+	overlay.hpBar = play.NewHpBar()
+
+	discord.UpdatePlay(cursor)
+
+	ruleset.AddListener(overlay.hitReceived)
+
+	return overlay
+}
+
+func (overlay *CatchOverlay) hitReceived(cursor *graphics.Cursor, _ int64, _ *catch.Object, _ catch.HitResult, comboResult catch.ComboResult, _ int64) {
+	if cursor != overlay.cursor {
+		return
+	}
+
+	if comboResult == catch.Increase {
+		overlay.comboCounter.Increase()
+	} else if comboResult == catch.Reset {
+		overlay.comboCounter.Reset()
+	}
+
+	sc := overlay.ruleset.GetScore(overlay.cursor)
+
+	overlay.scoreGlider.SetValue(float64(sc.Score), settings.Gameplay.Score.StaticScore)
+	overlay.accuracyGlider.SetValue(sc.Accuracy, settings.Gameplay.Score.StaticAccuracy)
+}
+
+func (overlay *CatchOverlay) Update(time float64) {
+	overlay.comboCounter.Update(time)
+
+	overlay.hpBar.SetHp(overlay.ruleset.GetHP(overlay.cursor))
+	overlay.hpBar.Update(time)
+
+	overlay.scoreGlider.Update(time)
+	overlay.accuracyGlider.Update(time)
+}
+
+func (overlay *CatchOverlay) SetMusic(music bass.ITrack) {
+	overlay.music = music
+}
+
+func (overlay *CatchOverlay) DrawBackground(_ *batch.QuadBatch, _ []color2.Color, _ float64) {}
+
+func (overlay *CatchOverlay) DrawBeforeObjects(_ *batch.QuadBatch, _ []color2.Color, _ float64) {}
+
+func (overlay *CatchOverlay) DrawNormal(_ *batch.QuadBatch, _ []color2.Color, _ float64) {}
+
+func (overlay *CatchOverlay) DrawHUD(batch *batch.QuadBatch, _ []color2.Color, alpha float64) {
+	prev := batch.Projection
+	batch.SetCamera(overlay.camera.GetProjectionView())
+	batch.ResetTransform()
+
+	overlay.drawScore(batch, alpha)
+	overlay.comboCounter.Draw(batch, alpha)
+	overlay.hpBar.Draw(batch, alpha)
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, 1)
+	batch.SetCamera(prev)
+}
+
+func (overlay *CatchOverlay) drawScore(batch *batch.QuadBatch, alpha float64) {
+	scoreAlpha := settings.Gameplay.Score.Opacity * alpha
+
+	if scoreAlpha < 0.001 || !settings.Gameplay.Score.Show {
+		return
+	}
+
+	xOff := settings.Gameplay.Score.XOffset
+	yOff := settings.Gameplay.Score.YOffset
+
+	scoreScale := settings.Gameplay.Score.Scale
+	rightOffset := -9.6 * scoreScale
+
+	scoreSize := overlay.scoreFont.GetSize() * scoreScale * 0.96
+	scoreOverlap := overlay.scoreFont.Overlap * scoreSize / overlay.scoreFont.GetSize()
+
+	accSize := scoreSize * 0.6
+	accOverlap := overlay.scoreFont.Overlap * accSize / overlay.scoreFont.GetSize()
+	accYPos := scoreSize + vAccOffset*scoreScale
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, scoreAlpha)
+
+	scoreText := fmt.Sprintf("%08d", int64(math.Round(overlay.scoreGlider.GetValue())))
+	overlay.scoreFont.DrawOrigin(batch, overlay.ScaledWidth+rightOffset+scoreOverlap+xOff, yOff, vector.TopRight, scoreSize, true, scoreText)
+
+	accText := fmt.Sprintf("%5.2f%%", overlay.accuracyGlider.GetValue())
+	overlay.scoreFont.DrawOrigin(batch, overlay.ScaledWidth+rightOffset+accOverlap+xOff, accYPos+yOff, vector.TopRight, accSize, true, accText)
+}
+
+// IsBroken returns true so cursors, which don't have any meaning in osu!catch, aren't drawn
+func (overlay *CatchOverlay) IsBroken(_ *graphics.Cursor) bool {
+	return true
+}
+
+func (overlay *CatchOverlay) DisableAudioSubmission(b bool) {
+	overlay.comboCounter.DisableAudioSubmission(b)
+}
+
+func (overlay *CatchOverlay) ShouldDrawHUDBeforeCursor() bool {
+	return true
+}
@@ -0,0 +1,217 @@
+package overlays
+
+import (
+	"encoding/json"
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// HUD elements that can be placed by the layout file, in default draw order, with the points they're placed around by default
+var hudElements = []struct {
+	name   string
+	origin string
+}{
+	{"ScoreBoard", "Left"},
+	{"Score", "TopRight"},
+	{"Accuracy", "TopRight"},
+	{"ComboCounter", "BottomLeft"},
+	{"HpBar", "TopLeft"},
+	{"KeyOverlay", "Right"},
+	{"Mods", "TopRight"},
+	{"PPCounter", "TopLeft"},
+	{"StrainGraph", "BottomLeft"},
+	{"HitCounter", "BottomRight"},
+	{"HitErrorMeter", "Bottom"},
+	{"AimErrorMeter", "BottomRight"},
+}
+
+// hudElementLayout transforms a HUD element on top of its settings
+type hudElementLayout struct {
+	Show bool
+
+	// Point of the screen the element is scaled and rotated around
+	Origin  string
+	XOffset float64
+	YOffset float64
+
+	Scale    float64
+	Rotation float64 // in degrees, clockwise
+	Opacity  float64
+
+	// Elements with higher Z are drawn on top, meters are drawn below the cursor so they're sorted separately
+	Z int
+}
+
+// hudLayout holds the layout loaded from Gameplay.HUDLayout file, it's reloaded when the file changes outside of recording
+type hudLayout struct {
+	path string
+
+	mutex    *sync.Mutex
+	elements map[string]*hudElementLayout
+
+	watcher *fsnotify.Watcher
+}
+
+var (
+	hudLayoutsMutex = &sync.Mutex{}
+	hudLayouts      = make(map[string]*hudLayout)
+)
+
+// getHUDLayout returns the layout of given file. Overlays are recreated on seeking and map reloading, so they share it with its watcher
+func getHUDLayout(path string) *hudLayout {
+	hudLayoutsMutex.Lock()
+	defer hudLayoutsMutex.Unlock()
+
+	layout, ok := hudLayouts[path]
+	if !ok {
+		layout = newHUDLayout(path)
+		hudLayouts[path] = layout
+	}
+
+	return layout
+}
+
+func newHUDLayout(path string) *hudLayout {
+	layout := &hudLayout{
+		path:     path,
+		mutex:    &sync.Mutex{},
+		elements: loadHUDLayout(path),
+	}
+
+	if path != "" && !settings.RECORD {
+		layout.watch()
+	}
+
+	return layout
+}
+
+// loadHUDLayout reads the layout file, elements missing from it get their default layout
+func loadHUDLayout(path string) map[string]*hudElementLayout {
+	elements := make(map[string]*hudElementLayout)
+
+	for _, e := range hudElements {
+		elements[e.name] = &hudElementLayout{
+			Show:    true,
+			Origin:  e.origin,
+			Scale:   1,
+			Opacity: 1,
+		}
+	}
+
+	if path == "" {
+		return elements
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Println("HUDLayout: Failed to read", path+":", err)
+		return elements
+	}
+
+	var raw map[string]json.RawMessage
+
+	if err = json.Unmarshal(data, &raw); err != nil {
+		log.Println("HUDLayout: Failed to parse", path+":", err)
+		return elements
+	}
+
+	for name, value := range raw {
+		element, ok := elements[name]
+		if !ok {
+			log.Println("HUDLayout: Unknown element:", name)
+			continue
+		}
+
+		if err = json.Unmarshal(value, element); err != nil {
+			log.Println("HUDLayout: Failed to parse", name+":", err)
+		}
+	}
+
+	return elements
+}
+
+// watch reloads the layout when its file is saved, the directory is watched as editors often save by replacing the file
+func (layout *hudLayout) watch() {
+	var err error
+
+	layout.watcher, err = fsnotify.NewWatcher()
+	if err != nil {
+		log.Println("HUDLayout: Failed to watch layout file:", err)
+		return
+	}
+
+	abs, _ := filepath.Abs(layout.path)
+
+	goroutines.Run(func() {
+		for {
+			select {
+			case event, ok := <-layout.watcher.Events:
+				if !ok {
+					return
+				}
+
+				if eventAbs, _ := filepath.Abs(event.Name); eventAbs != abs || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+
+				log.Println("HUDLayout: Detected", layout.path, "modification, reloading...")
+
+				time.Sleep(time.Millisecond * 200)
+
+				// Saving usually fires a few events
+			drain:
+				for {
+					select {
+					case <-layout.watcher.Events:
+					default:
+						break drain
+					}
+				}
+
+				elements := loadHUDLayout(layout.path)
+
+				layout.mutex.Lock()
+				layout.elements = elements
+				layout.mutex.Unlock()
+			case err, ok := <-layout.watcher.Errors:
+				if !ok {
+					return
+				}
+
+				log.Println("HUDLayout:", err)
+			}
+		}
+	})
+
+	if err = layout.watcher.Add(filepath.Dir(abs)); err != nil {
+		log.Println("HUDLayout: Failed to watch layout file:", err)
+	}
+}
+
+// getElements returns the current layout, it's replaced as a whole on reload so it can be read without locking
+func (layout *hudLayout) getElements() map[string]*hudElementLayout {
+	layout.mutex.Lock()
+	defer layout.mutex.Unlock()
+
+	return layout.elements
+}
+
+// getTransform returns camera matrix placing the element on a width x height HUD
+func (element *hudElementLayout) getTransform(camera mgl32.Mat4, width, height float64) mgl32.Mat4 {
+	origin := vector.ParseOrigin(element.Origin).AddS(1, 1).Mult(vector.NewVec2d(width, height)).Scl(0.5)
+	position := origin.AddS(element.XOffset, element.YOffset)
+
+	return camera.Mul4(mgl32.Translate3D(position.X32(), position.Y32(), 0)).
+		Mul4(mgl32.HomogRotate3DZ(float32(element.Rotation * math.Pi / 180))).
+		Mul4(mgl32.Scale3D(float32(element.Scale), float32(element.Scale), 1)).
+		Mul4(mgl32.Translate3D(-origin.X32(), -origin.Y32(), 0))
+}
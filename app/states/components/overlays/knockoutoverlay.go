@@ -112,7 +112,8 @@ func newBubble(position vector.Vector2d, time float64, name string, combo int64,
 }
 
 type KnockoutOverlay struct {
-	controller   *dance.ReplayController
+	controller   dance.KnockoutController
+	ruleset      dance.KnockoutRuleset
 	font         *font.Font
 	players      map[string]*knockoutPlayer
 	playersArray []*knockoutPlayer
@@ -139,9 +140,10 @@ type KnockoutOverlay struct {
 	alivePlayers int
 }
 
-func NewKnockoutOverlay(replayController *dance.ReplayController) *KnockoutOverlay {
+func NewKnockoutOverlay(replayController dance.KnockoutController, ruleset dance.KnockoutRuleset) *KnockoutOverlay {
 	overlay := new(KnockoutOverlay)
 	overlay.controller = replayController
+	overlay.ruleset = ruleset
 
 	if font.GetFont("Quicksand Bold") == nil {
 		file, _ := assets.Open("assets/fonts/Quicksand-Bold.ttf")
@@ -188,7 +190,7 @@ func NewKnockoutOverlay(replayController *dance.ReplayController) *KnockoutOverl
 		}
 	}
 
-	replayController.GetRuleset().SetListener(overlay.hitReceived)
+	ruleset.SetListener(overlay.hitReceived)
 
 	sortFunc := func(number int64, instantSort bool) {
 		alive := 0
@@ -233,7 +235,7 @@ func NewKnockoutOverlay(replayController *dance.ReplayController) *KnockoutOverl
 		discord.UpdateKnockout(alive, len(overlay.playersArray))
 	}
 
-	replayController.GetRuleset().SetEndListener(func(time int64, number int64) {
+	ruleset.SetEndListener(func(time int64, number int64) {
 		if number == int64(len(replayController.GetBeatMap().HitObjects)-1) && settings.Knockout.RevivePlayersAtEnd {
 			for _, player := range overlay.players {
 				player.hasBroken = false
@@ -268,7 +270,8 @@ func (overlay *KnockoutOverlay) hitReceived(cursor *graphics.Cursor, time int64,
 
 	player := overlay.players[overlay.names[cursor]]
 
-	if overlay.controller.GetRuleset().GetBeatMap().Diff.Mods.Active(difficulty.HardRock) != overlay.controller.GetReplays()[player.oldIndex].ModsV.Active(difficulty.HardRock) {
+	// osu!catch doesn't flip the playfield with HardRock
+	if settings.MODE == settings.ModeOsu && overlay.ruleset.GetBeatMap().Diff.Mods.Active(difficulty.HardRock) != overlay.controller.GetReplays()[player.oldIndex].ModsV.Active(difficulty.HardRock) {
 		position.Y = 384 - position.Y
 	}
 
@@ -278,7 +281,7 @@ func (overlay *KnockoutOverlay) hitReceived(cursor *graphics.Cursor, time int64,
 	player.scoreDisp.SetValue(float64(score), false)
 	player.ppDisp.SetValue(player.pp, false)
 
-	sc := overlay.controller.GetRuleset().GetScore(cursor)
+	sc := overlay.ruleset.GetScore(cursor)
 
 	player.perObjectStats[number].score = score
 	player.perObjectStats[number].pp = ppResults.Total
@@ -370,7 +373,7 @@ func (overlay *KnockoutOverlay) Update(time float64) {
 		player.accDisp.Update(overlay.normalTime)
 		player.lastCombo = r.Combo
 
-		currentHp := overlay.controller.GetRuleset().GetHP(overlay.controller.GetCursors()[player.oldIndex])
+		currentHp := overlay.ruleset.GetHP(overlay.controller.GetCursors()[player.oldIndex])
 
 		if player.displayHp < currentHp {
 			player.displayHp = math.Min(1.0, player.displayHp+math.Abs(currentHp-player.displayHp)/4*delta/16.667)
@@ -470,19 +473,26 @@ func (overlay *KnockoutOverlay) DrawHUD(batch *batch.QuadBatch, colors []color2.
 	controller := overlay.controller
 	replays := controller.GetReplays()
 
-	scl := overlay.ScaledHeight * 0.9 / 51
+	cumulativeHeight := 0.0
+
+	for _, r := range replays {
+		cumulativeHeight += overlay.players[r.Name].height.GetValue()
+	}
+
+	rowScale := overlay.getRowScale(cumulativeHeight)
+	cumulativeHeight *= rowScale
+
+	scl := overlay.ScaledHeight * 0.9 / 51 * rowScale
+	rowHeight := overlay.ScaledHeight * 0.9 * 1.04 / 51 * rowScale
 	//margin := scl*0.02
 
 	highestCombo := int64(0)
 	highestPP := 0.0
 	highestACC := 0.0
 	highestScore := int64(0)
-	cumulativeHeight := 0.0
 	maxPlayerWidth := 0.0
 
 	for _, r := range replays {
-		cumulativeHeight += overlay.players[r.Name].height.GetValue()
-
 		highestCombo = mutils.Max(highestCombo, overlay.players[r.Name].sCombo)
 		highestPP = math.Max(highestPP, overlay.players[r.Name].pp)
 		highestACC = math.Max(highestACC, r.Accuracy)
@@ -510,14 +520,14 @@ func (overlay *KnockoutOverlay) DrawHUD(batch *batch.QuadBatch, colors []color2.
 	xSlideLeft := (overlay.fade.GetValue() - 1.0) * maxLength
 	xSlideRight := (1.0 - overlay.fade.GetValue()) * (cS + overlay.font.GetWidthMonospaced(scl, fmt.Sprintf("%dx ", highestCombo)) + 0.5*scl)
 
-	rowPosY := math.Max((overlay.ScaledHeight-cumulativeHeight)/2, scl)
+	rowPosY := overlay.getRowsY(cumulativeHeight, scl)
 	// Draw textures like keys, grade, hit values
 	for _, rep := range overlay.playersArray {
 		r := replays[rep.oldIndex]
 		player := overlay.players[r.Name]
 
-		rowBaseY := rowPosY + rep.index.GetValue()*(overlay.ScaledHeight*0.9*1.04/(51)) + player.height.GetValue()/2 /*+margin*10*/
-		rowPosY -= overlay.ScaledHeight*0.9*1.04/(51) - player.height.GetValue()
+		rowBaseY := rowPosY + rep.index.GetValue()*rowHeight + player.height.GetValue()*rowScale/2 /*+margin*10*/
+		rowPosY -= rowHeight - player.height.GetValue()*rowScale
 
 		//batch.SetColor(0.1, 0.8, 0.4, alpha*player.fade.GetValue()*0.4)
 		//add := 0.3 + float64(int(math.Round(rep.index.GetValue()))%2)*0.2
@@ -602,7 +612,7 @@ func (overlay *KnockoutOverlay) DrawHUD(batch *batch.QuadBatch, colors []color2.
 
 	batch.ResetTransform()
 
-	rowPosY = math.Max((overlay.ScaledHeight-cumulativeHeight)/2, scl)
+	rowPosY = overlay.getRowsY(cumulativeHeight, scl)
 	ascScl := overlay.font.GetAscent() * (scl / overlay.font.GetSize()) / 2
 
 	// Draw texts
@@ -610,8 +620,8 @@ func (overlay *KnockoutOverlay) DrawHUD(batch *batch.QuadBatch, colors []color2.
 		r := replays[rep.oldIndex]
 		player := overlay.players[r.Name]
 
-		rowBaseY := rowPosY + rep.index.GetValue()*(overlay.ScaledHeight*0.9*1.04/(51)) + player.height.GetValue()/2 /*+margin*10*/
-		rowPosY -= overlay.ScaledHeight*0.9*1.04/(51) - player.height.GetValue()
+		rowBaseY := rowPosY + rep.index.GetValue()*rowHeight + player.height.GetValue()*rowScale/2 /*+margin*10*/
+		rowPosY -= rowHeight - player.height.GetValue()*rowScale
 
 		batch.SetColor(1, 1, 1, alpha*player.fade.GetValue())
 
@@ -639,6 +649,27 @@ func (overlay *KnockoutOverlay) DrawHUD(batch *batch.QuadBatch, colors []color2.
 	}
 }
 
+// getRowScale returns how much rows have to be shrunk to fit below the playfield in portrait layout, with a row-high margin above and below
+func (overlay *KnockoutOverlay) getRowScale(cumulativeHeight float64) float64 {
+	if !settings.Playfield.IsPortrait() {
+		return 1
+	}
+
+	margin := overlay.ScaledHeight * 0.9 / 51
+	available := overlay.ScaledHeight - playfieldBottom(overlay.ScaledHeight)
+
+	return math.Max(0, math.Min(1, available/(cumulativeHeight+2*margin)))
+}
+
+// getRowsY returns where the first row starts, rows are stacked below the playfield in portrait layout
+func (overlay *KnockoutOverlay) getRowsY(cumulativeHeight, scl float64) float64 {
+	if settings.Playfield.IsPortrait() {
+		return playfieldBottom(overlay.ScaledHeight) + scl
+	}
+
+	return math.Max((overlay.ScaledHeight-cumulativeHeight)/2, scl)
+}
+
 func (overlay *KnockoutOverlay) IsBroken(cursor *graphics.Cursor) bool {
 	return overlay.players[overlay.names[cursor]].hasBroken
 }
@@ -646,7 +677,7 @@ func (overlay *KnockoutOverlay) IsBroken(cursor *graphics.Cursor) bool {
 func (overlay *KnockoutOverlay) updateBreaks(time float64) {
 	inBreak := false
 
-	for _, b := range overlay.controller.GetRuleset().GetBeatMap().Pauses {
+	for _, b := range overlay.ruleset.GetBeatMap().Pauses {
 		if overlay.audioTime < b.GetStartTime() {
 			break
 		}
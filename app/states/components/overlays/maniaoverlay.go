@@ -0,0 +1,203 @@
+package overlays
+
+import (
+	"fmt"
+	"github.com/wieku/danser-go/app/audio"
+	camera2 "github.com/wieku/danser-go/app/bmath/camera"
+	"github.com/wieku/danser-go/app/discord"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/mania"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/app/states/components/overlays/play"
+	"github.com/wieku/danser-go/framework/bass"
+	"github.com/wieku/danser-go/framework/graphics/batch"
+	"github.com/wieku/danser-go/framework/graphics/font"
+	"github.com/wieku/danser-go/framework/math/animation"
+	color2 "github.com/wieku/danser-go/framework/math/color"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"math"
+)
+
+// ManiaOverlay shows score, combo and health of a single osu!mania player
+type ManiaOverlay struct {
+	ruleset *mania.ManiaRuleSet
+	cursor  *graphics.Cursor
+
+	music bass.ITrack
+
+	scoreGlider    *animation.TargetGlider
+	accuracyGlider *animation.TargetGlider
+
+	ScaledWidth  float64
+	ScaledHeight float64
+	camera       *camera2.Camera
+
+	scoreFont *font.Font
+
+	comboCounter *play.ComboCounter
+	hpBar        *play.HpBar
+
+	audioDisabled bool
+}
+
+func NewManiaOverlay(ruleset *mania.ManiaRuleSet, cursor *graphics.Cursor) *ManiaOverlay {
+	loadFonts()
+
+	overlay := &ManiaOverlay{
+		ruleset: ruleset,
+		cursor:  cursor,
+	}
+
+	overlay.ScaledHeight = 768
+	overlay.ScaledWidth = settings.Graphics.GetAspectRatio() * overlay.ScaledHeight
+
+	overlay.camera = camera2.NewCamera()
+	overlay.camera.SetViewportF(0, int(overlay.ScaledHeight), int(overlay.ScaledWidth), 0)
+	overlay.camera.Update()
+
+	overlay.scoreGlider = animation.NewTargetGlider(0, 0)
+	overlay.accuracyGlider = animation.NewTargetGlider(100, 2)
+
+	overlay.scoreFont = skin.GetFont("score")
+
+	overlay.comboCounter = play.NewComboCounter()
+	overlay.hpBar = play.NewHpBar()
+
+	discord.UpdatePlay(cursor)
+
+	ruleset.AddListener(overlay.hitReceived)
+	ruleset.AddPressListener(overlay.pressReceived)
+
+	return overlay
+}
+
+func (overlay *ManiaOverlay) hitReceived(cursor *graphics.Cursor, _ int64, _ int64, _ int, _ mania.HitResult, comboResult mania.ComboResult, _ int64) {
+	if cursor != overlay.cursor {
+		return
+	}
+
+	if comboResult == mania.Increase {
+		overlay.comboCounter.Increase()
+	} else if comboResult == mania.Reset {
+		overlay.comboCounter.Reset()
+	}
+
+	sc := overlay.ruleset.GetScore(overlay.cursor)
+
+	overlay.scoreGlider.SetValue(float64(sc.Score), settings.Gameplay.Score.StaticScore)
+	overlay.accuracyGlider.SetValue(sc.Accuracy, settings.Gameplay.Score.StaticAccuracy)
+}
+
+// pressReceived plays hitsounds of the closest object in the column, osu!mania plays them on every key press instead of on judgements
+func (overlay *ManiaOverlay) pressReceived(cursor *graphics.Cursor, time int64, column int, object *mania.Object) {
+	if cursor != overlay.cursor || overlay.audioDisabled {
+		return
+	}
+
+	point := overlay.ruleset.GetBeatMap().Timings.GetPointAt(float64(time))
+
+	xPos := (float64(column) + 0.5) * 512 / float64(overlay.ruleset.GetKeys())
+
+	if object == nil {
+		audio.PlaySample(point.SampleSet, 0, 1, point.SampleIndex, point.SampleVolume, -1, xPos)
+		return
+	}
+
+	point = overlay.ruleset.GetBeatMap().Timings.GetPointAt(object.StartTime)
+
+	sampleSet, index, volume := object.HitSound.SampleSet, object.HitSound.CustomIndex, point.SampleVolume
+
+	if sampleSet == 0 {
+		sampleSet = point.SampleSet
+	}
+
+	if index == 0 {
+		index = point.SampleIndex
+	}
+
+	if object.HitSound.CustomVolume > 0 {
+		volume = object.HitSound.CustomVolume
+	}
+
+	audio.PlaySample(sampleSet, object.HitSound.AdditionSet, object.Sample, index, volume, object.Source, xPos)
+}
+
+func (overlay *ManiaOverlay) Update(time float64) {
+	overlay.comboCounter.Update(time)
+
+	overlay.hpBar.SetHp(overlay.ruleset.GetHP(overlay.cursor))
+	overlay.hpBar.Update(time)
+
+	overlay.scoreGlider.Update(time)
+	overlay.accuracyGlider.Update(time)
+}
+
+func (overlay *ManiaOverlay) SetMusic(music bass.ITrack) {
+	overlay.music = music
+}
+
+func (overlay *ManiaOverlay) DrawBackground(_ *batch.QuadBatch, _ []color2.Color, _ float64) {}
+
+func (overlay *ManiaOverlay) DrawBeforeObjects(_ *batch.QuadBatch, _ []color2.Color, _ float64) {}
+
+func (overlay *ManiaOverlay) DrawNormal(_ *batch.QuadBatch, _ []color2.Color, _ float64) {}
+
+func (overlay *ManiaOverlay) DrawHUD(batch *batch.QuadBatch, _ []color2.Color, alpha float64) {
+	prev := batch.Projection
+	batch.SetCamera(overlay.camera.GetProjectionView())
+	batch.ResetTransform()
+
+	overlay.drawScore(batch, alpha)
+	overlay.comboCounter.Draw(batch, alpha)
+	overlay.hpBar.Draw(batch, alpha)
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, 1)
+	batch.SetCamera(prev)
+}
+
+func (overlay *ManiaOverlay) drawScore(batch *batch.QuadBatch, alpha float64) {
+	scoreAlpha := settings.Gameplay.Score.Opacity * alpha
+
+	if scoreAlpha < 0.001 || !settings.Gameplay.Score.Show {
+		return
+	}
+
+	xOff := settings.Gameplay.Score.XOffset
+	yOff := settings.Gameplay.Score.YOffset
+
+	scoreScale := settings.Gameplay.Score.Scale
+	rightOffset := -9.6 * scoreScale
+
+	scoreSize := overlay.scoreFont.GetSize() * scoreScale * 0.96
+	scoreOverlap := overlay.scoreFont.Overlap * scoreSize / overlay.scoreFont.GetSize()
+
+	accSize := scoreSize * 0.6
+	accOverlap := overlay.scoreFont.Overlap * accSize / overlay.scoreFont.GetSize()
+	accYPos := scoreSize + vAccOffset*scoreScale
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, scoreAlpha)
+
+	scoreText := fmt.Sprintf("%08d", int64(math.Round(overlay.scoreGlider.GetValue())))
+	overlay.scoreFont.DrawOrigin(batch, overlay.ScaledWidth+rightOffset+scoreOverlap+xOff, yOff, vector.TopRight, scoreSize, true, scoreText)
+
+	accText := fmt.Sprintf("%5.2f%%", overlay.accuracyGlider.GetValue())
+	overlay.scoreFont.DrawOrigin(batch, overlay.ScaledWidth+rightOffset+accOverlap+xOff, accYPos+yOff, vector.TopRight, accSize, true, accText)
+}
+
+// IsBroken returns true so cursors, which don't have any meaning in osu!mania, aren't drawn
+func (overlay *ManiaOverlay) IsBroken(_ *graphics.Cursor) bool {
+	return true
+}
+
+func (overlay *ManiaOverlay) DisableAudioSubmission(b bool) {
+	overlay.audioDisabled = b
+
+	overlay.comboCounter.DisableAudioSubmission(b)
+}
+
+func (overlay *ManiaOverlay) ShouldDrawHUDBeforeCursor() bool {
+	return true
+}
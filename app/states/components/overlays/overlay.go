@@ -1,10 +1,13 @@
 package overlays
 
 import (
+	camera2 "github.com/wieku/danser-go/app/bmath/camera"
 	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/framework/bass"
 	"github.com/wieku/danser-go/framework/graphics/batch"
 	color2 "github.com/wieku/danser-go/framework/math/color"
+	"github.com/wieku/danser-go/framework/math/vector"
 )
 
 type Overlay interface {
@@ -18,3 +21,10 @@ type Overlay interface {
 	DisableAudioSubmission(b bool)
 	ShouldDrawHUDBeforeCursor() bool
 }
+
+// playfieldBottom returns the bottom edge of the playfield in units of an overlay scaledHeight units high
+func playfieldBottom(scaledHeight float64) float64 {
+	bottom := graphics.Camera.Project(vector.NewVec2d(camera2.OsuWidth, camera2.OsuHeight)).Y + settings.Graphics.GetHeightF()/2
+
+	return bottom * scaledHeight / settings.Graphics.GetHeightF()
+}
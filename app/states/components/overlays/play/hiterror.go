@@ -116,7 +116,7 @@ func (meter *HitErrorMeter) Add(time, error float64, positionalMiss bool) {
 
 	errorPos := error * 0.8
 	if settings.Gameplay.HitErrorMeter.ScaleWithSpeed {
-		errorPos /= meter.diff.Speed
+		errorPos /= meter.diff.GetSpeedAt(time)
 	}
 
 	middle := sprite.NewSpriteSingle(&pixel, 3.0, vector.NewVec2d(meter.Width/2+errorPos*scale, meter.Height-errorBase*2*scale), vector.Centre)
@@ -151,6 +151,11 @@ func (hpBar *HpBar) Draw(batch *batch.QuadBatch, alpha float64) {
 	hpBar.explodes.Draw(hpBar.lastTime, batch)
 }
 
+// GetHeight returns the height of the bar in HUD units
+func (hpBar *HpBar) GetHeight() float64 {
+	return float64(hpBar.healthBackground.Texture.Height) * settings.Gameplay.HpBar.Scale
+}
+
 func (hpBar *HpBar) SlideOut() {
 	if settings.Gameplay.HpBar.YOffset < 0.01 {
 		hpBar.hpSlide.AddEvent(hpBar.lastTime, hpBar.lastTime+500, -20)
@@ -38,6 +38,10 @@ type PPDisplay struct {
 
 	mods           difficulty.Modifier
 	experimentalPP bool
+
+	// Overrides position and align from settings if set
+	position *vector.Vector2d
+	origin   vector.Vector2d
 }
 
 func NewPPDisplay(mods difficulty.Modifier, experimentalPP bool) *PPDisplay {
@@ -60,6 +64,12 @@ func NewPPDisplay(mods difficulty.Modifier, experimentalPP bool) *PPDisplay {
 	}
 }
 
+// SetPosition places the counter ignoring PPCounter position and align settings
+func (ppDisplay *PPDisplay) SetPosition(position, origin vector.Vector2d) {
+	ppDisplay.position = &position
+	ppDisplay.origin = origin
+}
+
 func (ppDisplay *PPDisplay) Add(results performance.PPv2Results) {
 	static := settings.Gameplay.PPCounter.Static
 
@@ -115,6 +125,10 @@ func (ppDisplay *PPDisplay) Draw(batch *batch.QuadBatch, alpha float64) {
 	position := vector.NewVec2d(settings.Gameplay.PPCounter.XPosition, settings.Gameplay.PPCounter.YPosition)
 	origin := vector.ParseOrigin(settings.Gameplay.PPCounter.Align)
 
+	if ppDisplay.position != nil {
+		position, origin = *ppDisplay.position, ppDisplay.origin
+	}
+
 	cS := settings.Gameplay.PPCounter.Color
 	color := color2.NewHSVA(float32(cS.Hue), float32(cS.Saturation), float32(cS.Value), float32(ppAlpha))
 
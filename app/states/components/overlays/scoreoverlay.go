@@ -35,6 +35,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -66,6 +67,10 @@ type ScoreOverlay struct {
 	lastPresses [4]float64
 	keyOverlay  *sprite.Manager
 	keys        []*sprite.Sprite
+	keysY       float64
+
+	// Moves score and mods below the HP bar in portrait layout
+	scoreOffsetY float64
 
 	ScaledWidth  float64
 	ScaledHeight float64
@@ -128,6 +133,9 @@ type ScoreOverlay struct {
 	strainGraph *play.StrainGraph
 
 	underlay *sprite.Sprite
+
+	layout     *hudLayout
+	hudDrawers map[string]func(batch *batch.QuadBatch, alpha float64)
 }
 
 func loadFonts() {
@@ -174,6 +182,18 @@ func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOve
 	overlay.ScaledHeight = 768
 	overlay.ScaledWidth = settings.Graphics.GetAspectRatio() * overlay.ScaledHeight
 
+	overlay.keysY = overlay.ScaledHeight/2 - 64
+	hitErrorY := overlay.ScaledHeight
+
+	portrait := settings.Playfield.IsPortrait()
+
+	if portrait {
+		pfBottom := playfieldBottom(overlay.ScaledHeight)
+
+		overlay.keysY = pfBottom + 16
+		hitErrorY = pfBottom + 48
+	}
+
 	overlay.initUnderlay()
 
 	overlay.results = play.NewHitResults(ruleset.GetBeatMap().Diff)
@@ -225,7 +245,7 @@ func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOve
 
 	overlay.keyOverlay = sprite.NewManager()
 
-	keyBg := sprite.NewSpriteSingle(skin.GetTexture("inputoverlay-background"), 0, vector.NewVec2d(overlay.ScaledWidth, overlay.ScaledHeight/2-64), vector.TopLeft)
+	keyBg := sprite.NewSpriteSingle(skin.GetTexture("inputoverlay-background"), 0, vector.NewVec2d(overlay.ScaledWidth, overlay.keysY), vector.TopLeft)
 	keyBg.SetScaleV(vector.NewVec2d(1.05, 1))
 	keyBg.ShowForever(true)
 	keyBg.SetRotation(math.Pi / 2)
@@ -233,7 +253,7 @@ func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOve
 	overlay.keyOverlay.Add(keyBg)
 
 	for i := 0; i < 4; i++ {
-		posY := overlay.ScaledHeight/2 - 64 + (30.4+float64(i)*47.2)*settings.Gameplay.KeyOverlay.Scale
+		posY := overlay.keysY + (30.4+float64(i)*47.2)*settings.Gameplay.KeyOverlay.Scale
 
 		key := sprite.NewSpriteSingle(skin.GetTexture("inputoverlay-key"), 1, vector.NewVec2d(overlay.ScaledWidth-24*settings.Gameplay.KeyOverlay.Scale, posY), vector.Centre)
 		key.ShowForever(true)
@@ -242,7 +262,7 @@ func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOve
 		overlay.keyOverlay.Add(key)
 	}
 
-	overlay.hitErrorMeter = play.NewHitErrorMeter(overlay.ScaledWidth, overlay.ScaledHeight, ruleset.GetBeatMap().Diff)
+	overlay.hitErrorMeter = play.NewHitErrorMeter(overlay.ScaledWidth, hitErrorY, ruleset.GetBeatMap().Diff)
 
 	overlay.aimErrorMeter = play.NewAimErrorMeter(ruleset.GetBeatMap().Diff)
 
@@ -272,6 +292,12 @@ func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOve
 
 	overlay.hpBar = play.NewHpBar()
 
+	if portrait {
+		overlay.scoreOffsetY = overlay.hpBar.GetHeight()
+
+		overlay.ppDisplay.SetPosition(vector.NewVec2d(overlay.ScaledWidth/2, hitErrorY+8), vector.TopCentre)
+	}
+
 	overlay.hitCounts = play.NewHitDisplay(overlay.ruleset, overlay.cursor)
 
 	overlay.shapeRenderer = shape.NewRenderer()
@@ -289,6 +315,23 @@ func NewScoreOverlay(ruleset *osu.OsuRuleSet, cursor *graphics.Cursor) *ScoreOve
 
 	overlay.initArrows()
 
+	overlay.layout = getHUDLayout(settings.Gameplay.GetHUDLayoutPath())
+
+	overlay.hudDrawers = map[string]func(batch *batch.QuadBatch, alpha float64){
+		"ScoreBoard":    overlay.entry.Draw,
+		"Score":         overlay.drawScore,
+		"Accuracy":      overlay.drawAccuracy,
+		"ComboCounter":  overlay.comboCounter.Draw,
+		"HpBar":         overlay.hpBar.Draw,
+		"KeyOverlay":    overlay.drawKeys,
+		"Mods":          overlay.drawMods,
+		"PPCounter":     overlay.ppDisplay.Draw,
+		"StrainGraph":   overlay.strainGraph.Draw,
+		"HitCounter":    overlay.hitCounts.Draw,
+		"HitErrorMeter": overlay.hitErrorMeter.Draw,
+		"AimErrorMeter": overlay.aimErrorMeter.Draw,
+	}
+
 	return overlay
 }
 
@@ -596,9 +639,9 @@ func (overlay *ScoreOverlay) DrawNormal(batch *batch.QuadBatch, _ []color2.Color
 	prev := batch.Projection
 	batch.SetCamera(overlay.camera.GetProjectionView())
 
-	overlay.hitErrorMeter.Draw(batch, alpha)
-	overlay.aimErrorMeter.Draw(batch, alpha)
+	overlay.drawElements(batch, alpha, "HitErrorMeter", "AimErrorMeter")
 
+	batch.ResetTransform()
 	batch.SetScale(1, 1)
 	batch.SetColor(1, 1, 1, alpha)
 
@@ -615,43 +658,20 @@ func (overlay *ScoreOverlay) DrawHUD(batch *batch.QuadBatch, _ []color2.Color, a
 	batch.ResetTransform()
 
 	if !settings.Gameplay.Underlay.AboveHpBar {
-		batch.SetColor(1, 1, 1, alpha)
-		overlay.underlay.Draw(0, batch)
+		overlay.drawUnderlay(batch, alpha)
 	}
 
-	overlay.entry.Draw(batch, alpha)
-
 	overlay.passContainer.Draw(overlay.audioTime, batch)
 
-	overlay.drawScore(batch, alpha)
-	overlay.comboCounter.Draw(batch, alpha)
-	overlay.hpBar.Draw(batch, alpha)
-
-	if settings.Gameplay.Underlay.AboveHpBar {
-		batch.ResetTransform()
-		batch.SetColor(1, 1, 1, alpha)
-		overlay.underlay.Draw(0, batch)
-	}
-
-	overlay.drawKeys(batch, alpha)
+	overlay.drawElements(batch, alpha, "ScoreBoard", "Score", "Accuracy", "ComboCounter", "HpBar", "KeyOverlay", "Mods", "PPCounter", "StrainGraph", "HitCounter")
 
 	batch.ResetTransform()
 	batch.SetColor(1, 1, 1, alpha)
 
-	if settings.Gameplay.Mods.Show {
-		batch.SetTranslation(vector.NewVec2d(settings.Gameplay.Mods.XOffset, settings.Gameplay.Mods.YOffset))
-		overlay.mods.Draw(overlay.lastTime, batch)
-		batch.ResetTransform()
-	}
-
 	if settings.Gameplay.ShowWarningArrows {
 		overlay.arrows.Draw(overlay.audioTime, batch)
 	}
 
-	overlay.ppDisplay.Draw(batch, alpha)
-	overlay.strainGraph.Draw(batch, alpha)
-	overlay.hitCounts.Draw(batch, alpha)
-
 	if overlay.panel != nil {
 		settings.Playfield.Bloom.Enabled = false
 		overlay.panel.Draw(batch, overlay.resultsFade.GetValue())
@@ -660,6 +680,53 @@ func (overlay *ScoreOverlay) DrawHUD(batch *batch.QuadBatch, _ []color2.Color, a
 	batch.SetCamera(prev)
 }
 
+// drawElements draws HUD elements sorted by Z, each placed by its layout
+func (overlay *ScoreOverlay) drawElements(batch *batch.QuadBatch, alpha float64, names ...string) {
+	elements := overlay.layout.getElements()
+
+	sort.SliceStable(names, func(i, j int) bool {
+		return elements[names[i]].Z < elements[names[j]].Z
+	})
+
+	camera := batch.Projection
+
+	for _, name := range names {
+		element := elements[name]
+
+		if !element.Show || element.Opacity < 0.001 {
+			continue
+		}
+
+		batch.SetCamera(element.getTransform(camera, overlay.ScaledWidth, overlay.ScaledHeight))
+		batch.ResetTransform()
+
+		overlay.hudDrawers[name](batch, alpha*element.Opacity)
+
+		batch.SetCamera(camera)
+
+		if name == "HpBar" && settings.Gameplay.Underlay.AboveHpBar {
+			overlay.drawUnderlay(batch, alpha)
+		}
+	}
+}
+
+func (overlay *ScoreOverlay) drawUnderlay(batch *batch.QuadBatch, alpha float64) {
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, alpha)
+	overlay.underlay.Draw(0, batch)
+}
+
+// getScoreSizes returns font sizes of score and accuracy and vertical position of the latter
+func (overlay *ScoreOverlay) getScoreSizes() (scoreSize, accSize, accYPos float64) {
+	scoreScale := settings.Gameplay.Score.Scale
+
+	scoreSize = overlay.scoreFont.GetSize() * scoreScale * 0.96
+	accSize = scoreSize * 0.6
+	accYPos = scoreSize + vAccOffset*scoreScale
+
+	return
+}
+
 func (overlay *ScoreOverlay) drawScore(batch *batch.QuadBatch, alpha float64) {
 	scoreAlpha := settings.Gameplay.Score.Opacity * alpha
 
@@ -668,44 +735,17 @@ func (overlay *ScoreOverlay) drawScore(batch *batch.QuadBatch, alpha float64) {
 	}
 
 	xOff := settings.Gameplay.Score.XOffset
-	yOff := settings.Gameplay.Score.YOffset
+	yOff := settings.Gameplay.Score.YOffset + overlay.scoreOffsetY
 
 	scoreScale := settings.Gameplay.Score.Scale
 	rightOffset := -9.6 * scoreScale
 
-	progress := overlay.getProgress()
-
-	scoreSize := overlay.scoreFont.GetSize() * scoreScale * 0.96
+	scoreSize, _, _ := overlay.getScoreSizes()
 	scoreOverlap := overlay.scoreFont.Overlap * scoreSize / overlay.scoreFont.GetSize()
 
-	accSize := scoreSize * 0.6
-	accOverlap := overlay.scoreFont.Overlap * accSize / overlay.scoreFont.GetSize()
-	accYPos := scoreSize + vAccOffset*scoreScale
-
-	accOffset := overlay.ScaledWidth - overlay.scoreFont.GetWidthMonospaced(accSize, "99.99%") + accOverlap - 38.4*scoreScale + rightOffset
-
-	batch.Flush()
-
-	overlay.shapeRenderer.SetCamera(overlay.camera.GetProjectionView())
-
-	if settings.Gameplay.Score.ProgressBar == "Pie" {
-		if progress < 0.0 {
-			overlay.shapeRenderer.SetColor(0.4, 0.8, 0.4, 0.6*scoreAlpha)
-		} else {
-			overlay.shapeRenderer.SetColor(1, 1, 1, 0.6*scoreAlpha)
-		}
-
-		overlay.shapeRenderer.Begin()
-		overlay.shapeRenderer.DrawCircleProgressS(vector.NewVec2f(float32(accOffset+xOff), float32(accYPos+accSize/2+yOff)), 16*float32(settings.Gameplay.Score.Scale), 40, float32(progress))
-		overlay.shapeRenderer.End()
-
-		batch.SetColor(1, 1, 1, scoreAlpha)
-		batch.SetScale(scoreScale, scoreScale)
-		batch.SetTranslation(vector.NewVec2d(accOffset+xOff, accYPos+accSize/2+yOff))
-		batch.DrawTexture(*overlay.circularMetre)
+	if progress := overlay.getProgress(); settings.Gameplay.Score.ProgressBar != "Pie" && progress > 0.0 {
+		batch.Flush()
 
-		accOffset -= 44.8 * scoreScale
-	} else if progress > 0.0 {
 		thickness := barThickness * scoreScale
 
 		var positionX, positionY, bWidth float64
@@ -728,6 +768,7 @@ func (overlay *ScoreOverlay) drawScore(batch *batch.QuadBatch, alpha float64) {
 
 		positionY += thickness / 2
 
+		overlay.shapeRenderer.SetCamera(batch.Projection)
 		overlay.shapeRenderer.SetColor(1, 1, 0.5, 0.5*scoreAlpha)
 
 		overlay.shapeRenderer.Begin()
@@ -742,6 +783,53 @@ func (overlay *ScoreOverlay) drawScore(batch *batch.QuadBatch, alpha float64) {
 
 	scoreText := fmt.Sprintf("%08d", int64(math.Round(overlay.scoreGlider.GetValue())))
 	overlay.scoreFont.DrawOrigin(batch, overlay.ScaledWidth+rightOffset+scoreOverlap+xOff, yOff, vector.TopRight, scoreSize, true, scoreText)
+}
+
+func (overlay *ScoreOverlay) drawAccuracy(batch *batch.QuadBatch, alpha float64) {
+	scoreAlpha := settings.Gameplay.Score.Opacity * alpha
+
+	if scoreAlpha < 0.001 || !settings.Gameplay.Score.Show {
+		return
+	}
+
+	xOff := settings.Gameplay.Score.XOffset
+	yOff := settings.Gameplay.Score.YOffset + overlay.scoreOffsetY
+
+	scoreScale := settings.Gameplay.Score.Scale
+	rightOffset := -9.6 * scoreScale
+
+	_, accSize, accYPos := overlay.getScoreSizes()
+	accOverlap := overlay.scoreFont.Overlap * accSize / overlay.scoreFont.GetSize()
+
+	accOffset := overlay.ScaledWidth - overlay.scoreFont.GetWidthMonospaced(accSize, "99.99%") + accOverlap - 38.4*scoreScale + rightOffset
+
+	if settings.Gameplay.Score.ProgressBar == "Pie" {
+		progress := overlay.getProgress()
+
+		batch.Flush()
+
+		overlay.shapeRenderer.SetCamera(batch.Projection)
+
+		if progress < 0.0 {
+			overlay.shapeRenderer.SetColor(0.4, 0.8, 0.4, 0.6*scoreAlpha)
+		} else {
+			overlay.shapeRenderer.SetColor(1, 1, 1, 0.6*scoreAlpha)
+		}
+
+		overlay.shapeRenderer.Begin()
+		overlay.shapeRenderer.DrawCircleProgressS(vector.NewVec2f(float32(accOffset+xOff), float32(accYPos+accSize/2+yOff)), 16*float32(settings.Gameplay.Score.Scale), 40, float32(progress))
+		overlay.shapeRenderer.End()
+
+		batch.SetColor(1, 1, 1, scoreAlpha)
+		batch.SetScale(scoreScale, scoreScale)
+		batch.SetTranslation(vector.NewVec2d(accOffset+xOff, accYPos+accSize/2+yOff))
+		batch.DrawTexture(*overlay.circularMetre)
+
+		accOffset -= 44.8 * scoreScale
+	}
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, scoreAlpha)
 
 	accText := fmt.Sprintf("%5.2f%%", overlay.accuracyGlider.GetValue())
 	overlay.scoreFont.DrawOrigin(batch, overlay.ScaledWidth+rightOffset+accOverlap+xOff, accYPos+yOff, vector.TopRight, accSize, true, accText)
@@ -756,6 +844,22 @@ func (overlay *ScoreOverlay) drawScore(batch *batch.QuadBatch, alpha float64) {
 	} else if overlay.rankBack.Texture != nil {
 		batch.DrawTexture(*overlay.rankBack.Texture)
 	}
+
+	batch.ResetTransform()
+}
+
+func (overlay *ScoreOverlay) drawMods(batch *batch.QuadBatch, alpha float64) {
+	if !settings.Gameplay.Mods.Show {
+		return
+	}
+
+	batch.ResetTransform()
+	batch.SetColor(1, 1, 1, alpha)
+	batch.SetTranslation(vector.NewVec2d(settings.Gameplay.Mods.XOffset, settings.Gameplay.Mods.YOffset+overlay.scoreOffsetY))
+
+	overlay.mods.Draw(overlay.lastTime, batch)
+
+	batch.ResetTransform()
 }
 
 func (overlay *ScoreOverlay) drawKeys(batch *batch.QuadBatch, alpha float64) {
@@ -781,7 +885,7 @@ func (overlay *ScoreOverlay) drawKeys(batch *batch.QuadBatch, alpha float64) {
 
 	for i := 0; i < 4; i++ {
 		posX := overlay.ScaledWidth - 24*keyScale
-		posY := overlay.ScaledHeight/2 - 64 + (30.4+float64(i)*47.2)*keyScale
+		posY := overlay.keysY + (30.4+float64(i)*47.2)*keyScale
 		scale := overlay.keys[i].GetScale().Y * keyScale
 
 		text := strconv.Itoa(overlay.keyCounters[i])
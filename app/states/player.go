@@ -3,6 +3,8 @@ package states
 import (
 	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
+	"github.com/wieku/danser-go/app/api"
+	"github.com/wieku/danser-go/app/audio"
 	"github.com/wieku/danser-go/app/beatmap"
 	"github.com/wieku/danser-go/app/beatmap/difficulty"
 	camera2 "github.com/wieku/danser-go/app/bmath/camera"
@@ -10,6 +12,7 @@ import (
 	"github.com/wieku/danser-go/app/discord"
 	"github.com/wieku/danser-go/app/graphics"
 	"github.com/wieku/danser-go/app/input"
+	"github.com/wieku/danser-go/app/rulesets/osu"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/states/components/common"
 	"github.com/wieku/danser-go/app/states/components/containers"
@@ -35,6 +38,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"sync"
 )
 
 const windowsOffset = 15
@@ -95,7 +99,7 @@ type Player struct {
 	updateLimiter *frame.Limiter
 
 	objectsAlpha    *animation.Glider
-	objectContainer *containers.HitObjectContainer
+	objectContainer containers.ObjectContainer
 
 	MapEnd      float64
 	RunningTime float64
@@ -108,6 +112,31 @@ type Player struct {
 	ScaledHeight float64
 
 	nightcore *common.NightcoreProcessor
+
+	objectsEnd float64
+
+	seekMutex  sync.Mutex
+	seekOffset float64
+
+	reloadMutex   sync.Mutex
+	reloadPending bool
+
+	seekable bool
+
+	commandMutex sync.Mutex
+	commands     []func()
+
+	playbackRate float64
+	hudHidden    bool
+
+	// Index of the last break storyboard's Passing/Failing state was evaluated at
+	storyboardBreak int
+
+	// Built by the update thread, API reads only this
+	apiMutex      sync.Mutex
+	apiState      api.State
+	apiStarted    bool
+	apiLastUpdate int64
 }
 
 func NewPlayer(beatMap *beatmap.BeatMap) *Player {
@@ -191,8 +220,17 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 	player.background = common.NewBackground(true)
 	player.background.SetBeatmap(beatMap, true, true)
 
+	if storyboard := player.background.GetStoryboard(); storyboard != nil {
+		audio.AddListener(storyboard.HitSoundPlayed)
+	}
+
+	playfieldFit := camera2.LandscapeFit
+	if settings.Playfield.IsPortrait() {
+		playfieldFit = camera2.PortraitFit
+	}
+
 	player.mainCamera = camera2.NewCamera()
-	player.mainCamera.SetOsuViewport(int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight()), settings.Playfield.Scale, settings.Playfield.OsuShift)
+	player.mainCamera.SetOsuViewportFit(int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight()), settings.Playfield.Scale, playfieldFit, settings.Playfield.OsuShift)
 	player.mainCamera.Update()
 
 	player.bgCamera = camera2.NewCamera()
@@ -217,33 +255,18 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 
 	player.bMap.Reset()
 
-	if settings.PLAY {
-		player.controller = dance.NewPlayerController()
-
-		player.controller.SetBeatMap(player.bMap)
-		player.controller.InitCursors()
-		player.overlay = overlays.NewScoreOverlay(player.controller.(*dance.PlayerController).GetRuleset(), player.controller.GetCursors()[0])
-	} else if settings.KNOCKOUT {
-		controller := dance.NewReplayController()
-		player.controller = controller
+	player.initController()
 
-		player.controller.SetBeatMap(player.bMap)
-		player.controller.InitCursors()
+	if controller, ok := player.controller.(*dance.ReplayController); ok && !settings.RECORD && controller.EnableSeeking() {
+		input.RegisterListener(player.seekKeyEvent)
 
-		if settings.PLAYERS == 1 {
-			player.overlay = overlays.NewScoreOverlay(player.controller.(*dance.ReplayController).GetRuleset(), player.controller.GetCursors()[0])
-		} else {
-			player.overlay = overlays.NewKnockoutOverlay(controller.(*dance.ReplayController))
-		}
-	} else {
-		player.controller = dance.NewGenericController()
-		player.controller.SetBeatMap(player.bMap)
-		player.controller.InitCursors()
+		player.seekable = true
 	}
 
-	player.lastTime = -1
+	player.playbackRate = 1
+	player.storyboardBreak = -1
 
-	player.objectContainer = containers.NewHitObjectContainer(beatMap)
+	player.lastTime = -1
 
 	player.Scl = 1
 	player.fadeOut = 1.0
@@ -253,6 +276,10 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 	player.speedGlider = animation.NewGlider(settings.SPEED)
 	player.pitchGlider = animation.NewGlider(settings.PITCH)
 
+	if beatMap.Diff.CheckModActive(difficulty.TimeRampMask) {
+		player.addRateRamp(math.Inf(-1))
+	}
+
 	player.hudGlider = animation.NewGlider(0)
 	player.hudGlider.SetEasing(easing.OutQuad)
 
@@ -281,12 +308,7 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 	skipTime = math.Max(skipTime, settings.START*1000) - preempt
 
 	beatmapStart := math.Max(beatMap.HitObjects[0].GetStartTime(), settings.START*1000) - preempt
-	beatmapEnd := beatMap.HitObjects[len(beatMap.HitObjects)-1].GetEndTime() + float64(beatMap.Diff.Hit50)
-
-	if !math.IsInf(settings.END, 1) {
-		end := settings.END * 1000
-		beatmapEnd = math.Min(end, beatMap.HitObjects[len(beatMap.HitObjects)-1].GetEndTime()) + float64(beatMap.Diff.Hit50)
-	}
+	beatmapEnd := player.getObjectsEnd()
 
 	startOffset := 0.0
 
@@ -359,6 +381,8 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 		s.SetBeatmapEnd(beatmapEnd + fadeOut)
 	}
 
+	player.objectsEnd = beatmapEnd
+
 	if !math.IsInf(settings.END, 1) {
 		for _, o := range beatMap.HitObjects {
 			if o.GetEndTime() <= beatmapEnd {
@@ -464,10 +488,25 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 		return player
 	}
 
+	_, isReplay := player.controller.(*dance.ReplayController)
+
+	// Replays were played on the map as it was, saved replay and hit log of -play would have to cover two different maps
+	if settings.PLAY && (settings.Gameplay.SaveReplays || settings.Recording.IsHitEventLogEnabled()) {
+		log.Println("Beatmap: Saving replays or hit log is enabled, map won't be reloaded on save")
+	} else if !isReplay {
+		player.watchMap()
+	}
+
+	player.updateAPIState(qpc.GetNanoTime())
+
 	goroutines.RunOS(func() {
 		var lastTimeNano = qpc.GetNanoTime()
 
 		for !input.Win.ShouldClose() {
+			player.processCommands()
+			player.processSeek()
+			player.processReload()
+
 			currentTimeNano := qpc.GetNanoTime()
 
 			delta := float64(currentTimeNano-lastTimeNano) / 1000000.0
@@ -482,7 +521,7 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 				if player.rawPositionF < player.startPointE || player.start {
 					player.rawPositionF += delta
 				} else {
-					speed = settings.SPEED
+					speed = player.speedGlider.GetValue()
 					player.rawPositionF += delta * speed
 				}
 			} else {
@@ -508,6 +547,8 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 
 			player.updateMain(delta)
 
+			player.updateAPIState(currentTimeNano)
+
 			lastTimeNano = currentTimeNano
 
 			player.updateLimiter.Sync()
@@ -520,13 +561,118 @@ func NewPlayer(beatMap *beatmap.BeatMap) *Player {
 	return player
 }
 
+// initController creates the controller, overlay and object container for the current mode, it's called again when the map is reloaded
+func (player *Player) initController() {
+	player.overlay = nil
+	player.objectContainer = nil
+
+	if settings.MODE == settings.ModeTaiko {
+		controller := dance.NewTaikoController()
+		player.controller = controller
+
+		player.controller.SetBeatMap(player.bMap)
+		player.controller.InitCursors()
+
+		ruleset := controller.(*dance.TaikoController).GetRuleset()
+
+		player.overlay = overlays.NewTaikoOverlay(ruleset, player.controller.GetCursors()[0])
+		player.objectContainer = containers.NewTaikoPlayfield(ruleset, player.controller.GetCursors()[0])
+	} else if settings.MODE == settings.ModeMania {
+		controller := dance.NewManiaController()
+		player.controller = controller
+
+		player.controller.SetBeatMap(player.bMap)
+		player.controller.InitCursors()
+
+		ruleset := controller.(*dance.ManiaController).GetRuleset()
+
+		player.overlay = overlays.NewManiaOverlay(ruleset, player.controller.GetCursors()[0])
+		player.objectContainer = containers.NewManiaPlayfield(ruleset, player.controller.GetCursors()[0])
+	} else if settings.MODE == settings.ModeCatch {
+		controller := dance.NewCatchController()
+		player.controller = controller
+
+		player.controller.SetBeatMap(player.bMap)
+		player.controller.InitCursors()
+
+		ruleset := controller.(*dance.CatchController).GetRuleset()
+
+		if settings.PLAYERS == 1 {
+			player.overlay = overlays.NewCatchOverlay(ruleset, player.controller.GetCursors()[0])
+		} else {
+			player.overlay = overlays.NewKnockoutOverlay(controller.(*dance.CatchController), ruleset)
+		}
+
+		player.objectContainer = containers.NewCatchPlayfield(ruleset, player.controller.GetCursors())
+	} else if settings.PLAY {
+		player.controller = dance.NewPlayerController()
+
+		player.controller.SetBeatMap(player.bMap)
+		player.controller.InitCursors()
+		player.overlay = overlays.NewScoreOverlay(player.controller.(*dance.PlayerController).GetRuleset(), player.controller.GetCursors()[0])
+	} else if settings.KNOCKOUT {
+		controller := dance.NewReplayController()
+		player.controller = controller
+
+		player.controller.SetBeatMap(player.bMap)
+		player.controller.InitCursors()
+
+		player.overlay = player.newReplayOverlay()
+	} else {
+		player.controller = dance.NewGenericController()
+		player.controller.SetBeatMap(player.bMap)
+		player.controller.InitCursors()
+	}
+
+	if player.objectContainer == nil {
+		player.objectContainer = containers.NewHitObjectContainer(player.bMap)
+	}
+}
+
+// getObjectsEnd returns the time when the last object can't be judged anymore, capped by -end
+func (player *Player) getObjectsEnd() float64 {
+	lastEnd := player.bMap.HitObjects[len(player.bMap.HitObjects)-1].GetEndTime()
+
+	if !math.IsInf(settings.END, 1) {
+		lastEnd = math.Min(settings.END*1000, lastEnd)
+	}
+
+	return lastEnd + float64(player.bMap.Diff.Hit50)
+}
+
+// newReplayOverlay creates the overlay for replays and knockout, it's recreated after seeking backwards
+func (player *Player) newReplayOverlay() overlays.Overlay {
+	controller := player.controller.(*dance.ReplayController)
+
+	if settings.PLAYERS == 1 {
+		return overlays.NewScoreOverlay(controller.GetRuleset(), controller.GetCursors()[0])
+	}
+
+	return overlays.NewKnockoutOverlay(controller, controller.GetRuleset())
+}
+
+// addRateRamp makes the music follow WindUp/WindDown rate changes from a given time
+func (player *Player) addRateRamp(time float64) {
+	rampStart, rampEnd := player.bMap.Diff.GetRampBounds()
+
+	initialSpeed := player.bMap.Diff.GetSpeedAt(rampStart)
+	startRate := player.bMap.Diff.GetSpeedAt(time) / initialSpeed
+	finalRate := player.bMap.Diff.GetSpeedAt(rampEnd) / initialSpeed
+
+	startTime := math.Max(time, rampStart)
+
+	// WindUp and WindDown change the pitch along with the tempo, like osu!lazer does by default
+	player.speedGlider.AddEventS(startTime, math.Max(startTime, rampEnd), settings.SPEED*startRate, settings.SPEED*finalRate)
+	player.pitchGlider.AddEventS(startTime, math.Max(startTime, rampEnd), settings.PITCH*startRate, settings.PITCH*finalRate)
+}
+
 func (player *Player) Update(delta float64) bool {
 	speed := 1.0
 
 	if player.musicPlayer.GetState() == bass.MusicPlaying {
 		speed = player.musicPlayer.GetTempo() * player.musicPlayer.GetRelativeFrequency()
 	} else if !(player.progressMsF < player.startPointE || player.start) {
-		speed = settings.SPEED
+		speed = player.speedGlider.GetValue()
 	}
 
 	player.rawPositionF += delta * speed
@@ -545,6 +691,76 @@ func (player *Player) Update(delta float64) bool {
 	return false
 }
 
+// GetRuleset returns the ruleset judging the cursors, nil if nothing is judged (cursordance without knockout)
+func (player *Player) GetRuleset() *osu.OsuRuleSet {
+	switch controller := player.controller.(type) {
+	case *dance.PlayerController:
+		return controller.GetRuleset()
+	case *dance.ReplayController:
+		return controller.GetRuleset()
+	}
+
+	return nil
+}
+
+// updateStoryboardPassing evaluates storyboard's Passing/Failing state when a break starts, like osu! stable does.
+// Before the first break storyboard is passing.
+func (player *Player) updateStoryboardPassing() {
+	storyboard := player.background.GetStoryboard()
+	if storyboard == nil {
+		return
+	}
+
+	breakIndex := -1
+
+	for i, pause := range player.bMap.Pauses {
+		if player.progressMsF < pause.GetStartTime() {
+			break
+		}
+
+		breakIndex = i
+	}
+
+	if breakIndex == player.storyboardBreak {
+		return
+	}
+
+	player.storyboardBreak = breakIndex
+
+	storyboard.SetPassing(breakIndex == -1 || player.isPassing())
+}
+
+// isPassing returns true if any of judged players has at least half of HP, cursor dances are always passing
+func (player *Player) isPassing() bool {
+	var getHP func(cursor *graphics.Cursor) float64
+
+	switch controller := player.controller.(type) {
+	case *dance.PlayerController, *dance.ReplayController:
+		ruleset := player.GetRuleset()
+		if ruleset == nil {
+			return true
+		}
+
+		getHP = ruleset.GetHP
+	case *dance.TaikoController:
+		getHP = controller.GetRuleset().GetHP
+	case *dance.CatchController:
+		getHP = controller.GetRuleset().GetHP
+	case *dance.ManiaController:
+		getHP = controller.GetRuleset().GetHP
+	default:
+		return true
+	}
+
+	for _, cursor := range player.controller.GetCursors() {
+		if getHP(cursor) >= 0.5 {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (player *Player) GetTime() float64 {
 	return player.progressMsF
 }
@@ -553,6 +769,10 @@ func (player *Player) GetTimeOffset() float64 {
 	return player.progressMsF - player.startOffset
 }
 
+func (player *Player) GetRunningTime() float64 {
+	return player.RunningTime
+}
+
 func (player *Player) updateMain(delta float64) {
 	if player.rawPositionF >= player.startPoint && !player.start {
 		player.musicPlayer.Play()
@@ -575,7 +795,7 @@ func (player *Player) updateMain(delta float64) {
 	player.speedGlider.Update(player.progressMsF)
 	player.pitchGlider.Update(player.progressMsF)
 
-	player.musicPlayer.SetTempo(player.speedGlider.GetValue())
+	player.musicPlayer.SetTempo(player.speedGlider.GetValue() * player.playbackRate)
 	player.musicPlayer.SetPitch(player.pitchGlider.GetValue())
 
 	if player.progressMsF >= player.startPointE {
@@ -611,8 +831,16 @@ func (player *Player) updateMain(delta float64) {
 		player.overlay.Update(player.progressMsF)
 	}
 
+	player.updateStoryboardPassing()
+
 	player.updateMusic(delta)
 
+	// Updated here and not while drawing so chunks of -chunks, which skip drawing, get the same colors
+	if player.progressMsF > 0 {
+		settings.Cursor.Colors.Update(player.progressMsF - player.lastProgressMsF)
+		player.lastProgressMsF = player.progressMsF
+	}
+
 	player.coin.Update(player.progressMsF)
 	player.coin.SetAlpha(float32(player.fxGlider.GetValue()))
 
@@ -688,12 +916,6 @@ func (player *Player) Draw(float64) {
 
 	player.background.Draw(player.progressMsF, player.batch, player.blurGlider.GetValue(), bgAlpha, player.bgCamera.GetProjectionView())
 
-	if player.progressMsF > 0 {
-		timeDiff := player.progressMsF - player.lastProgressMsF
-		settings.Cursor.Colors.Update(timeDiff)
-		player.lastProgressMsF = player.progressMsF
-	}
-
 	cursorColors := settings.Cursor.GetColors(settings.DIVIDES, len(player.controller.GetCursors()), player.Scl, player.cursorGlider.GetValue())
 
 	if player.overlay != nil {
@@ -739,11 +961,12 @@ func (player *Player) Draw(float64) {
 
 	player.background.DrawOverlay(player.progressMsF, player.batch, bgAlpha, player.bgCamera.GetProjectionView())
 
-	if player.overlay != nil && player.overlay.ShouldDrawHUDBeforeCursor() {
+	if player.overlay != nil && !player.hudHidden && player.overlay.ShouldDrawHUDBeforeCursor() {
 		player.drawOverlayPart(player.overlay.DrawHUD, cursorColors, player.uiCamera.GetProjectionView())
 	}
 
-	if settings.Playfield.DrawCursors {
+	// Cursors are meaningful only in osu!standard, knockout overlay doesn't hide them in other modes
+	if settings.Playfield.DrawCursors && settings.MODE == settings.ModeOsu {
 		for _, g := range player.controller.GetCursors() {
 			g.UpdateRenderer()
 		}
@@ -779,7 +1002,7 @@ func (player *Player) Draw(float64) {
 
 	player.batch.SetAdditive(false)
 
-	if player.overlay != nil && !player.overlay.ShouldDrawHUDBeforeCursor() {
+	if player.overlay != nil && !player.hudHidden && !player.overlay.ShouldDrawHUDBeforeCursor() {
 		player.drawOverlayPart(player.overlay.DrawHUD, cursorColors, player.uiCamera.GetProjectionView())
 	}
 
@@ -909,8 +1132,15 @@ func (player *Player) drawDebug() {
 			drawWithBackground(12, fmt.Sprintf("Draw Calls: %d", statistic.GetPrevious(statistic.DrawCalls)))
 			drawWithBackground(13, fmt.Sprintf("Sprites Drawn: %d", statistic.GetPrevious(statistic.SpritesDrawn)))
 
+			prepared := fmt.Sprintf("Sprites Prepared: %d", statistic.GetPrevious(statistic.SpritesPrepared))
+			if prepareTime := statistic.GetPrevious(statistic.SpritePrepareTime); prepareTime > 0 {
+				prepared += fmt.Sprintf(" in %.2fms (%.1fx)", float64(prepareTime)/1000, float64(statistic.GetPrevious(statistic.SpritePrepareWork))/float64(prepareTime))
+			}
+
+			drawWithBackground(14, prepared)
+
 			if storyboard := player.background.GetStoryboard(); storyboard != nil {
-				drawWithBackground(14, fmt.Sprintf("SB sprites: %d", player.storyboardDrawn))
+				drawWithBackground(15, fmt.Sprintf("SB sprites: %d", player.storyboardDrawn))
 			}
 
 			player.batch.ResetTransform()
@@ -970,4 +1200,9 @@ func (player *Player) Show() {}
 
 func (player *Player) Hide() {}
 
-func (player *Player) Dispose() {}
+// Dispose stops the music so it's not mixed into the next map and drops storyboard's hitsound listener
+func (player *Player) Dispose() {
+	player.musicPlayer.Stop()
+
+	audio.RemoveListeners()
+}
@@ -0,0 +1,214 @@
+package states
+
+import (
+	"github.com/faiface/mainthread"
+	"github.com/fsnotify/fsnotify"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/objects"
+	"github.com/wieku/danser-go/app/dance"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/skin"
+	"github.com/wieku/danser-go/app/states/components/overlays"
+	"github.com/wieku/danser-go/framework/goroutines"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// watchMap reloads the map when its .osu file is saved
+func (player *Player) watchMap() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Println("Beatmap: Failed to watch beatmap file:", err)
+		return
+	}
+
+	path, _ := filepath.Abs(filepath.Join(settings.General.GetSongsDir(), player.bMap.Dir, player.bMap.File))
+
+	goroutines.Run(func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				abs, _ := filepath.Abs(event.Name)
+
+				// Editors often save by replacing the file so Create is watched too
+				if abs != path || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+
+				log.Println("Beatmap: Detected", event.Name, "modification, reloading...")
+
+				time.Sleep(time.Millisecond * 200)
+
+				// Saving usually fires a few events
+			drain:
+				for {
+					select {
+					case <-watcher.Events:
+					default:
+						break drain
+					}
+				}
+
+				player.reloadMutex.Lock()
+				player.reloadPending = true
+				player.reloadMutex.Unlock()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				log.Println("Beatmap:", err)
+			}
+		}
+	})
+
+	if err = watcher.Add(filepath.Dir(path)); err != nil {
+		log.Println("Beatmap: Failed to watch beatmap file:", err)
+	}
+}
+
+func (player *Player) processReload() {
+	player.reloadMutex.Lock()
+	pending := player.reloadPending
+	player.reloadPending = false
+	player.reloadMutex.Unlock()
+
+	if !pending {
+		return
+	}
+
+	// Sliders and overlays are recreated so it has to be done on the GL thread, it also stops drawing during the reload
+	mainthread.Call(player.reloadMap)
+}
+
+// loadReloadedMap parses the map again, returns nil if the file is broken or has no objects
+func (player *Player) loadReloadedMap() (beatMap *beatmap.BeatMap) {
+	// Map can be saved mid-edit, that shouldn't close danser
+	defer func() {
+		if err := recover(); err != nil {
+			log.Println("Beatmap: Failed to reload:", err)
+			beatMap = nil
+		}
+	}()
+
+	beatMap, err := beatmap.ReloadBeatMap(player.bMap)
+	if err != nil {
+		log.Println("Beatmap: Failed to reload:", err)
+		return nil
+	}
+
+	skin.ResetBeatmapColors()
+
+	beatmap.ParseObjects(beatMap, false, true)
+
+	if settings.PLAY {
+		// Objects that already passed would be missed, so the map is cut like with -start
+		first := len(beatMap.HitObjects)
+
+		for i, o := range beatMap.HitObjects {
+			if o.GetStartTime() > player.progressMsF {
+				first = i
+				break
+			}
+		}
+
+		beatMap.HitObjects = beatMap.HitObjects[first:]
+
+		for i, o := range beatMap.HitObjects {
+			o.SetID(int64(i))
+		}
+	}
+
+	if len(beatMap.HitObjects) == 0 {
+		log.Println("Beatmap: Reloaded map has no objects to play, keeping the old one")
+		return nil
+	}
+
+	return beatMap
+}
+
+// reloadMap swaps the map and rebuilds the controller and overlay, bringing them to the current time like seeking does.
+// Intro, breaks and fade out keep the timing of the map as it was opened.
+func (player *Player) reloadMap() {
+	beatMap := player.loadReloadedMap()
+	if beatMap == nil {
+		return
+	}
+
+	target := player.progressMsF
+
+	oldObjects := player.bMap.HitObjects
+	oldEnd := player.getObjectsEnd()
+
+	player.bMap = beatMap
+	player.bMap.Reset()
+
+	// Old controller would still react to keys
+	if controller, ok := player.controller.(*dance.PlayerController); ok {
+		controller.Dispose()
+	}
+
+	player.initController()
+
+	endDelta := player.getObjectsEnd() - oldEnd
+
+	player.objectsEnd += endDelta
+	player.mapEndL += endDelta
+	player.MapEnd += endDelta
+	player.RunningTime += endDelta
+
+	if player.overlay != nil {
+		if player.start {
+			player.overlay.SetMusic(player.musicPlayer)
+		}
+
+		if s, ok := player.overlay.(*overlays.ScoreOverlay); ok {
+			s.SetBeatmapEnd(player.mapEndL)
+		}
+
+		player.overlay.DisableAudioSubmission(true)
+	}
+
+	for _, o := range player.bMap.HitObjects {
+		o.DisableAudioSubmission(true)
+	}
+
+	for t := -1000.0; t < target; t++ {
+		player.controller.Update(t, 1)
+
+		if player.overlay != nil {
+			player.overlay.Update(t)
+		}
+	}
+
+	if player.overlay != nil {
+		player.overlay.DisableAudioSubmission(false)
+	}
+
+	for _, o := range player.bMap.HitObjects {
+		o.DisableAudioSubmission(o.GetStartTime() < target || o.GetEndTime() > player.objectsEnd)
+	}
+
+	player.coin.SetMap(player.bMap, player.musicPlayer)
+
+	if player.nightcore != nil {
+		player.nightcore.SetMap(player.bMap, player.musicPlayer)
+	}
+
+	for _, o := range oldObjects {
+		switch o := o.(type) {
+		case *objects.Slider:
+			o.StopSlideSamples()
+			o.DisposeBody()
+		case *objects.Spinner:
+			o.StopSpinSample()
+		}
+	}
+
+	log.Println("Beatmap reloaded:", len(player.bMap.HitObjects), "objects")
+}
@@ -0,0 +1,118 @@
+package states
+
+import (
+	"github.com/faiface/mainthread"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/dance"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/states/components/containers"
+	"github.com/wieku/danser-go/app/states/components/overlays"
+	"github.com/wieku/danser-go/framework/math/mutils"
+	"log"
+	"math"
+)
+
+// seekStep is the time skipped by a single arrow key press
+const seekStep = 5000.0
+
+// seekLeadIn makes rewinding start from an earlier snapshot, so objects judged shortly before the target time are visible again
+const seekLeadIn = 2000.0
+
+func (player *Player) seekKeyEvent(_ *glfw.Window, key glfw.Key, _ int, action glfw.Action, _ glfw.ModifierKey) {
+	if action != glfw.Press && action != glfw.Repeat {
+		return
+	}
+
+	switch key {
+	case glfw.KeyLeft:
+		player.requestSeek(-seekStep)
+	case glfw.KeyRight:
+		player.requestSeek(seekStep)
+	}
+}
+
+// requestSeek schedules a seek by a given offset, it's done by the update thread
+func (player *Player) requestSeek(offset float64) {
+	player.seekMutex.Lock()
+	player.seekOffset += offset
+	player.seekMutex.Unlock()
+}
+
+func (player *Player) processSeek() {
+	player.seekMutex.Lock()
+	offset := player.seekOffset
+	player.seekOffset = 0
+	player.seekMutex.Unlock()
+
+	if offset == 0 || !player.start {
+		return
+	}
+
+	// Overlays are recreated so it has to be done on the GL thread, it also stops drawing during the seek
+	mainthread.Call(func() {
+		player.seekTo(player.progressMsF + offset)
+	})
+}
+
+func (player *Player) seekTo(target float64) {
+	controller := player.controller.(*dance.ReplayController)
+
+	target = mutils.ClampF(target, player.startPoint, player.mapEndL)
+
+	from := player.progressMsF
+
+	if math.Abs(target-from) < 1 {
+		return
+	}
+
+	log.Printf("Seeking to %.0fms...", target)
+
+	if target < from {
+		from = controller.Rewind(target - seekLeadIn)
+
+		player.bMap.Reset()
+		player.objectContainer = containers.NewHitObjectContainer(player.bMap)
+
+		player.overlay = player.newReplayOverlay()
+		player.overlay.SetMusic(player.musicPlayer)
+
+		if s, ok := player.overlay.(*overlays.ScoreOverlay); ok {
+			s.SetBeatmapEnd(player.mapEndL)
+		}
+
+		// Rebuild combo, score and knockout state of the new overlay
+		controller.GetRuleset().ResendHistory()
+	}
+
+	for _, o := range player.bMap.HitObjects {
+		o.DisableAudioSubmission(true)
+	}
+
+	player.overlay.DisableAudioSubmission(true)
+
+	for t := from + 1; t < target; t++ {
+		player.controller.Update(t, 1)
+		player.overlay.Update(t)
+	}
+
+	player.overlay.DisableAudioSubmission(false)
+
+	for _, o := range player.bMap.HitObjects {
+		o.DisableAudioSubmission(o.GetStartTime() < target || o.GetEndTime() > player.objectsEnd)
+	}
+
+	if player.bMap.Diff.CheckModActive(difficulty.TimeRampMask) {
+		rate := player.bMap.Diff.GetSpeedAt(target) / player.bMap.Diff.GetSpeedAt(math.Inf(-1))
+
+		player.speedGlider.AddEventS(target, target, settings.SPEED*rate, settings.SPEED*rate)
+		player.pitchGlider.AddEventS(target, target, settings.PITCH*rate, settings.PITCH*rate)
+
+		player.addRateRamp(target)
+	}
+
+	player.musicPlayer.SetPosition((target - (player.progressMsF - player.rawPositionF)) / 1000)
+
+	player.rawPositionF += target - player.progressMsF
+	player.progressMsF = target
+}
@@ -6,3 +6,12 @@ type State interface {
 	Draw(delta float64)
 	Dispose()
 }
+
+// TimedState is a State following map's time, recording and screenshot loops update it themselves
+type TimedState interface {
+	State
+	Update(delta float64) bool
+	GetTime() float64
+	GetTimeOffset() float64
+	GetRunningTime() float64
+}
@@ -0,0 +1,254 @@
+package states
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	camera2 "github.com/wieku/danser-go/app/bmath/camera"
+	"github.com/wieku/danser-go/app/discord"
+	"github.com/wieku/danser-go/app/input"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/states/components/common"
+	"github.com/wieku/danser-go/framework/bass"
+	"github.com/wieku/danser-go/framework/frame"
+	"github.com/wieku/danser-go/framework/goroutines"
+	batch2 "github.com/wieku/danser-go/framework/graphics/batch"
+	"github.com/wieku/danser-go/framework/graphics/viewport"
+	"github.com/wieku/danser-go/framework/math/animation"
+	"github.com/wieku/danser-go/framework/qpc"
+	"log"
+	"math"
+	"path/filepath"
+	"runtime"
+)
+
+// StoryboardViewer plays map's storyboard and video with the music, without objects, cursors and HUD
+type StoryboardViewer struct {
+	batch       *batch2.QuadBatch
+	background  *common.Background
+	musicPlayer bass.ITrack
+	camera      *camera2.Camera
+	widescreen  bool
+
+	startPoint   float64
+	startOffset  float64
+	mapEnd       float64
+	runningTime  float64
+	start        bool
+	lastMusicPos float64
+
+	rawPositionF float64
+	progressMsF  float64
+
+	fadeGlider    *animation.Glider
+	updateLimiter *frame.Limiter
+}
+
+func NewStoryboardViewer(beatMap *beatmap.BeatMap) *StoryboardViewer {
+	viewer := new(StoryboardViewer)
+
+	if settings.Graphics.Experimental.UsePersistentBuffers {
+		viewer.batch = batch2.NewQuadBatchPersistent()
+	} else {
+		viewer.batch = batch2.NewQuadBatch()
+	}
+
+	discord.SetMap(beatMap.Artist, beatMap.Name, beatMap.Difficulty)
+
+	log.Println("Playing storyboard of:", beatMap.Artist, "-", beatMap.Name, "["+beatMap.Difficulty+"]")
+
+	track := bass.NewTrack(filepath.Join(settings.General.GetSongsDir(), beatMap.Dir, beatMap.Audio))
+	if track == nil {
+		log.Println("Failed to create music stream, creating a dummy stream...")
+
+		length := 1.0
+		if len(beatMap.HitObjects) > 0 {
+			length += beatMap.HitObjects[len(beatMap.HitObjects)-1].GetEndTime() / 1000
+		}
+
+		viewer.musicPlayer = bass.NewTrackVirtual(length)
+	} else {
+		viewer.musicPlayer = track
+	}
+
+	viewer.background = common.NewBackground(true)
+	viewer.background.SetBeatmap(beatMap, true, true)
+	viewer.background.SetTrack(viewer.musicPlayer)
+
+	viewer.camera = camera2.NewCamera()
+
+	if sb := viewer.background.GetStoryboard(); sb != nil {
+		viewer.setWidescreen(sb.IsWideScreen())
+	} else {
+		viewer.setWidescreen(true)
+	}
+
+	viewer.startPoint = math.Max(0, settings.START*1000)
+	viewer.startOffset = settings.START*1000 - settings.Playfield.LeadInTime*1000
+
+	viewer.mapEnd = viewer.musicPlayer.GetLength() * 1000
+	if !math.IsInf(settings.END, 1) {
+		viewer.mapEnd = math.Min(viewer.mapEnd, settings.END*1000)
+	}
+
+	viewer.runningTime = viewer.mapEnd - viewer.startOffset
+
+	viewer.rawPositionF = viewer.startOffset
+	viewer.progressMsF = viewer.startOffset
+
+	fadeOut := settings.Playfield.FadeOutTime * 1000
+
+	viewer.fadeGlider = animation.NewGlider(0)
+	viewer.fadeGlider.AddEvent(viewer.startOffset, viewer.startOffset+500, 1)
+	viewer.fadeGlider.AddEvent(viewer.mapEnd-fadeOut, viewer.mapEnd, 0)
+
+	viewer.background.Update(viewer.progressMsF, 0, 0)
+
+	viewer.updateLimiter = frame.NewLimiter(2000)
+
+	if settings.RECORD {
+		return viewer
+	}
+
+	goroutines.RunOS(func() {
+		var lastTimeNano = qpc.GetNanoTime()
+
+		for !input.Win.ShouldClose() {
+			currentTimeNano := qpc.GetNanoTime()
+
+			delta := float64(currentTimeNano-lastTimeNano) / 1000000.0
+
+			speed := settings.SPEED
+
+			if viewer.musicPlayer.GetState() == bass.MusicStopped {
+				viewer.rawPositionF += delta * speed
+			} else {
+				musicPos := viewer.musicPlayer.GetPosition() * 1000
+				speed = viewer.musicPlayer.GetTempo()
+
+				if musicPos != viewer.lastMusicPos || viewer.musicPlayer.GetState() == bass.MusicPaused {
+					viewer.rawPositionF = musicPos
+					viewer.lastMusicPos = musicPos
+				} else if musicPos > 1 {
+					// Music position is reported in intervals, see Player
+					viewer.rawPositionF += delta * speed
+				}
+			}
+
+			platformOffset := 0.0
+			if runtime.GOOS == "windows" {
+				platformOffset = windowsOffset
+			}
+
+			viewer.progressMsF = viewer.rawPositionF + (platformOffset+float64(settings.Audio.Offset)+float64(settings.LOCALOFFSET))*speed
+
+			viewer.updateMain()
+
+			lastTimeNano = currentTimeNano
+
+			viewer.updateLimiter.Sync()
+		}
+
+		viewer.musicPlayer.Stop()
+		bass.StopLoops()
+	})
+
+	return viewer
+}
+
+func (viewer *StoryboardViewer) Update(delta float64) bool {
+	speed := settings.SPEED
+
+	if viewer.musicPlayer.GetState() == bass.MusicPlaying {
+		speed = viewer.musicPlayer.GetTempo() * viewer.musicPlayer.GetRelativeFrequency()
+	}
+
+	viewer.rawPositionF += delta * speed
+
+	viewer.progressMsF = viewer.rawPositionF + float64(settings.LOCALOFFSET)*speed
+
+	viewer.updateMain()
+
+	if viewer.progressMsF >= viewer.mapEnd {
+		viewer.musicPlayer.Stop()
+		bass.StopLoops()
+
+		return true
+	}
+
+	return false
+}
+
+func (viewer *StoryboardViewer) updateMain() {
+	if viewer.rawPositionF >= viewer.startPoint && !viewer.start {
+		viewer.musicPlayer.Play()
+		viewer.musicPlayer.SetTempo(settings.SPEED)
+		viewer.musicPlayer.SetPitch(settings.PITCH)
+		viewer.musicPlayer.SetPosition(viewer.startPoint / 1000)
+
+		discord.SetDuration(int64((viewer.mapEnd - viewer.startPoint) / settings.SPEED))
+
+		viewer.start = true
+	}
+
+	viewer.musicPlayer.Update()
+
+	viewer.fadeGlider.Update(viewer.progressMsF)
+
+	if viewer.musicPlayer.GetState() == bass.MusicPlaying {
+		viewer.musicPlayer.SetVolumeRelative(viewer.fadeGlider.GetValue())
+	}
+
+	viewer.background.Update(viewer.progressMsF, 0, 0)
+}
+
+func (viewer *StoryboardViewer) GetTime() float64 {
+	return viewer.progressMsF
+}
+
+func (viewer *StoryboardViewer) GetTimeOffset() float64 {
+	return viewer.progressMsF - viewer.startOffset
+}
+
+func (viewer *StoryboardViewer) GetRunningTime() float64 {
+	return viewer.runningTime
+}
+
+func (viewer *StoryboardViewer) Draw(float64) {
+	// Storyboard may be reloaded with a different WidescreenStoryboard value
+	if sb := viewer.background.GetStoryboard(); sb != nil && sb.IsWideScreen() != viewer.widescreen {
+		viewer.setWidescreen(sb.IsWideScreen())
+	}
+
+	w, h := settings.Graphics.GetWidthF(), settings.Graphics.GetHeightF()
+
+	sbWidth := camera2.StoryboardWidth
+	if viewer.widescreen {
+		sbWidth = camera2.StoryboardWideWidth
+	}
+
+	scl := math.Min(w/sbWidth, h/camera2.StoryboardHeight)
+	clipW, clipH := sbWidth*scl, camera2.StoryboardHeight*scl
+
+	// Sprites outside storyboard's area aren't visible in osu! either
+	viewport.PushScissorPos(int((w-clipW)/2), int((h-clipH)/2), int(clipW), int(clipH))
+
+	alpha := viewer.fadeGlider.GetValue()
+
+	viewer.background.Draw(viewer.progressMsF, viewer.batch, 0, alpha, viewer.camera.GetProjectionView())
+	viewer.background.DrawOverlay(viewer.progressMsF, viewer.batch, alpha, viewer.camera.GetProjectionView())
+
+	viewport.PopScissor()
+}
+
+func (viewer *StoryboardViewer) setWidescreen(widescreen bool) {
+	viewer.widescreen = widescreen
+	viewer.camera.SetStoryboardViewport(int(settings.Graphics.GetWidth()), int(settings.Graphics.GetHeight()), widescreen)
+}
+
+func (viewer *StoryboardViewer) Show() {}
+
+func (viewer *StoryboardViewer) Hide() {}
+
+// Dispose stops the music so it's not mixed into the next map
+func (viewer *StoryboardViewer) Dispose() {
+	viewer.musicPlayer.Stop()
+}
@@ -2,6 +2,8 @@ package storyboard
 
 import (
 	"fmt"
+	"github.com/faiface/mainthread"
+	"github.com/fsnotify/fsnotify"
 	"github.com/wieku/danser-go/app/beatmap"
 	"github.com/wieku/danser-go/app/settings"
 	"github.com/wieku/danser-go/app/skin"
@@ -18,10 +20,13 @@ import (
 	"github.com/wieku/danser-go/framework/math/vector"
 	"github.com/wieku/danser-go/framework/qpc"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Storyboard struct {
@@ -47,6 +52,19 @@ type Storyboard struct {
 
 	videos     []sprite.ISprite
 	videoAlpha float64
+
+	triggers []*Trigger
+
+	beatMap *beatmap.BeatMap
+
+	// Guards Update from reload swapping storyboard's content
+	mutex *sync.Mutex
+
+	// Game events are queued and applied on the update thread
+	eventMutex    *sync.Mutex
+	pendingHits   []hitSound
+	pendingStates []int
+	passing       bool
 }
 
 func getSection(line string) string {
@@ -59,14 +77,56 @@ func getSection(line string) string {
 }
 
 func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
+	storyboard, found := loadStoryboard(beatMap, nil)
+	if !found {
+		return nil
+	}
+
+	log.Println("Storyboard loaded")
+
+	storyboard.beatMap = beatMap
+	storyboard.mutex = &sync.Mutex{}
+	storyboard.eventMutex = &sync.Mutex{}
+	storyboard.passing = true
+
+	storyboard.currentTime = -1000000
+	storyboard.limiter = frame.NewLimiter(2000)
+	storyboard.counter = frame.NewCounter()
+
+	if !settings.RECORD {
+		storyboard.watch()
+	}
+
+	return storyboard
+}
+
+// loadStoryboard parses storyboard files of the map, previous storyboard's videos and already played samples are kept when reloading.
+// Reloading returns nil if storyboard files are broken.
+func loadStoryboard(beatMap *beatmap.BeatMap, previous *Storyboard) (storyboard *Storyboard, found bool) {
 	path := filepath.Join(settings.General.GetSongsDir(), beatMap.Dir)
 
-	files := []string{
-		filepath.Join(path, beatMap.File),
-		filepath.Join(path, files2.FixName(fmt.Sprintf("%s - %s (%s).osb", beatMap.Artist, beatMap.Name, beatMap.Creator))),
+	var file *os.File
+
+	// Files can be saved mid-edit, that shouldn't close danser
+	if previous != nil {
+		defer func() {
+			if err := recover(); err != nil {
+				log.Println("Storyboard: Failed to reload:", err)
+
+				if file != nil {
+					file.Close()
+				}
+
+				if storyboard != nil && storyboard.atlas != nil {
+					storyboard.atlas.Dispose()
+				}
+
+				storyboard, found = nil, false
+			}
+		}()
 	}
 
-	storyboard := &Storyboard{
+	storyboard = &Storyboard{
 		textures:   make(map[string]*texture.TextureRegion),
 		samples:    make(map[string]*bass.Sample),
 		zIndex:     -1,
@@ -78,6 +138,11 @@ func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
 		videos:     make([]sprite.ISprite, 0),
 	}
 
+	storyboard.background.SetParallel(true)
+	storyboard.pass.SetParallel(true)
+	storyboard.foreground.SetParallel(true)
+	storyboard.overlay.SetParallel(true)
+
 	storyboard.pathCache, _ = files2.NewFileMap(path)
 
 	var currentSection string
@@ -88,8 +153,10 @@ func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
 	hasVideo := false
 	hasAudio := false
 
-	for _, fS := range files {
-		file, err := os.Open(fS)
+	for _, fS := range getStoryboardFiles(beatMap) {
+		var err error
+
+		file, err = os.Open(fS)
 
 		log.Println("Trying to load storyboard from: ", fS)
 
@@ -135,6 +202,13 @@ func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
 					startTime, _ := strconv.ParseFloat(spl[1], 64)
 					volume, _ := strconv.ParseFloat(spl[4], 64)
 
+					hasAudio = true
+
+					// Playing them again would double the sound
+					if previous != nil && startTime < previous.currentTime {
+						continue
+					}
+
 					sample := strings.TrimSpace(strings.ReplaceAll(spl[3], `"`, ""))
 
 					if filepath.Ext(sample) == "" {
@@ -144,9 +218,7 @@ func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
 					sbSprite := sprite.NewAudioSprite(storyboard.getSample(sample), startTime, volume/100)
 
 					storyboard.addSpriteToLayer(spl[2], sbSprite)
-
-					hasAudio = true
-				} else if settings.Playfield.Background.LoadVideos && (strings.HasPrefix(line, "Video") || strings.HasPrefix(line, "1")) {
+				} else if settings.Playfield.Background.LoadVideos && previous == nil && (strings.HasPrefix(line, "Video") || strings.HasPrefix(line, "1")) {
 					spl := strings.Split(line, ",")
 
 					video := video2.NewVideo(filepath.Join(path, strings.TrimSpace(strings.ReplaceAll(spl[2], `"`, ""))), -1, vector.NewVec2d(320, 240), vector.Centre)
@@ -191,6 +263,18 @@ func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
 		}
 
 		file.Close()
+		file = nil
+	}
+
+	// Videos keep decoding where they were
+	if previous != nil {
+		for _, video := range previous.videos {
+			storyboard.background.Add(video)
+		}
+
+		storyboard.videos = previous.videos
+
+		hasVideo = len(storyboard.videos) > 0
 	}
 
 	storyboard.hasVisuals = storyboard.numSprites > 0 || hasVideo
@@ -198,10 +282,11 @@ func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
 	if storyboard.numSprites == 0 {
 		if storyboard.atlas != nil {
 			storyboard.atlas.Dispose()
+			storyboard.atlas = nil
 		}
 
 		if !hasVideo && !hasAudio {
-			return nil
+			return storyboard, false
 		} else if !storyboard.widescreen {
 			storyboard.widescreen = true
 		}
@@ -213,13 +298,121 @@ func NewStoryboard(beatMap *beatmap.BeatMap) *Storyboard {
 		}
 	}
 
-	log.Println("Storyboard loaded")
+	return storyboard, true
+}
 
-	storyboard.currentTime = -1000000
-	storyboard.limiter = frame.NewLimiter(2000)
-	storyboard.counter = frame.NewCounter()
+func getStoryboardFiles(beatMap *beatmap.BeatMap) []string {
+	path := filepath.Join(settings.General.GetSongsDir(), beatMap.Dir)
 
-	return storyboard
+	return []string{
+		filepath.Join(path, beatMap.File),
+		filepath.Join(path, files2.FixName(fmt.Sprintf("%s - %s (%s).osb", beatMap.Artist, beatMap.Name, beatMap.Creator))),
+	}
+}
+
+// watch reloads the storyboard when map's .osb or .osu file is saved
+func (storyboard *Storyboard) watch() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Println("Storyboard: Failed to watch storyboard files:", err)
+		return
+	}
+
+	files := make(map[string]bool)
+
+	for _, f := range getStoryboardFiles(storyboard.beatMap) {
+		abs, _ := filepath.Abs(f)
+		files[abs] = true
+	}
+
+	goroutines.Run(func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				abs, _ := filepath.Abs(event.Name)
+
+				// Editors often save by replacing the file so Create is watched too
+				if !files[abs] || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+
+				log.Println("Storyboard: Detected", event.Name, "modification, reloading...")
+
+				time.Sleep(time.Millisecond * 200)
+
+				// Saving usually fires a few events
+			drain:
+				for {
+					select {
+					case <-watcher.Events:
+					default:
+						break drain
+					}
+				}
+
+				mainthread.CallNonBlock(storyboard.reload)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				log.Println("Storyboard:", err)
+			}
+		}
+	})
+
+	dir, _ := filepath.Abs(filepath.Join(settings.General.GetSongsDir(), storyboard.beatMap.Dir))
+
+	if err = watcher.Add(dir); err != nil {
+		log.Println("Storyboard: Failed to watch storyboard files:", err)
+	}
+}
+
+// reload rebuilds the storyboard at current time, it has to run on the main thread as textures are loaded again.
+// Old storyboard is kept if new files can't be parsed.
+func (storyboard *Storyboard) reload() {
+	fresh, _ := loadStoryboard(storyboard.beatMap, storyboard)
+	if fresh == nil {
+		return
+	}
+
+	// Game events stay queued for the swapped content
+	fresh.eventMutex = &sync.Mutex{}
+	fresh.passing = storyboard.passing
+
+	fresh.Update(storyboard.currentTime)
+
+	storyboard.mutex.Lock()
+
+	oldAtlas := storyboard.atlas
+
+	storyboard.textures = fresh.textures
+	storyboard.atlas = fresh.atlas
+	storyboard.samples = fresh.samples
+	storyboard.background = fresh.background
+	storyboard.pass = fresh.pass
+	storyboard.foreground = fresh.foreground
+	storyboard.overlay = fresh.overlay
+	storyboard.zIndex = fresh.zIndex
+	storyboard.bgFileUsed = fresh.bgFileUsed
+	storyboard.widescreen = fresh.widescreen
+	storyboard.numSprites = fresh.numSprites
+	storyboard.pathCache = fresh.pathCache
+	storyboard.hasVisuals = fresh.hasVisuals
+	storyboard.videos = fresh.videos
+	storyboard.triggers = fresh.triggers
+
+	storyboard.mutex.Unlock()
+
+	if oldAtlas != nil {
+		oldAtlas.Dispose()
+	}
+
+	log.Println("Storyboard reloaded")
 }
 
 func (storyboard *Storyboard) loadSprite(currentSprite string, commands []string) {
@@ -269,12 +462,17 @@ func (storyboard *Storyboard) loadSprite(currentSprite string, commands []string
 	if len(textures) != 0 {
 		sbSprite := sprite.NewAnimation(textures, frameDelay, loopForever, float64(storyboard.zIndex), pos, origin)
 
-		transforms := parseCommands(commands)
+		transforms, triggers := parseCommands(commands)
 
 		sbSprite.ShowForever(false)
-		sbSprite.AddTransforms(transforms)
-		sbSprite.AdjustTimesToTransformations()
-		sbSprite.ResetValuesToTransforms()
+
+		if len(triggers) > 0 {
+			storyboard.addTriggers(sbSprite.Sprite, transforms, triggers)
+		} else {
+			sbSprite.AddTransforms(transforms)
+			sbSprite.AdjustTimesToTransformations()
+			sbSprite.ResetValuesToTransforms()
+		}
 
 		storyboard.addSpriteToLayer(spl[1], sbSprite)
 
@@ -282,6 +480,41 @@ func (storyboard *Storyboard) loadSprite(currentSprite string, commands []string
 	}
 }
 
+// addTriggers sets up sprite with trigger groups, sprite lives as long as any trigger can run
+func (storyboard *Storyboard) addTriggers(sbSprite *sprite.Sprite, transforms []*animation.Transformation, triggers []*Trigger) {
+	startTime := math.MaxFloat64
+	endTime := -math.MaxFloat64
+
+	// Initial values come from the first command of each type, triggered commands included
+	for _, trigger := range triggers {
+		sbSprite.AddTransforms(trigger.transforms)
+
+		trigger.sprite = sbSprite
+		trigger.siblings = triggers
+
+		startTime = math.Min(startTime, trigger.startTime)
+		endTime = math.Max(endTime, trigger.endTime+trigger.duration)
+	}
+
+	sbSprite.ResetValuesToTransforms()
+	sbSprite.ClearTransformations()
+
+	sbSprite.AddTransforms(transforms)
+	sbSprite.ResetValuesToTransforms()
+
+	if len(transforms) > 0 {
+		sbSprite.AdjustTimesToTransformations()
+
+		startTime = math.Min(startTime, sbSprite.GetStartTime())
+		endTime = math.Max(endTime, sbSprite.GetEndTime())
+	}
+
+	sbSprite.SetStartTime(startTime)
+	sbSprite.SetEndTime(endTime)
+
+	storyboard.triggers = append(storyboard.triggers, triggers...)
+}
+
 func (storyboard *Storyboard) addSpriteToLayer(layer string, sbSprite sprite.ISprite) {
 	switch layer {
 	case "0", "Background":
@@ -395,7 +628,87 @@ func (storyboard *Storyboard) SetFPS(i int) {
 	storyboard.limiter.FPS = i
 }
 
+// HitSoundPlayed queues HitSound triggers, it's meant to be registered with audio.AddListener
+func (storyboard *Storyboard) HitSoundPlayed(sampleSet int, hitsoundIndex, index int, _ float64, objNum int64) {
+	if len(storyboard.triggers) == 0 || hitsoundIndex > 3 { // slider ticks and slides don't trigger
+		return
+	}
+
+	storyboard.eventMutex.Lock()
+	defer storyboard.eventMutex.Unlock()
+
+	// Samples of a single hit are played one after another
+	if n := len(storyboard.pendingHits); n == 0 || storyboard.pendingHits[n-1].objNum != objNum {
+		storyboard.pendingHits = append(storyboard.pendingHits, hitSound{objNum: objNum, index: index})
+	}
+
+	hit := &storyboard.pendingHits[len(storyboard.pendingHits)-1]
+
+	if hitsoundIndex == 0 {
+		hit.sampleSet = sampleSet
+	} else {
+		hit.additionSet = sampleSet
+		hit.additions |= 1 << hitsoundIndex
+	}
+}
+
+// SetPassing queues Passing or Failing triggers when player's state changes
+func (storyboard *Storyboard) SetPassing(passing bool) {
+	if len(storyboard.triggers) == 0 {
+		return
+	}
+
+	storyboard.eventMutex.Lock()
+	defer storyboard.eventMutex.Unlock()
+
+	if storyboard.passing == passing {
+		return
+	}
+
+	storyboard.passing = passing
+
+	if passing {
+		storyboard.pendingStates = append(storyboard.pendingStates, triggerPassing)
+	} else {
+		storyboard.pendingStates = append(storyboard.pendingStates, triggerFailing)
+	}
+}
+
+func (storyboard *Storyboard) processTriggers(time float64) {
+	storyboard.eventMutex.Lock()
+
+	hits, states := storyboard.pendingHits, storyboard.pendingStates
+	storyboard.pendingHits, storyboard.pendingStates = nil, nil
+
+	storyboard.eventMutex.Unlock()
+
+	for _, hit := range hits {
+		for _, trigger := range storyboard.triggers {
+			if trigger.isArmed(time) && trigger.matches(hit) {
+				trigger.activate(time)
+			}
+		}
+	}
+
+	for _, state := range states {
+		for _, trigger := range storyboard.triggers {
+			if trigger.isArmed(time) && trigger.condition == state {
+				trigger.activate(time)
+			}
+		}
+	}
+}
+
 func (storyboard *Storyboard) Update(time float64) {
+	if storyboard.mutex != nil {
+		storyboard.mutex.Lock()
+		defer storyboard.mutex.Unlock()
+	}
+
+	if len(storyboard.triggers) > 0 {
+		storyboard.processTriggers(time)
+	}
+
 	storyboard.background.Update(time)
 	storyboard.pass.Update(time)
 	storyboard.foreground.Update(time)
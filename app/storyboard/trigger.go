@@ -0,0 +1,202 @@
+package storyboard
+
+import (
+	"github.com/wieku/danser-go/framework/graphics/sprite"
+	"github.com/wieku/danser-go/framework/math/animation"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	triggerHitSound = iota
+	triggerPassing
+	triggerFailing
+)
+
+var triggerSampleSets = []string{"All", "Normal", "Soft", "Drum"}
+
+var triggerAdditions = []string{"Whistle", "Finish", "Clap"}
+
+// hitSound is a set of samples played by a single hit
+type hitSound struct {
+	objNum      int64
+	sampleSet   int // 0 if normal sample wasn't played
+	additionSet int
+	additions   int // bitmask of additions like in .osu files
+	index       int
+}
+
+// Trigger holds commands played when the game event happens between trigger's start and end time. Commands' times are
+// relative to the event, starting a trigger stops other triggers of the same sprite and group.
+type Trigger struct {
+	condition int
+
+	// HitSound filters, 0 or -1 for custom index accept anything
+	sampleSet   int
+	additionSet int
+	addition    int
+	customIndex int
+
+	startTime float64
+	endTime   float64
+	group     int64
+
+	transforms []*animation.Transformation
+	duration   float64
+
+	sprite   *sprite.Sprite
+	siblings []*Trigger
+	active   []*animation.Transformation
+}
+
+// NewTrigger parses T command, returns nil if the trigger type isn't supported
+func NewTrigger(data []string) *Trigger {
+	trigger := &Trigger{customIndex: -1}
+
+	name := data[1]
+
+	switch {
+	case name == "Passing":
+		trigger.condition = triggerPassing
+	case name == "Failing":
+		trigger.condition = triggerFailing
+	case strings.HasPrefix(name, "HitSound"):
+		trigger.condition = triggerHitSound
+
+		if !trigger.parseHitSound(strings.TrimPrefix(name, "HitSound")) {
+			log.Println("Failed to parse trigger:", data)
+			return nil
+		}
+	default:
+		log.Println("Unsupported trigger:", name)
+		return nil
+	}
+
+	var err error
+
+	if trigger.startTime, err = strconv.ParseFloat(data[2], 64); err != nil {
+		log.Println("Failed to parse: ", data)
+		panic(err)
+	}
+
+	if trigger.endTime, err = strconv.ParseFloat(data[3], 64); err != nil {
+		log.Println("Failed to parse: ", data)
+		panic(err)
+	}
+
+	if len(data) > 4 {
+		if trigger.group, err = strconv.ParseInt(data[4], 10, 64); err != nil {
+			log.Println("Failed to parse: ", data)
+			panic(err)
+		}
+	}
+
+	return trigger
+}
+
+// parseHitSound parses [SampleSet][AdditionsSampleSet][Addition][CustomSampleSet] part of the trigger name
+func (trigger *Trigger) parseHitSound(filters string) bool {
+	var sets []int
+
+	for len(sets) < 2 {
+		set := -1
+
+		for i, name := range triggerSampleSets {
+			if strings.HasPrefix(filters, name) {
+				set = i
+				filters = strings.TrimPrefix(filters, name)
+
+				break
+			}
+		}
+
+		if set == -1 {
+			break
+		}
+
+		sets = append(sets, set)
+	}
+
+	for i, name := range triggerAdditions {
+		if strings.HasPrefix(filters, name) {
+			trigger.addition = 2 << i
+			filters = strings.TrimPrefix(filters, name)
+
+			break
+		}
+	}
+
+	if filters != "" {
+		index, err := strconv.ParseInt(filters, 10, 32)
+		if err != nil {
+			return false
+		}
+
+		trigger.customIndex = int(index)
+	}
+
+	switch {
+	case len(sets) == 2:
+		trigger.sampleSet, trigger.additionSet = sets[0], sets[1]
+	case len(sets) == 1 && trigger.addition > 0: // HitSoundDrumWhistle means drum whistle
+		trigger.additionSet = sets[0]
+	case len(sets) == 1:
+		trigger.sampleSet = sets[0]
+	}
+
+	return true
+}
+
+func (trigger *Trigger) Add(command []string) {
+	if parsed := parseCommand(command); parsed != nil {
+		trigger.transforms = append(trigger.transforms, parsed...)
+
+		for _, t := range parsed {
+			trigger.duration = math.Max(trigger.duration, t.GetEndTime())
+		}
+	}
+}
+
+func (trigger *Trigger) matches(hit hitSound) bool {
+	if trigger.condition != triggerHitSound {
+		return false
+	}
+
+	if trigger.sampleSet > 0 && hit.sampleSet != trigger.sampleSet {
+		return false
+	}
+
+	if trigger.additionSet > 0 && (hit.additions == 0 || hit.additionSet != trigger.additionSet) {
+		return false
+	}
+
+	if trigger.addition > 0 && hit.additions&trigger.addition == 0 {
+		return false
+	}
+
+	return trigger.customIndex < 0 || hit.index == trigger.customIndex
+}
+
+func (trigger *Trigger) isArmed(time float64) bool {
+	return time >= trigger.startTime && time <= trigger.endTime
+}
+
+// activate starts trigger's commands at given time, cancelling triggers of the same group
+func (trigger *Trigger) activate(time float64) {
+	for _, t := range trigger.siblings {
+		if t.group == trigger.group && len(t.active) > 0 {
+			trigger.sprite.RemoveTransforms(t.active)
+			t.active = nil
+		}
+	}
+
+	trigger.active = make([]*animation.Transformation, 0, len(trigger.transforms))
+
+	for _, t := range trigger.transforms {
+		trigger.active = append(trigger.active, t.Clone(time+t.GetStartTime(), time+t.GetEndTime()))
+	}
+
+	trigger.sprite.AddTransforms(trigger.active)
+}
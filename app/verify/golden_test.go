@@ -0,0 +1,137 @@
+package verify
+
+import (
+	"encoding/json"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/env"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Every directory in testdata/golden is a single case containing map.osu, replay.osr and expected.json.
+// Expected values are compared instead of the ones stored in the replay, so cases from osu! stable
+// where danser is known to diverge can be pinned down as well. Replay's mode selects the ruleset,
+// judgements of other modes are counted the way .osr stores them.
+//
+// Cases marked "stable" have to be real osu! stable replays of maps that can be redistributed,
+// with values copied from stable's results screen (the ones stored in the .osr are the same).
+// None are included yet, until then scoring is only checked against hand-derived values and danser's own output.
+const goldenDir = "testdata/golden"
+
+// Where expected values come from, so a failing danser regression isn't mistaken for a difference from osu! stable
+var expectedSources = map[string]string{
+	"stable":  "osu! stable's results screen",
+	"derived": "hit windows and score formula worked out by hand",
+	"danser":  "danser's own output, pinned to catch regressions",
+}
+
+type expectedResult struct {
+	Source string `json:"source"`
+	Note   string `json:"note"`
+
+	Score     int64 `json:"score"`
+	MaxCombo  uint  `json:"maxCombo"`
+	Count300  uint  `json:"count300"`
+	Count100  uint  `json:"count100"`
+	Count50   uint  `json:"count50"`
+	CountMiss uint  `json:"countMiss"`
+
+	// Checked only if given, osu!standard cases don't need them
+	CountGeki *uint `json:"countGeki"`
+	CountKatu *uint `json:"countKatu"`
+}
+
+func TestGoldenReplays(t *testing.T) {
+	env.Init("danser")
+
+	songsDir, err := filepath.Abs(goldenDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings.General.OsuSongsDir = songsDir
+
+	cases, err := os.ReadDir(songsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources := make(map[string]int)
+
+	for _, c := range cases {
+		if !c.IsDir() {
+			continue
+		}
+
+		t.Run(c.Name(), func(t *testing.T) {
+			sources[runGoldenCase(t, filepath.Join(songsDir, c.Name()), c.Name())]++
+		})
+	}
+
+	if sources["stable"] == 0 {
+		t.Log("no cases with values from osu! stable, divergences from it won't be caught")
+	}
+}
+
+// runGoldenCase returns the source of case's expected values
+func runGoldenCase(t *testing.T, dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "expected.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected expectedResult
+	if err = json.Unmarshal(data, &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	source, ok := expectedSources[expected.Source]
+	if !ok {
+		t.Fatalf("unknown source of expected values: %q", expected.Source)
+	}
+
+	beatMap := beatmap.NewBeatMap()
+	beatMap.Dir = name
+	beatMap.File = "map.osu"
+
+	if err = beatmap.ParseBeatMap(beatMap); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Run(beatMap, filepath.Join(dir, "replay.osr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	computed := result.Computed
+
+	check := func(field string, expected, computed uint) {
+		if expected != computed {
+			t.Errorf("%s: expected %d, got %d", field, expected, computed)
+		}
+	}
+
+	check("score", uint(expected.Score), uint(computed.Score))
+	check("max combo", expected.MaxCombo, computed.Combo)
+	check("300", expected.Count300, computed.Count300)
+	check("100", expected.Count100, computed.Count100)
+	check("50", expected.Count50, computed.Count50)
+	check("miss", expected.CountMiss, computed.CountMiss)
+
+	if expected.CountGeki != nil {
+		check("geki", *expected.CountGeki, computed.CountGeki)
+	}
+
+	if expected.CountKatu != nil {
+		check("katu", *expected.CountKatu, computed.CountKatu)
+	}
+
+	if t.Failed() {
+		t.Logf("expected values come from %s: %s", source, expected.Note)
+		t.Log("\n" + result.Report())
+	}
+
+	return expected.Source
+}
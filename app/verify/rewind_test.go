@@ -0,0 +1,128 @@
+package verify
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/dance"
+	"github.com/wieku/danser-go/app/graphics"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/env"
+	"github.com/wieku/danser-go/framework/math/vector"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Short enough for every golden case to have several snapshots to go back to
+const rewindSnapshotInterval = 250.0
+
+type rewindState struct {
+	score osu.Score
+	hp    float64
+}
+
+// Replaying from a restored snapshot has to go through the same states as the uninterrupted run
+func TestRewindGoldenReplays(t *testing.T) {
+	env.Init("danser")
+
+	songsDir, err := filepath.Abs(goldenDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings.General.OsuSongsDir = songsDir
+
+	cases, err := os.ReadDir(songsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		if !c.IsDir() {
+			continue
+		}
+
+		t.Run(c.Name(), func(t *testing.T) {
+			beatMap := beatmap.NewBeatMap()
+			beatMap.Dir = c.Name()
+			beatMap.File = "map.osu"
+
+			if err := beatmap.ParseBeatMap(beatMap); err != nil {
+				t.Fatal(err)
+			}
+
+			loaded, replay, err := load(beatMap, filepath.Join(songsDir, c.Name(), "replay.osr"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			controller, ok := loaded.(*dance.ReplayController)
+			if !ok {
+				t.Skip("only osu!standard replays can be rewound")
+			}
+
+			controller.SetSnapshotInterval(rewindSnapshotInterval)
+
+			if !controller.EnableSeeking() {
+				t.Fatal("replay controller should be seekable")
+			}
+
+			// Additional listeners like the hit log can't get judgements simulated again after rewinding
+			var judgements []int64
+
+			controller.GetRuleset().AddListener(func(_ *graphics.Cursor, time int64, number int64, _ vector.Vector2d, _ osu.HitResult, _ osu.ComboResult, _ performance.PPv2Results, _ int64) {
+				judgements = append(judgements, number)
+			})
+
+			cursor := controller.GetCursors()[0]
+
+			getState := func() rewindState {
+				return rewindState{
+					score: controller.GetRuleset().GetScore(cursor),
+					hp:    controller.GetRuleset().GetHP(cursor),
+				}
+			}
+
+			start, end := simulationBounds(beatMap, replay)
+
+			states := make([]rewindState, 0, int(end-start)+1)
+
+			for time := start; time <= end; time++ {
+				controller.Update(time, 1)
+				states = append(states, getState())
+			}
+
+			judged := len(judgements)
+
+			// Every rewind goes further back than the previous one, so snapshots taken again after the first one are used as well
+			for _, target := range []float64{end - 1000, (start + end) / 2, start + rewindSnapshotInterval*2} {
+				from := controller.Rewind(target)
+
+				if from > target {
+					t.Fatalf("restored snapshot at %.0f is after the requested time %.0f", from, target)
+				}
+
+				if from <= start {
+					t.Fatalf("rewinding to %.0f restored the initial snapshot", target)
+				}
+
+				if state := getState(); state != states[int(from-start)] {
+					t.Fatalf("expected %+v right after rewinding to %.0f, got %+v", states[int(from-start)], from, state)
+				}
+
+				for time := from + 1; time <= end; time++ {
+					controller.Update(time, 1)
+
+					if state := getState(); state != states[int(time-start)] {
+						t.Fatalf("after rewinding to %.0f expected %+v at %.0f, got %+v", from, states[int(time-start)], time, state)
+					}
+				}
+			}
+
+			if len(judgements) != judged {
+				t.Fatalf("additional listener got %d judgements instead of %d", len(judgements), judged)
+			}
+		})
+	}
+}
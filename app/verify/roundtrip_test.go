@@ -0,0 +1,91 @@
+package verify
+
+import (
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/dance"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/framework/env"
+	"path/filepath"
+	"testing"
+)
+
+// Replay saved from danser's own play has to be judged the same way when it's played back
+func TestRecordedReplayRoundTrip(t *testing.T) {
+	env.Init("danser")
+
+	songsDir, err := filepath.Abs(goldenDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outDir := t.TempDir()
+
+	settings.General.OsuSongsDir = songsDir
+	settings.Gameplay.SaveReplays = true
+	settings.Gameplay.SavedReplaysDir = outDir
+	settings.HEADLESS = true
+	settings.KNOCKOUT = true
+	settings.KNOCKOUTREPLAYS = []string{}
+	settings.REPLAY = ""
+	settings.TAG = 1
+
+	defer func() {
+		settings.Gameplay.SaveReplays = false
+	}()
+
+	loadMap := func() *beatmap.BeatMap {
+		beatMap := beatmap.NewBeatMap()
+		beatMap.Dir = "fc"
+		beatMap.File = "map.osu"
+
+		if err := beatmap.ParseBeatMap(beatMap); err != nil {
+			t.Fatal(err)
+		}
+
+		return beatMap
+	}
+
+	beatMap := loadMap()
+	beatmap.ParseTimingPointsAndPauses(beatMap)
+	beatmap.ParseObjects(beatMap, false, false)
+
+	controller := dance.NewReplayController().(*dance.ReplayController)
+	controller.SetBeatMap(beatMap)
+	controller.InitCursors()
+
+	start := beatMap.HitObjects[0].GetStartTime() - beatMap.Diff.Preempt - 1000
+	end := beatMap.HitObjects[len(beatMap.HitObjects)-1].GetEndTime() + 2000
+
+	for time := start; time <= end; time++ {
+		controller.Update(time, 1)
+	}
+
+	played := controller.GetRuleset().GetScore(controller.GetCursors()[0])
+
+	files, err := filepath.Glob(filepath.Join(outDir, "*.osr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("expected 1 saved replay, found %d", len(files))
+	}
+
+	result, err := Run(loadMap(), files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Mods.Active(difficulty.Autoplay) {
+		t.Errorf("saved replay has Autoplay mod: %s", result.Mods.String())
+	}
+
+	if !result.Matches() {
+		t.Errorf("stored score doesn't match the played back one\n%s", result.Report())
+	}
+
+	if result.Computed.Score != played.Score || result.Computed.Combo != played.Combo {
+		t.Errorf("expected score %d and combo %d from the live play, got %d and %d", played.Score, played.Combo, result.Computed.Score, result.Computed.Combo)
+	}
+}
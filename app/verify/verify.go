@@ -0,0 +1,220 @@
+package verify
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"github.com/wieku/danser-go/app/beatmap"
+	"github.com/wieku/danser-go/app/beatmap/difficulty"
+	"github.com/wieku/danser-go/app/dance"
+	"github.com/wieku/danser-go/app/rulesets/osu"
+	"github.com/wieku/danser-go/app/rulesets/osu/performance"
+	"github.com/wieku/danser-go/app/settings"
+	"github.com/wieku/danser-go/app/utils"
+	"github.com/wieku/rplpa"
+	"math"
+	"os"
+	"strings"
+)
+
+// Result holds the values stored in the replay next to the ones computed by danser's ruleset
+type Result struct {
+	Username string
+	Mods     difficulty.Modifier
+
+	Stored   osu.Score
+	Computed osu.Score
+}
+
+// Run simulates the replay on given beatmap without creating a window, initializing audio or rendering anything.
+// Beatmap should be freshly loaded (without parsed objects) and has to be the one the replay was made on.
+// Judgements of other modes are counted the way .osr stores them, e.g. taiko's strong hits as geki and katu or mania's MAX as geki.
+func Run(beatMap *beatmap.BeatMap, replayPath string) (*Result, error) {
+	controller, replay, err := load(beatMap, replayPath)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := simulationBounds(beatMap, replay)
+
+	for time := start; time <= end; time++ {
+		controller.Update(time, 1)
+	}
+
+	return &Result{
+		Username: replay.Username,
+		Mods:     difficulty.Modifier(replay.Mods),
+		Stored: osu.Score{
+			Score:        int64(replay.Score),
+			Combo:        uint(replay.MaxCombo),
+			PerfectCombo: replay.Fullcombo,
+			Count300:     uint(replay.Count300),
+			CountGeki:    uint(replay.CountGeki),
+			Count100:     uint(replay.Count100),
+			CountKatu:    uint(replay.CountKatu),
+			Count50:      uint(replay.Count50),
+			CountMiss:    uint(replay.CountMiss),
+		},
+		Computed: getScore(controller),
+	}, nil
+}
+
+// load parses beatmap's objects and creates a headless controller of replay's mode
+func load(beatMap *beatmap.BeatMap, replayPath string) (dance.Controller, *rplpa.Replay, error) {
+	data, err := os.ReadFile(replayPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replay, err := rplpa.ParseReplay(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if replay.PlayMode < settings.ModeOsu || replay.PlayMode > settings.ModeMania {
+		return nil, nil, fmt.Errorf("unknown game mode: %d", replay.PlayMode)
+	}
+
+	if replay.ReplayData == nil || len(replay.ReplayData) < 2 {
+		return nil, nil, fmt.Errorf("replay is missing input data")
+	}
+
+	settings.HEADLESS = true
+	settings.KNOCKOUT = true
+	settings.PLAY = false
+	settings.REPLAY = replayPath
+	settings.MODE = int(replay.PlayMode)
+
+	beatMap.Diff.SetMods(difficulty.Modifier(replay.Mods))
+	beatmap.ParseTimingPointsAndPauses(beatMap)
+	beatmap.ParseObjects(beatMap, false, false)
+
+	if len(beatMap.HitObjects) == 0 {
+		return nil, nil, fmt.Errorf("beatmap has no hit objects")
+	}
+
+	var controller dance.Controller
+
+	switch settings.MODE {
+	case settings.ModeTaiko:
+		controller = dance.NewTaikoController()
+	case settings.ModeCatch:
+		controller = dance.NewCatchController()
+	case settings.ModeMania:
+		controller = dance.NewManiaController()
+	default:
+		controller = dance.NewReplayController()
+	}
+
+	controller.SetBeatMap(beatMap)
+	controller.InitCursors()
+
+	return controller, replay, nil
+}
+
+// getScore returns the score of replay's player with judgements counted the way .osr stores them
+func getScore(controller dance.Controller) osu.Score {
+	cursor := controller.GetCursors()[0]
+
+	switch controller := controller.(type) {
+	case *dance.TaikoController:
+		score := controller.GetRuleset().GetScore(cursor)
+
+		return osu.Score{
+			Score:        score.Score,
+			Accuracy:     score.Accuracy,
+			Grade:        score.Grade,
+			Combo:        score.Combo,
+			PerfectCombo: score.PerfectCombo,
+			Count300:     score.CountGreat,
+			CountGeki:    score.CountGreatStrong,
+			Count100:     score.CountOk,
+			CountKatu:    score.CountOkStrong,
+			CountMiss:    score.CountMiss,
+			PP:           performance.PPv2Results{Total: score.PP.Total},
+		}
+	case *dance.CatchController:
+		return controller.GetRuleset().GetScore(cursor)
+	case *dance.ManiaController:
+		score := controller.GetRuleset().GetScore(cursor)
+
+		return osu.Score{
+			Score:        score.Score,
+			Accuracy:     score.Accuracy,
+			Grade:        score.Grade,
+			Combo:        score.Combo,
+			PerfectCombo: score.PerfectCombo,
+			Count300:     score.Count300,
+			CountGeki:    score.CountMax,
+			Count100:     score.Count100,
+			CountKatu:    score.Count200,
+			Count50:      score.Count50,
+			CountMiss:    score.CountMiss,
+		}
+	}
+
+	return controller.(*dance.ReplayController).GetRuleset().GetScore(cursor)
+}
+
+// simulationBounds returns the time range covering both the beatmap and the replay
+func simulationBounds(beatMap *beatmap.BeatMap, replay *rplpa.Replay) (start, end float64) {
+	replayEnd := 0.0
+	for _, frame := range replay.ReplayData {
+		if frame.Time > 0 {
+			replayEnd += float64(frame.Time)
+		}
+	}
+
+	start = math.Min(0, beatMap.HitObjects[0].GetStartTime()-beatMap.Diff.Preempt) - 1000
+	end = math.Max(beatMap.HitObjects[len(beatMap.HitObjects)-1].GetEndTime()+float64(beatMap.Diff.Hit50), replayEnd) + 1000
+
+	return
+}
+
+// Matches returns true if the score, max combo and all judgement counts are the same
+func (result *Result) Matches() bool {
+	s, c := result.Stored, result.Computed
+
+	return s.Score == c.Score &&
+		s.Combo == c.Combo &&
+		s.Count300 == c.Count300 &&
+		s.CountGeki == c.CountGeki &&
+		s.Count100 == c.Count100 &&
+		s.CountKatu == c.CountKatu &&
+		s.Count50 == c.Count50 &&
+		s.CountMiss == c.CountMiss
+}
+
+// Report renders a table comparing stored and computed values
+func (result *Result) Report() string {
+	s, c := result.Stored, result.Computed
+
+	tableString := &strings.Builder{}
+	table := tablewriter.NewWriter(tableString)
+	table.SetHeader([]string{"", "Stored", "Computed", ""})
+
+	addRow := func(name string, stored, computed uint) {
+		mark := ""
+		if stored != computed {
+			mark = "MISMATCH"
+		}
+
+		table.Append([]string{name, utils.Humanize(stored), utils.Humanize(computed), mark})
+	}
+
+	addRow("Score", uint(s.Score), uint(c.Score))
+	addRow("Max Combo", s.Combo, c.Combo)
+	addRow("300", s.Count300, c.Count300)
+	addRow("Geki", s.CountGeki, c.CountGeki)
+	addRow("100", s.Count100, c.Count100)
+	addRow("Katu", s.CountKatu, c.CountKatu)
+	addRow("50", s.Count50, c.Count50)
+	addRow("Miss", s.CountMiss, c.CountMiss)
+
+	table.Append([]string{"Accuracy", "", fmt.Sprintf("%.2f", c.Accuracy), ""})
+	table.Append([]string{"Grade", "", c.Grade.String(), ""})
+	table.Append([]string{"PP", "", fmt.Sprintf("%.2f", c.PP.Total), ""})
+
+	table.Render()
+
+	return tableString.String()
+}
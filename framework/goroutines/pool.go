@@ -0,0 +1,72 @@
+package goroutines
+
+import (
+	"sync"
+)
+
+// Pool runs parts of a job on a fixed set of goroutines so hot loops don't have to start new ones every frame
+type Pool struct {
+	workers int
+	jobs    chan func()
+}
+
+func NewPool(workers int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	pool := &Pool{
+		workers: workers,
+		jobs:    make(chan func(), workers*4),
+	}
+
+	for i := 0; i < workers; i++ {
+		Run(func() {
+			for job := range pool.jobs {
+				job()
+			}
+		})
+	}
+
+	return pool
+}
+
+// ParallelFor splits [0, n) into ranges of at least minChunk elements and calls fn on them concurrently.
+// Calling goroutine processes the first range and waits until the rest is done.
+func (pool *Pool) ParallelFor(n, minChunk int, fn func(from, to int)) {
+	if minChunk < 1 {
+		minChunk = 1
+	}
+
+	chunks := n / minChunk
+	if chunks > pool.workers+1 {
+		chunks = pool.workers + 1
+	}
+
+	if chunks <= 1 {
+		fn(0, n)
+		return
+	}
+
+	size := (n + chunks - 1) / chunks
+
+	var wg sync.WaitGroup
+
+	for from := size; from < n; from += size {
+		start, end := from, from+size
+		if end > n {
+			end = n
+		}
+
+		wg.Add(1)
+
+		pool.jobs <- func() {
+			defer wg.Done()
+			fn(start, end)
+		}
+	}
+
+	fn(0, size)
+
+	wg.Wait()
+}
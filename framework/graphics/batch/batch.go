@@ -38,6 +38,9 @@ type QuadBatch struct {
 	drawing       bool
 	maxSprites    int
 	chunkOffset   int
+
+	// textures holds textures of sprites written by DrawInstances
+	textures []texture.Texture
 }
 
 func NewQuadBatch() *QuadBatch {
@@ -168,18 +171,27 @@ func (batch *QuadBatch) Flush() {
 		return
 	}
 
-	if batch.texture.GetLocation() == 0 {
-		batch.texture.Bind(0)
-	}
+	batch.vao.UnmapVBO("quads", 0, batch.currentFloats)
 
-	batch.shader.SetUniform("tex", int32(batch.texture.GetLocation()))
+	batch.drawInstances(batch.texture, 0, batch.currentSize)
 
-	batch.vao.UnmapVBO("quads", 0, batch.currentFloats)
+	batch.nextChunk()
+}
+
+// drawInstances draws count sprites starting from the given one in the current chunk of the instance buffer
+func (batch *QuadBatch) drawInstances(texture texture.Texture, first, count int) {
+	if texture.GetLocation() == 0 {
+		texture.Bind(0)
+	}
 
-	batch.vao.DrawInstanced(batch.chunkOffset/batch.vertexSize, batch.currentSize)
+	batch.shader.SetUniform("tex", int32(texture.GetLocation()))
 
-	statistic.Add(statistic.SpritesDrawn, int64(batch.currentSize))
+	batch.vao.DrawInstanced(batch.chunkOffset/batch.vertexSize+first, count)
 
+	statistic.Add(statistic.SpritesDrawn, int64(count))
+}
+
+func (batch *QuadBatch) nextChunk() {
 	nextChunk := batch.vao.MapVBO("quads", batch.maxSprites*batch.vertexSize)
 
 	batch.data = nextChunk.Data
@@ -333,6 +345,23 @@ func (batch *QuadBatch) DrawStObject(position, origin, scale vector.Vector2d, fl
 
 	batch.bind(texture.Texture)
 
+	batch.PrepareStObject(batch.data[batch.currentFloats:], position, origin, scale, flipX, flipY, rotation, color, additive, texture)
+
+	batch.currentFloats += batch.vertexSize
+	batch.currentSize++
+
+	if batch.currentSize >= batch.maxSprites {
+		batch.Flush()
+	}
+}
+
+// PrepareStObject writes instance data of DrawStObject to dst without drawing it, so many objects can be prepared concurrently
+// inside DrawInstances. Returns false if the object is not visible.
+func (batch *QuadBatch) PrepareStObject(dst []float32, position, origin, scale vector.Vector2d, flipX, flipY bool, rotation float64, color color2.Color, additive bool, texture texture.TextureRegion) bool {
+	if texture.Texture == nil || color.A*batch.color.A < 0.001 {
+		return false
+	}
+
 	scaleX := float32(scale.X * float64(texture.Width) / 2 * batch.scale.X * batch.subscale.X)
 	scaleY := float32(scale.Y * float64(texture.Height) / 2 * batch.scale.Y * batch.subscale.Y)
 
@@ -366,30 +395,75 @@ func (batch *QuadBatch) DrawStObject(position, origin, scale vector.Vector2d, fl
 		add = 0
 	}
 
-	idx := batch.currentFloats
+	dst[0] = packUV(origin.X32()*0.5+0.5, origin.Y32()*0.5+0.5)
+	dst[1] = scaleX
+	dst[2] = scaleY
+	dst[3] = posX
+	dst[4] = posY
+	dst[5] = rot
+	dst[6] = u1
+	dst[7] = u2
+	dst[8] = v1
+	dst[9] = v2
+	dst[10] = layer
+	dst[11] = color2.PackFloat(r, g, b, a)
+	dst[12] = add
+
+	return true
+}
 
-	batch.data[idx] = packUV(origin.X32()*0.5+0.5, origin.Y32()*0.5+0.5)
-	batch.data[idx+1] = scaleX
-	batch.data[idx+2] = scaleY
-	batch.data[idx+3] = posX
-	batch.data[idx+4] = posY
-	batch.data[idx+5] = rot
-	batch.data[idx+6] = u1
-	batch.data[idx+7] = u2
-	batch.data[idx+8] = v1
-	batch.data[idx+9] = v2
-	batch.data[idx+10] = layer
-	batch.data[idx+11] = color2.PackFloat(r, g, b, a)
-	batch.data[idx+12] = add
+// DrawInstances lets fill write instance data of count sprites straight into the mapped instance buffer, so it can be done concurrently.
+// Sprites are given to fill in parts of at most batch's size, first is the index of the first one in the part. fill has to set the texture
+// of every written sprite in textures, sprites left with nil aren't drawn. Every run of sprites sharing a texture takes a single draw call.
+func (batch *QuadBatch) DrawInstances(count int, fill func(first int, data []float32, textures []texture.Texture)) {
+	batch.Flush()
 
-	batch.currentFloats += batch.vertexSize
-	batch.currentSize++
+	if len(batch.textures) < batch.maxSprites {
+		batch.textures = make([]texture.Texture, batch.maxSprites)
+	}
 
-	if batch.currentSize >= batch.maxSprites {
-		batch.Flush()
+	for first := 0; first < count; first += batch.maxSprites {
+		size := count - first
+		if size > batch.maxSprites {
+			size = batch.maxSprites
+		}
+
+		textures := batch.textures[:size]
+
+		for i := range textures {
+			textures[i] = nil
+		}
+
+		fill(first, batch.data[:size*batch.vertexSize], textures)
+
+		batch.vao.UnmapVBO("quads", 0, size*batch.vertexSize)
+
+		runStart := 0
+
+		for i := 1; i <= size; i++ {
+			if i < size && textures[i] == textures[runStart] {
+				continue
+			}
+
+			if textures[runStart] != nil {
+				batch.texture = textures[runStart]
+				batch.drawInstances(textures[runStart], runStart, i-runStart)
+			}
+
+			runStart = i
+		}
+
+		statistic.Add(statistic.SpritesPrepared, int64(size))
+
+		batch.nextChunk()
 	}
 }
 
+// GetInstanceSize returns the number of floats a single sprite takes in the instance buffer
+func (batch *QuadBatch) GetInstanceSize() int {
+	return batch.vertexSize
+}
+
 func (batch *QuadBatch) SetCamera(camera mgl32.Mat4) {
 	if batch.Projection == camera {
 		return
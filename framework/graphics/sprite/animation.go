@@ -58,3 +58,13 @@ func (animation *Animation) Draw(time float64, batch *batch.QuadBatch) {
 
 	animation.Sprite.Draw(time, batch)
 }
+
+func (animation *Animation) prepareInstance(time float64, batch *batch.QuadBatch, dst []float32) texture.Texture {
+	if animation.textures == nil || len(animation.textures) == 0 || animation.textures[animation.currentFrame] == nil {
+		return nil
+	}
+
+	animation.Texture = animation.textures[animation.currentFrame]
+
+	return animation.Sprite.prepareInstance(time, batch, dst)
+}
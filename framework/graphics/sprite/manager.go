@@ -1,12 +1,35 @@
 package sprite
 
 import (
+	"github.com/wieku/danser-go/framework/goroutines"
 	"github.com/wieku/danser-go/framework/graphics/batch"
+	"github.com/wieku/danser-go/framework/graphics/texture"
+	"github.com/wieku/danser-go/framework/qpc"
+	"github.com/wieku/danser-go/framework/statistic"
 	"math"
+	"runtime"
 	"sort"
 	"sync"
+	"sync/atomic"
 )
 
+// Managers with fewer sprites than that are updated and drawn on the calling goroutine
+const parallelThreshold = 1000
+
+// Smallest number of sprites given to a single worker
+const parallelChunk = 256
+
+var workerPool *goroutines.Pool
+var workerPoolOnce sync.Once
+
+func getWorkerPool() *goroutines.Pool {
+	workerPoolOnce.Do(func() {
+		workerPool = goroutines.NewPool(runtime.NumCPU() - 1)
+	})
+
+	return workerPool
+}
+
 type Manager struct {
 	spriteQueue     []ISprite
 	spriteProcessed []ISprite
@@ -17,12 +40,20 @@ type Manager struct {
 
 	mutex *sync.Mutex
 	dirty bool
+
+	parallel bool
 }
 
 func NewManager() *Manager {
 	return &Manager{mutex: &sync.Mutex{}}
 }
 
+// SetParallel makes the manager update and prepare big amounts of sprites on a worker pool.
+// Sprites can't depend on each other in Update, also Draw can't change batch's state.
+func (manager *Manager) SetParallel(parallel bool) {
+	manager.parallel = parallel
+}
+
 func (manager *Manager) Add(sprite ISprite) {
 	startTime := sprite.GetStartTime()
 	if sprite.IsAlwaysVisible() {
@@ -70,9 +101,20 @@ func (manager *Manager) Update(time float64) {
 		manager.spriteQueue = manager.spriteQueue[toRemove:]
 	}
 
+	if manager.parallel && len(manager.spriteProcessed) >= parallelThreshold {
+		getWorkerPool().ParallelFor(len(manager.spriteProcessed), parallelChunk, func(from, to int) {
+			for _, c := range manager.spriteProcessed[from:to] {
+				c.Update(time)
+			}
+		})
+	} else {
+		for _, c := range manager.spriteProcessed {
+			c.Update(time)
+		}
+	}
+
 	for i := 0; i < len(manager.spriteProcessed); i++ {
 		c := manager.spriteProcessed[i]
-		c.Update(time)
 
 		if time >= c.GetEndTime() && !c.IsAlwaysVisible() {
 			copy(manager.spriteProcessed[i:], manager.spriteProcessed[i+1:])
@@ -139,9 +181,80 @@ func (manager *Manager) Draw(time float64, batch *batch.QuadBatch) {
 
 	manager.mutex.Unlock()
 
+	if manager.parallel && manager.visibleObjects >= parallelThreshold {
+		manager.drawPrepared(time, batch)
+		return
+	}
+
 	for i := 0; i < manager.visibleObjects; i++ {
 		if manager.drawArray[i] != nil {
 			manager.drawArray[i].Draw(time, batch)
 		}
 	}
 }
+
+// drawPrepared computes instance data of sprites on the worker pool straight into batch's instance buffer
+func (manager *Manager) drawPrepared(time float64, batch *batch.QuadBatch) {
+	var wallTime, workTime int64
+
+	for i := 0; i < manager.visibleObjects; {
+		if manager.drawArray[i] == nil {
+			i++
+			continue
+		}
+
+		if getPreparable(manager.drawArray[i]) == nil {
+			// Sprites that can't be prepared are drawn in place to keep the depth order
+			manager.drawArray[i].Draw(time, batch)
+
+			i++
+			continue
+		}
+
+		end := i + 1
+		for end < manager.visibleObjects && getPreparable(manager.drawArray[end]) != nil {
+			end++
+		}
+
+		sprites := manager.drawArray[i:end]
+
+		batch.DrawInstances(len(sprites), func(first int, data []float32, textures []texture.Texture) {
+			size := batch.GetInstanceSize()
+
+			start := qpc.GetNanoTime()
+
+			getWorkerPool().ParallelFor(len(textures), parallelChunk, func(from, to int) {
+				workStart := qpc.GetNanoTime()
+
+				for j := from; j < to; j++ {
+					textures[j] = getPreparable(sprites[first+j]).prepareInstance(time, batch, data[j*size:(j+1)*size])
+				}
+
+				atomic.AddInt64(&workTime, qpc.GetNanoTime()-workStart)
+			})
+
+			wallTime += qpc.GetNanoTime() - start
+		})
+
+		i = end
+	}
+
+	statistic.Add(statistic.SpritePrepareTime, wallTime/1e3)
+	statistic.Add(statistic.SpritePrepareWork, workTime/1e3)
+}
+
+type preparableSprite interface {
+	prepareInstance(time float64, batch *batch.QuadBatch, dst []float32) texture.Texture
+}
+
+// getPreparable returns sprites whose instance data can be computed concurrently, types embedding Sprite may need to draw on the GL thread so they're not included
+func getPreparable(s ISprite) preparableSprite {
+	switch s := s.(type) {
+	case *Sprite:
+		return s
+	case *Animation:
+		return s
+	}
+
+	return nil
+}
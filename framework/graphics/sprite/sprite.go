@@ -160,6 +160,23 @@ func (sprite *Sprite) ClearTransformationsOfType(transformationType animation.Tr
 	}
 }
 
+// RemoveTransforms removes given transformations, ones that already finished are skipped
+func (sprite *Sprite) RemoveTransforms(transformations []*animation.Transformation) {
+	toRemove := make(map[*animation.Transformation]bool, len(transformations))
+
+	for _, t := range transformations {
+		toRemove[t] = true
+	}
+
+	for i := 0; i < len(sprite.transforms); i++ {
+		if toRemove[sprite.transforms[i]] {
+			copy(sprite.transforms[i:], sprite.transforms[i+1:])
+			sprite.transforms = sprite.transforms[:len(sprite.transforms)-1]
+			i--
+		}
+	}
+}
+
 func (sprite *Sprite) AdjustTimesToTransformations() {
 	if len(sprite.transforms) == 0 {
 		return
@@ -203,6 +220,26 @@ func (sprite *Sprite) UpdateAndDraw(time float64, batch *batch.QuadBatch) {
 }
 
 func (sprite *Sprite) Draw(time float64, batch *batch.QuadBatch) {
+	region, position, scale, color, ok := sprite.getDrawData(time)
+	if !ok {
+		return
+	}
+
+	batch.DrawStObject(position, sprite.origin, scale, sprite.flipX, sprite.flipY, sprite.rotation, color, sprite.additive, region)
+}
+
+// prepareInstance writes sprite's instance data to dst instead of drawing it, returns nil if sprite is not visible
+func (sprite *Sprite) prepareInstance(time float64, batch *batch.QuadBatch, dst []float32) texture.Texture {
+	region, position, scale, color, ok := sprite.getDrawData(time)
+	if !ok || !batch.PrepareStObject(dst, position, sprite.origin, scale, sprite.flipX, sprite.flipY, sprite.rotation, color, sprite.additive, region) {
+		return nil
+	}
+
+	return region.Texture
+}
+
+// getDrawData returns sprite's texture region, position, scale and color after applying cuts, ok is false if sprite is not visible
+func (sprite *Sprite) getDrawData(time float64) (region texture.TextureRegion, position, scale vector.Vector2d, color color2.Color, ok bool) {
 	if (!sprite.showForever && time < sprite.startTime && time >= sprite.endTime) || sprite.color.A < 0.01 {
 		return
 	}
@@ -216,9 +253,9 @@ func (sprite *Sprite) Draw(time float64, batch *batch.QuadBatch) {
 		alpha -= math32.Ceil(sprite.color.A) - 1 // HACK, some osu! storyboards use alpha higher than 1 to make flashing effect
 	}
 
-	region := *sprite.Texture
-	position := sprite.position
-	scale := sprite.scale.Abs()
+	region = *sprite.Texture
+	position = sprite.position
+	scale = sprite.scale.Abs()
 
 	if sprite.cutX > 0.0 {
 		if math.Abs(sprite.origin.X-sprite.cutOrigin.X) > 0 {
@@ -246,7 +283,7 @@ func (sprite *Sprite) Draw(time float64, batch *batch.QuadBatch) {
 		region.V2 = (region.V2-middle)*ratio + middle
 	}
 
-	batch.DrawStObject(position, sprite.origin, scale, sprite.flipX, sprite.flipY, sprite.rotation, color2.NewRGBA(sprite.color.R, sprite.color.G, sprite.color.B, alpha), sprite.additive, region)
+	return region, position, scale, color2.NewRGBA(sprite.color.R, sprite.color.G, sprite.color.B, alpha), true
 }
 
 func (sprite *Sprite) GetOrigin() vector.Vector2d {
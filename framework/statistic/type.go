@@ -11,4 +11,10 @@ const (
 	VerticesDrawn
 	VertexUpload
 	SpritesDrawn
+	SpritesPrepared
+
+	// SpritePrepareTime is the time in microseconds spent preparing sprites on the worker pool,
+	// SpritePrepareWork sums the time of all workers, so their ratio is the speedup over a single thread
+	SpritePrepareTime
+	SpritePrepareWork
 )
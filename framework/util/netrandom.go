@@ -0,0 +1,103 @@
+package util
+
+import "math"
+
+const (
+	netMBig  = math.MaxInt32
+	netMSeed = 161803398
+)
+
+// NetRandom is .NET's seeded System.Random (Knuth's subtractive generator), osu!lazer's mods use it so it's needed to reproduce their results
+type NetRandom struct {
+	seedArray [56]int32
+	iNext     int
+	iNextP    int
+}
+
+func NewNetRandom(seed int32) *NetRandom {
+	r := &NetRandom{iNextP: 21}
+
+	subtraction := int32(netMBig)
+	if seed != math.MinInt32 {
+		subtraction = seed
+		if subtraction < 0 {
+			subtraction = -subtraction
+		}
+	}
+
+	mj := netMSeed - subtraction
+	r.seedArray[55] = mj
+
+	mk := int32(1)
+	ii := 0
+
+	for i := 1; i < 55; i++ {
+		if ii += 21; ii >= 55 {
+			ii -= 55
+		}
+
+		r.seedArray[ii] = mk
+
+		mk = mj - mk
+		if mk < 0 {
+			mk += netMBig
+		}
+
+		mj = r.seedArray[ii]
+	}
+
+	for k := 1; k < 5; k++ {
+		for i := 1; i < 56; i++ {
+			n := i + 30
+			if n >= 55 {
+				n -= 55
+			}
+
+			r.seedArray[i] -= r.seedArray[1+n]
+			if r.seedArray[i] < 0 {
+				r.seedArray[i] += netMBig
+			}
+		}
+	}
+
+	return r
+}
+
+func (r *NetRandom) internalSample() int32 {
+	locINext := r.iNext + 1
+	if locINext >= 56 {
+		locINext = 1
+	}
+
+	locINextP := r.iNextP + 1
+	if locINextP >= 56 {
+		locINextP = 1
+	}
+
+	retVal := r.seedArray[locINext] - r.seedArray[locINextP]
+
+	if retVal == netMBig {
+		retVal--
+	}
+
+	if retVal < 0 {
+		retVal += netMBig
+	}
+
+	r.seedArray[locINext] = retVal
+
+	r.iNext = locINext
+	r.iNextP = locINextP
+
+	return retVal
+}
+
+// Next returns a random int32 in [0, MaxInt32) range
+func (r *NetRandom) Next() int32 {
+	return r.internalSample()
+}
+
+// NextDouble returns a random float64 in [0, 1) range
+func (r *NetRandom) NextDouble() float64 {
+	return float64(r.internalSample()) * (1.0 / netMBig)
+}